@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -75,6 +76,12 @@ func main() {
 	versionRepo := repository.NewDocumentVersionRepository(db)
 	metadataRepo := repository.NewDocumentMetadataRepository(db)
 	searchIndexRepo := repository.NewSearchIndexRepository(db)
+	searchStatsRepo := repository.NewSearchStatsRepository(db)
+	synonymRepo := repository.NewSynonymRepository(db)
+	indexingJobRepo := repository.NewIndexingJobRepository(db)
+	searchAnalyticsRepo := repository.NewSearchAnalyticsRepository(db)
+	embeddingModelRepo := repository.NewEmbeddingModelRepository(db)
+	embeddingCacheRepo := repository.NewEmbeddingCacheRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	passwordResetTokenRepo := repository.NewPasswordResetTokenRepository(db)
 	metricsRepo := repository.NewMetricsRepository(db)
@@ -99,25 +106,92 @@ func main() {
 	cacheService := service.NewMemoryCache()
 
 	// 初始化嵌入服务
-	// 优先使用 OpenAI 服务，如果 API Key 未设置，则使用模拟服务
+	// EMBEDDING_PROVIDER 可选 openai、http（Ollama、TEI 或自定义JSON接口）、hashing（离线特征哈希，无需网络）、mock；
+	// 未设置时优先使用 OpenAI 服务，如果 API Key 未设置，则使用模拟服务
 	openaiAPIKey := getEnv("OPENAI_API_KEY", "")
 	openaiModel := getEnv("OPENAI_MODEL", "")
+	embeddingProvider := getEnv("EMBEDDING_PROVIDER", "")
+	if embeddingProvider == "" {
+		embeddingProvider = "mock"
+		if openaiAPIKey != "" {
+			embeddingProvider = "openai"
+		}
+	}
 	var embeddingService service.EmbeddingService
-	if openaiAPIKey != "" {
+	switch embeddingProvider {
+	case "openai":
+		if openaiAPIKey == "" {
+			log.Fatal("EMBEDDING_PROVIDER=openai requires OPENAI_API_KEY")
+		}
 		log.Printf("Using OpenAI embedding service with model: %s", openaiModel)
 		embeddingService = service.NewOpenAIEmbeddingService(openaiAPIKey, openaiModel)
-	} else {
-		log.Println("OpenAI API key not provided, using mock embedding service")
+	case "hashing":
+		embeddingService, err = service.NewHashingEmbeddingService(service.NewHashingEmbeddingConfigFromEnv())
+		if err != nil {
+			log.Fatalf("Failed to create hashing embedding service: %v", err)
+		}
+		log.Printf("Using offline hashing embedding service: %s", embeddingService.ModelName())
+	case "http":
+		httpConfig, err := service.NewHTTPEmbeddingConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid HTTP embedding configuration: %v", err)
+		}
+		embeddingService, err = service.NewHTTPEmbeddingService(httpConfig)
+		if err != nil {
+			log.Fatalf("Failed to create HTTP embedding service: %v", err)
+		}
+		log.Printf("Using HTTP embedding service (%s) at %s: %s", httpConfig.Preset, httpConfig.URL, embeddingService.ModelName())
+	case "mock":
+		log.Println("Using mock embedding service")
 		embeddingService = service.NewMockEmbeddingService()
+	default:
+		log.Fatalf("Unknown EMBEDDING_PROVIDER: %s", embeddingProvider)
+	}
+	// 分批、限流、重试并由断路器保护，失败时由索引任务重试
+	embeddingService = service.NewBatchEmbeddingService(embeddingService, service.NewEmbeddingBatchConfigFromEnv(), nil)
+	// 超过模型token上限的文本切分为多个窗口，窗口向量按模型配置的策略合并或只使用第一个窗口
+	embeddingService = service.NewLongTextEmbeddingService(embeddingService, service.NewLongTextEmbeddingConfigFromEnv())
+	// 按模型和文本哈希持久化缓存向量，相同内容在各版本间和查询时不重复调用嵌入服务
+	embeddingCache := service.NewCachedEmbeddingService(embeddingService, embeddingCacheRepo, service.NewEmbeddingCacheConfigFromEnv())
+	embeddingService = embeddingCache
+
+	// 设置pgvector向量检索：检查维度、创建 embedding 列和近似最近邻索引
+	embeddingDimension := resolveEmbeddingDimension(embeddingService)
+
+	// 登记活动嵌入模型，为旧分块补齐模型信息；模型变化后语义搜索只使用新模型的向量，需要重新生成其他模型的向量
+	indexingJobService := service.NewIndexingJobService(indexingJobRepo, service.NewIndexingJobConfigFromEnv())
+	embeddingModelService := service.NewEmbeddingModelService(embeddingModelRepo, searchIndexRepo, searchStatsRepo, embeddingService, indexingJobService)
+	if active, previous, err := embeddingModelService.Sync(context.Background(), embeddingDimension); err != nil {
+		log.Printf("Warning: failed to register embedding model: %v", err)
+	} else if previous != nil {
+		log.Printf("Warning: 嵌入模型已从 %s（维度 %d）切换为 %s（维度 %d），请通过 POST /api/v1/search/embedding-models/reembed 重新生成向量",
+			previous.Name, previous.Dimension, active.Name, active.Dimension)
+	}
+
+	if err := setupVectorSearch(db, embeddingDimension, embeddingService.ModelName(), getEnv("VECTOR_INDEX_TYPE", "hnsw")); err != nil {
+		log.Printf("Warning: failed to setup pgvector search: %v", err)
+	}
+	vectorStatus, err := searchIndexRepo.DetectVectorSearch(context.Background(), embeddingDimension)
+	if err != nil {
+		log.Printf("Warning: failed to detect vector search mode: %v", err)
+	}
+	if vectorStatus.Mode == model.VectorSearchModePgvector {
+		log.Printf("向量检索方式: pgvector（维度: %d, 索引: %s）", vectorStatus.Dimension, vectorStatus.IndexType)
+	} else {
+		log.Printf("向量检索方式: 内存计算（原因: %s）", vectorStatus.Reason)
 	}
 
-	searchService := service.NewSearchService(
+	synonymService := service.NewSynonymService(synonymRepo, cacheService)
+	searchService := service.NewSearchServiceWithConfig(
 		searchIndexRepo,
+		searchStatsRepo,
+		synonymService,
 		documentRepo,
 		versionRepo,
 		cacheService,
 		embeddingService,
 		true, // 启用索引
+		service.NewSearchConfigFromEnv(),
 	)
 	documentService := service.NewDocumentService(
 		documentRepo,
@@ -126,6 +200,7 @@ func main() {
 		storageService,
 		parserService,
 		searchService,
+		indexingJobService,
 		baseStorageDir,
 	)
 
@@ -146,6 +221,9 @@ func main() {
 	// 初始化监控服务
 	monitorService := service.NewMonitorService(metricsRepo, logRepo, db)
 
+	// 初始化搜索分析服务
+	searchAnalyticsService := service.NewSearchAnalyticsService(searchAnalyticsRepo, service.NewSearchAnalyticsConfigFromEnv())
+
 	// 初始化健康检查服务
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -154,6 +232,7 @@ func main() {
 	healthService := service.NewHealthService(sqlDB)
 	healthService.RegisterCheck(service.NewDatabaseHealthCheck(sqlDB))
 	healthService.RegisterCheck(service.NewStorageHealthCheck(storageService))
+	healthService.RegisterCheck(service.NewVectorSearchHealthCheck(vectorStatus))
 
 	// 初始化备份服务
 	postgresBackup := service.NewPostgreSQLBackup(dbHost, dbPort, dbUser, dbPassword, dbName)
@@ -162,21 +241,37 @@ func main() {
 
 	// 初始化处理器
 	documentHandler := handler.NewDocumentHandler(documentService)
-	searchHandler := handler.NewSearchHandler(searchService)
+	searchHandler := handler.NewSearchHandler(searchService, searchAnalyticsService)
+	synonymHandler := handler.NewSynonymHandler(synonymService)
+	embeddingModelHandler := handler.NewEmbeddingModelHandler(embeddingModelService)
+	indexingJobHandler := handler.NewIndexingJobHandler(indexingJobService)
 	aiFormatHandler := handler.NewAIFormatHandler(service.NewAIFriendlyFormatService(documentService), documentService)
-	mcpHandler := handler.NewMCPHandler(service.NewMCPService(db, searchService, documentService, versionRepo, searchIndexRepo))
+	mcpHandler := handler.NewMCPHandler(service.NewMCPService(db, searchService, documentService, versionRepo, searchIndexRepo, searchAnalyticsService))
 	userHandler := handler.NewUserHandler(userService)
-	monitorHandler := handler.NewMonitorHandler(monitorService)
+	monitorHandler := handler.NewMonitorHandler(monitorService, searchAnalyticsService)
 	healthHandler := handler.NewHealthHandler(healthService)
 	backupHandler := handler.NewBackupHandler(backupService)
 
 	// 初始化路由器
-	appRouter := router.NewRouter(documentHandler, searchHandler, aiFormatHandler, mcpHandler, userHandler, monitorHandler, healthHandler, backupHandler, userService, monitorService)
+	appRouter := router.NewRouter(documentHandler, searchHandler, synonymHandler, embeddingModelHandler, indexingJobHandler, aiFormatHandler, mcpHandler, userHandler, monitorHandler, healthHandler, backupHandler, userService, monitorService)
 	r := appRouter.SetupRoutes()
 
+	// 启动文档解析和索引任务的工作协程池，上次退出时未完成的任务会继续执行
+	indexingJobService.Start(context.Background())
+
+	// 定期淘汰长期未使用的嵌入向量缓存
+	go embeddingCache.RunCleanup(context.Background())
+
 	// 启动指标收集定时任务（每30秒收集一次）
 	go startMetricsCollection(monitorService)
 
+	// 检查BM25语料统计，旧数据没有统计时在后台重建
+	go func() {
+		if err := searchService.EnsureStatistics(context.Background()); err != nil {
+			log.Printf("Failed to rebuild search statistics: %v", err)
+		}
+	}()
+
 	// 启动服务器
 	log.Printf("Server starting on port %s", serverPort)
 	log.Printf("Storage directory: %s", baseStorageDir)
@@ -209,6 +304,23 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// defaultIVFFlatLists IVFFlat 索引默认的列表数量
+const defaultIVFFlatLists = 100
+
+// ivfflatLists 读取 VECTOR_IVFFLAT_LISTS，不是正整数时使用默认值，避免拼接到建索引语句中的值未经校验
+func ivfflatLists() int {
+	value := os.Getenv("VECTOR_IVFFLAT_LISTS")
+	if value == "" {
+		return defaultIVFFlatLists
+	}
+	lists, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || lists <= 0 {
+		log.Printf("WARNING: VECTOR_IVFFLAT_LISTS=%q 不是正整数，使用默认值 %d", value, defaultIVFFlatLists)
+		return defaultIVFFlatLists
+	}
+	return lists
+}
+
 // buildDSN 构建数据库连接字符串
 func buildDSN(host, port, user, password, dbname string) string {
 	return "host=" + host + " user=" + user + " password=" + password + " dbname=" + dbname + " port=" + port + " sslmode=disable TimeZone=Asia/Shanghai"
@@ -221,6 +333,14 @@ func autoMigrate(db *gorm.DB) error {
 		&model.Document{},
 		&model.DocumentVersion{},
 		&model.DocumentMetadata{},
+		&model.SearchTermStat{},
+		&model.SearchCorpusStats{},
+		&model.SynonymSet{},
+		&model.IndexingJob{},
+		&model.SearchQueryLog{},
+		&model.SearchClick{},
+		&model.EmbeddingModel{},
+		&model.EmbeddingCacheEntry{},
 	)
 	if err != nil {
 		return err
@@ -448,10 +568,173 @@ func setupSearchIndices(db *gorm.DB) error {
 		return fmt.Errorf("failed to create trigger: %v", err)
 	}
 
+	// 设置全文检索列、触发器和索引
+	if err := setupFullTextSearch(db); err != nil {
+		return err
+	}
+
 	log.Println("搜索索引表设置完成")
 	return nil
 }
 
+// setupFullTextSearch 设置搜索索引表的全文检索支持
+// search_vector 列由触发器维护：章节标题权重为 A，正文权重为 B
+func setupFullTextSearch(db *gorm.DB) error {
+	if err := db.Exec(`
+		ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS search_vector tsvector;
+	`).Error; err != nil {
+		return fmt.Errorf("failed to add search_vector column: %v", err)
+	}
+
+	if err := db.Exec(`
+		CREATE OR REPLACE FUNCTION search_indices_search_vector_update()
+		RETURNS TRIGGER AS $$
+		BEGIN
+		    NEW.search_vector :=
+		        setweight(to_tsvector('simple', coalesce(NEW.section, '')), 'A') ||
+		        setweight(to_tsvector('simple', coalesce(NEW.content, '')), 'B');
+		    RETURN NEW;
+		END;
+		$$ language 'plpgsql';
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create search_vector trigger function: %v", err)
+	}
+
+	if err := db.Exec(`
+		DROP TRIGGER IF EXISTS search_indices_search_vector_update ON search_indices;
+		CREATE TRIGGER search_indices_search_vector_update
+		    BEFORE INSERT OR UPDATE OF content, section ON search_indices
+		    FOR EACH ROW
+		    EXECUTE FUNCTION search_indices_search_vector_update();
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create search_vector trigger: %v", err)
+	}
+
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_search_indices_search_vector ON search_indices USING gin(search_vector);
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create search_vector index: %v", err)
+	}
+
+	// 回填已有数据
+	result := db.Exec(`
+		UPDATE search_indices
+		SET search_vector =
+		    setweight(to_tsvector('simple', coalesce(section, '')), 'A') ||
+		    setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+		WHERE search_vector IS NULL;
+	`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to backfill search_vector: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已回填 %d 条搜索索引的全文检索向量", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// resolveEmbeddingDimension 获取嵌入向量维度，优先使用 EMBEDDING_DIMENSIONS，否则调用嵌入服务探测
+func resolveEmbeddingDimension(embeddingService service.EmbeddingService) int {
+	if value := getEnv("EMBEDDING_DIMENSIONS", ""); value != "" {
+		dimension, err := strconv.Atoi(value)
+		if err == nil && dimension > 0 {
+			return dimension
+		}
+		log.Printf("Warning: invalid EMBEDDING_DIMENSIONS: %s", value)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	embedding, err := embeddingService.GenerateEmbedding(ctx, "dimension probe")
+	if err != nil {
+		log.Printf("Warning: failed to probe embedding dimension: %v", err)
+		return 0
+	}
+	return len(embedding)
+}
+
+// setupVectorSearch 设置pgvector向量检索
+// 检查 embedding 列维度与嵌入服务是否一致，从 vector 列回填活动模型 embeddingModel 的向量，并按 indexType（hnsw/ivfflat/none）维护近似最近邻索引
+func setupVectorSearch(db *gorm.DB, dimension int, embeddingModel, indexType string) error {
+	if dimension <= 0 {
+		return fmt.Errorf("unknown embedding dimension")
+	}
+
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`).Error; err != nil {
+		return fmt.Errorf("pgvector extension is not available: %v", err)
+	}
+
+	// 检查 embedding 列的维度，vector 类型的 atttypmod 即为维度
+	var columnDimensions []int
+	if err := db.Raw(`
+		SELECT a.atttypmod FROM pg_attribute a
+		WHERE a.attrelid = 'search_indices'::regclass AND a.attname = 'embedding' AND NOT a.attisdropped
+	`).Scan(&columnDimensions).Error; err != nil {
+		return fmt.Errorf("failed to check embedding column: %v", err)
+	}
+
+	switch {
+	case len(columnDimensions) == 0:
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE search_indices ADD COLUMN embedding vector(%d)`, dimension)).Error; err != nil {
+			return fmt.Errorf("failed to add embedding column: %v", err)
+		}
+		log.Printf("已创建 embedding 列，维度: %d", dimension)
+	case columnDimensions[0] <= 0:
+		// 未指定维度的列，尝试转换为固定维度
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE search_indices ALTER COLUMN embedding TYPE vector(%d)`, dimension)).Error; err != nil {
+			return fmt.Errorf("failed to set embedding dimension: %v", err)
+		}
+	case columnDimensions[0] != dimension:
+		// 嵌入模型的维度变化：embedding 列中是旧模型的向量，原始向量保留在 vector 列中，
+		// 清空后改为新维度，重新生成向量后再回填
+		log.Printf("embedding 列维度 %d 与嵌入服务维度 %d 不一致，重建 embedding 列", columnDimensions[0], dimension)
+		for _, name := range []string{"hnsw", "ivfflat"} {
+			if err := db.Exec(`DROP INDEX IF EXISTS idx_search_indices_embedding_` + name).Error; err != nil {
+				return fmt.Errorf("failed to drop %s index: %v", name, err)
+			}
+		}
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE search_indices ALTER COLUMN embedding TYPE vector(%d) USING NULL`, dimension)).Error; err != nil {
+			return fmt.Errorf("failed to change embedding dimension: %v", err)
+		}
+	}
+
+	// 从 vector 列回填活动模型生成的向量
+	result := db.Exec(`
+		UPDATE search_indices SET embedding = vector::text::vector
+		WHERE embedding IS NULL AND embedding_model = ? AND jsonb_typeof(vector) = 'array' AND jsonb_array_length(vector) = ?
+	`, embeddingModel, dimension)
+	if result.Error != nil {
+		return fmt.Errorf("failed to backfill embedding column: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已回填 %d 条搜索索引的 embedding", result.RowsAffected)
+	}
+
+	// 维护近似最近邻索引，切换索引类型时删除旧索引
+	indexes := map[string]string{
+		"hnsw":    `CREATE INDEX IF NOT EXISTS idx_search_indices_embedding_hnsw ON search_indices USING hnsw (embedding vector_cosine_ops)`,
+		"ivfflat": `CREATE INDEX IF NOT EXISTS idx_search_indices_embedding_ivfflat ON search_indices USING ivfflat (embedding vector_cosine_ops) WITH (lists = ` + strconv.Itoa(ivfflatLists()) + `)`,
+	}
+	if _, ok := indexes[indexType]; !ok && indexType != "none" {
+		return fmt.Errorf("unsupported vector index type: %s", indexType)
+	}
+	for name := range indexes {
+		if name != indexType {
+			if err := db.Exec(`DROP INDEX IF EXISTS idx_search_indices_embedding_` + name).Error; err != nil {
+				return fmt.Errorf("failed to drop %s index: %v", name, err)
+			}
+		}
+	}
+	if createSQL, ok := indexes[indexType]; ok {
+		if err := db.Exec(createSQL).Error; err != nil {
+			return fmt.Errorf("failed to create %s index: %v", indexType, err)
+		}
+	}
+
+	return nil
+}
+
 // migrateMCPTables 迁移MCP相关表
 func migrateMCPTables(db *gorm.DB) error {
 	log.Println("正在迁移MCP相关表...")
@@ -411,3 +411,24 @@ func TestDatabaseConnection(t *testing.T) {
 	t.Logf("无法直接验证最大连接数设置，但设置方法已调用")
 	t.Logf("连接池状态: %+v", stats)
 }
+
+// TestIVFFlatLists 测试 IVFFlat 列表数量只接受正整数
+func TestIVFFlatLists(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 100},
+		{"200", 200},
+		{" 50 ", 50},
+		{"0", 100},
+		{"-10", 100},
+		{"100); DROP TABLE search_indices; --", 100},
+	}
+	for _, tt := range tests {
+		t.Setenv("VECTOR_IVFFLAT_LISTS", tt.value)
+		if got := ivfflatLists(); got != tt.want {
+			t.Errorf("ivfflatLists() with %q = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
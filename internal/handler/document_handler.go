@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -200,8 +201,25 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 		filters["tags"] = strings.Split(tags, ",")
 	}
 
-	// 调用服务层获取文档列表
-	documents, total, err := h.documentService.GetDocuments(context.Background(), page, size, filters)
+	// 调用服务层获取文档列表，传入 cursor 参数时使用游标分页，忽略 page 参数
+	var documents []*model.Document
+	var total int64
+	var nextCursor string
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		documents, total, nextCursor, err = h.documentService.GetDocumentsAfter(context.Background(), cursor, size, filters)
+	} else {
+		documents, total, err = h.documentService.GetDocuments(context.Background(), page, size, filters)
+		if err == nil && int64(page*size) < total {
+			nextCursor = service.NextDocumentCursor(documents, size, service.DocumentCursorFingerprint(filters))
+		}
+	}
+	if errors.Is(err, model.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "游标无效或与当前过滤条件不匹配",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -221,10 +239,11 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": gin.H{
-			"total": total,
-			"items": documents,
-			"page":  page,
-			"size":  size,
+			"total":       total,
+			"items":       documents,
+			"page":        page,
+			"size":        size,
+			"next_cursor": nextCursor,
 		},
 		"message": "获取成功",
 	})
@@ -598,12 +617,11 @@ func (h *DocumentHandler) GetLatestVersion(c *gin.Context) {
 	})
 }
 
-// BuildAllMissingIndexes 为所有缺少索引的文档构建搜索索引
+// BuildAllMissingIndexes 将所有文档版本的索引对账任务加入队列，返回任务信息，可通过任务接口查询进度和结果
 func (h *DocumentHandler) BuildAllMissingIndexes(c *gin.Context) {
-	log.Printf("DEBUG: 开始为所有缺少索引的文档构建搜索索引")
-
-	if err := h.documentService.BuildAllMissingIndexes(context.Background()); err != nil {
-		log.Printf("DEBUG: 构建搜索索引失败: %v", err)
+	job, err := h.documentService.ScheduleIndexReconcile(c.Request.Context())
+	if err != nil {
+		log.Printf("WARNING: 创建索引对账任务失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "构建搜索索引失败: " + err.Error(),
@@ -611,10 +629,11 @@ func (h *DocumentHandler) BuildAllMissingIndexes(c *gin.Context) {
 		return
 	}
 
-	log.Printf("DEBUG: 成功为所有缺少索引的文档构建搜索索引")
-	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
-		"message": "搜索索引构建成功",
+	log.Printf("INFO: 索引对账任务已加入队列 - 任务ID: %s", job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    202,
+		"data":    job,
+		"message": "搜索索引构建任务已加入队列",
 	})
 }
 
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmbeddingModelHandler 嵌入模型管理处理器
+type EmbeddingModelHandler struct {
+	embeddingModelService service.EmbeddingModelService
+}
+
+// NewEmbeddingModelHandler 创建嵌入模型管理处理器实例
+func NewEmbeddingModelHandler(embeddingModelService service.EmbeddingModelService) *EmbeddingModelHandler {
+	return &EmbeddingModelHandler{
+		embeddingModelService: embeddingModelService,
+	}
+}
+
+// GetStatus 获取活动嵌入模型、登记的模型、各模型的分块数量和需要重新生成向量的分块数量
+func (h *EmbeddingModelHandler) GetStatus(c *gin.Context) {
+	status, err := h.embeddingModelService.GetStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取嵌入模型状态失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    status,
+		"message": "获取成功",
+	})
+}
+
+// Reembed 将使用活动模型重新生成向量的任务加入队列，返回任务信息，可通过任务接口查询进度和结果
+func (h *EmbeddingModelHandler) Reembed(c *gin.Context) {
+	job, err := h.embeddingModelService.ScheduleReembed(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建重新生成向量任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    202,
+		"data":    job,
+		"message": "重新生成向量任务已加入队列",
+	})
+}
+
+// GetVocabulary 从语料统计生成离线特征哈希嵌入使用的词表统计文件，
+// 保存后通过 HASHING_EMBEDDING_VOCABULARY 指定，用于按IDF加权
+func (h *EmbeddingModelHandler) GetVocabulary(c *gin.Context) {
+	minDocFreq, err := strconv.ParseInt(c.DefaultQuery("min_doc_freq", "2"), 10, 64)
+	if err != nil || minDocFreq < 1 {
+		minDocFreq = 2
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200000"))
+	if err != nil || limit < 0 {
+		limit = 200000
+	}
+
+	vocabulary, err := h.embeddingModelService.BuildVocabulary(c.Request.Context(), minDocFreq, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成词表统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 直接返回词表文件内容，便于保存后供离线嵌入服务加载
+	c.Header("Content-Disposition", "attachment; filename=hashing_vocabulary.json")
+	c.JSON(http.StatusOK, vocabulary)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexingJobHandler 索引任务处理器
+type IndexingJobHandler struct {
+	jobService service.IndexingJobService
+}
+
+// NewIndexingJobHandler 创建索引任务处理器实例
+func NewIndexingJobHandler(jobService service.IndexingJobService) *IndexingJobHandler {
+	return &IndexingJobHandler{
+		jobService: jobService,
+	}
+}
+
+// ListJobs 获取索引任务列表，支持按 status、type、document_id、version 过滤
+func (h *IndexingJobHandler) ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	filters := make(map[string]interface{})
+	for _, field := range []string{"status", "type", "document_id", "version"} {
+		if value := c.Query(field); value != "" {
+			filters[field] = value
+		}
+	}
+
+	jobs, total, err := h.jobService.ListJobs(c.Request.Context(), page, size, filters)
+	if err != nil {
+		writeIndexingJobError(c, "获取索引任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"items": jobs,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+		"message": "获取成功",
+	})
+}
+
+// GetJob 获取索引任务
+func (h *IndexingJobHandler) GetJob(c *gin.Context) {
+	job, err := h.jobService.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeIndexingJobError(c, "获取索引任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    job,
+		"message": "获取成功",
+	})
+}
+
+// RetryJob 重新执行失败或已取消的索引任务
+func (h *IndexingJobHandler) RetryJob(c *gin.Context) {
+	job, err := h.jobService.RetryJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeIndexingJobError(c, "重试索引任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    job,
+		"message": "任务已重新加入队列",
+	})
+}
+
+// CancelJob 取消待执行或执行中的索引任务
+func (h *IndexingJobHandler) CancelJob(c *gin.Context) {
+	job, err := h.jobService.CancelJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeIndexingJobError(c, "取消索引任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    job,
+		"message": "任务已取消",
+	})
+}
+
+// writeIndexingJobError 根据索引任务服务的错误类型返回对应的状态码
+func writeIndexingJobError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrIndexingJobNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrIndexingJobState):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message + ": " + err.Error(),
+	})
+}
@@ -13,13 +13,15 @@ import (
 
 // MonitorHandler 监控处理器
 type MonitorHandler struct {
-	monitorService service.MonitorService
+	monitorService         service.MonitorService
+	searchAnalyticsService service.SearchAnalyticsService
 }
 
 // NewMonitorHandler 创建监控处理器实例
-func NewMonitorHandler(monitorService service.MonitorService) *MonitorHandler {
+func NewMonitorHandler(monitorService service.MonitorService, searchAnalyticsService service.SearchAnalyticsService) *MonitorHandler {
 	return &MonitorHandler{
-		monitorService: monitorService,
+		monitorService:         monitorService,
+		searchAnalyticsService: searchAnalyticsService,
 	}
 }
 
@@ -260,8 +262,65 @@ func (h *MonitorHandler) CleanupOldData(c *gin.Context) {
 		return
 	}
 
+	// 清理旧搜索日志，默认使用配置的搜索日志保留天数
+	searchRetentionDays := h.searchAnalyticsService.RetentionDays()
+	if value := c.Query("search_retention_days"); value != "" {
+		searchRetentionDays, err = strconv.Atoi(value)
+		if err != nil || searchRetentionDays < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "无效的搜索日志保留天数",
+				"details": "search_retention_days 必须是正整数",
+			})
+			return
+		}
+	}
+	deletedSearches, err := h.searchAnalyticsService.Cleanup(c.Request.Context(), searchRetentionDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "清理旧搜索日志失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                "旧数据清理成功",
+		"retention_days":         retentionDays,
+		"search_retention_days":  searchRetentionDays,
+		"deleted_search_queries": deletedSearches,
+	})
+}
+
+// GetSearchAnalytics 获取搜索分析报告：热门查询、无结果查询、低点击率查询和各库检索需求
+func (h *MonitorHandler) GetSearchAnalytics(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "无效的统计天数",
+			"details": "days 必须是正整数",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "无效的数量限制",
+			"details": "limit 必须是 1 到 100 之间的整数",
+		})
+		return
+	}
+
+	report, err := h.searchAnalyticsService.GetReport(c.Request.Context(), days, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取搜索分析报告失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":        "旧数据清理成功",
-		"retention_days": retentionDays,
+		"report": report,
+		"days":   days,
 	})
 }
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/UniverseHappiness/LAST-doc/internal/model"
 	"github.com/UniverseHappiness/LAST-doc/internal/service"
@@ -13,13 +16,15 @@ import (
 
 // SearchHandler 搜索处理器
 type SearchHandler struct {
-	searchService service.SearchService
+	searchService    service.SearchService
+	analyticsService service.SearchAnalyticsService
 }
 
 // NewSearchHandler 创建搜索处理器实例
-func NewSearchHandler(searchService service.SearchService) *SearchHandler {
+func NewSearchHandler(searchService service.SearchService, analyticsService service.SearchAnalyticsService) *SearchHandler {
 	return &SearchHandler{
-		searchService: searchService,
+		searchService:    searchService,
+		analyticsService: analyticsService,
 	}
 }
 
@@ -47,12 +52,9 @@ func (h *SearchHandler) Search(c *gin.Context) {
 	}
 
 	// 执行搜索
-	response, err := h.searchService.Search(context.Background(), &request)
+	response, err := h.search(c, &request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "搜索失败: " + err.Error(),
-		})
+		writeSearchError(c, err)
 		return
 	}
 
@@ -103,6 +105,15 @@ func (h *SearchHandler) SearchGet(c *gin.Context) {
 	if section := c.Query("section"); section != "" {
 		filters["section"] = section
 	}
+	if language := c.Query("language"); language != "" {
+		filters["language"] = language
+	}
+	if library := c.Query("library"); library != "" {
+		filters["library"] = library
+	}
+	if tag := c.Query("tag"); tag != "" {
+		filters["tag"] = tag
+	}
 
 	// 构建搜索请求
 	request := &model.SearchRequest{
@@ -111,15 +122,102 @@ func (h *SearchHandler) SearchGet(c *gin.Context) {
 		Page:       page,
 		Size:       size,
 		SearchType: searchType,
+		HybridMode: c.Query("hybrid_mode"),
+		Rerank:     c.Query("rerank"),
+		Cursor:     c.Query("cursor"),
+	}
+	request.AutoCorrect, _ = strconv.ParseBool(c.Query("auto_correct"))
+	request.Explain, _ = strconv.ParseBool(c.Query("explain"))
+
+	// 解析混合搜索参数
+	if request.HybridMode != "" && request.HybridMode != "rrf" && request.HybridMode != "weighted" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "hybrid_mode 只能为 rrf 或 weighted",
+		})
+		return
+	}
+	if value := c.Query("hybrid_alpha"); value != "" {
+		alpha, err := strconv.ParseFloat(value, 64)
+		if err != nil || alpha < 0 || alpha > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "hybrid_alpha 必须是 0 到 1 之间的数字",
+			})
+			return
+		}
+		request.HybridAlpha = &alpha
+	}
+	switch request.Rerank {
+	case "", service.RerankerNone, service.RerankerHeuristic, service.RerankerCrossEncoder:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "rerank 只能为 none、heuristic 或 cross-encoder",
+		})
+		return
+	}
+	if value := c.Query("rerank_top_n"); value != "" {
+		topN, err := strconv.Atoi(value)
+		if err != nil || topN < 1 || topN > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "rerank_top_n 必须是 1 到 500 之间的整数",
+			})
+			return
+		}
+		request.RerankTopN = &topN
+	}
+	if value := c.Query("facets"); value != "" {
+		for _, facet := range strings.Split(value, ",") {
+			facet = strings.TrimSpace(facet)
+			if !model.IsValidFacet(facet) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    400,
+					"message": "不支持的分面: " + facet,
+				})
+				return
+			}
+			request.Facets = append(request.Facets, facet)
+		}
+	}
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		if !model.IsValidGroupBy(groupBy) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "group_by 只能为 document_id 或 library",
+			})
+			return
+		}
+		request.GroupBy = groupBy
+	}
+	if value := c.Query("per_group"); value != "" {
+		perGroup, err := strconv.Atoi(value)
+		if err != nil || perGroup < 1 || perGroup > 20 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "per_group 必须是 1 到 20 之间的整数",
+			})
+			return
+		}
+		request.PerGroup = perGroup
+	}
+	if value := c.Query("rrf_k"); value != "" {
+		rrfK, err := strconv.Atoi(value)
+		if err != nil || rrfK < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "rrf_k 必须是正整数",
+			})
+			return
+		}
+		request.RRFK = &rrfK
 	}
 
 	// 执行搜索
-	response, err := h.searchService.Search(context.Background(), request)
+	response, err := h.search(c, request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "搜索失败: " + err.Error(),
-		})
+		writeSearchError(c, err)
 		return
 	}
 
@@ -130,6 +228,67 @@ func (h *SearchHandler) SearchGet(c *gin.Context) {
 	})
 }
 
+// FindSimilar 查找与指定分块或文档版本相似的分块（more like this）
+func (h *SearchHandler) FindSimilar(c *gin.Context) {
+	request := &model.SimilarRequest{
+		IndexID:       c.Query("index_id"),
+		DocumentID:    c.Query("document_id"),
+		SourceVersion: c.Query("source_version"),
+		Filters:       make(map[string]interface{}),
+	}
+	if request.IndexID == "" && request.DocumentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "index_id 和 document_id 至少需要提供一个",
+		})
+		return
+	}
+
+	// 解析结果的过滤条件
+	for _, key := range []string{"library", "version", "content_type", "section", "language", "tag"} {
+		if value := c.Query(key); value != "" {
+			request.Filters[key] = value
+		}
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	if err != nil || size < 1 || size > 100 {
+		size = 10
+	}
+	request.Size = size
+	request.IncludeSameDocument, _ = strconv.ParseBool(c.Query("include_same_document"))
+
+	response, err := h.searchService.FindSimilar(c.Request.Context(), request)
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    response,
+		"message": "查找相似内容成功",
+	})
+}
+
+// search 执行搜索并记录搜索分析日志
+func (h *SearchHandler) search(c *gin.Context, request *model.SearchRequest) (*model.SearchResponse, error) {
+	start := time.Now()
+	response, err := h.searchService.Search(context.Background(), request)
+	if h.analyticsService != nil {
+		h.analyticsService.RecordSearch(model.SearchSourceREST, searchCaller(c), request, response, err, time.Since(start))
+	}
+	return response, err
+}
+
+// searchCaller 返回搜索请求的调用方：已登录时为用户ID，否则为客户端IP
+func searchCaller(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
 // BuildIndex 构建文档索引
 func (h *SearchHandler) BuildIndex(c *gin.Context) {
 	documentID := c.Param("id")
@@ -254,3 +413,71 @@ func (h *SearchHandler) DeleteIndexByVersion(c *gin.Context) {
 		"message": "删除成功",
 	})
 }
+
+// writeSearchError 返回搜索失败的响应，查询语法错误返回400及出错位置，无效的版本范围、不可用的重排序器和无效的游标返回400，
+// 嵌入服务不可用时语义搜索和相似内容查询返回503，查询向量维度与嵌入模型不一致时返回409
+func writeSearchError(c *gin.Context, err error) {
+	var parseErr *service.QueryParseError
+	if errors.As(err, &parseErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "查询语法错误: " + parseErr.Message,
+			"data":    parseErr,
+		})
+		return
+	}
+	if errors.Is(err, service.ErrRerankerUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "重排序器不可用: " + err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidVersionRange) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "版本范围无效: " + err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, model.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "游标无效或与当前查询不匹配",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrEmbeddingUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "嵌入服务不可用，无法执行语义搜索: " + err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, model.ErrVectorDimensionMismatch) {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "查询向量维度与嵌入模型不一致，请重新生成向量: " + err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrSimilarSourceRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "index_id 和 document_id 至少需要提供一个",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrSimilarSourceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "源分块或文档版本不存在: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "搜索失败: " + err.Error(),
+	})
+}
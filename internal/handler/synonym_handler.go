@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SynonymHandler 同义词管理处理器
+type SynonymHandler struct {
+	synonymService service.SynonymService
+}
+
+// NewSynonymHandler 创建同义词管理处理器实例
+func NewSynonymHandler(synonymService service.SynonymService) *SynonymHandler {
+	return &SynonymHandler{
+		synonymService: synonymService,
+	}
+}
+
+// ListSynonymSets 获取同义词组列表，可通过 library 参数只获取指定库的同义词组
+func (h *SynonymHandler) ListSynonymSets(c *gin.Context) {
+	sets, err := h.synonymService.ListSynonymSets(c.Request.Context(), c.Query("library"))
+	if err != nil {
+		writeSynonymError(c, "获取同义词组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    sets,
+		"message": "获取成功",
+	})
+}
+
+// CreateSynonymSet 创建同义词组
+func (h *SynonymHandler) CreateSynonymSet(c *gin.Context) {
+	var request model.SynonymSetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	set, err := h.synonymService.CreateSynonymSet(c.Request.Context(), &request)
+	if err != nil {
+		writeSynonymError(c, "创建同义词组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    set,
+		"message": "创建成功",
+	})
+}
+
+// GetSynonymSet 获取同义词组
+func (h *SynonymHandler) GetSynonymSet(c *gin.Context) {
+	set, err := h.synonymService.GetSynonymSet(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeSynonymError(c, "获取同义词组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    set,
+		"message": "获取成功",
+	})
+}
+
+// UpdateSynonymSet 更新同义词组
+func (h *SynonymHandler) UpdateSynonymSet(c *gin.Context) {
+	var request model.SynonymSetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	set, err := h.synonymService.UpdateSynonymSet(c.Request.Context(), c.Param("id"), &request)
+	if err != nil {
+		writeSynonymError(c, "更新同义词组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"data":    set,
+		"message": "更新成功",
+	})
+}
+
+// DeleteSynonymSet 删除同义词组
+func (h *SynonymHandler) DeleteSynonymSet(c *gin.Context) {
+	if err := h.synonymService.DeleteSynonymSet(c.Request.Context(), c.Param("id")); err != nil {
+		writeSynonymError(c, "删除同义词组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+	})
+}
+
+// writeSynonymError 根据同义词服务的错误类型返回对应的状态码
+func writeSynonymError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidSynonymSet):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrSynonymSetNotFound):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor 游标无法解析或与当前查询不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor 游标分页位置（search_after 风格），记录上一页最后一条结果的排序值和ID
+// 搜索结果按 (Score DESC, ID ASC) 排序，文档列表按 (CreatedAt DESC, ID DESC) 排序
+type Cursor struct {
+	Score     float64    `json:"s,omitempty"` // 搜索结果的得分
+	CreatedAt *time.Time `json:"t,omitempty"` // 文档的创建时间
+	ID        string     `json:"id"`
+	Rank      float64    `json:"r,omitempty"` // 搜索结果在召回顺序中的排序值，见 SearchIndex.SortKey
+	Offset    int        `json:"o,omitempty"` // 已返回的结果数量
+	Query     string     `json:"q,omitempty"` // 查询指纹，防止游标用于其他查询
+}
+
+// Encode 将游标编码为不透明的字符串
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析游标字符串，fingerprint 与游标中记录的查询指纹不一致时返回 ErrInvalidCursor
+func DecodeCursor(token, fingerprint string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Query != fingerprint {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCursor_EncodeDecode 测试游标编码后可以还原
+func TestCursor_EncodeDecode(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 8, 30, 0, 123456000, time.UTC)
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"搜索结果", Cursor{Score: 0.8125, ID: "idx-1", Offset: 20, Query: "abc"}},
+		{"文档列表", Cursor{CreatedAt: &createdAt, ID: "doc-1", Query: "def"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor.Encode(), tt.cursor.Query)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if got.Score != tt.cursor.Score || got.ID != tt.cursor.ID || got.Offset != tt.cursor.Offset {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+			if (got.CreatedAt == nil) != (tt.cursor.CreatedAt == nil) ||
+				(got.CreatedAt != nil && !got.CreatedAt.Equal(*tt.cursor.CreatedAt)) {
+				t.Errorf("DecodeCursor() CreatedAt = %v, want %v", got.CreatedAt, tt.cursor.CreatedAt)
+			}
+		})
+	}
+}
+
+// TestDecodeCursor_Invalid 测试无效游标
+func TestDecodeCursor_Invalid(t *testing.T) {
+	valid := (&Cursor{ID: "idx-1", Query: "abc"}).Encode()
+	tests := []struct {
+		name        string
+		token       string
+		fingerprint string
+	}{
+		{"不是base64", "%%%", "abc"},
+		{"不是JSON", "bm90LWpzb24", "abc"},
+		{"缺少ID", (&Cursor{Query: "abc"}).Encode(), "abc"},
+		{"查询不一致", valid, "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.token, tt.fingerprint); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -79,6 +79,19 @@ func (DocumentVersion) TableName() string {
 	return "document_versions"
 }
 
+// LibraryVersion 库中的文档版本，用于按库解析版本范围
+type LibraryVersion struct {
+	DocumentID string `json:"document_id"`
+	Library    string `json:"library"`
+	Version    string `json:"version"`
+}
+
+// DocumentVersionRef 文档的指定版本，用作搜索的过滤条件
+type DocumentVersionRef struct {
+	DocumentID string `json:"document_id"`
+	Version    string `json:"version"`
+}
+
 // DocumentMetadata 定义文档元数据模型
 type DocumentMetadata struct {
 	ID         string                 `json:"id" gorm:"primaryKey"`
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// EmbeddingCacheEntry 按嵌入模型和规范化文本的SHA-256缓存的嵌入向量
+type EmbeddingCacheEntry struct {
+	Model      string    `json:"model" gorm:"primaryKey"`
+	TextHash   string    `json:"text_hash" gorm:"primaryKey;type:varchar(64)"`
+	Vector     string    `json:"vector" gorm:"type:jsonb;not null"` // 以JSON字符串格式存储
+	Dimension  int       `json:"dimension"`
+	Hits       int64     `json:"hits" gorm:"not null;default:0"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	LastUsedAt time.Time `json:"last_used_at" gorm:"not null;index"` // 写入或最近一次命中的时间，用于淘汰长期未使用的向量
+}
+
+// TableName 返回嵌入向量缓存表名
+func (EmbeddingCacheEntry) TableName() string {
+	return "embedding_cache"
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// 嵌入服务不可用时生成的备用向量所属的模型名称和维度
+const (
+	FallbackEmbeddingModel     = "fallback"
+	FallbackEmbeddingDimension = 100
+)
+
+// ErrVectorDimensionMismatch 查询向量的维度与嵌入模型登记的维度不一致
+var ErrVectorDimensionMismatch = errors.New("query vector dimension does not match embedding model")
+
+// UnknownEmbeddingModel 记录模型之前写入、维度与当前模型不一致的向量所属的模型名称
+const UnknownEmbeddingModel = "unknown"
+
+// EmbeddingModel 登记的嵌入模型，同一时间只有一个活动模型
+type EmbeddingModel struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name        string     `json:"name" gorm:"not null;uniqueIndex"`
+	Dimension   int        `json:"dimension" gorm:"not null"`
+	Active      bool       `json:"active" gorm:"not null;default:false;index"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 返回嵌入模型表名
+func (EmbeddingModel) TableName() string {
+	return "embedding_models"
+}
+
+// EmbeddingModelUsage 按嵌入模型和维度统计的搜索索引分块数量
+type EmbeddingModelUsage struct {
+	Model     string `json:"model"`
+	Dimension int    `json:"dimension"`
+	Chunks    int64  `json:"chunks"`
+}
+
+// EmbeddingModelStatus 嵌入模型状态，StaleChunks 为向量不属于活动模型、需要重新生成的分块数量
+type EmbeddingModelStatus struct {
+	Active      *EmbeddingModel       `json:"active"`
+	Models      []*EmbeddingModel     `json:"models"`
+	Usage       []EmbeddingModelUsage `json:"usage"`
+	StaleChunks int64                 `json:"stale_chunks"`
+}
+
+// ReembedResult 重新生成向量任务的结果
+type ReembedResult struct {
+	Model    string `json:"model"`
+	Total    int    `json:"total"`    // 任务开始时需要重新生成向量的分块数量
+	Embedded int    `json:"embedded"` // 重新生成向量的分块数量
+	Skipped  int    `json:"skipped"`  // 内容为空、写入备用向量的分块数量
+}
+
+// HashingVocabulary 离线特征哈希嵌入使用的词表统计，由语料的检索词统计生成
+type HashingVocabulary struct {
+	Documents int64            `json:"documents"` // 统计时的分块总数
+	Terms     map[string]int64 `json:"terms"`     // 检索词及包含该词的分块数量
+}
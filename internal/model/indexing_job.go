@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// IndexingJobType 定义索引任务类型
+type IndexingJobType string
+
+const (
+	IndexingJobTypeParseDocument    IndexingJobType = "parse_document"    // 解析上传的文档版本
+	IndexingJobTypeBuildIndex       IndexingJobType = "build_index"       // 构建文档版本的搜索索引
+	IndexingJobTypeReconcileIndexes IndexingJobType = "reconcile_indexes" // 对账所有文档版本的搜索索引
+	IndexingJobTypeReembedIndexes   IndexingJobType = "reembed_indexes"   // 使用活动嵌入模型重新生成其他模型的向量
+)
+
+// IndexingJobStatus 定义索引任务状态
+type IndexingJobStatus string
+
+const (
+	IndexingJobStatusPending   IndexingJobStatus = "pending"
+	IndexingJobStatusRunning   IndexingJobStatus = "running"
+	IndexingJobStatusSucceeded IndexingJobStatus = "succeeded"
+	IndexingJobStatusFailed    IndexingJobStatus = "failed"
+	IndexingJobStatusCancelled IndexingJobStatus = "cancelled"
+)
+
+// 索引任务的默认优先级，数值越大越先执行
+const (
+	IndexingJobPriorityParse     = 20
+	IndexingJobPriorityIndex     = 10
+	IndexingJobPriorityReconcile = 0
+	IndexingJobPriorityReembed   = 0
+)
+
+// IndexingJob 持久化的文档解析和索引任务
+type IndexingJob struct {
+	ID            string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Type          IndexingJobType   `json:"type" gorm:"not null;index"`
+	Status        IndexingJobStatus `json:"status" gorm:"not null;index"`
+	Priority      int               `json:"priority" gorm:"not null;default:0"`
+	DocumentID    string            `json:"document_id" gorm:"index"` // 对账任务为空
+	Version       string            `json:"version"`
+	FilePath      string            `json:"file_path"` // 解析任务的文件路径
+	Attempts      int               `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts   int               `json:"max_attempts" gorm:"not null;default:3"`
+	LastError     string            `json:"last_error" gorm:"type:text"`
+	ProgressDone  int               `json:"progress_done" gorm:"not null;default:0"`  // 已完成的分块（对账任务为文档版本）数量
+	ProgressTotal int               `json:"progress_total" gorm:"not null;default:0"` // 分块（对账任务为文档版本）总数
+	Result        string            `json:"result" gorm:"type:jsonb"`                 // 任务结果，JSON字符串
+	RunAt         time.Time         `json:"run_at" gorm:"not null;index"`             // 最早执行时间，重试时按退避时间推后
+	WorkerID      string            `json:"worker_id"`                                // 正在执行任务的工作进程
+	HeartbeatAt   *time.Time        `json:"heartbeat_at"`                             // 执行中任务的最近心跳，超时视为工作进程已退出
+	StartedAt     *time.Time        `json:"started_at"`
+	FinishedAt    *time.Time        `json:"finished_at"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 返回索引任务表名
+func (IndexingJob) TableName() string {
+	return "indexing_jobs"
+}
+
+// IsFinished 判断任务是否已结束
+func (j *IndexingJob) IsFinished() bool {
+	switch j.Status {
+	case IndexingJobStatusSucceeded, IndexingJobStatusFailed, IndexingJobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsLastAttempt 判断当前执行是否为最后一次尝试，失败后不再重试
+func (j *IndexingJob) IsLastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
@@ -167,8 +167,9 @@ type MCPSearchDocument struct {
 	Snippet       string  `json:"snippet"` // 包含查询词的上下文片段
 	ContentType   string  `json:"content_type"`
 	Section       string  `json:"section"`
-	StartPosition int     `json:"start_position"` // 片段在原文档中的起始位置（字符数）
-	EndPosition   int     `json:"end_position"`   // 片段在原文档中的结束位置（字符数）
+	Language      string  `json:"language,omitempty"` // 代码块语言，仅 code 类型有值
+	StartPosition int     `json:"start_position"`     // 片段在原文档中的起始位置（字符偏移）
+	EndPosition   int     `json:"end_position"`       // 片段在原文档中的结束位置（字符偏移，不包含）
 }
 
 // MCPAPIKey API密钥模型
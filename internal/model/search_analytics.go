@@ -0,0 +1,83 @@
+package model
+
+import "time"
+
+// 搜索请求的来源
+const (
+	SearchSourceREST = "rest"
+	SearchSourceMCP  = "mcp"
+)
+
+// SearchQueryLog 搜索请求日志，用于统计检索需求和点击率
+type SearchQueryLog struct {
+	ID                string      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Query             string      `json:"query" gorm:"type:text;not null"`
+	NormalizedQuery   string      `json:"normalized_query" gorm:"type:text;not null;index"` // 小写并合并空白后的查询，用于聚合
+	SearchType        string      `json:"search_type" gorm:"not null"`
+	Filters           string      `json:"filters" gorm:"type:jsonb"`
+	Library           string      `json:"library" gorm:"index"` // 过滤条件中的库，没有时为排名第一的结果所属库
+	ResultCount       int64       `json:"result_count"`
+	ResultIDs         StringArray `json:"result_ids" gorm:"type:character varying[]"`          // 返回的搜索索引ID，按排名排列
+	ResultDocumentIDs StringArray `json:"result_document_ids" gorm:"type:character varying[]"` // 返回结果所属的文档ID
+	LatencyMs         int64       `json:"latency_ms"`
+	Source            string      `json:"source" gorm:"not null;index"` // rest 或 mcp
+	Caller            string      `json:"caller" gorm:"index"`          // 用户ID、MCP API密钥ID或客户端IP
+	Error             string      `json:"error,omitempty" gorm:"type:text"`
+	Clicks            int         `json:"clicks" gorm:"not null;default:0"`
+	CreatedAt         time.Time   `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName 返回搜索请求日志表名
+func (SearchQueryLog) TableName() string {
+	return "search_query_logs"
+}
+
+// SearchClick 搜索结果的点击，由搜索后获取文档内容的请求关联到最近的搜索
+type SearchClick struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	QueryLogID string    `json:"query_log_id" gorm:"type:uuid;not null;index"`
+	TargetID   string    `json:"target_id" gorm:"not null"` // 被获取的搜索索引ID或文档ID
+	DocumentID string    `json:"document_id"`
+	Position   int       `json:"position"` // 目标在搜索结果中的排名，从1开始，只匹配到文档时为文档首个结果的排名
+	Caller     string    `json:"caller" gorm:"index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName 返回搜索点击表名
+func (SearchClick) TableName() string {
+	return "search_clicks"
+}
+
+// SearchQueryStat 按规范化查询聚合的统计
+type SearchQueryStat struct {
+	Query           string    `json:"query"`
+	Searches        int64     `json:"searches"`
+	ZeroResults     int64     `json:"zero_results"`
+	ClickedSearches int64     `json:"clicked_searches"`
+	CTR             float64   `json:"ctr"` // 有点击的搜索占比
+	AvgResults      float64   `json:"avg_results"`
+	AvgLatencyMs    float64   `json:"avg_latency_ms"`
+	LastSearchedAt  time.Time `json:"last_searched_at"`
+}
+
+// LibraryDemand 按库聚合的检索需求
+type LibraryDemand struct {
+	Library         string  `json:"library"`
+	Searches        int64   `json:"searches"`
+	ZeroResults     int64   `json:"zero_results"`
+	ClickedSearches int64   `json:"clicked_searches"`
+	CTR             float64 `json:"ctr"`
+}
+
+// SearchAnalyticsReport 搜索分析报告
+type SearchAnalyticsReport struct {
+	Since             time.Time         `json:"since"`
+	TotalSearches     int64             `json:"total_searches"`
+	ZeroResultRate    float64           `json:"zero_result_rate"`
+	CTR               float64           `json:"ctr"`
+	TopQueries        []SearchQueryStat `json:"top_queries"`
+	ZeroResultQueries []SearchQueryStat `json:"zero_result_queries"`
+	LowCTRQueries     []SearchQueryStat `json:"low_ctr_queries"`
+	LibraryDemand     []LibraryDemand   `json:"library_demand"`
+	BySource          map[string]int64  `json:"by_source"`
+}
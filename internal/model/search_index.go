@@ -35,21 +35,56 @@ func (v *Vector) Scan(value interface{}) error {
 
 // SearchIndex 定义搜索索引模型
 type SearchIndex struct {
-	ID            string    `json:"id" gorm:"primaryKey"`
-	DocumentID    string    `json:"document_id" gorm:"not null;index"`
-	Version       string    `json:"version" gorm:"not null;index"`
-	Content       string    `json:"content" gorm:"type:text;not null"`
-	ContentType   string    `json:"content_type" gorm:"not null;index"` // text, code, etc.
-	Section       string    `json:"section" gorm:"index"`               // 文档章节
-	Keywords      string    `json:"keywords" gorm:"type:text"`          // 关键词
-	Vector        string    `json:"vector" gorm:"type:jsonb"`           // 语义向量，以JSON字符串格式存储
-	Embedding     []float32 `json:"embedding" gorm:"-"`                 // 真实嵌入向量，使用pgvector扩展（暂时禁用GORM自动迁移）
-	Metadata      string    `json:"metadata" gorm:"type:jsonb"`         // 额外元数据
-	Score         float32   `json:"score"`                              // 搜索相关度得分
-	StartPosition int       `json:"start_position"`                     // 片段在原文档中的起始位置（字符数）
-	EndPosition   int       `json:"end_position"`                       // 片段在原文档中的结束位置（字符数）
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                 string    `json:"id" gorm:"primaryKey"`
+	DocumentID         string    `json:"document_id" gorm:"not null;index"`
+	Version            string    `json:"version" gorm:"not null;index"`
+	Content            string    `json:"content" gorm:"type:text;not null"`
+	ContentType        string    `json:"content_type" gorm:"not null;index"`         // text, code, etc.
+	Section            string    `json:"section" gorm:"index"`                       // 文档章节
+	Keywords           string    `json:"keywords" gorm:"type:text"`                  // 关键词
+	Vector             string    `json:"vector" gorm:"type:jsonb"`                   // 语义向量，以JSON字符串格式存储
+	Embedding          []float32 `json:"embedding" gorm:"-"`                         // 真实嵌入向量，使用pgvector扩展（暂时禁用GORM自动迁移）
+	Metadata           string    `json:"metadata" gorm:"type:jsonb"`                 // 额外元数据
+	Score              float32   `json:"score"`                                      // 搜索相关度得分
+	SortKey            float64   `json:"-" gorm:"-"`                                 // 召回顺序的排序值（全文检索得分或向量余弦距离），用于游标分页定位
+	StartPosition      int       `json:"start_position"`                             // 片段在原文档中的起始位置（字符偏移，不是字节偏移）
+	EndPosition        int       `json:"end_position"`                               // 片段在原文档中的结束位置（字符偏移，不包含）
+	ContentHash        string    `json:"content_hash" gorm:"type:varchar(64);index"` // 分块内容的SHA-256，用于增量重建索引时复用向量
+	EmbeddingModel     string    `json:"embedding_model" gorm:"index"`               // 生成向量的嵌入模型，语义搜索只比较活动模型的向量
+	EmbeddingDimension int       `json:"embedding_dimension"`                        // 向量维度
+	CreatedAt          time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// IndexBuildResult 增量构建索引的结果，Reused 与 Embedded 之和为分块总数
+type IndexBuildResult struct {
+	Added    int `json:"added"`    // 新写入的分块数量
+	Removed  int `json:"removed"`  // 删除的分块数量
+	Updated  int `json:"updated"`  // 内容未变化、位置或元数据变化而原地更新的分块数量
+	Reused   int `json:"reused"`   // 复用已有向量的分块数量，包括内容未变化而保留的分块
+	Embedded int `json:"embedded"` // 重新生成向量的分块数量
+}
+
+// Add 累加另一次构建的结果
+func (r *IndexBuildResult) Add(other *IndexBuildResult) {
+	r.Added += other.Added
+	r.Removed += other.Removed
+	r.Updated += other.Updated
+	r.Reused += other.Reused
+	r.Embedded += other.Embedded
+}
+
+// HasChanges 判断索引是否有变化
+func (r *IndexBuildResult) HasChanges() bool {
+	return r.Added > 0 || r.Removed > 0 || r.Updated > 0
+}
+
+// IndexReconcileResult 对账所有文档版本索引的结果
+type IndexReconcileResult struct {
+	IndexBuildResult
+	Versions int `json:"versions"` // 检查的文档版本数量
+	Changed  int `json:"changed"`  // 索引有变化的文档版本数量
+	Failed   int `json:"failed"`   // 构建失败的文档版本数量
 }
 
 // SearchRequest 定义搜索请求模型
@@ -59,14 +94,129 @@ type SearchRequest struct {
 	Page       int                    `json:"page" binding:"min=1"`
 	Size       int                    `json:"size" binding:"min=1,max=100"`
 	SearchType string                 `json:"searchType" binding:"required"` // keyword, semantic, hybrid
+
+	// 混合搜索参数，未设置时使用服务端默认配置
+	HybridMode  string   `json:"hybrid_mode" binding:"omitempty,oneof=rrf weighted"` // 融合方式：rrf 或 weighted
+	HybridAlpha *float64 `json:"hybrid_alpha" binding:"omitempty,min=0,max=1"`       // 语义搜索的权重，关键词搜索的权重为 1-hybrid_alpha
+	RRFK        *int     `json:"rrf_k" binding:"omitempty,min=1"`                    // RRF 的平滑常数 k
+
+	// 重排序参数，未设置时使用服务端默认配置
+	Rerank     string `json:"rerank" binding:"omitempty,oneof=none heuristic cross-encoder"` // 重排序器：none、heuristic 或 cross-encoder
+	RerankTopN *int   `json:"rerank_top_n" binding:"omitempty,min=1,max=500"`                // 参与重排序的候选数量
+
+	// 游标分页：上一页响应中的 next_cursor，设置后忽略 page
+	Cursor string `json:"cursor"`
+
+	// 结果过少且有拼写建议时，自动使用纠正后的查询重新搜索
+	AutoCorrect bool `json:"auto_correct"`
+
+	// 在响应中返回同义词扩展等检索明细
+	Explain bool `json:"explain"`
+
+	// 需要统计的分面，如 library、version、document_type、tags、content_type、language
+	Facets []string `json:"facets" binding:"omitempty,dive,oneof=library version document_type tags content_type language"`
+
+	// 结果分组：按文档或库折叠，每组最多返回 PerGroup 个分块，分页和总数按组计算
+	GroupBy  string `json:"group_by" binding:"omitempty,oneof=document_id library"`
+	PerGroup int    `json:"per_group" binding:"omitempty,min=1,max=20"` // 每组返回的分块数量，未设置时使用服务端默认配置
+}
+
+// SimilarRequest 定义相似内容（more like this）请求模型，IndexID 和 DocumentID 至少提供一个
+type SimilarRequest struct {
+	IndexID       string                 `json:"index_id"`       // 源分块的搜索索引ID，优先于 DocumentID
+	DocumentID    string                 `json:"document_id"`    // 源文档ID，使用该文档版本全部分块的平均向量
+	SourceVersion string                 `json:"source_version"` // 源文档版本，未指定时使用最新版本
+	Filters       map[string]interface{} `json:"filters"`        // 结果的过滤条件，如 library、version、content_type、language
+	Size          int                    `json:"size" binding:"omitempty,min=1,max=100"`
+
+	// 是否包含与源同一文档的分块，默认排除；包含时仍排除源分块本身
+	IncludeSameDocument bool `json:"include_same_document"`
 }
 
 // SearchResponse 定义搜索响应模型
 type SearchResponse struct {
-	Total int64          `json:"total"`
-	Items []SearchResult `json:"items"`
-	Page  int            `json:"page"`
-	Size  int            `json:"size"`
+	Total  int64                    `json:"total"`
+	Items  []SearchResult           `json:"items"`
+	Page   int                      `json:"page"`
+	Size   int                      `json:"size"`
+	Facets map[string][]FacetBucket `json:"facets,omitempty"` // 各分面的取值及数量，基于全部匹配结果统计
+
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页游标，没有更多结果时为空
+
+	// 嵌入服务不可用时混合搜索只返回关键词搜索结果，此时为 true
+	Degraded bool `json:"degraded,omitempty"`
+
+	// 拼写建议，只在关键词搜索结果过少时返回
+	Suggestions    []SpellingSuggestion `json:"suggestions,omitempty"`
+	SuggestedQuery string               `json:"suggested_query,omitempty"` // 使用最佳候选词替换后的查询
+	CorrectedQuery string               `json:"corrected_query,omitempty"` // 自动纠正后实际执行的查询
+
+	Expansions []QueryExpansion `json:"expansions,omitempty"` // 同义词扩展明细，只在请求 explain 时返回
+
+	// 结果分组，只在请求 group_by 时返回；此时 Total 为组数，Items 为各组分块按组依次排列
+	Groups    []SearchResultGroup `json:"groups,omitempty"`
+	TotalHits int64               `json:"total_hits,omitempty"` // 分组时匹配的分块总数
+}
+
+// SearchResultGroup 按文档或库分组的搜索结果
+type SearchResultGroup struct {
+	Key   string         `json:"key"`   // 分组的取值，即文档ID或库名称
+	Score float32        `json:"score"` // 组内最高得分
+	Hits  int            `json:"hits"`  // 候选结果中属于该组的分块数量
+	Items []SearchResult `json:"items"` // 组内得分最高的分块
+}
+
+// 搜索结果分组方式
+const (
+	GroupByDocument = "document_id"
+	GroupByLibrary  = "library"
+)
+
+// IsValidGroupBy 判断是否为支持的搜索结果分组方式
+func IsValidGroupBy(groupBy string) bool {
+	return groupBy == GroupByDocument || groupBy == GroupByLibrary
+}
+
+// SpellingSuggestion 查询词的拼写建议
+type SpellingSuggestion struct {
+	Term       string              `json:"term"`       // 查询中的原词
+	Candidates []SpellingCandidate `json:"candidates"` // 按编辑距离和文档频率排序的候选词
+}
+
+// SpellingCandidate 拼写建议的候选词
+type SpellingCandidate struct {
+	Term     string `json:"term"`
+	Distance int    `json:"distance"` // 与原词的编辑距离
+	DocFreq  int64  `json:"doc_freq"` // 包含该词的分块数量
+}
+
+// 搜索分面
+const (
+	FacetLibrary      = "library"
+	FacetVersion      = "version"
+	FacetDocumentType = "document_type"
+	FacetTags         = "tags"
+	FacetContentType  = "content_type"
+	FacetLanguage     = "language"
+)
+
+// SearchFacets 支持的搜索分面
+var SearchFacets = []string{FacetLibrary, FacetVersion, FacetDocumentType, FacetTags, FacetContentType, FacetLanguage}
+
+// IsValidFacet 判断是否为支持的搜索分面
+func IsValidFacet(facet string) bool {
+	for _, name := range SearchFacets {
+		if name == facet {
+			return true
+		}
+	}
+	return false
+}
+
+// FacetBucket 分面中的一个取值及匹配的分块数量
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
 }
 
 // SearchResult 定义搜索结果模型
@@ -81,10 +231,40 @@ type SearchResult struct {
 	Score       float32                `json:"score"`
 	ContentType string                 `json:"content_type"`
 	Section     string                 `json:"section"`
+	Highlights  []Highlight            `json:"highlights,omitempty"` // 命中片段，按在文档中的位置排列
 	Metadata    map[string]interface{} `json:"metadata"`
 }
 
+// Highlight 命中片段，偏移均为相对原文档的字符位置
+type Highlight struct {
+	Fragment string       `json:"fragment"`
+	Start    int          `json:"start"` // 片段在原文档中的起始位置
+	End      int          `json:"end"`   // 片段在原文档中的结束位置（不包含）
+	Matches  []MatchRange `json:"matches"`
+}
+
+// MatchRange 查询词在原文档中的命中位置
+type MatchRange struct {
+	Term  string `json:"term"` // 命中的查询词或短语
+	Start int    `json:"start"`
+	End   int    `json:"end"` // 不包含
+}
+
 // TableName 指定SearchIndex模型的表名
 func (SearchIndex) TableName() string {
 	return "search_indices"
 }
+
+// 向量检索方式
+const (
+	VectorSearchModePgvector = "pgvector"  // 使用pgvector在数据库中计算相似度
+	VectorSearchModeInMemory = "in_memory" // 读取候选向量在内存中计算相似度
+)
+
+// VectorSearchStatus 向量检索状态，启动时检测并在健康检查中报告
+type VectorSearchStatus struct {
+	Mode      string `json:"mode"`             // 向量检索方式
+	IndexType string `json:"index_type"`       // 近似最近邻索引类型：hnsw、ivfflat 或 none
+	Dimension int    `json:"dimension"`        // embedding 列的维度
+	Reason    string `json:"reason,omitempty"` // 使用内存计算的原因
+}
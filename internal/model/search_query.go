@@ -0,0 +1,52 @@
+package model
+
+// QueryNodeType 查询语法树节点类型
+type QueryNodeType string
+
+const (
+	QueryNodeTerm   QueryNodeType = "term"   // 单个关键词，Prefix 为 true 时按前缀匹配
+	QueryNodePhrase QueryNodeType = "phrase" // 短语，要求词语相邻且顺序一致
+	QueryNodeAnd    QueryNodeType = "and"    // 所有子节点都匹配
+	QueryNodeOr     QueryNodeType = "or"     // 任意子节点匹配
+	QueryNodeNot    QueryNodeType = "not"    // 子节点不匹配，只有一个子节点
+)
+
+// QueryNode 结构化查询的语法树节点，字段限定条件在解析时已转换为过滤条件
+type QueryNode struct {
+	Type     QueryNodeType `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	Prefix   bool          `json:"prefix,omitempty"`
+	Synonym  bool          `json:"synonym,omitempty"` // 由同义词扩展生成的节点
+	Children []*QueryNode  `json:"children,omitempty"`
+}
+
+// IsLeaf 判断是否为关键词或短语节点
+func (n *QueryNode) IsLeaf() bool {
+	return n.Type == QueryNodeTerm || n.Type == QueryNodePhrase
+}
+
+// PositiveLeaves 返回不在 NOT 之下的关键词和短语节点，用于计算相关度得分
+func (n *QueryNode) PositiveLeaves() []*QueryNode {
+	var leaves []*QueryNode
+	n.collectLeaves(false, &leaves)
+	return leaves
+}
+
+// collectLeaves 递归收集关键词和短语节点，negated 表示当前节点是否被奇数个 NOT 包含
+func (n *QueryNode) collectLeaves(negated bool, leaves *[]*QueryNode) {
+	if n == nil {
+		return
+	}
+	if n.IsLeaf() {
+		if !negated {
+			*leaves = append(*leaves, n)
+		}
+		return
+	}
+	if n.Type == QueryNodeNot {
+		negated = !negated
+	}
+	for _, child := range n.Children {
+		child.collectLeaves(negated, leaves)
+	}
+}
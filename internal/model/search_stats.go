@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// SearchTermStat 检索词统计，记录包含该词的分块数量，用于计算BM25的IDF
+type SearchTermStat struct {
+	Term      string    `json:"term" gorm:"primaryKey;type:varchar(255)"`
+	DocFreq   int64     `json:"doc_freq" gorm:"not null;default:0"` // 包含该词的分块数量
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定SearchTermStat模型的表名
+func (SearchTermStat) TableName() string {
+	return "search_term_stats"
+}
+
+// SearchCorpusStats 索引语料统计，只有一行记录
+type SearchCorpusStats struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	ChunkCount  int64     `json:"chunk_count" gorm:"not null;default:0"`  // 分块总数
+	TotalLength int64     `json:"total_length" gorm:"not null;default:0"` // 所有分块的检索词总数
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定SearchCorpusStats模型的表名
+func (SearchCorpusStats) TableName() string {
+	return "search_corpus_stats"
+}
+
+// AverageLength 返回分块的平均长度
+func (s *SearchCorpusStats) AverageLength() float64 {
+	if s == nil || s.ChunkCount <= 0 {
+		return 0
+	}
+	return float64(s.TotalLength) / float64(s.ChunkCount)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// SynonymSet 同义词组，组内的词（包括缩写）在检索时可以互相替换
+type SynonymSet struct {
+	ID          string      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Library     string      `json:"library" gorm:"not null;default:'';index"` // 所属库，为空表示对所有库生效
+	Terms       StringArray `json:"terms" gorm:"type:character varying[];not null"`
+	Description string      `json:"description"`
+	CreatedAt   time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 返回同义词组表名
+func (SynonymSet) TableName() string {
+	return "search_synonym_sets"
+}
+
+// SynonymSetRequest 创建或更新同义词组的请求
+type SynonymSetRequest struct {
+	Library     string   `json:"library"`
+	Terms       []string `json:"terms" binding:"required,min=2"`
+	Description string   `json:"description"`
+}
+
+// QueryExpansion 查询词的同义词扩展明细
+type QueryExpansion struct {
+	Term     string   `json:"term"`     // 查询中的原词
+	Synonyms []string `json:"synonyms"` // 扩展的同义词
+	Weight   float64  `json:"weight"`   // 同义词相对原词的得分权重
+}
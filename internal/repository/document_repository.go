@@ -13,6 +13,7 @@ type DocumentRepository interface {
 	Create(ctx context.Context, document *model.Document) error
 	GetByID(ctx context.Context, id string) (*model.Document, error)
 	List(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
+	ListAfter(ctx context.Context, after *model.Cursor, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
 	Update(ctx context.Context, id string, updates map[string]interface{}) error
 	Delete(ctx context.Context, id string) error
 	GetByLibrary(ctx context.Context, library string, page, size int) ([]*model.Document, int64, error)
@@ -56,42 +57,7 @@ func (r *documentRepository) List(ctx context.Context, page, size int, filters m
 	var documents []*model.Document
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&model.Document{})
-
-	// 应用过滤条件
-	if filters != nil {
-		if library, ok := filters["library"]; ok {
-			query = query.Where("library = ?", library)
-		}
-		if docType, ok := filters["type"]; ok {
-			query = query.Where("type = ?", docType)
-		}
-		if category, ok := filters["category"]; ok {
-			query = query.Where("category = ?", category)
-		}
-		if version, ok := filters["version"]; ok {
-			query = query.Where("version = ?", version)
-		}
-		if status, ok := filters["status"]; ok {
-			query = query.Where("status = ?", status)
-		}
-		if name, ok := filters["name"]; ok {
-			query = query.Where("name LIKE ?", "%"+name.(string)+"%")
-		}
-		if tags, ok := filters["tags"]; ok {
-			if tagList, ok := tags.([]string); ok && len(tagList) > 0 {
-				for _, tag := range tagList {
-					query = query.Where("? = ANY(tags)", tag)
-				}
-			}
-		}
-		if startTime, ok := filters["start_time"]; ok {
-			query = query.Where("created_at >= ?", startTime)
-		}
-		if endTime, ok := filters["end_time"]; ok {
-			query = query.Where("created_at <= ?", endTime)
-		}
-	}
+	query := r.applyFilters(r.db.WithContext(ctx).Model(&model.Document{}), filters)
 
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -104,8 +70,8 @@ func (r *documentRepository) List(ctx context.Context, page, size int, filters m
 		query = query.Offset(offset).Limit(size)
 	}
 
-	// 排序
-	query = query.Order("created_at DESC")
+	// 排序，创建时间相同时按ID排序，与游标分页的顺序一致
+	query = query.Order("created_at DESC, id DESC")
 
 	// 执行查询
 	if err := query.Find(&documents).Error; err != nil {
@@ -205,3 +171,68 @@ func (r *documentRepository) Count(ctx context.Context, filters map[string]inter
 	err := query.Count(&count).Error
 	return count, err
 }
+
+// ListAfter 按创建时间倒序获取游标之后的文档，after 为空时从第一条开始
+func (r *documentRepository) ListAfter(ctx context.Context, after *model.Cursor, size int, filters map[string]interface{}) ([]*model.Document, int64, error) {
+	var documents []*model.Document
+	var total int64
+
+	query := r.applyFilters(r.db.WithContext(ctx).Model(&model.Document{}), filters)
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 使用 (created_at, id) 定位，不需要扫描之前的页
+	if after != nil && after.CreatedAt != nil {
+		query = query.Where("(created_at, id) < (?, ?)", *after.CreatedAt, after.ID)
+	}
+
+	if err := query.Order("created_at DESC, id DESC").Limit(size).Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return documents, total, nil
+}
+
+// applyFilters 应用文档列表的过滤条件
+func (r *documentRepository) applyFilters(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	if filters == nil {
+		return query
+	}
+
+	if library, ok := filters["library"]; ok {
+		query = query.Where("library = ?", library)
+	}
+	if docType, ok := filters["type"]; ok {
+		query = query.Where("type = ?", docType)
+	}
+	if category, ok := filters["category"]; ok {
+		query = query.Where("category = ?", category)
+	}
+	if version, ok := filters["version"]; ok {
+		query = query.Where("version = ?", version)
+	}
+	if status, ok := filters["status"]; ok {
+		query = query.Where("status = ?", status)
+	}
+	if name, ok := filters["name"]; ok {
+		query = query.Where("name LIKE ?", "%"+name.(string)+"%")
+	}
+	if tags, ok := filters["tags"]; ok {
+		if tagList, ok := tags.([]string); ok && len(tagList) > 0 {
+			for _, tag := range tagList {
+				query = query.Where("? = ANY(tags)", tag)
+			}
+		}
+	}
+	if startTime, ok := filters["start_time"]; ok {
+		query = query.Where("created_at >= ?", startTime)
+	}
+	if endTime, ok := filters["end_time"]; ok {
+		query = query.Where("created_at <= ?", endTime)
+	}
+
+	return query
+}
@@ -24,6 +24,7 @@ type DocumentVersionRepository interface {
 	Delete(ctx context.Context, id string) error
 	DeleteByDocumentID(ctx context.Context, documentID string) error
 	Count(ctx context.Context, documentID string) (int64, error)
+	GetLibraryVersions(ctx context.Context, library string) ([]*model.LibraryVersion, error)
 }
 
 // documentVersionRepository 文档版本仓库实现
@@ -192,3 +193,20 @@ func (r *documentVersionRepository) Count(ctx context.Context, documentID string
 		Count(&count).Error
 	return count, err
 }
+
+// GetLibraryVersions 获取库中已处理完成的文档版本，library 为空时返回所有库
+func (r *documentVersionRepository) GetLibraryVersions(ctx context.Context, library string) ([]*model.LibraryVersion, error) {
+	var versions []*model.LibraryVersion
+	query := r.db.WithContext(ctx).
+		Table("document_versions").
+		Select("document_versions.document_id, documents.library, TRIM(document_versions.version) AS version").
+		Joins("JOIN documents ON documents.id = document_versions.document_id").
+		Where("document_versions.status = ?", model.DocumentStatusCompleted)
+	if library != "" {
+		query = query.Where("documents.library = ?", library)
+	}
+	if err := query.Scan(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
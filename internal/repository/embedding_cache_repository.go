@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// EmbeddingCacheRepository 嵌入向量缓存仓库接口
+type EmbeddingCacheRepository interface {
+	Get(ctx context.Context, embeddingModel string, hashes []string) (map[string]string, error)
+	Save(ctx context.Context, entries []*model.EmbeddingCacheEntry) error
+	Touch(ctx context.Context, embeddingModel string, hashes []string) error
+	DeleteUnusedBefore(ctx context.Context, before time.Time) (int64, error)
+	TrimToSize(ctx context.Context, maxEntries int64) (int64, error)
+}
+
+// embeddingCacheRepository 嵌入向量缓存仓库实现
+type embeddingCacheRepository struct {
+	db *gorm.DB
+}
+
+// NewEmbeddingCacheRepository 创建嵌入向量缓存仓库实例
+func NewEmbeddingCacheRepository(db *gorm.DB) EmbeddingCacheRepository {
+	return &embeddingCacheRepository{
+		db: db,
+	}
+}
+
+// Get 按文本哈希获取模型的缓存向量（JSON字符串）
+func (r *embeddingCacheRepository) Get(ctx context.Context, embeddingModel string, hashes []string) (map[string]string, error) {
+	vectors := make(map[string]string, len(hashes))
+	for start := 0; start < len(hashes); start += contentHashBatchSize {
+		end := start + contentHashBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		var rows []struct {
+			TextHash string
+			Vector   string
+		}
+		err := r.db.WithContext(ctx).Model(&model.EmbeddingCacheEntry{}).
+			Select("text_hash, vector::text AS vector").
+			Where("model = ? AND text_hash IN ?", embeddingModel, hashes[start:end]).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			vectors[row.TextHash] = row.Vector
+		}
+	}
+	return vectors, nil
+}
+
+// Save 写入缓存向量，已存在的向量保持不变
+func (r *embeddingCacheRepository) Save(ctx context.Context, entries []*model.EmbeddingCacheEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entries, 100).Error
+}
+
+// Touch 记录缓存向量的命中，更新命中次数和最近使用时间
+func (r *embeddingCacheRepository) Touch(ctx context.Context, embeddingModel string, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&model.EmbeddingCacheEntry{}).
+		Where("model = ? AND text_hash IN ?", embeddingModel, hashes).
+		UpdateColumns(map[string]interface{}{
+			"hits":         gorm.Expr("hits + 1"),
+			"last_used_at": time.Now(),
+		}).Error
+}
+
+// DeleteUnusedBefore 删除 before 之后没有使用过的缓存向量，返回删除的数量
+func (r *embeddingCacheRepository) DeleteUnusedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("last_used_at < ?", before).Delete(&model.EmbeddingCacheEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// TrimToSize 缓存向量超过 maxEntries 条时删除最久未使用的向量，返回删除的数量
+func (r *embeddingCacheRepository) TrimToSize(ctx context.Context, maxEntries int64) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM embedding_cache WHERE (model, text_hash) IN (
+			SELECT model, text_hash FROM embedding_cache ORDER BY last_used_at DESC OFFSET ?
+		)
+	`, maxEntries)
+	return result.RowsAffected, result.Error
+}
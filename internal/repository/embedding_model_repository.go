@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// EmbeddingModelRepository 嵌入模型仓库接口
+type EmbeddingModelRepository interface {
+	GetActive(ctx context.Context) (*model.EmbeddingModel, error)
+	List(ctx context.Context) ([]*model.EmbeddingModel, error)
+	Activate(ctx context.Context, name string, dimension int) (*model.EmbeddingModel, error)
+}
+
+// embeddingModelRepository 嵌入模型仓库实现
+type embeddingModelRepository struct {
+	db *gorm.DB
+}
+
+// NewEmbeddingModelRepository 创建嵌入模型仓库实例
+func NewEmbeddingModelRepository(db *gorm.DB) EmbeddingModelRepository {
+	return &embeddingModelRepository{
+		db: db,
+	}
+}
+
+// GetActive 获取活动模型，没有时返回 nil
+func (r *embeddingModelRepository) GetActive(ctx context.Context) (*model.EmbeddingModel, error) {
+	var embeddingModel model.EmbeddingModel
+	err := r.db.WithContext(ctx).Where("active = ?", true).First(&embeddingModel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &embeddingModel, nil
+}
+
+// List 获取登记的嵌入模型，按创建时间排序
+func (r *embeddingModelRepository) List(ctx context.Context) ([]*model.EmbeddingModel, error) {
+	var models []*model.EmbeddingModel
+	err := r.db.WithContext(ctx).Order("created_at").Find(&models).Error
+	return models, err
+}
+
+// Activate 登记模型（已登记时更新维度）并设为唯一的活动模型
+func (r *embeddingModelRepository) Activate(ctx context.Context, name string, dimension int) (*model.EmbeddingModel, error) {
+	var embeddingModel model.EmbeddingModel
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("name = ?", name).First(&embeddingModel).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Model(&model.EmbeddingModel{}).Where("name <> ? AND active = ?", name, true).Update("active", false).Error; err != nil {
+			return err
+		}
+
+		if embeddingModel.ID == "" {
+			now := time.Now()
+			embeddingModel = model.EmbeddingModel{Name: name, Dimension: dimension, Active: true, ActivatedAt: &now}
+			return tx.Create(&embeddingModel).Error
+		}
+		updates := map[string]interface{}{"dimension": dimension}
+		if !embeddingModel.Active {
+			now := time.Now()
+			updates["active"] = true
+			updates["activated_at"] = now
+		}
+		return tx.Model(&embeddingModel).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &embeddingModel, nil
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// IndexingJobRepository 索引任务仓库接口
+type IndexingJobRepository interface {
+	Create(ctx context.Context, job *model.IndexingJob) error
+	GetByID(ctx context.Context, id string) (*model.IndexingJob, error)
+	List(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.IndexingJob, int64, error)
+	FindPending(ctx context.Context, jobType model.IndexingJobType, documentID, version string) (*model.IndexingJob, error)
+	ClaimNext(ctx context.Context, workerID string) (*model.IndexingJob, error)
+	Heartbeat(ctx context.Context, id, workerID string, done, total int) (bool, error)
+	Finish(ctx context.Context, id, workerID string, status model.IndexingJobStatus, result, lastError string) (bool, error)
+	Reschedule(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error)
+	Requeue(ctx context.Context, id, workerID string, lastError string) (bool, error)
+	Cancel(ctx context.Context, id string) (bool, error)
+	Retry(ctx context.Context, id string) (bool, error)
+	RequeueStale(ctx context.Context, heartbeatBefore time.Time) (int64, error)
+}
+
+// indexingJobRepository 索引任务仓库实现
+type indexingJobRepository struct {
+	db *gorm.DB
+}
+
+// NewIndexingJobRepository 创建索引任务仓库实例
+func NewIndexingJobRepository(db *gorm.DB) IndexingJobRepository {
+	return &indexingJobRepository{
+		db: db,
+	}
+}
+
+// Create 创建索引任务
+func (r *indexingJobRepository) Create(ctx context.Context, job *model.IndexingJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// GetByID 根据ID获取索引任务
+func (r *indexingJobRepository) GetByID(ctx context.Context, id string) (*model.IndexingJob, error) {
+	var job model.IndexingJob
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 获取索引任务列表，支持按状态、类型、文档ID和版本过滤，按创建时间倒序排列
+func (r *indexingJobRepository) List(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.IndexingJob, int64, error) {
+	var jobs []*model.IndexingJob
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.IndexingJob{})
+	for _, field := range []string{"status", "type", "document_id", "version"} {
+		if value, ok := filters[field]; ok && value != "" {
+			query = query.Where(field+" = ?", value)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && size > 0 {
+		query = query.Offset((page - 1) * size).Limit(size)
+	}
+	if err := query.Order("created_at DESC, id DESC").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// FindPending 查找同类型、同文档版本的待执行任务，不存在时返回 gorm.ErrRecordNotFound
+func (r *indexingJobRepository) FindPending(ctx context.Context, jobType model.IndexingJobType, documentID, version string) (*model.IndexingJob, error) {
+	var job model.IndexingJob
+	err := r.db.WithContext(ctx).
+		Where("type = ? AND document_id = ? AND version = ? AND status = ?", jobType, documentID, version, model.IndexingJobStatusPending).
+		Order("created_at ASC").
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimNext 领取下一个可执行的任务：按优先级从高到低、执行时间从早到晚
+// 使用 FOR UPDATE SKIP LOCKED 保证多个工作进程不会领取同一个任务，没有可执行任务时返回 nil
+func (r *indexingJobRepository) ClaimNext(ctx context.Context, workerID string) (*model.IndexingJob, error) {
+	now := time.Now()
+	var jobs []*model.IndexingJob
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE indexing_jobs
+		SET status = ?, attempts = attempts + 1, worker_id = ?, heartbeat_at = ?, started_at = ?, finished_at = NULL, updated_at = ?
+		WHERE id = (
+			SELECT id FROM indexing_jobs
+			WHERE status = ? AND run_at <= ?
+			ORDER BY priority DESC, run_at ASC, created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, model.IndexingJobStatusRunning, workerID, now, now, now, model.IndexingJobStatusPending, now).Scan(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// Heartbeat 更新执行中任务的心跳和进度，任务已被取消或被其他工作进程接管时返回 false
+func (r *indexingJobRepository) Heartbeat(ctx context.Context, id, workerID string, done, total int) (bool, error) {
+	now := time.Now()
+	return r.updateRunning(ctx, id, workerID, map[string]interface{}{
+		"progress_done":  done,
+		"progress_total": total,
+		"heartbeat_at":   now,
+		"updated_at":     now,
+	})
+}
+
+// Finish 结束执行中的任务，任务已被取消或被其他工作进程接管时返回 false
+func (r *indexingJobRepository) Finish(ctx context.Context, id, workerID string, status model.IndexingJobStatus, result, lastError string) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      status,
+		"last_error":  lastError,
+		"finished_at": now,
+		"updated_at":  now,
+	}
+	if result != "" {
+		updates["result"] = result
+	}
+	return r.updateRunning(ctx, id, workerID, updates)
+}
+
+// Reschedule 将执行失败的任务放回队列，在 runAt 之后重试
+func (r *indexingJobRepository) Reschedule(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error) {
+	return r.updateRunning(ctx, id, workerID, map[string]interface{}{
+		"status":       model.IndexingJobStatusPending,
+		"run_at":       runAt,
+		"last_error":   lastError,
+		"worker_id":    "",
+		"heartbeat_at": nil,
+		"updated_at":   time.Now(),
+	})
+}
+
+// Requeue 将未执行完的任务立即放回队列并退还本次领取计入的尝试次数，用于工作进程停止时中断的任务
+func (r *indexingJobRepository) Requeue(ctx context.Context, id, workerID string, lastError string) (bool, error) {
+	now := time.Now()
+	return r.updateRunning(ctx, id, workerID, map[string]interface{}{
+		"status":       model.IndexingJobStatusPending,
+		"attempts":     gorm.Expr("GREATEST(attempts - 1, 0)"),
+		"run_at":       now,
+		"last_error":   lastError,
+		"worker_id":    "",
+		"heartbeat_at": nil,
+		"updated_at":   now,
+	})
+}
+
+// updateRunning 更新由指定工作进程执行中的任务
+func (r *indexingJobRepository) updateRunning(ctx context.Context, id, workerID string, updates map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.IndexingJob{}).
+		Where("id = ? AND status = ? AND worker_id = ?", id, model.IndexingJobStatusRunning, workerID).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Cancel 取消待执行或执行中的任务，任务已结束时返回 false
+func (r *indexingJobRepository) Cancel(ctx context.Context, id string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.IndexingJob{}).
+		Where("id = ? AND status IN ?", id, []model.IndexingJobStatus{model.IndexingJobStatusPending, model.IndexingJobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      model.IndexingJobStatusCancelled,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Retry 将失败或已取消的任务重新放回队列，重置尝试次数和进度
+func (r *indexingJobRepository) Retry(ctx context.Context, id string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.IndexingJob{}).
+		Where("id = ? AND status IN ?", id, []model.IndexingJobStatus{model.IndexingJobStatusFailed, model.IndexingJobStatusCancelled}).
+		Updates(map[string]interface{}{
+			"status":         model.IndexingJobStatusPending,
+			"attempts":       0,
+			"last_error":     "",
+			"progress_done":  0,
+			"progress_total": 0,
+			"run_at":         now,
+			"worker_id":      "",
+			"heartbeat_at":   nil,
+			"started_at":     nil,
+			"finished_at":    nil,
+			"updated_at":     now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RequeueStale 回收心跳超时的执行中任务（工作进程已退出），还有重试次数的放回队列，否则标记为失败
+func (r *indexingJobRepository) RequeueStale(ctx context.Context, heartbeatBefore time.Time) (int64, error) {
+	now := time.Now()
+	var recovered int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stale := func() *gorm.DB {
+			return tx.Model(&model.IndexingJob{}).
+				Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", model.IndexingJobStatusRunning, heartbeatBefore)
+		}
+
+		failed := stale().Where("attempts >= max_attempts").Updates(map[string]interface{}{
+			"status":      model.IndexingJobStatusFailed,
+			"last_error":  "worker stopped before the job finished",
+			"finished_at": now,
+			"updated_at":  now,
+		})
+		if failed.Error != nil {
+			return failed.Error
+		}
+
+		requeued := stale().Updates(map[string]interface{}{
+			"status":       model.IndexingJobStatusPending,
+			"run_at":       now,
+			"worker_id":    "",
+			"heartbeat_at": nil,
+			"updated_at":   now,
+		})
+		if requeued.Error != nil {
+			return requeued.Error
+		}
+
+		recovered = failed.RowsAffected + requeued.RowsAffected
+		return nil
+	})
+	return recovered, err
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// SearchAnalyticsRepository 搜索分析仓库接口
+type SearchAnalyticsRepository interface {
+	CreateQueryLog(ctx context.Context, entry *model.SearchQueryLog) error
+	FindRecentQueryLog(ctx context.Context, caller, targetID string, since time.Time) (*model.SearchQueryLog, error)
+	CreateClick(ctx context.Context, click *model.SearchClick) error
+	GetTotals(ctx context.Context, since time.Time) (total, zeroResults, clicked int64, err error)
+	CountBySource(ctx context.Context, since time.Time) (map[string]int64, error)
+	GetTopQueries(ctx context.Context, since time.Time, limit int) ([]model.SearchQueryStat, error)
+	GetZeroResultQueries(ctx context.Context, since time.Time, limit int) ([]model.SearchQueryStat, error)
+	GetLowCTRQueries(ctx context.Context, since time.Time, minSearches int64, limit int) ([]model.SearchQueryStat, error)
+	GetLibraryDemand(ctx context.Context, since time.Time, limit int) ([]model.LibraryDemand, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// searchAnalyticsRepository 搜索分析仓库实现
+type searchAnalyticsRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchAnalyticsRepository 创建搜索分析仓库实例
+func NewSearchAnalyticsRepository(db *gorm.DB) SearchAnalyticsRepository {
+	return &searchAnalyticsRepository{
+		db: db,
+	}
+}
+
+// CreateQueryLog 保存搜索请求日志
+func (r *searchAnalyticsRepository) CreateQueryLog(ctx context.Context, entry *model.SearchQueryLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// FindRecentQueryLog 查找调用方在 since 之后最近一次返回了目标（搜索索引ID或文档ID）的搜索，没有时返回 gorm.ErrRecordNotFound
+func (r *searchAnalyticsRepository) FindRecentQueryLog(ctx context.Context, caller, targetID string, since time.Time) (*model.SearchQueryLog, error) {
+	var entry model.SearchQueryLog
+	err := r.db.WithContext(ctx).
+		Where("caller = ? AND created_at >= ?", caller, since).
+		Where("(? = ANY(result_ids) OR ? = ANY(result_document_ids))", targetID, targetID).
+		Order("created_at DESC").
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateClick 保存搜索结果的点击，并累加对应搜索的点击次数
+func (r *searchAnalyticsRepository) CreateClick(ctx context.Context, click *model.SearchClick) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(click).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.SearchQueryLog{}).
+			Where("id = ?", click.QueryLogID).
+			UpdateColumn("clicks", gorm.Expr("clicks + 1")).Error
+	})
+}
+
+// successfulSince 返回 since 之后执行成功的搜索
+func (r *searchAnalyticsRepository) successfulSince(ctx context.Context, since time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.SearchQueryLog{}).
+		Where("created_at >= ? AND COALESCE(error, '') = ''", since)
+}
+
+// GetTotals 统计 since 之后的搜索总数、无结果搜索数和有点击的搜索数
+func (r *searchAnalyticsRepository) GetTotals(ctx context.Context, since time.Time) (int64, int64, int64, error) {
+	var row struct {
+		Total       int64
+		ZeroResults int64
+		Clicked     int64
+	}
+	err := r.successfulSince(ctx, since).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE result_count = 0) AS zero_results, COUNT(*) FILTER (WHERE clicks > 0) AS clicked").
+		Scan(&row).Error
+	return row.Total, row.ZeroResults, row.Clicked, err
+}
+
+// CountBySource 按来源统计 since 之后的搜索数
+func (r *searchAnalyticsRepository) CountBySource(ctx context.Context, since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Source string
+		Count  int64
+	}
+	if err := r.successfulSince(ctx, since).Select("source, COUNT(*) AS count").Group("source").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Source] = row.Count
+	}
+	return counts, nil
+}
+
+// queryStatColumns 按规范化查询聚合的统计列
+const queryStatColumns = `normalized_query AS query,
+	COUNT(*) AS searches,
+	COUNT(*) FILTER (WHERE result_count = 0) AS zero_results,
+	COUNT(*) FILTER (WHERE clicks > 0) AS clicked_searches,
+	AVG(result_count) AS avg_results,
+	AVG(latency_ms) AS avg_latency_ms,
+	MAX(created_at) AS last_searched_at`
+
+// queryStats 按规范化查询聚合 since 之后的搜索
+func (r *searchAnalyticsRepository) queryStats(ctx context.Context, since time.Time, scope func(db *gorm.DB) *gorm.DB, limit int) ([]model.SearchQueryStat, error) {
+	var stats []model.SearchQueryStat
+	query := r.successfulSince(ctx, since).Select(queryStatColumns).Group("normalized_query")
+	if err := scope(query).Limit(limit).Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if stats[i].Searches > 0 {
+			stats[i].CTR = float64(stats[i].ClickedSearches) / float64(stats[i].Searches)
+		}
+	}
+	return stats, nil
+}
+
+// GetTopQueries 获取搜索次数最多的查询
+func (r *searchAnalyticsRepository) GetTopQueries(ctx context.Context, since time.Time, limit int) ([]model.SearchQueryStat, error) {
+	return r.queryStats(ctx, since, func(db *gorm.DB) *gorm.DB {
+		return db.Order("searches DESC, last_searched_at DESC")
+	}, limit)
+}
+
+// GetZeroResultQueries 获取没有结果次数最多的查询
+func (r *searchAnalyticsRepository) GetZeroResultQueries(ctx context.Context, since time.Time, limit int) ([]model.SearchQueryStat, error) {
+	return r.queryStats(ctx, since, func(db *gorm.DB) *gorm.DB {
+		return db.Having("COUNT(*) FILTER (WHERE result_count = 0) > 0").
+			Order("zero_results DESC, last_searched_at DESC")
+	}, limit)
+}
+
+// GetLowCTRQueries 获取有结果但点击率最低的查询，只统计搜索次数不少于 minSearches 的查询
+func (r *searchAnalyticsRepository) GetLowCTRQueries(ctx context.Context, since time.Time, minSearches int64, limit int) ([]model.SearchQueryStat, error) {
+	return r.queryStats(ctx, since, func(db *gorm.DB) *gorm.DB {
+		return db.Where("result_count > 0").
+			Having("COUNT(*) >= ?", minSearches).
+			Order("COUNT(*) FILTER (WHERE clicks > 0)::float / COUNT(*) ASC, searches DESC")
+	}, limit)
+}
+
+// GetLibraryDemand 按库统计检索需求，按搜索次数排序
+func (r *searchAnalyticsRepository) GetLibraryDemand(ctx context.Context, since time.Time, limit int) ([]model.LibraryDemand, error) {
+	var demand []model.LibraryDemand
+	err := r.successfulSince(ctx, since).
+		Select(`library,
+			COUNT(*) AS searches,
+			COUNT(*) FILTER (WHERE result_count = 0) AS zero_results,
+			COUNT(*) FILTER (WHERE clicks > 0) AS clicked_searches`).
+		Where("library <> ''").
+		Group("library").
+		Order("searches DESC").
+		Limit(limit).
+		Scan(&demand).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range demand {
+		if demand[i].Searches > 0 {
+			demand[i].CTR = float64(demand[i].ClickedSearches) / float64(demand[i].Searches)
+		}
+	}
+	return demand, nil
+}
+
+// DeleteBefore 删除 before 之前的搜索日志和点击，返回删除的搜索日志数量
+func (r *searchAnalyticsRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("created_at < ?", before).Delete(&model.SearchClick{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("created_at < ?", before).Delete(&model.SearchQueryLog{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
@@ -6,10 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/UniverseHappiness/LAST-doc/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ErrRecordNotFound 记录未找到错误
@@ -24,16 +31,30 @@ type SearchIndexRepository interface {
 	GetByDocumentIDAndVersion(ctx context.Context, documentID, version string) ([]*model.SearchIndex, error)
 	Search(ctx context.Context, query string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error)
 	SearchByKeywords(ctx context.Context, keywords []string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error)
+	SearchByQuery(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error)
+	SearchByQueryAfter(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error)
+	Facets(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, facets []string, limit int) (map[string][]model.FacetBucket, error)
 	SearchByVector(ctx context.Context, vector string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error)
+	SearchByVectorAfter(ctx context.Context, vector string, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error)
 	DeleteByDocumentID(ctx context.Context, documentID string) error
 	DeleteByDocumentIDAndVersion(ctx context.Context, documentID, version string) error
+	DeleteByIDs(ctx context.Context, ids []string) error
+	GetVectorsByContentHashes(ctx context.Context, hashes []string, embeddingModel string) (map[string]string, error)
 	Update(ctx context.Context, id string, updates map[string]interface{}) error
 	GetIndexingStatus(ctx context.Context, documentID string) (map[string]interface{}, error)
+	ListBatch(ctx context.Context, afterID string, limit int) ([]*model.SearchIndex, error)
+	ListStaleEmbeddings(ctx context.Context, embeddingModel, afterID string, limit int) ([]*model.SearchIndex, error)
+	CountStaleEmbeddings(ctx context.Context, embeddingModel string) (int64, error)
+	CountByEmbeddingModel(ctx context.Context) ([]model.EmbeddingModelUsage, error)
+	UpdateEmbeddings(ctx context.Context, indices []*model.SearchIndex) error
+	BackfillEmbeddingModels(ctx context.Context, embeddingModel string, dimension int) (int64, error)
+	DetectVectorSearch(ctx context.Context, dimension int) (*model.VectorSearchStatus, error)
 }
 
 // searchIndexRepository 搜索索引仓库实现
 type searchIndexRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	vectorStatus atomic.Pointer[model.VectorSearchStatus]
 }
 
 // NewSearchIndexRepository 创建搜索索引仓库实例
@@ -56,6 +77,10 @@ func (r *searchIndexRepository) CreateBatch(ctx context.Context, indices []*mode
 		return nil
 	}
 
+	// pgvector 可用时同时写入 embedding 列
+	vectorStatus := r.vectorStatus.Load()
+	useEmbedding := vectorStatus != nil && vectorStatus.Mode == model.VectorSearchModePgvector
+
 	// 手动处理批量插入，避免 GORM 的 JSON 序列化问题
 	log.Printf("DEBUG: 开始事务处理批量插入")
 	tx := r.db.WithContext(ctx).Begin()
@@ -72,11 +97,24 @@ func (r *searchIndexRepository) CreateBatch(ctx context.Context, indices []*mode
 
 		log.Printf("DEBUG: 处理批次 %d-%d，共 %d 个索引", i+1, end, end-i)
 		batch := indices[i:end]
-		values := make([]interface{}, 0, len(batch)*9)
+		values := make([]interface{}, 0, len(batch)*17)
 		valueStrings := make([]string, 0, len(batch))
 
 		for _, index := range batch {
-			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			createdAt := index.CreatedAt
+			if createdAt.IsZero() {
+				createdAt = time.Now()
+			}
+			updatedAt := index.UpdatedAt
+			if updatedAt.IsZero() {
+				updatedAt = createdAt
+			}
+
+			placeholders := "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?"
+			if useEmbedding {
+				placeholders += ", ?::vector"
+			}
+			valueStrings = append(valueStrings, placeholders+")")
 			values = append(values,
 				index.ID,
 				index.DocumentID,
@@ -87,11 +125,29 @@ func (r *searchIndexRepository) CreateBatch(ctx context.Context, indices []*mode
 				index.Keywords,
 				index.Vector, // 已经是 JSON 字符串格式
 				index.Metadata,
+				index.StartPosition,
+				index.EndPosition,
+				index.ContentHash,
+				index.EmbeddingModel,
+				index.EmbeddingDimension,
+				createdAt,
+				updatedAt,
 			)
+			if useEmbedding {
+				// 维度与 embedding 列不一致的向量（如备用向量）不写入，只保留在 vector 列中
+				var embedding interface{}
+				if len(index.Embedding) == vectorStatus.Dimension {
+					embedding = formatVector(index.Embedding)
+				}
+				values = append(values, embedding)
+			}
 		}
 
-		query := fmt.Sprintf("INSERT INTO search_indices (id, document_id, version, content, content_type, section, keywords, vector, metadata) VALUES %s",
-			strings.Join(valueStrings, ","))
+		columns := "id, document_id, version, content, content_type, section, keywords, vector, metadata, start_position, end_position, content_hash, embedding_model, embedding_dimension, created_at, updated_at"
+		if useEmbedding {
+			columns += ", embedding"
+		}
+		query := fmt.Sprintf("INSERT INTO search_indices (%s) VALUES %s", columns, strings.Join(valueStrings, ","))
 
 		log.Printf("DEBUG: 执行插入查询，参数数量: %d", len(values))
 		if err := tx.Exec(query, values...).Error; err != nil {
@@ -141,149 +197,320 @@ func (r *searchIndexRepository) GetByDocumentIDAndVersion(ctx context.Context, d
 	return indices, nil
 }
 
-// Search 关键词搜索
-func (r *searchIndexRepository) Search(ctx context.Context, query string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+// ListBatch 按ID顺序分批获取搜索索引（只包含ID、章节和内容），afterID 为上一批最后一条记录的ID
+func (r *searchIndexRepository) ListBatch(ctx context.Context, afterID string, limit int) ([]*model.SearchIndex, error) {
 	var indices []*model.SearchIndex
-	var total int64
+	err := r.db.WithContext(ctx).
+		Select("id", "section", "content").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&indices).Error
+	if err != nil {
+		return nil, err
+	}
+	return indices, nil
+}
 
-	db := r.db.WithContext(ctx).Model(&model.SearchIndex{})
+// ListStaleEmbeddings 按ID顺序分批获取向量不是由 embeddingModel 生成的搜索索引（只包含ID和内容），afterID 为上一批最后一条记录的ID
+func (r *searchIndexRepository) ListStaleEmbeddings(ctx context.Context, embeddingModel, afterID string, limit int) ([]*model.SearchIndex, error) {
+	var indices []*model.SearchIndex
+	err := r.db.WithContext(ctx).
+		Select("id", "content", "embedding_model").
+		Where("id > ? AND COALESCE(embedding_model, '') <> ?", afterID, embeddingModel).
+		Order("id").
+		Limit(limit).
+		Find(&indices).Error
+	if err != nil {
+		return nil, err
+	}
+	return indices, nil
+}
 
-	// 构建搜索查询
-	searchQuery := db.Where("content LIKE ?", "%"+query+"%")
+// CountStaleEmbeddings 统计向量不是由 embeddingModel 生成的搜索索引数量
+func (r *searchIndexRepository) CountStaleEmbeddings(ctx context.Context, embeddingModel string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.SearchIndex{}).
+		Where("COALESCE(embedding_model, '') <> ?", embeddingModel).
+		Count(&count).Error
+	return count, err
+}
 
-	// 应用过滤条件
-	searchQuery = r.applyFilters(searchQuery, filters)
+// CountByEmbeddingModel 按嵌入模型和维度统计搜索索引数量
+func (r *searchIndexRepository) CountByEmbeddingModel(ctx context.Context) ([]model.EmbeddingModelUsage, error) {
+	var usage []model.EmbeddingModelUsage
+	err := r.db.WithContext(ctx).Model(&model.SearchIndex{}).
+		Select("COALESCE(embedding_model, '') AS model, COALESCE(embedding_dimension, 0) AS dimension, COUNT(*) AS chunks").
+		Group("COALESCE(embedding_model, ''), COALESCE(embedding_dimension, 0)").
+		Order("chunks DESC").
+		Scan(&usage).Error
+	return usage, err
+}
 
-	// 获取总数
-	if err := searchQuery.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+// UpdateEmbeddings 更新搜索索引的向量和嵌入模型，pgvector 可用时同时更新 embedding 列
+func (r *searchIndexRepository) UpdateEmbeddings(ctx context.Context, indices []*model.SearchIndex) error {
+	vectorStatus := r.vectorStatus.Load()
+	useEmbedding := vectorStatus != nil && vectorStatus.Mode == model.VectorSearchModePgvector
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, index := range indices {
+			updates := map[string]interface{}{
+				"vector":              index.Vector,
+				"embedding_model":     index.EmbeddingModel,
+				"embedding_dimension": index.EmbeddingDimension,
+				"updated_at":          time.Now(),
+			}
+			if useEmbedding {
+				// 维度与 embedding 列不一致的向量（如备用向量）不写入，清除旧模型的向量
+				updates["embedding"] = nil
+				if len(index.Embedding) == vectorStatus.Dimension {
+					updates["embedding"] = gorm.Expr("?::vector", formatVector(index.Embedding))
+				}
+			}
+			if err := tx.Model(&model.SearchIndex{}).Where("id = ?", index.ID).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	// 分页查询
-	if page > 0 && size > 0 {
-		offset := (page - 1) * size
-		searchQuery = searchQuery.Offset(offset).Limit(size)
-	}
+// BackfillEmbeddingModels 为记录嵌入模型之前写入的搜索索引补齐模型和维度：
+// 备用向量记为 fallback，维度与活动模型一致的向量记为活动模型，其余记为 unknown，返回更新的记录数
+func (r *searchIndexRepository) BackfillEmbeddingModels(ctx context.Context, embeddingModel string, dimension int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		UPDATE search_indices SET
+			embedding_dimension = CASE WHEN jsonb_typeof(vector) = 'array' THEN jsonb_array_length(vector) ELSE 0 END,
+			embedding_model = CASE
+				WHEN jsonb_typeof(vector) = 'array' AND jsonb_array_length(vector) = ? THEN ?
+				WHEN jsonb_typeof(vector) = 'array' AND jsonb_array_length(vector) = ? THEN ?
+				ELSE ?
+			END
+		WHERE COALESCE(embedding_model, '') = ''
+	`, model.FallbackEmbeddingDimension, model.FallbackEmbeddingModel, dimension, embeddingModel, model.UnknownEmbeddingModel)
+	return result.RowsAffected, result.Error
+}
 
-	// 执行查询
-	if err := searchQuery.Order("created_at DESC").Find(&indices).Error; err != nil {
-		return nil, 0, err
-	}
+// Search 关键词搜索，查询按空白拆分为多个关键词
+func (r *searchIndexRepository) Search(ctx context.Context, query string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.SearchByKeywords(ctx, strings.Fields(query), filters, page, size)
+}
 
-	return indices, total, nil
+// rankedSearchIndex 带全文检索得分（或向量相似度）和向量距离的搜索索引
+type rankedSearchIndex struct {
+	model.SearchIndex
+	SearchRank     float64 `gorm:"column:search_rank"`
+	SearchDistance float64 `gorm:"column:search_distance"`
 }
 
-// SearchByKeywords 根据关键词搜索
+// SearchByKeywords 根据关键词进行全文检索
+// 关键词之间为 OR 关系，包含空格的关键词按短语匹配，以 * 结尾的关键词按前缀匹配，结果按 ts_rank_cd 得分排序
 func (r *searchIndexRepository) SearchByKeywords(ctx context.Context, keywords []string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
-	var indices []*model.SearchIndex
-	var total int64
-
-	db := r.db.WithContext(ctx).Model(&model.SearchIndex{})
+	return r.SearchByQuery(ctx, keywordsQuery(keywords), filters, page, size)
+}
 
-	// 构建关键词搜索查询
-	if len(keywords) == 0 {
-		return []*model.SearchIndex{}, 0, nil
-	}
+// SearchByQuery 根据查询语法树进行全文检索，结果按肯定关键词的 ts_rank_cd 得分排序
+func (r *searchIndexRepository) SearchByQuery(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.searchByQuery(ctx, query, filters, nil, page, size)
+}
 
-	log.Printf("DEBUG: SearchByKeywords called with keywords: %v", keywords)
+// SearchByQueryAfter 全文检索的 search_after 翻页：返回按 (得分 DESC, ID ASC) 排在 after 之后的 size 个结果
+// after.Rank 为上一个结果的 SortKey（全文检索得分），总数不受游标影响
+func (r *searchIndexRepository) SearchByQueryAfter(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error) {
+	return r.searchByQuery(ctx, query, filters, after, 1, size)
+}
 
-	// 构建基础查询 - 使用OR条件匹配任意关键词
-	searchQuery := db.Where("1=0") // 初始设置为不匹配任何记录
+// searchByQuery 全文检索，after 不为空时在数据库中定位到游标之后
+func (r *searchIndexRepository) searchByQuery(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, after *model.Cursor, page, size int) ([]*model.SearchIndex, int64, error) {
+	var total int64
 
-	// 为每个关键词添加OR条件，匹配任意关键词
-	for i, keyword := range keywords {
-		log.Printf("DEBUG: Adding keyword filter: %s", keyword)
-		searchQuery = searchQuery.Or("content LIKE ?", "%"+keyword+"%")
-		if i == 0 {
-			// 记录第一个关键词的查询条件
-			log.Printf("DEBUG: First keyword condition: content LIKE '%%%s%%'", keyword)
-		}
+	if query == nil {
+		return []*model.SearchIndex{}, 0, nil
+	}
+	whereExpr, whereArgs := buildQueryCondition(query)
+	rankQuery := newTextSearchQuery(query.PositiveLeaves())
+	if whereExpr == "" || rankQuery.empty() {
+		return []*model.SearchIndex{}, 0, nil
 	}
 
-	log.Printf("DEBUG: Final search query built for %d keywords", len(keywords))
+	searchQuery := r.db.WithContext(ctx).Model(&model.SearchIndex{}).Where(whereExpr, whereArgs...)
 
 	// 应用过滤条件
 	searchQuery = r.applyFilters(searchQuery, filters)
 
-	// 开启SQL日志记录
-	searchQuery = searchQuery.Debug()
-
 	// 获取总数 - 使用单独的查询，避免分页影响
 	if err := searchQuery.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	log.Printf("DEBUG: Total count before pagination: %d", total)
+	rankExpr, rankArgs := rankQuery.rankExpression()
+	rankExpr = "(" + rankExpr + ")::float8"
 
-	// 分页查询 - 使用游标式分页提高性能
+	// 游标定位：得分更低，或得分相同且ID更大
+	if after != nil {
+		args := append(append([]interface{}{}, rankArgs...), after.Rank)
+		args = append(append(args, rankArgs...), after.Rank, after.ID)
+		searchQuery = searchQuery.Where("("+rankExpr+" < ? OR ("+rankExpr+" = ? AND id > ?))", args...)
+	}
+
+	// 分页查询
 	if page > 0 && size > 0 {
 		offset := (page - 1) * size
 		searchQuery = searchQuery.Offset(offset).Limit(size)
 	}
 
-	// 执行查询 - 添加排序以获得更一致的结果
-	if err := searchQuery.Order("created_at DESC").Find(&indices).Error; err != nil {
+	var ranked []rankedSearchIndex
+	if err := searchQuery.
+		Select("search_indices.*, "+rankExpr+" AS search_rank", rankArgs...).
+		Order("search_rank DESC, id").
+		Find(&ranked).Error; err != nil {
 		return nil, 0, err
 	}
 
-	log.Printf("DEBUG: Found %d indices, total count: %d", len(indices), total)
-
-	// 显示数据库中的样本内容用于调试
-	if len(indices) == 0 {
-		var sampleIndices []*model.SearchIndex
-		if err := db.Limit(3).Find(&sampleIndices).Error; err == nil {
-			log.Printf("DEBUG: Sample database content:")
-			for i, idx := range sampleIndices {
-				log.Printf("DEBUG: Sample %d: content='%s', keywords='%s'", i+1, idx.Content, idx.Keywords)
-			}
-		}
-	}
-
-	// 为每个结果计算相关性得分
-	for _, index := range indices {
-		r.calculateKeywordScore(index, keywords)
+	indices := make([]*model.SearchIndex, len(ranked))
+	for i := range ranked {
+		indices[i] = &ranked[i].SearchIndex
+		indices[i].Score = float32(ranked[i].SearchRank)
+		indices[i].SortKey = ranked[i].SearchRank
 	}
 
 	return indices, total, nil
 }
 
-// calculateKeywordScore 计算关键词搜索的相关性得分
-func (r *searchIndexRepository) calculateKeywordScore(index *model.SearchIndex, keywords []string) {
-	if len(keywords) == 0 {
-		index.Score = 0
-		return
-	}
+// facetExpressions 各分面对应的分组表达式，tags 需要关联文档表单独统计
+var facetExpressions = map[string]string{
+	model.FacetLibrary:      "metadata->>'document_library'",
+	model.FacetVersion:      "TRIM(version)",
+	model.FacetDocumentType: "metadata->>'document_type'",
+	model.FacetContentType:  "content_type",
+	model.FacetLanguage:     "metadata->>'language'",
+}
 
-	content := strings.ToLower(index.Content)
-	matchCount := 0
+// Facets 统计全部匹配结果在各分面上的分布，每个分面按数量降序最多返回 limit 个取值
+// query 为空时统计满足过滤条件的全部索引
+func (r *searchIndexRepository) Facets(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, facets []string, limit int) (map[string][]model.FacetBucket, error) {
+	result := make(map[string][]model.FacetBucket, len(facets))
+
+	var whereExpr string
+	var whereArgs []interface{}
+	if query != nil {
+		if whereExpr, whereArgs = buildQueryCondition(query); whereExpr == "" {
+			for _, facet := range facets {
+				result[facet] = []model.FacetBucket{}
+			}
+			return result, nil
+		}
+	}
 
-	for _, keyword := range keywords {
-		keywordLower := strings.ToLower(keyword)
-		count := strings.Count(content, keywordLower)
-		if count > 0 {
-			matchCount++
+	// matched 返回匹配结果的查询，每个分面单独构建避免条件互相影响
+	matched := func() *gorm.DB {
+		db := r.db.WithContext(ctx).Model(&model.SearchIndex{})
+		if whereExpr != "" {
+			db = db.Where(whereExpr, whereArgs...)
 		}
+		return r.applyFilters(db, filters)
 	}
 
-	// 计算最终得分：匹配度
-	if len(keywords) > 0 {
-		index.Score = float32(matchCount) / float32(len(keywords))
-	} else {
-		index.Score = 0
+	for _, facet := range facets {
+		buckets := []model.FacetBucket{}
+		var err error
+
+		if facet == model.FacetTags {
+			err = r.db.WithContext(ctx).
+				Table("(?) AS matched", matched().Select("document_id")).
+				Joins("JOIN documents ON documents.id = matched.document_id").
+				Joins("CROSS JOIN LATERAL unnest(documents.tags) AS tag").
+				Select("tag AS value, COUNT(*) AS count").
+				Group("tag").
+				Order("count DESC, value").
+				Limit(limit).
+				Scan(&buckets).Error
+		} else if expr, ok := facetExpressions[facet]; ok {
+			err = matched().
+				Select(expr + " AS value, COUNT(*) AS count").
+				Where(expr + " <> ''").
+				Group(expr).
+				Order("count DESC, value").
+				Limit(limit).
+				Scan(&buckets).Error
+		} else {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to count facet %s: %v", facet, err)
+		}
+		result[facet] = buckets
 	}
+
+	return result, nil
 }
 
 // SearchByVector 向量搜索（语义搜索）
 func (r *searchIndexRepository) SearchByVector(ctx context.Context, vector string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.searchByVector(ctx, vector, filters, nil, page, size)
+}
+
+// SearchByVectorAfter 向量搜索的 search_after 翻页：返回按 (余弦距离 ASC, ID ASC) 排在 after 之后的 size 个结果
+// after.Rank 为上一个结果的 SortKey（余弦距离），after.Offset 为已返回的结果数量，用于调整近似检索的召回范围
+func (r *searchIndexRepository) SearchByVectorAfter(ctx context.Context, vector string, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error) {
+	return r.searchByVector(ctx, vector, filters, after, 1, size)
+}
+
+// searchByVector 向量搜索，after 不为空时定位到游标之后
+func (r *searchIndexRepository) searchByVector(ctx context.Context, vector string, filters map[string]interface{}, after *model.Cursor, page, size int) ([]*model.SearchIndex, int64, error) {
 	// 将JSON字符串解析为向量
 	var queryVector []float32
 	if err := json.Unmarshal([]byte(vector), &queryVector); err != nil {
-		// 如果解析失败，返回空结果
-		return []*model.SearchIndex{}, 0, nil
+		return nil, 0, fmt.Errorf("invalid query vector: %v", err)
+	}
+	if len(queryVector) == 0 {
+		return nil, 0, errors.New("invalid query vector: empty")
+	}
+
+	// 查询向量必须与嵌入模型登记的维度一致，否则相似度没有意义
+	dimension, err := r.embeddingModelDimension(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkVectorDimension(queryVector, dimension); err != nil {
+		return nil, 0, err
+	}
+
+	// pgvector 可用且维度一致时在数据库中计算相似度，否则回退到内存计算
+	if status := r.vectorStatus.Load(); status != nil && status.Mode == model.VectorSearchModePgvector && len(queryVector) == status.Dimension {
+		return r.pgvectorSearch(ctx, queryVector, status, filters, after, page, size)
 	}
 
 	// 使用增强的向量搜索功能，结合多种相似度计算方法
-	return r.enhancedVectorSearch(ctx, queryVector, filters, page, size)
+	return r.enhancedVectorSearch(ctx, queryVector, filters, after, page, size)
+}
+
+// embeddingModelDimension 返回过滤条件中嵌入模型（未指定时为活动模型）登记的维度，模型未登记时返回0
+func (r *searchIndexRepository) embeddingModelDimension(ctx context.Context, filters map[string]interface{}) (int, error) {
+	query := r.db.WithContext(ctx).Model(&model.EmbeddingModel{})
+	if name, ok := filters["embedding_model"].(string); ok && name != "" {
+		query = query.Where("name = ?", name)
+	} else {
+		query = query.Where("active = ?", true)
+	}
+
+	var dimensions []int
+	if err := query.Limit(1).Pluck("dimension", &dimensions).Error; err != nil {
+		return 0, err
+	}
+	if len(dimensions) == 0 {
+		return 0, nil
+	}
+	return dimensions[0], nil
+}
+
+// checkVectorDimension 检查查询向量维度，dimension 为0表示模型未登记，不做检查
+func checkVectorDimension(vector []float32, dimension int) error {
+	if dimension > 0 && len(vector) != dimension {
+		return fmt.Errorf("%w: got %d, want %d", model.ErrVectorDimensionMismatch, len(vector), dimension)
+	}
+	return nil
 }
 
 // DeleteByDocumentID 根据文档ID删除搜索索引
@@ -296,6 +523,46 @@ func (r *searchIndexRepository) DeleteByDocumentIDAndVersion(ctx context.Context
 	return r.db.WithContext(ctx).Where("document_id = ? AND TRIM(version) = ?", documentID, version).Delete(&model.SearchIndex{}).Error
 }
 
+// DeleteByIDs 根据ID批量删除搜索索引
+func (r *searchIndexRepository) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.SearchIndex{}).Error
+}
+
+// contentHashBatchSize 按内容哈希查询向量时每批的哈希数量
+const contentHashBatchSize = 500
+
+// GetVectorsByContentHashes 按内容哈希查找已有分块中由 embeddingModel 生成的向量（JSON字符串），同一哈希有多个分块时取最近更新的一个
+func (r *searchIndexRepository) GetVectorsByContentHashes(ctx context.Context, hashes []string, embeddingModel string) (map[string]string, error) {
+	vectors := make(map[string]string, len(hashes))
+	for start := 0; start < len(hashes); start += contentHashBatchSize {
+		end := start + contentHashBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		var rows []struct {
+			ContentHash string
+			Vector      string
+		}
+		err := r.db.WithContext(ctx).Raw(`
+			SELECT DISTINCT ON (content_hash) content_hash, vector::text AS vector
+			FROM search_indices
+			WHERE content_hash IN ? AND embedding_model = ? AND vector IS NOT NULL
+			ORDER BY content_hash, updated_at DESC
+		`, hashes[start:end], embeddingModel).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			vectors[row.ContentHash] = row.Vector
+		}
+	}
+	return vectors, nil
+}
+
 // Update 更新搜索索引
 func (r *searchIndexRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
 	return r.db.WithContext(ctx).Model(&model.SearchIndex{}).Where("id = ?", id).Updates(updates).Error
@@ -344,12 +611,56 @@ func (r *searchIndexRepository) applyFilters(db *gorm.DB, filters map[string]int
 		db = db.Where("TRIM(version) = ?", version)
 	}
 
+	// 语义搜索只比较活动嵌入模型生成的向量
+	if embeddingModel, ok := filters["embedding_model"].(string); ok && embeddingModel != "" {
+		db = db.Where("embedding_model = ?", embeddingModel)
+	}
+
+	// 排除指定文档和指定搜索索引，用于相似内容查询排除源文档或源分块
+	if documentID, ok := filters["exclude_document_id"].(string); ok && documentID != "" {
+		db = db.Where("document_id <> ?", documentID)
+	}
+	if ids, ok := filters["exclude_ids"].([]string); ok && len(ids) > 0 {
+		db = db.Where("id NOT IN ?", ids)
+	}
+
+	// 版本范围解析得到的文档版本，为空表示没有满足条件的版本
+	if refs, ok := filters["document_versions"].([]model.DocumentVersionRef); ok {
+		if len(refs) == 0 {
+			db = db.Where("1 = 0")
+		} else {
+			pairs := make([][]interface{}, len(refs))
+			for i, ref := range refs {
+				pairs[i] = []interface{}{ref.DocumentID, ref.Version}
+			}
+			db = db.Where("(document_id, TRIM(version)) IN ?", pairs)
+		}
+	}
+
 	if contentType, ok := filters["content_type"]; ok && contentType != "" && contentType != nil {
 		db = db.Where("content_type = ?", contentType)
 	}
 
 	if section, ok := filters["section"]; ok && section != "" && section != nil {
-		db = db.Where("section = ?", section)
+		// 章节为标题路径，匹配完整路径或最后一级标题
+		name := fmt.Sprint(section)
+		db = db.Where("(section ILIKE ? OR section ILIKE ?)", escapeLike(name), "% > "+escapeLike(name))
+	}
+
+	if library, ok := filters["library"]; ok && library != "" && library != nil {
+		db = db.Where("metadata->>'document_library' = ?", library)
+	}
+
+	if documentType, ok := filters["document_type"]; ok && documentType != "" && documentType != nil {
+		db = db.Where("metadata->>'document_type' = ?", documentType)
+	}
+
+	if tag, ok := filters["tag"]; ok && tag != "" && tag != nil {
+		db = db.Where("document_id IN (SELECT id FROM documents WHERE ? = ANY(tags))", tag)
+	}
+
+	if language, ok := filters["language"]; ok && language != "" && language != nil {
+		db = db.Where("metadata->>'language' = ?", strings.ToLower(fmt.Sprint(language)))
 	}
 
 	return db
@@ -420,29 +731,14 @@ func (r *searchIndexRepository) euclideanDistance(a, b []float32) float32 {
 }
 
 // enhancedVectorSearch 增强向量搜索，使用多种相似度计算方法
-func (r *searchIndexRepository) enhancedVectorSearch(ctx context.Context, vector []float32, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+func (r *searchIndexRepository) enhancedVectorSearch(ctx context.Context, vector []float32, filters map[string]interface{}, after *model.Cursor, page, size int) ([]*model.SearchIndex, int64, error) {
 	var indices []*model.SearchIndex
 	var total int64
 
 	db := r.db.WithContext(ctx).Model(&model.SearchIndex{})
 
-	// 检查是否有 embedding 列（pgvector 扩展是否可用）
-	var hasEmbeddingColumn bool
-	err := r.db.Raw("SELECT column_name FROM information_schema.columns WHERE table_name = 'search_indices' AND column_name = 'embedding'").Scan(&hasEmbeddingColumn).Error
-	if err != nil {
-		log.Printf("Error checking for embedding column: %v", err)
-		hasEmbeddingColumn = false
-	}
-
-	var searchQuery *gorm.DB
-	if hasEmbeddingColumn {
-		// 使用 pgvector 的 embedding 列进行搜索
-		searchQuery = db.Where("embedding IS NOT NULL")
-	} else {
-		// 回退到传统的 vector 列
-		searchQuery = db.Where("vector IS NOT NULL")
-		log.Printf("pgvector extension not available, falling back to traditional vector search")
-	}
+	// 使用 JSON 格式的 vector 列在内存中计算相似度
+	searchQuery := db.Where("vector IS NOT NULL")
 
 	// 应用过滤条件
 	searchQuery = r.applyFilters(searchQuery, filters)
@@ -461,34 +757,23 @@ func (r *searchIndexRepository) enhancedVectorSearch(ctx context.Context, vector
 		return nil, 0, err
 	}
 
-	// 计算多种相似度并合并得分
-	for _, index := range indices {
-		if hasEmbeddingColumn && len(index.Embedding) > 0 {
-			// 使用 pgvector embedding 计算相似度
-			cosineSim := r.cosineSimilarity(vector, index.Embedding)
-			euclideanDist := r.euclideanDistance(vector, index.Embedding)
-			euclideanSim := 1.0 / (1.0 + euclideanDist) // 转换为相似度分数
-			index.Score = 0.7*float32(cosineSim) + 0.3*float32(euclideanSim)
-		} else if len(index.Vector) > 0 {
-			// 回退到传统向量
-			// 解析JSON字符串格式的向量
-			var vectorSlice []float32
-			if err := json.Unmarshal([]byte(index.Vector), &vectorSlice); err == nil {
-				cosineSim := r.cosineSimilarity(vector, vectorSlice)
-				euclideanDist := r.euclideanDistance(vector, vectorSlice)
-				euclideanSim := 1.0 / (1.0 + euclideanDist) // 转换为相似度分数
-				index.Score = 0.7*float32(cosineSim) + 0.3*float32(euclideanSim)
-			}
-		}
-	}
+	// 计算多种相似度并合并得分，丢弃维度不一致的向量
+	scanned := len(indices)
+	indices = r.scoreVectorCandidates(vector, indices)
+	total -= int64(scanned - len(indices))
 
-	// 按相似度排序 - 使用更高效的排序算法
-	for i := 0; i < len(indices)-1; i++ {
-		for j := i + 1; j < len(indices); j++ {
-			if indices[i].Score < indices[j].Score {
-				indices[i], indices[j] = indices[j], indices[i]
-			}
+	// 与 pgvector 一致按 (距离 ASC, ID ASC) 排序，游标定位到上一个结果之后
+	sort.Slice(indices, func(i, j int) bool {
+		if indices[i].SortKey != indices[j].SortKey {
+			return indices[i].SortKey < indices[j].SortKey
 		}
+		return indices[i].ID < indices[j].ID
+	})
+	if after != nil {
+		start := sort.Search(len(indices), func(i int) bool {
+			return indices[i].SortKey > after.Rank || (indices[i].SortKey == after.Rank && indices[i].ID > after.ID)
+		})
+		indices = indices[start:]
 	}
 
 	// 应用分页
@@ -508,6 +793,197 @@ func (r *searchIndexRepository) enhancedVectorSearch(ctx context.Context, vector
 	return indices, total, nil
 }
 
+// scoreVectorCandidates 按余弦相似度和欧氏距离计算候选结果的得分，丢弃无法解析或维度与查询向量不一致的向量
+func (r *searchIndexRepository) scoreVectorCandidates(vector []float32, indices []*model.SearchIndex) []*model.SearchIndex {
+	scored := indices[:0]
+	for _, index := range indices {
+		var vectorSlice []float32
+		if err := json.Unmarshal([]byte(index.Vector), &vectorSlice); err != nil || len(vectorSlice) != len(vector) {
+			continue
+		}
+		cosineSim := r.cosineSimilarity(vector, vectorSlice)
+		euclideanDist := r.euclideanDistance(vector, vectorSlice)
+		euclideanSim := 1.0 / (1.0 + euclideanDist) // 转换为相似度分数
+		index.Score = 0.7*float32(cosineSim) + 0.3*float32(euclideanSim)
+		index.SortKey = 1 - float64(index.Score)
+		scored = append(scored, index)
+	}
+	return scored
+}
+
+// ivfflatProbes IVFFlat 索引查询时探测的列表数量
+const ivfflatProbes = 10
+
+// HNSW 查询时 ef_search 的取值范围，pgvector 不接受超过1000的值
+const (
+	minEFSearch = 40
+	maxEFSearch = 1000
+)
+
+// efSearchStatement 根据需要召回的结果数量生成设置 hnsw.ef_search 的语句，取值限制在 pgvector 允许的范围内；
+// 翻页很深时近似检索可能召回不足，但不会导致整个搜索失败
+func efSearchStatement(limit int) string {
+	efSearch := limit
+	if efSearch < minEFSearch {
+		efSearch = minEFSearch
+	}
+	if efSearch > maxEFSearch {
+		efSearch = maxEFSearch
+	}
+	return fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch)
+}
+
+// pgvectorSearch 使用pgvector在数据库中按余弦距离排序，可利用HNSW/IVFFlat索引
+func (r *searchIndexRepository) pgvectorSearch(ctx context.Context, vector []float32, status *model.VectorSearchStatus, filters map[string]interface{}, after *model.Cursor, page, size int) ([]*model.SearchIndex, int64, error) {
+	var total int64
+	var ranked []rankedSearchIndex
+
+	literal := formatVector(vector)
+	// 近似检索需要覆盖游标之前已返回的结果，否则过滤后结果不足
+	limit := page * size
+	if after != nil {
+		limit = after.Offset + size
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 调整近似检索的召回范围，避免过滤条件和分页导致结果不足
+		switch status.IndexType {
+		case "hnsw":
+			if err := tx.Exec(efSearchStatement(limit)).Error; err != nil {
+				return err
+			}
+		case "ivfflat":
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL ivfflat.probes = %d", ivfflatProbes)).Error; err != nil {
+				return err
+			}
+		}
+
+		searchQuery := r.applyFilters(tx.Model(&model.SearchIndex{}).Where("embedding IS NOT NULL"), filters)
+		if err := searchQuery.Count(&total).Error; err != nil {
+			return err
+		}
+
+		// 游标定位：距离更大，或距离相同且ID更大
+		if after != nil {
+			searchQuery = searchQuery.Where("(embedding <=> ?::vector, id) > (?::float8, ?)", literal, after.Rank, after.ID)
+		}
+
+		searchQuery = searchQuery.
+			Select("search_indices.*, embedding <=> ?::vector AS search_distance", literal).
+			Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "embedding <=> ?::vector, id", Vars: []interface{}{literal}}})
+		if page > 0 && size > 0 {
+			searchQuery = searchQuery.Offset((page - 1) * size).Limit(size)
+		}
+		return searchQuery.Find(&ranked).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	indices := make([]*model.SearchIndex, len(ranked))
+	for i := range ranked {
+		indices[i] = &ranked[i].SearchIndex
+		indices[i].Score = float32(1 - ranked[i].SearchDistance)
+		indices[i].SortKey = ranked[i].SearchDistance
+	}
+	return indices, total, nil
+}
+
+// DetectVectorSearch 检测pgvector扩展、embedding 列维度和近似最近邻索引，决定向量检索方式
+// dimension 为嵌入服务生成的向量维度，与 embedding 列不一致时回退到内存计算
+func (r *searchIndexRepository) DetectVectorSearch(ctx context.Context, dimension int) (*model.VectorSearchStatus, error) {
+	status := &model.VectorSearchStatus{Mode: model.VectorSearchModeInMemory, IndexType: "none"}
+	defer func() { r.vectorStatus.Store(status) }()
+
+	db := r.db.WithContext(ctx)
+
+	var hasExtension bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')").Scan(&hasExtension).Error; err != nil {
+		status.Reason = fmt.Sprintf("检查pgvector扩展失败: %v", err)
+		return status, err
+	}
+	if !hasExtension {
+		status.Reason = "pgvector扩展未安装"
+		return status, nil
+	}
+
+	columnDimension, err := r.embeddingColumnDimension(ctx)
+	if err != nil {
+		status.Reason = fmt.Sprintf("检查embedding列失败: %v", err)
+		return status, err
+	}
+	status.Dimension = columnDimension
+	switch {
+	case columnDimension < 0:
+		status.Reason = "embedding列不存在"
+		return status, nil
+	case columnDimension == 0:
+		status.Reason = "embedding列未指定维度"
+		return status, nil
+	case dimension > 0 && columnDimension != dimension:
+		status.Reason = fmt.Sprintf("embedding列维度 %d 与嵌入服务维度 %d 不一致", columnDimension, dimension)
+		return status, nil
+	}
+
+	var indexDefs []string
+	if err := db.Raw("SELECT indexdef FROM pg_indexes WHERE tablename = 'search_indices' AND indexdef LIKE '%(embedding %'").Scan(&indexDefs).Error; err != nil {
+		return status, err
+	}
+	for _, def := range indexDefs {
+		lower := strings.ToLower(def)
+		if strings.Contains(lower, "using hnsw") {
+			status.IndexType = "hnsw"
+			break
+		}
+		if strings.Contains(lower, "using ivfflat") {
+			status.IndexType = "ivfflat"
+		}
+	}
+
+	status.Mode = model.VectorSearchModePgvector
+	return status, nil
+}
+
+// embeddingColumnDimension 返回 embedding 列的维度，列不存在时返回 -1，未指定维度时返回 0
+func (r *searchIndexRepository) embeddingColumnDimension(ctx context.Context) (int, error) {
+	var columns []struct {
+		TypeName string
+		TypeMod  int
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT t.typname AS type_name, a.atttypmod AS type_mod
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE a.attrelid = 'search_indices'::regclass AND a.attname = 'embedding' AND NOT a.attisdropped
+	`).Scan(&columns).Error
+	if err != nil {
+		return -1, err
+	}
+	if len(columns) == 0 || columns[0].TypeName != "vector" {
+		return -1, nil
+	}
+	// vector 类型的 atttypmod 即为维度，未指定维度时为 -1
+	if columns[0].TypeMod <= 0 {
+		return 0, nil
+	}
+	return columns[0].TypeMod, nil
+}
+
+// formatVector 将向量格式化为pgvector的文本表示
+func formatVector(vector []float32) string {
+	var builder strings.Builder
+	builder.Grow(len(vector) * 10)
+	builder.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	builder.WriteByte(']')
+	return builder.String()
+}
+
 // sqrt 计算平方根
 func sqrt(x float32) float32 {
 	// 简单的平方根实现，实际项目中可以使用 math.Sqrt
@@ -517,3 +993,189 @@ func sqrt(x float32) float32 {
 	}
 	return z
 }
+
+// textSearchQuery 全文检索查询条件
+type textSearchQuery struct {
+	tsExprs   []string      // 各关键词对应的 tsquery 表达式，之间为 OR 关系
+	tsArgs    []interface{} // tsquery 表达式的参数
+	likeTerms []string      // 包含中文的关键词，simple 分词器无法切分中文，退化为模糊匹配
+}
+
+// buildTextSearchQuery 根据关键词构建全文检索查询条件
+func buildTextSearchQuery(keywords []string) textSearchQuery {
+	return newTextSearchQuery(keywordsQuery(keywords).Children)
+}
+
+// keywordsQuery 将关键词转换为 OR 关系的查询语法树
+// 包含空格的关键词作为短语，以 * 结尾的关键词按前缀匹配
+func keywordsQuery(keywords []string) *model.QueryNode {
+	query := &model.QueryNode{Type: model.QueryNodeOr}
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+
+		node := &model.QueryNode{Type: model.QueryNodeTerm, Text: keyword}
+		if strings.HasSuffix(keyword, "*") {
+			node.Text = strings.TrimSuffix(keyword, "*")
+			node.Prefix = true
+		}
+		if strings.ContainsAny(node.Text, " \t") {
+			node.Type = model.QueryNodePhrase
+		}
+		query.Children = append(query.Children, node)
+	}
+	return query
+}
+
+// newTextSearchQuery 根据关键词和短语节点构建全文检索查询条件，各节点之间为 OR 关系
+func newTextSearchQuery(leaves []*model.QueryNode) textSearchQuery {
+	var query textSearchQuery
+	for _, leaf := range leaves {
+		query.add(leaf)
+	}
+	return query
+}
+
+// add 添加一个关键词或短语节点
+func (q *textSearchQuery) add(node *model.QueryNode) {
+	text := strings.TrimSpace(node.Text)
+	if text == "" {
+		return
+	}
+
+	if containsHan(text) {
+		q.likeTerms = append(q.likeTerms, strings.ToLower(text))
+		return
+	}
+
+	switch {
+	case node.Prefix:
+		// 前缀匹配：转换为 to_tsquery 语法，最后一个词加 :*
+		words := splitWords(text)
+		if len(words) == 0 {
+			return
+		}
+		words[len(words)-1] += ":*"
+		q.tsExprs = append(q.tsExprs, "to_tsquery('simple', ?)")
+		q.tsArgs = append(q.tsArgs, strings.Join(words, " <-> "))
+	case node.Type == model.QueryNodePhrase:
+		// 短语匹配：要求词语相邻且顺序一致
+		q.tsExprs = append(q.tsExprs, "phraseto_tsquery('simple', ?)")
+		q.tsArgs = append(q.tsArgs, text)
+	default:
+		q.tsExprs = append(q.tsExprs, "plainto_tsquery('simple', ?)")
+		q.tsArgs = append(q.tsArgs, text)
+	}
+}
+
+// buildQueryCondition 将查询语法树转换为SQL匹配条件，无法生成条件的节点返回空字符串
+func buildQueryCondition(node *model.QueryNode) (string, []interface{}) {
+	switch node.Type {
+	case model.QueryNodeAnd, model.QueryNodeOr:
+		separator := " AND "
+		if node.Type == model.QueryNodeOr {
+			separator = " OR "
+		}
+
+		var parts []string
+		var args []interface{}
+		for _, child := range node.Children {
+			condition, childArgs := buildQueryCondition(child)
+			if condition == "" {
+				continue
+			}
+			parts = append(parts, "("+condition+")")
+			args = append(args, childArgs...)
+		}
+		return strings.Join(parts, separator), args
+	case model.QueryNodeNot:
+		if len(node.Children) != 1 {
+			return "", nil
+		}
+		condition, args := buildQueryCondition(node.Children[0])
+		if condition == "" {
+			return "", nil
+		}
+		// search_vector 为空时 @@ 返回 NULL，需要按不匹配处理
+		return "NOT COALESCE((" + condition + "), false)", args
+	default:
+		query := newTextSearchQuery([]*model.QueryNode{node})
+		if query.empty() {
+			return "", nil
+		}
+		return query.whereClause()
+	}
+}
+
+// empty 判断查询条件是否为空
+func (q textSearchQuery) empty() bool {
+	return len(q.tsExprs) == 0 && len(q.likeTerms) == 0
+}
+
+// tsQueryExpression 返回合并后的 tsquery 表达式
+func (q textSearchQuery) tsQueryExpression() string {
+	return "(" + strings.Join(q.tsExprs, " || ") + ")"
+}
+
+// whereClause 返回匹配条件及其参数
+func (q textSearchQuery) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(q.tsExprs) > 0 {
+		conditions = append(conditions, "search_vector @@ "+q.tsQueryExpression())
+		args = append(args, q.tsArgs...)
+	}
+	for _, term := range q.likeTerms {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions = append(conditions, "(content ILIKE ? OR section ILIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+
+	return strings.Join(conditions, " OR "), args
+}
+
+// rankExpression 返回相关度得分表达式及其参数
+// 全文检索部分使用 ts_rank_cd（章节权重 A，正文权重 B），中文关键词按出现次数和章节命中计分，
+// 总分通过 x/(x+1) 归一化到 [0, 1)
+func (q textSearchQuery) rankExpression() (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+
+	if len(q.tsExprs) > 0 {
+		parts = append(parts, "ts_rank_cd(search_vector, "+q.tsQueryExpression()+")")
+		args = append(args, q.tsArgs...)
+	}
+	for _, term := range q.likeTerms {
+		parts = append(parts, "0.4 * (char_length(content) - char_length(replace(lower(content), ?, ''))) / ?::float"+
+			" + CASE WHEN section ILIKE ? THEN 1.0 ELSE 0 END")
+		args = append(args, term, utf8.RuneCountInString(term), "%"+escapeLike(term)+"%")
+	}
+
+	raw := "(" + strings.Join(parts, " + ") + ")"
+	return raw + " / (" + raw + " + 1)", append(args, args...)
+}
+
+// containsHan 判断字符串是否包含中文字符
+func containsHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitWords 按非字母数字字符拆分为小写单词
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// escapeLike 转义 LIKE 模式中的特殊字符
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
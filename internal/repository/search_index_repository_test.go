@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestBuildTextSearchQuery 测试全文检索查询条件的构建
+func TestBuildTextSearchQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		keywords  []string
+		wantExprs []string
+		wantArgs  []interface{}
+		wantLike  []string
+	}{
+		{
+			name:      "普通关键词",
+			keywords:  []string{"Server", "config"},
+			wantExprs: []string{"plainto_tsquery('simple', ?)", "plainto_tsquery('simple', ?)"},
+			wantArgs:  []interface{}{"Server", "config"},
+		},
+		{
+			name:      "短语",
+			keywords:  []string{"http server"},
+			wantExprs: []string{"phraseto_tsquery('simple', ?)"},
+			wantArgs:  []interface{}{"http server"},
+		},
+		{
+			name:      "前缀",
+			keywords:  []string{"Conf*", "http.Ser*"},
+			wantExprs: []string{"to_tsquery('simple', ?)", "to_tsquery('simple', ?)"},
+			wantArgs:  []interface{}{"conf:*", "http <-> ser:*"},
+		},
+		{
+			name:     "中文关键词退化为模糊匹配",
+			keywords: []string{"安装", "配置*"},
+			wantLike: []string{"安装", "配置"},
+		},
+		{
+			name:     "忽略空关键词和无效前缀",
+			keywords: []string{" ", "*", "--*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := buildTextSearchQuery(tt.keywords)
+			if strings.Join(query.tsExprs, ",") != strings.Join(tt.wantExprs, ",") {
+				t.Errorf("tsExprs = %v, want %v", query.tsExprs, tt.wantExprs)
+			}
+			if len(query.tsArgs) != len(tt.wantArgs) {
+				t.Fatalf("tsArgs = %v, want %v", query.tsArgs, tt.wantArgs)
+			}
+			for i := range tt.wantArgs {
+				if query.tsArgs[i] != tt.wantArgs[i] {
+					t.Errorf("tsArgs[%d] = %v, want %v", i, query.tsArgs[i], tt.wantArgs[i])
+				}
+			}
+			if strings.Join(query.likeTerms, ",") != strings.Join(tt.wantLike, ",") {
+				t.Errorf("likeTerms = %v, want %v", query.likeTerms, tt.wantLike)
+			}
+			wantEmpty := len(tt.wantExprs) == 0 && len(tt.wantLike) == 0
+			if query.empty() != wantEmpty {
+				t.Errorf("empty() = %v, want %v", query.empty(), wantEmpty)
+			}
+		})
+	}
+}
+
+// TestTextSearchQuery_Clauses 测试匹配条件和得分表达式的参数数量与占位符一致
+func TestTextSearchQuery_Clauses(t *testing.T) {
+	query := buildTextSearchQuery([]string{"server", "安装_100%"})
+
+	where, whereArgs := query.whereClause()
+	if n := strings.Count(where, "?"); n != len(whereArgs) {
+		t.Errorf("whereClause 占位符数量 %d 与参数数量 %d 不一致", n, len(whereArgs))
+	}
+	if !strings.Contains(where, "search_vector @@") || !strings.Contains(where, " OR ") {
+		t.Errorf("whereClause = %q, 应同时包含全文检索和模糊匹配条件", where)
+	}
+	if whereArgs[1] != `%安装\_100\%%` {
+		t.Errorf("模糊匹配参数 = %v, 特殊字符未转义", whereArgs[1])
+	}
+
+	rank, rankArgs := query.rankExpression()
+	if n := strings.Count(rank, "?"); n != len(rankArgs) {
+		t.Errorf("rankExpression 占位符数量 %d 与参数数量 %d 不一致", n, len(rankArgs))
+	}
+	if !strings.Contains(rank, "ts_rank_cd(search_vector") {
+		t.Errorf("rankExpression = %q, 应包含 ts_rank_cd", rank)
+	}
+}
+
+// TestFormatVector 测试向量格式化为pgvector文本表示
+func TestFormatVector(t *testing.T) {
+	tests := []struct {
+		vector []float32
+		want   string
+	}{
+		{[]float32{}, "[]"},
+		{[]float32{1, -0.5, 0.25}, "[1,-0.5,0.25]"},
+		{[]float32{0.1}, "[0.1]"},
+	}
+
+	for _, tt := range tests {
+		if got := formatVector(tt.vector); got != tt.want {
+			t.Errorf("formatVector(%v) = %q, want %q", tt.vector, got, tt.want)
+		}
+	}
+}
+
+// TestCheckVectorDimension 测试查询向量维度与模型不一致时返回错误
+func TestCheckVectorDimension(t *testing.T) {
+	vector := []float32{0.1, 0.2, 0.3}
+	if err := checkVectorDimension(vector, 3); err != nil {
+		t.Errorf("checkVectorDimension() error = %v, want nil", err)
+	}
+	if err := checkVectorDimension(vector, 0); err != nil {
+		t.Errorf("checkVectorDimension() error = %v, want nil for unregistered model", err)
+	}
+	if err := checkVectorDimension(vector, 1536); !errors.Is(err, model.ErrVectorDimensionMismatch) {
+		t.Errorf("checkVectorDimension() error = %v, want ErrVectorDimensionMismatch", err)
+	}
+}
+
+// TestScoreVectorCandidates 测试维度不一致或无法解析的向量被丢弃而不是得到固定得分
+func TestScoreVectorCandidates(t *testing.T) {
+	r := &searchIndexRepository{}
+	indices := []*model.SearchIndex{
+		{ID: "same", Vector: "[1, 0]"},
+		{ID: "orthogonal", Vector: "[0, 1]"},
+		{ID: "other-dimension", Vector: "[1, 0, 0]"},
+		{ID: "invalid", Vector: "not a vector"},
+	}
+
+	scored := r.scoreVectorCandidates([]float32{1, 0}, indices)
+	if len(scored) != 2 || scored[0].ID != "same" || scored[1].ID != "orthogonal" {
+		t.Fatalf("scored = %v, want only vectors with the query dimension", scored)
+	}
+	if scored[0].Score <= scored[1].Score {
+		t.Errorf("scores = %v, %v, want identical vector scored higher", scored[0].Score, scored[1].Score)
+	}
+}
+
+// TestBuildQueryCondition 测试查询语法树转换为SQL匹配条件
+func TestBuildQueryCondition(t *testing.T) {
+	term := func(text string) *model.QueryNode {
+		return &model.QueryNode{Type: model.QueryNodeTerm, Text: text}
+	}
+
+	tests := []struct {
+		name     string
+		node     *model.QueryNode
+		want     string
+		wantArgs int
+	}{
+		{
+			name:     "关键词",
+			node:     term("gorm"),
+			want:     "search_vector @@ (plainto_tsquery('simple', ?))",
+			wantArgs: 1,
+		},
+		{
+			name: "短语与排除条件",
+			node: &model.QueryNode{Type: model.QueryNodeAnd, Children: []*model.QueryNode{
+				{Type: model.QueryNodePhrase, Text: "connection pool"},
+				{Type: model.QueryNodeNot, Children: []*model.QueryNode{term("deprecated")}},
+			}},
+			want: "(search_vector @@ (phraseto_tsquery('simple', ?))) AND " +
+				"(NOT COALESCE((search_vector @@ (plainto_tsquery('simple', ?))), false))",
+			wantArgs: 2,
+		},
+		{
+			name: "OR 中的中文关键词",
+			node: &model.QueryNode{Type: model.QueryNodeOr, Children: []*model.QueryNode{term("gin"), term("路由")}},
+			want: "(search_vector @@ (plainto_tsquery('simple', ?))) OR " +
+				"((content ILIKE ? OR section ILIKE ?))",
+			wantArgs: 3,
+		},
+		{
+			name:     "忽略无法生成条件的子节点",
+			node:     &model.QueryNode{Type: model.QueryNodeAnd, Children: []*model.QueryNode{term("gin"), {Type: model.QueryNodeTerm, Text: "--", Prefix: true}}},
+			want:     "(search_vector @@ (plainto_tsquery('simple', ?)))",
+			wantArgs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := buildQueryCondition(tt.node)
+			if got != tt.want {
+				t.Errorf("buildQueryCondition() = %q, want %q", got, tt.want)
+			}
+			if len(args) != tt.wantArgs || strings.Count(got, "?") != len(args) {
+				t.Errorf("参数数量 = %d, 占位符数量 = %d, want %d", len(args), strings.Count(got, "?"), tt.wantArgs)
+			}
+		})
+	}
+}
+
+// TestFacetExpressions 测试除 tags 外的分面都有对应的分组表达式
+func TestFacetExpressions(t *testing.T) {
+	for _, facet := range model.SearchFacets {
+		if facet == model.FacetTags {
+			continue
+		}
+		if _, ok := facetExpressions[facet]; !ok {
+			t.Errorf("分面 %s 没有对应的分组表达式", facet)
+		}
+	}
+}
+
+// TestEFSearchStatement 测试 hnsw.ef_search 限制在 pgvector 允许的范围内
+func TestEFSearchStatement(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  string
+	}{
+		{"小于下限", 10, "SET LOCAL hnsw.ef_search = 40"},
+		{"范围内", 200, "SET LOCAL hnsw.ef_search = 200"},
+		{"上限", 1000, "SET LOCAL hnsw.ef_search = 1000"},
+		{"深度翻页超过上限", 5020, "SET LOCAL hnsw.ef_search = 1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := efSearchStatement(tt.limit); got != tt.want {
+				t.Errorf("efSearchStatement(%d) = %q, want %q", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"gorm.io/gorm"
+)
+
+// corpusStatsID 语料统计表中唯一一行记录的ID
+const corpusStatsID = 1
+
+// termStatsBatchSize 批量更新检索词统计时每批的数量
+const termStatsBatchSize = 500
+
+// SearchStatsRepository 检索统计仓库接口，维护BM25所需的语料统计
+type SearchStatsRepository interface {
+	ApplyDelta(ctx context.Context, termDelta map[string]int64, chunkDelta, lengthDelta int64) error
+	Replace(ctx context.Context, termFreqs map[string]int64, chunkCount, totalLength int64) error
+	GetDocFreqs(ctx context.Context, terms []string) (map[string]int64, error)
+	GetPrefixDocFreqs(ctx context.Context, prefixes []string) (map[string]int64, error)
+	GetCorpusStats(ctx context.Context) (*model.SearchCorpusStats, error)
+	GetSpellingCandidates(ctx context.Context, term string, maxDistance, limit int) ([]model.SearchTermStat, error)
+	ListTopTerms(ctx context.Context, minDocFreq int64, limit int) ([]model.SearchTermStat, error)
+}
+
+// searchStatsRepository 检索统计仓库实现
+type searchStatsRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchStatsRepository 创建检索统计仓库实例
+func NewSearchStatsRepository(db *gorm.DB) SearchStatsRepository {
+	return &searchStatsRepository{
+		db: db,
+	}
+}
+
+// ApplyDelta 增量更新语料统计，索引新增时为正数，删除时为负数
+func (r *searchStatsRepository) ApplyDelta(ctx context.Context, termDelta map[string]int64, chunkDelta, lengthDelta int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := upsertTermStats(tx, termDelta, true); err != nil {
+			return err
+		}
+
+		// 文档频率降为0的检索词不再需要保留
+		if err := tx.Exec("DELETE FROM search_term_stats WHERE doc_freq <= 0").Error; err != nil {
+			return fmt.Errorf("failed to clean term stats: %v", err)
+		}
+
+		if err := tx.Exec(`
+			INSERT INTO search_corpus_stats (id, chunk_count, total_length, updated_at)
+			VALUES (?, GREATEST(?, 0), GREATEST(?, 0), NOW())
+			ON CONFLICT (id) DO UPDATE SET
+			    chunk_count = GREATEST(search_corpus_stats.chunk_count + ?, 0),
+			    total_length = GREATEST(search_corpus_stats.total_length + ?, 0),
+			    updated_at = NOW()
+		`, corpusStatsID, chunkDelta, lengthDelta, chunkDelta, lengthDelta).Error; err != nil {
+			return fmt.Errorf("failed to update corpus stats: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// Replace 使用全量统计结果替换现有统计
+func (r *searchStatsRepository) Replace(ctx context.Context, termFreqs map[string]int64, chunkCount, totalLength int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM search_term_stats").Error; err != nil {
+			return fmt.Errorf("failed to clear term stats: %v", err)
+		}
+
+		if err := upsertTermStats(tx, termFreqs, false); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+			INSERT INTO search_corpus_stats (id, chunk_count, total_length, updated_at)
+			VALUES (?, ?, ?, NOW())
+			ON CONFLICT (id) DO UPDATE SET
+			    chunk_count = EXCLUDED.chunk_count,
+			    total_length = EXCLUDED.total_length,
+			    updated_at = NOW()
+		`, corpusStatsID, chunkCount, totalLength).Error; err != nil {
+			return fmt.Errorf("failed to replace corpus stats: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// GetDocFreqs 获取检索词的文档频率，不存在的检索词不包含在结果中
+func (r *searchStatsRepository) GetDocFreqs(ctx context.Context, terms []string) (map[string]int64, error) {
+	result := make(map[string]int64, len(terms))
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	var stats []model.SearchTermStat
+	if err := r.db.WithContext(ctx).Where("term IN ?", terms).Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	for _, stat := range stats {
+		result[stat.Term] = stat.DocFreq
+	}
+	return result, nil
+}
+
+// GetPrefixDocFreqs 获取前缀匹配的检索词中最大的文档频率，作为前缀查询的文档频率估计
+func (r *searchStatsRepository) GetPrefixDocFreqs(ctx context.Context, prefixes []string) (map[string]int64, error) {
+	result := make(map[string]int64, len(prefixes))
+
+	for _, prefix := range prefixes {
+		var docFreq int64
+		if err := r.db.WithContext(ctx).
+			Model(&model.SearchTermStat{}).
+			Select("COALESCE(MAX(doc_freq), 0)").
+			Where("term LIKE ?", escapeLike(prefix)+"%").
+			Scan(&docFreq).Error; err != nil {
+			return nil, err
+		}
+		if docFreq > 0 {
+			result[prefix] = docFreq
+		}
+	}
+
+	return result, nil
+}
+
+// GetCorpusStats 获取语料统计，尚未统计时返回空统计
+func (r *searchStatsRepository) GetCorpusStats(ctx context.Context) (*model.SearchCorpusStats, error) {
+	var stats model.SearchCorpusStats
+	err := r.db.WithContext(ctx).Where("id = ?", corpusStatsID).Limit(1).Find(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.ID = corpusStatsID
+	return &stats, nil
+}
+
+// GetSpellingCandidates 从词表中获取可能是 term 正确拼写的检索词，按文档频率降序
+// 只返回长度相差不超过 maxDistance、且首字符与 term 的前两个字符之一相同的词
+func (r *searchStatsRepository) GetSpellingCandidates(ctx context.Context, term string, maxDistance, limit int) ([]model.SearchTermStat, error) {
+	runes := []rune(term)
+	if len(runes) == 0 {
+		return nil, nil
+	}
+	firsts := []string{string(runes[0])}
+	if len(runes) > 1 {
+		firsts = append(firsts, string(runes[1]))
+	}
+
+	var stats []model.SearchTermStat
+	err := r.db.WithContext(ctx).
+		Where("char_length(term) BETWEEN ? AND ?", len(runes)-maxDistance, len(runes)+maxDistance).
+		Where("LEFT(term, 1) IN ?", firsts).
+		Where("term <> ?", term).
+		Order("doc_freq DESC").
+		Limit(limit).
+		Find(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListTopTerms 获取文档频率不低于 minDocFreq 的检索词，按文档频率降序，limit 不大于0时不限制数量
+func (r *searchStatsRepository) ListTopTerms(ctx context.Context, minDocFreq int64, limit int) ([]model.SearchTermStat, error) {
+	query := r.db.WithContext(ctx).
+		Where("doc_freq >= ?", minDocFreq).
+		Order("doc_freq DESC").
+		Order("term")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var stats []model.SearchTermStat
+	if err := query.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// upsertTermStats 批量写入检索词统计，accumulate 为 true 时在原有值上累加
+func upsertTermStats(tx *gorm.DB, termFreqs map[string]int64, accumulate bool) error {
+	conflictAction := "EXCLUDED.doc_freq"
+	if accumulate {
+		conflictAction = "search_term_stats.doc_freq + EXCLUDED.doc_freq"
+	}
+
+	terms := make([]string, 0, len(termFreqs))
+	for term, freq := range termFreqs {
+		if freq != 0 {
+			terms = append(terms, term)
+		}
+	}
+
+	for start := 0; start < len(terms); start += termStatsBatchSize {
+		end := start + termStatsBatchSize
+		if end > len(terms) {
+			end = len(terms)
+		}
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*2)
+		for _, term := range terms[start:end] {
+			placeholders = append(placeholders, "(?, ?, NOW())")
+			args = append(args, term, termFreqs[term])
+		}
+
+		sql := "INSERT INTO search_term_stats (term, doc_freq, updated_at) VALUES " +
+			strings.Join(placeholders, ", ") +
+			" ON CONFLICT (term) DO UPDATE SET doc_freq = " + conflictAction + ", updated_at = NOW()"
+		if err := tx.Exec(sql, args...).Error; err != nil {
+			return fmt.Errorf("failed to update term stats: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// SynonymRepository 同义词组仓库接口
+type SynonymRepository interface {
+	Create(ctx context.Context, set *model.SynonymSet) error
+	Update(ctx context.Context, set *model.SynonymSet) error
+	Delete(ctx context.Context, id string) error
+	GetByID(ctx context.Context, id string) (*model.SynonymSet, error)
+	List(ctx context.Context) ([]*model.SynonymSet, error)
+}
+
+// synonymRepository 同义词组仓库实现
+type synonymRepository struct {
+	db *gorm.DB
+}
+
+// NewSynonymRepository 创建同义词组仓库实例
+func NewSynonymRepository(db *gorm.DB) SynonymRepository {
+	return &synonymRepository{
+		db: db,
+	}
+}
+
+// Create 创建同义词组
+func (r *synonymRepository) Create(ctx context.Context, set *model.SynonymSet) error {
+	return r.db.WithContext(ctx).Create(set).Error
+}
+
+// Update 更新同义词组的库、词语和描述
+func (r *synonymRepository) Update(ctx context.Context, set *model.SynonymSet) error {
+	result := r.db.WithContext(ctx).Model(&model.SynonymSet{}).Where("id = ?", set.ID).Updates(map[string]interface{}{
+		"library":     set.Library,
+		"terms":       set.Terms,
+		"description": set.Description,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete 删除同义词组
+func (r *synonymRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.SynonymSet{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetByID 根据ID获取同义词组
+func (r *synonymRepository) GetByID(ctx context.Context, id string) (*model.SynonymSet, error) {
+	var set model.SynonymSet
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&set).Error; err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// List 获取所有同义词组，按库和创建时间排序
+func (r *synonymRepository) List(ctx context.Context) ([]*model.SynonymSet, error) {
+	var sets []*model.SynonymSet
+	if err := r.db.WithContext(ctx).Order("library ASC, created_at ASC").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
@@ -12,6 +12,9 @@ import (
 type Router struct {
 	documentHandler   *handler.DocumentHandler
 	searchHandler     *handler.SearchHandler
+	synonymHandler    *handler.SynonymHandler
+	embeddingHandler  *handler.EmbeddingModelHandler
+	jobHandler        *handler.IndexingJobHandler
 	aiFormatHandler   *handler.AIFormatHandler
 	mcpHandler        *handler.MCPHandler
 	userHandler       *handler.UserHandler
@@ -24,10 +27,13 @@ type Router struct {
 }
 
 // NewRouter 创建路由器实例
-func NewRouter(documentHandler *handler.DocumentHandler, searchHandler *handler.SearchHandler, aiFormatHandler *handler.AIFormatHandler, mcpHandler *handler.MCPHandler, userHandler *handler.UserHandler, monitorHandler *handler.MonitorHandler, healthHandler *handler.HealthHandler, backupHandler *handler.BackupHandler, userService service.UserService, monitorService service.MonitorService) *Router {
+func NewRouter(documentHandler *handler.DocumentHandler, searchHandler *handler.SearchHandler, synonymHandler *handler.SynonymHandler, embeddingHandler *handler.EmbeddingModelHandler, jobHandler *handler.IndexingJobHandler, aiFormatHandler *handler.AIFormatHandler, mcpHandler *handler.MCPHandler, userHandler *handler.UserHandler, monitorHandler *handler.MonitorHandler, healthHandler *handler.HealthHandler, backupHandler *handler.BackupHandler, userService service.UserService, monitorService service.MonitorService) *Router {
 	return &Router{
 		documentHandler:   documentHandler,
 		searchHandler:     searchHandler,
+		synonymHandler:    synonymHandler,
+		embeddingHandler:  embeddingHandler,
+		jobHandler:        jobHandler,
 		aiFormatHandler:   aiFormatHandler,
 		mcpHandler:        mcpHandler,
 		userHandler:       userHandler,
@@ -106,18 +112,40 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			// 下载文档版本
 			documents.GET("/:id/versions/:version/download", r.documentHandler.DownloadDocumentVersion)
 
-			// 为所有缺少索引的文档构建搜索索引
+			// 将所有文档版本的索引对账任务加入队列
 			documents.POST("/build-missing-indexes", r.documentHandler.BuildAllMissingIndexes)
 		}
 
+		// 文档解析和索引任务路由
+		jobs := v1.Group("/jobs")
+		{
+			// 获取任务列表
+			jobs.GET("", r.jobHandler.ListJobs)
+
+			// 获取任务详情和进度
+			jobs.GET("/:id", r.jobHandler.GetJob)
+
+			// 重试和取消任务（仅管理员）
+			jobAdmin := jobs.Group("")
+			jobAdmin.Use(r.authMiddleware.RequireAuth())  // 需要认证
+			jobAdmin.Use(r.authMiddleware.RequireAdmin()) // 需要管理员权限
+			{
+				jobAdmin.POST("/:id/retry", r.jobHandler.RetryJob)
+				jobAdmin.POST("/:id/cancel", r.jobHandler.CancelJob)
+			}
+		}
+
 		// 搜索路由
 		search := v1.Group("/search")
 		{
-			// 搜索文档 (POST方式)
-			search.POST("", r.searchHandler.Search)
+			// 搜索文档 (POST方式)，已登录时按用户记录搜索日志
+			search.POST("", r.authMiddleware.OptionalAuth(), r.searchHandler.Search)
 
 			// 搜索文档 (GET方式)
-			search.GET("", r.searchHandler.SearchGet)
+			search.GET("", r.authMiddleware.OptionalAuth(), r.searchHandler.SearchGet)
+
+			// 查找与指定分块或文档版本相似的分块
+			search.GET("/similar", r.searchHandler.FindSimilar)
 
 			// 构建文档索引
 			search.POST("/documents/:id/versions/:version/index", r.searchHandler.BuildIndex)
@@ -130,6 +158,28 @@ func (r *Router) SetupRoutes() *gin.Engine {
 
 			// 删除指定版本的索引
 			search.DELETE("/documents/:id/versions/:version/index", r.searchHandler.DeleteIndexByVersion)
+
+			// 同义词管理（仅管理员）
+			synonyms := search.Group("/synonyms")
+			synonyms.Use(r.authMiddleware.RequireAuth())  // 需要认证
+			synonyms.Use(r.authMiddleware.RequireAdmin()) // 需要管理员权限
+			{
+				synonyms.GET("", r.synonymHandler.ListSynonymSets)
+				synonyms.POST("", r.synonymHandler.CreateSynonymSet)
+				synonyms.GET("/:id", r.synonymHandler.GetSynonymSet)
+				synonyms.PUT("/:id", r.synonymHandler.UpdateSynonymSet)
+				synonyms.DELETE("/:id", r.synonymHandler.DeleteSynonymSet)
+			}
+
+			// 嵌入模型管理（仅管理员）
+			embeddingModels := search.Group("/embedding-models")
+			embeddingModels.Use(r.authMiddleware.RequireAuth())  // 需要认证
+			embeddingModels.Use(r.authMiddleware.RequireAdmin()) // 需要管理员权限
+			{
+				embeddingModels.GET("", r.embeddingHandler.GetStatus)
+				embeddingModels.POST("/reembed", r.embeddingHandler.Reembed)
+				embeddingModels.GET("/vocabulary", r.embeddingHandler.GetVocabulary)
+			}
 		}
 
 		// AI友好格式路由
@@ -239,6 +289,9 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			// 获取性能报告
 			monitor.GET("/performance", r.monitorHandler.GetPerformanceReport)
 
+			// 获取搜索分析报告
+			monitor.GET("/search-analytics", r.monitorHandler.GetSearchAnalytics)
+
 			// 清理旧数据
 			monitor.POST("/cleanup", r.monitorHandler.CleanupOldData)
 		}
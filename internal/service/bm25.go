@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// bm25Term BM25查询词
+type bm25Term struct {
+	text   string
+	prefix bool    // 是否为前缀匹配
+	weight float64 // 得分权重，同义词扩展的词小于1，0 表示未设置（按1计算）
+}
+
+// bm25Explanation BM25得分明细，写入搜索结果的元数据
+type bm25Explanation struct {
+	Score float64            `json:"score"`
+	Terms map[string]float64 `json:"terms"` // 各查询词的得分贡献
+}
+
+// bm25Scorer BM25相关度计算器
+type bm25Scorer struct {
+	k1         float64
+	b          float64
+	chunkCount float64
+	avgLength  float64
+	docFreqs   map[string]int64
+}
+
+// bm25QueryTerms 将关键词转换为BM25查询词，以 * 结尾的关键词最后一个词按前缀匹配
+func bm25QueryTerms(keywords []string) []bm25Term {
+	var terms []bm25Term
+	seen := make(map[string]bool)
+
+	for _, keyword := range keywords {
+		prefix := strings.HasSuffix(keyword, "*")
+		tokens := tokenize(strings.TrimSuffix(keyword, "*"))
+		for i, token := range tokens {
+			term := bm25Term{text: token, prefix: prefix && i == len(tokens)-1}
+			key := term.key()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// appendWeightedTerms 以指定权重追加查询词，已存在的查询词保持原有权重
+func appendWeightedTerms(terms, extra []bm25Term, weight float64) []bm25Term {
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		seen[term.key()] = true
+	}
+	for _, term := range extra {
+		if seen[term.key()] {
+			continue
+		}
+		seen[term.key()] = true
+		term.weight = weight
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// boost 返回查询词的得分权重
+func (t bm25Term) boost() float64 {
+	if t.weight == 0 {
+		return 1
+	}
+	return t.weight
+}
+
+// key 返回查询词的唯一标识，前缀查询词以 * 结尾
+func (t bm25Term) key() string {
+	if t.prefix {
+		return t.text + "*"
+	}
+	return t.text
+}
+
+// matches 判断检索词是否匹配查询词
+func (t bm25Term) matches(token string) bool {
+	if t.prefix {
+		return strings.HasPrefix(token, t.text)
+	}
+	return token == t.text
+}
+
+// indexTokens 返回搜索索引参与统计的检索词（章节标题和内容）
+func indexTokens(index *model.SearchIndex) []string {
+	return tokenize(index.Section + "\n" + index.Content)
+}
+
+// idf 计算逆文档频率
+func (s *bm25Scorer) idf(term bm25Term) float64 {
+	df := float64(s.docFreqs[term.key()])
+	if df > s.chunkCount {
+		df = s.chunkCount
+	}
+	return math.Log(1 + (s.chunkCount-df+0.5)/(df+0.5))
+}
+
+// score 计算分块的BM25得分，返回总分和各查询词的得分贡献
+func (s *bm25Scorer) score(terms []bm25Term, tokens []string) (float64, map[string]float64) {
+	contributions := make(map[string]float64)
+	if len(terms) == 0 || len(tokens) == 0 {
+		return 0, contributions
+	}
+
+	lengthNorm := 1 - s.b
+	if s.avgLength > 0 {
+		lengthNorm += s.b * float64(len(tokens)) / s.avgLength
+	}
+
+	total := 0.0
+	for _, term := range terms {
+		tf := 0
+		for _, token := range tokens {
+			if term.matches(token) {
+				tf++
+			}
+		}
+		if tf == 0 {
+			continue
+		}
+
+		contribution := term.boost() * s.idf(term) * float64(tf) * (s.k1 + 1) / (float64(tf) + s.k1*lengthNorm)
+		contributions[term.key()] = contribution
+		total += contribution
+	}
+
+	return total, contributions
+}
+
+// newBM25Scorer 创建BM25计算器，优先使用维护的语料统计，统计不可用时使用候选结果估算
+func (s *searchService) newBM25Scorer(ctx context.Context, terms []bm25Term, candidates []*model.SearchIndex) *bm25Scorer {
+	scorer := &bm25Scorer{
+		k1:       s.config.BM25K1,
+		b:        s.config.BM25B,
+		docFreqs: make(map[string]int64),
+	}
+
+	if s.statsRepo != nil {
+		if err := s.loadCorpusStats(ctx, scorer, terms); err == nil && scorer.chunkCount > 0 {
+			return scorer
+		} else if err != nil {
+			log.Printf("WARNING: 获取BM25语料统计失败，使用候选结果估算: %v", err)
+		}
+	}
+
+	// 使用候选结果作为语料估算统计信息
+	scorer.chunkCount = float64(len(candidates))
+	scorer.docFreqs = make(map[string]int64)
+	totalLength := 0
+	for _, candidate := range candidates {
+		tokens := indexTokens(candidate)
+		totalLength += len(tokens)
+		for _, term := range terms {
+			for _, token := range tokens {
+				if term.matches(token) {
+					scorer.docFreqs[term.key()]++
+					break
+				}
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		scorer.avgLength = float64(totalLength) / float64(len(candidates))
+	}
+
+	return scorer
+}
+
+// loadCorpusStats 从统计仓库加载语料统计和查询词的文档频率
+func (s *searchService) loadCorpusStats(ctx context.Context, scorer *bm25Scorer, terms []bm25Term) error {
+	corpus, err := s.statsRepo.GetCorpusStats(ctx)
+	if err != nil {
+		return err
+	}
+	scorer.chunkCount = float64(corpus.ChunkCount)
+	scorer.avgLength = corpus.AverageLength()
+
+	var exact, prefixes []string
+	for _, term := range terms {
+		if term.prefix {
+			prefixes = append(prefixes, term.text)
+		} else {
+			exact = append(exact, term.text)
+		}
+	}
+
+	docFreqs, err := s.statsRepo.GetDocFreqs(ctx, exact)
+	if err != nil {
+		return err
+	}
+	for term, df := range docFreqs {
+		scorer.docFreqs[term] = df
+	}
+
+	prefixFreqs, err := s.statsRepo.GetPrefixDocFreqs(ctx, prefixes)
+	if err != nil {
+		return err
+	}
+	for prefix, df := range prefixFreqs {
+		scorer.docFreqs[prefix+"*"] = df
+	}
+
+	return nil
+}
+
+// corpusDelta 统计一组搜索索引对语料统计的影响
+func corpusDelta(indices []*model.SearchIndex) (map[string]int64, int64, int64) {
+	termDelta := make(map[string]int64)
+	var totalLength int64
+
+	for _, index := range indices {
+		tokens := indexTokens(index)
+		totalLength += int64(len(tokens))
+		for term := range termFrequencies(tokens) {
+			termDelta[term]++
+		}
+	}
+
+	return termDelta, int64(len(indices)), totalLength
+}
+
+// updateStatistics 根据新增和删除的索引增量更新语料统计
+func (s *searchService) updateStatistics(ctx context.Context, added, removed []*model.SearchIndex) {
+	if s.statsRepo == nil || (len(added) == 0 && len(removed) == 0) {
+		return
+	}
+
+	termDelta, chunkDelta, lengthDelta := corpusDelta(added)
+	removedTerms, removedChunks, removedLength := corpusDelta(removed)
+	for term, count := range removedTerms {
+		termDelta[term] -= count
+	}
+	chunkDelta -= removedChunks
+	lengthDelta -= removedLength
+
+	if err := s.statsRepo.ApplyDelta(ctx, termDelta, chunkDelta, lengthDelta); err != nil {
+		log.Printf("WARNING: 更新BM25语料统计失败: %v", err)
+	}
+}
+
+// statisticsBatchSize 重建语料统计时每批读取的索引数量
+const statisticsBatchSize = 500
+
+// EnsureStatistics 检查BM25语料统计，统计为空时根据现有索引全量重建
+func (s *searchService) EnsureStatistics(ctx context.Context) error {
+	if s.statsRepo == nil {
+		return nil
+	}
+
+	corpus, err := s.statsRepo.GetCorpusStats(ctx)
+	if err != nil {
+		return err
+	}
+	if corpus.ChunkCount > 0 {
+		return nil
+	}
+
+	termFreqs := make(map[string]int64)
+	var chunkCount, totalLength int64
+
+	afterID := ""
+	for {
+		batch, err := s.indexRepo.ListBatch(ctx, afterID, statisticsBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		terms, chunks, length := corpusDelta(batch)
+		for term, count := range terms {
+			termFreqs[term] += count
+		}
+		chunkCount += chunks
+		totalLength += length
+		afterID = batch[len(batch)-1].ID
+	}
+
+	if chunkCount == 0 {
+		return nil
+	}
+
+	log.Printf("重建BM25语料统计: %d 个分块, %d 个检索词", chunkCount, len(termFreqs))
+	return s.statsRepo.Replace(ctx, termFreqs, chunkCount, totalLength)
+}
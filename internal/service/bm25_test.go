@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestBM25QueryTerms 测试BM25查询词的生成
+func TestBM25QueryTerms(t *testing.T) {
+	terms := bm25QueryTerms([]string{"Gin", "gin", "conf*", "http server", "路由配置"})
+
+	want := []string{"gin", "conf*", "http", "server", "路由", "由配", "配置"}
+	if len(terms) != len(want) {
+		t.Fatalf("bm25QueryTerms() 返回 %d 个查询词, want %d", len(terms), len(want))
+	}
+	for i, term := range terms {
+		if term.key() != want[i] {
+			t.Errorf("terms[%d] = %q, want %q", i, term.key(), want[i])
+		}
+	}
+}
+
+// TestBM25Scorer_Score 测试BM25得分计算
+func TestBM25Scorer_Score(t *testing.T) {
+	scorer := &bm25Scorer{
+		k1:         1.2,
+		b:          0.75,
+		chunkCount: 100,
+		avgLength:  10,
+		docFreqs:   map[string]int64{"gin": 5, "the": 90, "conf*": 10},
+	}
+
+	gin := []bm25Term{{text: "gin"}}
+	short := []string{"gin", "router", "group"}
+	long := append([]string{"gin"}, make([]string, 40)...)
+
+	shortScore, contributions := scorer.score(gin, short)
+	longScore, _ := scorer.score(gin, long)
+	if shortScore <= longScore {
+		t.Errorf("相同词频时短分块得分 %f 应高于长分块得分 %f", shortScore, longScore)
+	}
+	if contributions["gin"] != shortScore {
+		t.Errorf("得分明细 %v 与总分 %f 不一致", contributions, shortScore)
+	}
+
+	// 常见词的IDF更低
+	rareScore, _ := scorer.score(gin, []string{"gin"})
+	commonScore, _ := scorer.score([]bm25Term{{text: "the"}}, []string{"the"})
+	if rareScore <= commonScore {
+		t.Errorf("罕见词得分 %f 应高于常见词得分 %f", rareScore, commonScore)
+	}
+
+	// 前缀匹配
+	prefixScore, contributions := scorer.score([]bm25Term{{text: "conf", prefix: true}}, []string{"config", "configure"})
+	if prefixScore <= 0 || contributions["conf*"] == 0 {
+		t.Errorf("前缀查询词应匹配 config 和 configure, score = %f", prefixScore)
+	}
+
+	// 不匹配时得分为0
+	if score, contributions := scorer.score(gin, []string{"echo"}); score != 0 || len(contributions) != 0 {
+		t.Errorf("不匹配时得分应为0, got %f %v", score, contributions)
+	}
+}
+
+// TestAppendWeightedTerms 测试同义词扩展的词按权重参与评分
+func TestAppendWeightedTerms(t *testing.T) {
+	scorer := &bm25Scorer{k1: 1.2, b: 0.75, chunkCount: 100, avgLength: 10, docFreqs: map[string]int64{"k8s": 5, "kubernetes": 5}}
+
+	terms := appendWeightedTerms(bm25QueryTerms([]string{"k8s"}), bm25QueryTerms([]string{"kubernetes", "k8s"}), 0.5)
+	if len(terms) != 2 || terms[0].boost() != 1 || terms[1].boost() != 0.5 {
+		t.Fatalf("appendWeightedTerms() = %+v", terms)
+	}
+
+	original, _ := scorer.score(terms, []string{"k8s"})
+	expanded, contributions := scorer.score(terms, []string{"kubernetes"})
+	if expanded*2 != original {
+		t.Errorf("同义词得分 %f 应为原词得分 %f 的一半", expanded, original)
+	}
+	if contributions["kubernetes"] != expanded {
+		t.Errorf("得分明细 %v 与总分 %f 不一致", contributions, expanded)
+	}
+}
+
+// TestNewBM25Scorer_FallbackToCandidates 测试没有语料统计时使用候选结果估算
+func TestNewBM25Scorer_FallbackToCandidates(t *testing.T) {
+	s := &searchService{config: DefaultSearchConfig()}
+	candidates := []*model.SearchIndex{
+		{ID: "1", Section: "路由", Content: "gin router"},
+		{ID: "2", Section: "中间件", Content: "gin middleware logger"},
+		{ID: "3", Section: "其他", Content: "echo"},
+	}
+
+	terms := bm25QueryTerms([]string{"gin", "logger"})
+	scorer := s.newBM25Scorer(context.Background(), terms, candidates)
+
+	if scorer.chunkCount != 3 {
+		t.Errorf("chunkCount = %f, want 3", scorer.chunkCount)
+	}
+	if scorer.docFreqs["gin"] != 2 || scorer.docFreqs["logger"] != 1 {
+		t.Errorf("docFreqs = %v, want gin=2 logger=1", scorer.docFreqs)
+	}
+	if scorer.avgLength <= 0 {
+		t.Errorf("avgLength = %f, 应大于0", scorer.avgLength)
+	}
+}
+
+// TestCorpusDelta 测试语料统计增量
+func TestCorpusDelta(t *testing.T) {
+	indices := []*model.SearchIndex{
+		{Section: "安装", Content: "go get gin"},
+		{Section: "使用", Content: "gin gin run"},
+	}
+
+	termDelta, chunks, length := corpusDelta(indices)
+	if chunks != 2 {
+		t.Errorf("chunks = %d, want 2", chunks)
+	}
+	if length != 8 {
+		t.Errorf("length = %d, want 8", length)
+	}
+	// 同一分块中多次出现只计一次文档频率
+	if termDelta["gin"] != 2 {
+		t.Errorf("termDelta[gin] = %d, want 2", termDelta["gin"])
+	}
+	if termDelta["go"] != 1 {
+		t.Errorf("termDelta[go] = %d, want 1", termDelta["go"])
+	}
+}
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"encoding/json"
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
 )
 
 // CacheItem 缓存项
@@ -92,3 +95,13 @@ func searchCacheKey(query, searchType string, filters map[string]interface{}, pa
 	// 简单的键生成，实际项目中可以使用更复杂的哈希算法
 	return query + "|" + searchType + "|" + strconv.Itoa(page) + "|" + strconv.Itoa(size)
 }
+
+// searchRequestCacheKey 生成搜索请求的缓存键，包含过滤条件和混合搜索等所有请求参数
+func searchRequestCacheKey(request *model.SearchRequest) string {
+	key := searchCacheKey(request.Query, request.SearchType, request.Filters, request.Page, request.Size)
+	options, err := json.Marshal(request)
+	if err != nil {
+		return key
+	}
+	return key + "|" + string(options)
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"testing"
 	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
 )
 
 // TestCacheItem_IsExpired 测试缓存项过期检查
@@ -487,3 +489,21 @@ func TestMemoryCache_EmptyStringKey(t *testing.T) {
 		t.Errorf("Get() value = %v, expected %v", retrievedValue, value)
 	}
 }
+
+// TestSearchRequestCacheKey 测试搜索请求缓存键包含过滤条件和混合搜索参数
+func TestSearchRequestCacheKey(t *testing.T) {
+	alpha := 0.2
+	base := &model.SearchRequest{Query: "gin", SearchType: "hybrid", Page: 1, Size: 10}
+	withFilter := &model.SearchRequest{Query: "gin", SearchType: "hybrid", Page: 1, Size: 10, Filters: map[string]interface{}{"language": "go"}}
+	withAlpha := &model.SearchRequest{Query: "gin", SearchType: "hybrid", Page: 1, Size: 10, HybridAlpha: &alpha}
+
+	if searchRequestCacheKey(base) == searchRequestCacheKey(withFilter) {
+		t.Error("不同过滤条件应该生成不同的key")
+	}
+	if searchRequestCacheKey(base) == searchRequestCacheKey(withAlpha) {
+		t.Error("不同混合搜索参数应该生成不同的key")
+	}
+	if searchRequestCacheKey(base) != searchRequestCacheKey(&model.SearchRequest{Query: "gin", SearchType: "hybrid", Page: 1, Size: 10}) {
+		t.Error("相同参数应该生成相同的key")
+	}
+}
@@ -0,0 +1,478 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// documentChunk 文档分块，对应一条搜索索引
+type documentChunk struct {
+	Content       string
+	Section       string   // 章节标题路径，如 "安装 > 使用Docker"
+	HeadingPath   []string // 章节标题层级
+	HeadingLevel  int      // 所在标题的级别，0表示文档开头无标题部分
+	ContentType   string   // text 或 code
+	Language      string   // 代码块语言（仅 code 分块）
+	CodeStyle     string   // 代码块形式：fenced 或 indented（仅 code 分块）
+	StartPosition int      // 分块在原文档中的起始位置（字节偏移）
+	EndPosition   int      // 分块在原文档中的结束位置（字节偏移，不包含）
+	StartChar     int      // 分块在原文档中的起始位置（字符偏移），写入索引并用于计算高亮位置
+	EndChar       int      // 分块在原文档中的结束位置（字符偏移，不包含）
+	Index         int      // 分块在文档中的序号
+}
+
+// markdownSection 按标题划分的文档章节
+type markdownSection struct {
+	start     int // 章节起始位置（包含标题行）
+	bodyStart int // 标题行之后的正文起始位置
+	end       int
+	path      []string
+	level     int
+}
+
+// documentChunker 文档分块器，先按markdown标题划分章节，再按长度切分过长的章节
+type documentChunker struct {
+	chunkSize int // 单个分块的最大字符数
+	overlap   int // 相邻分块重叠的字符数
+}
+
+// newDocumentChunker 创建文档分块器
+func newDocumentChunker(chunkSize, overlap int) *documentChunker {
+	if chunkSize <= 0 {
+		chunkSize = DefaultSearchConfig().ChunkSize
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	// 重叠部分不能超过分块长度，否则无法向前推进
+	if overlap >= chunkSize {
+		overlap = chunkSize / 4
+	}
+	return &documentChunker{
+		chunkSize: chunkSize,
+		overlap:   overlap,
+	}
+}
+
+// Chunk 将文档内容切分为多个分块，defaultSection 用于没有标题的开头部分
+// 代码块（围栏代码块和缩进代码块）会从正文中抽出，作为独立的 code 分块
+func (c *documentChunker) Chunk(content, defaultSection string) []documentChunk {
+	var chunks []documentChunk
+
+	sections := c.splitSections(content)
+	pendingStart := -1
+
+	for i, section := range sections {
+		body := content[section.bodyStart:section.end]
+		title := sectionTitle(section.path, defaultSection)
+		isLast := i == len(sections)-1
+
+		if strings.TrimSpace(body) == "" {
+			// 只有标题没有正文的章节并入下一个章节，避免产生只有标题的分块
+			if section.level > 0 && pendingStart < 0 {
+				pendingStart = section.start
+			}
+			// 文档以空标题结尾时仍然输出，保证标题可被检索
+			if !isLast || pendingStart < 0 {
+				continue
+			}
+			for _, r := range c.splitRange(content, pendingStart, section.end) {
+				chunks = append(chunks, newTextChunk(content, r, section, title))
+			}
+			break
+		}
+
+		start := section.start
+		if pendingStart >= 0 {
+			start = pendingStart
+			pendingStart = -1
+		}
+
+		// 正文被代码块分隔成若干段，分别切分
+		cursor := start
+		for _, block := range findCodeBlocks(content, section.bodyStart, section.end) {
+			chunks = append(chunks, c.textChunks(content, cursor, block.start, section, title)...)
+			for _, r := range c.splitRange(content, block.start, block.end) {
+				chunk := newTextChunk(content, r, section, title)
+				chunk.ContentType = "code"
+				chunk.Language = block.language
+				chunk.CodeStyle = block.style
+				chunks = append(chunks, chunk)
+			}
+			cursor = block.end
+		}
+		chunks = append(chunks, c.textChunks(content, cursor, section.end, section, title)...)
+	}
+
+	// 分块按起始位置递增排列，逐段累计字符偏移
+	pos, chars := 0, 0
+	for i := range chunks {
+		if chunks[i].StartPosition < pos {
+			pos, chars = 0, 0
+		}
+		chars += utf8.RuneCountInString(content[pos:chunks[i].StartPosition])
+		pos = chunks[i].StartPosition
+
+		chunks[i].Index = i
+		chunks[i].StartChar = chars
+		chunks[i].EndChar = chars + utf8.RuneCountInString(chunks[i].Content)
+	}
+
+	return chunks
+}
+
+// textChunks 切分一段正文，跳过只包含标题或空白的片段
+func (c *documentChunker) textChunks(content string, start, end int, section markdownSection, title string) []documentChunk {
+	if start >= end || !hasBodyText(content[start:end]) {
+		return nil
+	}
+
+	var chunks []documentChunk
+	for _, r := range c.splitRange(content, start, end) {
+		chunks = append(chunks, newTextChunk(content, r, section, title))
+	}
+	return chunks
+}
+
+// newTextChunk 根据范围创建正文分块
+func newTextChunk(content string, r [2]int, section markdownSection, title string) documentChunk {
+	return documentChunk{
+		Content:       content[r[0]:r[1]],
+		Section:       title,
+		HeadingPath:   section.path,
+		HeadingLevel:  section.level,
+		ContentType:   "text",
+		StartPosition: r[0],
+		EndPosition:   r[1],
+	}
+}
+
+// codeBlock 文档中的代码块
+type codeBlock struct {
+	start    int
+	end      int
+	language string
+	style    string // fenced 或 indented
+}
+
+// findCodeBlocks 查找 [start, end) 范围内的围栏代码块和缩进代码块
+func findCodeBlocks(content string, start, end int) []codeBlock {
+	var blocks []codeBlock
+	var current *codeBlock
+	var fence string
+
+	prevBlank := true
+	inList := false
+
+	for pos := start; pos < end; {
+		next := end
+		if nl := strings.IndexByte(content[pos:end], '\n'); nl >= 0 {
+			next = pos + nl + 1
+		}
+		line := strings.TrimRight(content[pos:next], "\r\n")
+		blank := strings.TrimSpace(line) == ""
+
+		// 围栏代码块内部，直到遇到关闭围栏
+		if fence != "" {
+			if marker, ok := fenceMarker(line); ok && isClosingFence(line, marker, fence) {
+				current.end = next
+				blocks = append(blocks, *current)
+				current = nil
+				fence = ""
+			}
+			prevBlank = false
+			pos = next
+			continue
+		}
+
+		// 缩进代码块持续到第一个未缩进的非空行
+		if current != nil {
+			if blank || isIndentedCode(line) {
+				if !blank {
+					current.end = next
+				}
+				prevBlank = blank
+				pos = next
+				continue
+			}
+			blocks = append(blocks, *current)
+			current = nil
+		}
+
+		if marker, ok := fenceMarker(line); ok {
+			fence = marker
+			language := ""
+			if fields := strings.Fields(strings.TrimLeft(line, " ")[len(marker):]); len(fields) > 0 {
+				language = strings.ToLower(fields[0])
+			}
+			current = &codeBlock{start: pos, end: next, language: language, style: "fenced"}
+			inList = false
+		} else if !blank && isIndentedCode(line) && prevBlank && !inList {
+			// 列表项之后的缩进内容属于列表，不视为代码
+			current = &codeBlock{start: pos, end: next, style: "indented"}
+		} else if !blank {
+			inList = isListItem(line) || (inList && (isIndentedCode(line) || !prevBlank))
+		}
+
+		prevBlank = blank
+		pos = next
+	}
+
+	// 未关闭的围栏代码块延续到范围末尾
+	if current != nil {
+		if current.style == "fenced" {
+			current.end = end
+		}
+		blocks = append(blocks, *current)
+	}
+
+	return blocks
+}
+
+// isIndentedCode 判断一行是否为缩进代码（4个空格或制表符开头）
+func isIndentedCode(line string) bool {
+	return strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+}
+
+// isListItem 判断一行是否为列表项
+func isListItem(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(trimmed) < 2 {
+		return false
+	}
+	if (trimmed[0] == '-' || trimmed[0] == '*' || trimmed[0] == '+') && trimmed[1] == ' ' {
+		return true
+	}
+	digits := 0
+	for digits < len(trimmed) && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
+	}
+	return digits > 0 && digits+1 < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') && trimmed[digits+1] == ' '
+}
+
+// hasBodyText 判断内容中是否有标题以外的非空行
+func hasBodyText(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if _, _, ok := parseHeading(line); ok {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// splitSections 按markdown标题划分章节，忽略代码块中的 # 行
+func (c *documentChunker) splitSections(content string) []markdownSection {
+	type heading struct {
+		level int
+		title string
+	}
+
+	var sections []markdownSection
+	var stack []heading
+
+	current := markdownSection{start: 0, bodyStart: 0}
+	var fence string
+
+	for pos := 0; pos < len(content); {
+		lineEnd := strings.IndexByte(content[pos:], '\n')
+		next := len(content)
+		if lineEnd >= 0 {
+			next = pos + lineEnd + 1
+		}
+		line := strings.TrimRight(content[pos:next], "\r\n")
+
+		if marker, ok := fenceMarker(line); ok {
+			if fence == "" {
+				fence = marker
+			} else if isClosingFence(line, marker, fence) {
+				fence = ""
+			}
+		} else if fence == "" {
+			if level, title, ok := parseHeading(line); ok {
+				current.end = pos
+				sections = append(sections, current)
+
+				for len(stack) > 0 && stack[len(stack)-1].level >= level {
+					stack = stack[:len(stack)-1]
+				}
+				stack = append(stack, heading{level: level, title: title})
+
+				path := make([]string, len(stack))
+				for i, h := range stack {
+					path[i] = h.title
+				}
+				current = markdownSection{start: pos, bodyStart: next, path: path, level: level}
+			}
+		}
+
+		pos = next
+	}
+
+	current.end = len(content)
+	sections = append(sections, current)
+	return sections
+}
+
+// splitRange 将 [start, end) 范围按分块长度切分，返回去除首尾空白后的范围列表
+func (c *documentChunker) splitRange(content string, start, end int) [][2]int {
+	var ranges [][2]int
+
+	for pos := start; pos < end; {
+		limit := advanceRunes(content, pos, end, c.chunkSize)
+		chunkEnd := end
+		if limit < end {
+			chunkEnd = findBreakPoint(content, pos, limit)
+		}
+
+		if s, e := trimRange(content, pos, chunkEnd); s < e {
+			ranges = append(ranges, [2]int{s, e})
+		}
+
+		if chunkEnd >= end {
+			break
+		}
+
+		// 回退重叠部分作为下一个分块的起点，尽量从行首开始
+		next := retreatRunes(content, chunkEnd, pos, c.overlap)
+		if nl := strings.IndexByte(content[next:chunkEnd], '\n'); nl >= 0 && next+nl+1 < chunkEnd {
+			next = next + nl + 1
+		}
+		if next <= pos {
+			next = chunkEnd
+		}
+		pos = next
+	}
+
+	return ranges
+}
+
+// findBreakPoint 在 [start, limit) 的后半部分寻找合适的断点：段落 > 换行 > 句末 > 空格
+func findBreakPoint(content string, start, limit int) int {
+	window := content[start:limit]
+	minBreak := len(window) / 2
+
+	if idx := strings.LastIndex(window, "\n\n"); idx > minBreak {
+		return start + idx + 2
+	}
+	if idx := strings.LastIndexByte(window, '\n'); idx > minBreak {
+		return start + idx + 1
+	}
+
+	best := -1
+	for _, sep := range []string{"。", "！", "？", "；", ". ", "! ", "? ", "; "} {
+		if idx := strings.LastIndex(window, sep); idx > minBreak && idx+len(sep) > best {
+			best = idx + len(sep)
+		}
+	}
+	if best > 0 {
+		return start + best
+	}
+
+	if idx := strings.LastIndexByte(window, ' '); idx > minBreak {
+		return start + idx + 1
+	}
+
+	return limit
+}
+
+// fenceMarker 判断一行是否为代码块围栏（``` 或 ~~~），返回围栏标记
+func fenceMarker(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", false
+	}
+	for _, ch := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == ch {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n], true
+		}
+	}
+	return "", false
+}
+
+// isClosingFence 判断围栏行是否关闭了当前代码块：字符相同、长度不小于开启围栏且后面没有其他内容
+func isClosingFence(line, marker, fence string) bool {
+	if marker[0] != fence[0] || len(marker) < len(fence) {
+		return false
+	}
+	rest := strings.TrimLeft(line, " ")[len(marker):]
+	return strings.TrimSpace(rest) == ""
+}
+
+// parseHeading 解析ATX风格的markdown标题行
+func parseHeading(line string) (int, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, "", false
+	}
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	if level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t' {
+		return 0, "", false
+	}
+
+	title := strings.TrimSpace(trimmed[level:])
+	title = strings.TrimSpace(strings.TrimRight(title, "#"))
+	return level, title, true
+}
+
+// sectionTitle 将标题路径拼接为章节名
+func sectionTitle(path []string, defaultSection string) string {
+	if len(path) == 0 {
+		return defaultSection
+	}
+	return strings.Join(path, " > ")
+}
+
+// advanceRunes 从 pos 向后移动 n 个字符，返回不超过 end 的字节位置
+func advanceRunes(content string, pos, end, n int) int {
+	for i := 0; i < n && pos < end; i++ {
+		_, size := utf8.DecodeRuneInString(content[pos:])
+		pos += size
+	}
+	if pos > end {
+		pos = end
+	}
+	return pos
+}
+
+// retreatRunes 从 pos 向前移动 n 个字符，返回不小于 start 的字节位置
+func retreatRunes(content string, pos, start, n int) int {
+	for i := 0; i < n && pos > start; i++ {
+		_, size := utf8.DecodeLastRuneInString(content[:pos])
+		pos -= size
+	}
+	if pos < start {
+		pos = start
+	}
+	return pos
+}
+
+// trimRange 去除范围首尾的空白字符
+func trimRange(content string, start, end int) (int, int) {
+	for start < end && isSpaceByte(content[start]) {
+		start++
+	}
+	for end > start && isSpaceByte(content[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+// isSpaceByte 判断是否为ASCII空白字符
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
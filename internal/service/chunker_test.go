@@ -0,0 +1,191 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestDocumentChunker_Sections 测试按标题划分章节
+func TestDocumentChunker_Sections(t *testing.T) {
+	content := "前言内容\n\n# 安装\n安装说明\n\n## 使用Docker\ndocker run\n\n# 配置\n配置说明\n"
+	chunker := newDocumentChunker(1000, 100)
+
+	chunks := chunker.Chunk(content, "文档")
+	if len(chunks) != 4 {
+		t.Fatalf("Chunk() 返回 %d 个分块, want 4", len(chunks))
+	}
+
+	expectedSections := []string{"文档", "安装", "安装 > 使用Docker", "配置"}
+	for i, chunk := range chunks {
+		if chunk.Section != expectedSections[i] {
+			t.Errorf("chunks[%d].Section = %q, want %q", i, chunk.Section, expectedSections[i])
+		}
+		if content[chunk.StartPosition:chunk.EndPosition] != chunk.Content {
+			t.Errorf("chunks[%d] 的位置信息与内容不一致", i)
+		}
+		if chunk.Index != i {
+			t.Errorf("chunks[%d].Index = %d, want %d", i, chunk.Index, i)
+		}
+		if want := utf8.RuneCountInString(content[:chunk.StartPosition]); chunk.StartChar != want {
+			t.Errorf("chunks[%d].StartChar = %d, want %d", i, chunk.StartChar, want)
+		}
+		if want := utf8.RuneCountInString(content[:chunk.EndPosition]); chunk.EndChar != want {
+			t.Errorf("chunks[%d].EndChar = %d, want %d", i, chunk.EndChar, want)
+		}
+	}
+}
+
+// TestDocumentChunker_IgnoreHeadingInCodeBlock 测试代码块中的 # 不被识别为标题
+func TestDocumentChunker_IgnoreHeadingInCodeBlock(t *testing.T) {
+	content := "# 示例\n```bash\n# 这是注释\necho hello\n```\n"
+	chunker := newDocumentChunker(1000, 100)
+
+	chunks := chunker.Chunk(content, "文档")
+	if len(chunks) != 1 {
+		t.Fatalf("Chunk() 返回 %d 个分块, want 1", len(chunks))
+	}
+	if chunks[0].Section != "示例" {
+		t.Errorf("Section = %q, want 示例", chunks[0].Section)
+	}
+}
+
+// TestDocumentChunker_HeadingOnlySection 测试只有标题的章节并入下一个章节
+func TestDocumentChunker_HeadingOnlySection(t *testing.T) {
+	content := "# 指南\n## 快速开始\n第一步\n"
+	chunker := newDocumentChunker(1000, 100)
+
+	chunks := chunker.Chunk(content, "文档")
+	if len(chunks) != 1 {
+		t.Fatalf("Chunk() 返回 %d 个分块, want 1", len(chunks))
+	}
+	if chunks[0].StartPosition != 0 {
+		t.Errorf("StartPosition = %d, want 0", chunks[0].StartPosition)
+	}
+	if chunks[0].Section != "指南 > 快速开始" {
+		t.Errorf("Section = %q, want 指南 > 快速开始", chunks[0].Section)
+	}
+}
+
+// TestDocumentChunker_SplitLongSection 测试过长章节的切分和重叠
+func TestDocumentChunker_SplitLongSection(t *testing.T) {
+	var builder strings.Builder
+	builder.WriteString("# 长章节\n")
+	for i := 0; i < 50; i++ {
+		builder.WriteString("这是一段用于测试分块的中文内容。This is an English sentence.\n")
+	}
+	content := builder.String()
+
+	chunkSize := 200
+	overlap := 40
+	chunker := newDocumentChunker(chunkSize, overlap)
+	chunks := chunker.Chunk(content, "文档")
+
+	if len(chunks) < 2 {
+		t.Fatalf("Chunk() 返回 %d 个分块, 期望切分为多个分块", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk.Content) {
+			t.Errorf("chunks[%d] 在多字节字符中间被截断", i)
+		}
+		if n := utf8.RuneCountInString(chunk.Content); n > chunkSize {
+			t.Errorf("chunks[%d] 长度 %d 超过分块大小 %d", i, n, chunkSize)
+		}
+		if chunk.Section != "长章节" {
+			t.Errorf("chunks[%d].Section = %q, want 长章节", i, chunk.Section)
+		}
+		if i > 0 && chunk.StartPosition >= chunks[i-1].EndPosition {
+			t.Errorf("chunks[%d] 与前一个分块没有重叠", i)
+		}
+	}
+
+	if chunks[len(chunks)-1].EndPosition != len(strings.TrimRight(content, "\n")) {
+		t.Errorf("最后一个分块没有覆盖到文档末尾")
+	}
+}
+
+// TestDocumentChunker_EmptyContent 测试空内容
+func TestDocumentChunker_EmptyContent(t *testing.T) {
+	chunker := newDocumentChunker(1000, 100)
+	if chunks := chunker.Chunk("   \n\n", "文档"); len(chunks) != 0 {
+		t.Errorf("Chunk() 返回 %d 个分块, want 0", len(chunks))
+	}
+}
+
+// TestNewDocumentChunker_InvalidConfig 测试无效的分块配置
+func TestNewDocumentChunker_InvalidConfig(t *testing.T) {
+	chunker := newDocumentChunker(0, -1)
+	if chunker.chunkSize != DefaultSearchConfig().ChunkSize {
+		t.Errorf("chunkSize = %d, want %d", chunker.chunkSize, DefaultSearchConfig().ChunkSize)
+	}
+	if chunker.overlap != 0 {
+		t.Errorf("overlap = %d, want 0", chunker.overlap)
+	}
+
+	chunker = newDocumentChunker(100, 200)
+	if chunker.overlap >= chunker.chunkSize {
+		t.Errorf("overlap = %d 不应大于等于 chunkSize = %d", chunker.overlap, chunker.chunkSize)
+	}
+}
+
+// TestDocumentChunker_CodeBlocks 测试代码块被抽取为独立的 code 分块
+func TestDocumentChunker_CodeBlocks(t *testing.T) {
+	content := "# 安装\n使用以下命令安装:\n\n```Bash title=\"install\"\ngo get example.com/pkg\n```\n\n安装完成后导入:\n\n    import \"example.com/pkg\"\n\n结束\n"
+	chunker := newDocumentChunker(1000, 100)
+
+	chunks := chunker.Chunk(content, "文档")
+
+	tests := []struct {
+		contentType string
+		language    string
+		codeStyle   string
+		contains    string
+	}{
+		{"text", "", "", "使用以下命令安装"},
+		{"code", "bash", "fenced", "go get example.com/pkg"},
+		{"text", "", "", "安装完成后导入"},
+		{"code", "", "indented", "import \"example.com/pkg\""},
+		{"text", "", "", "结束"},
+	}
+
+	if len(chunks) != len(tests) {
+		t.Fatalf("Chunk() 返回 %d 个分块, want %d", len(chunks), len(tests))
+	}
+
+	for i, tt := range tests {
+		chunk := chunks[i]
+		if chunk.ContentType != tt.contentType {
+			t.Errorf("chunks[%d].ContentType = %q, want %q", i, chunk.ContentType, tt.contentType)
+		}
+		if chunk.Language != tt.language {
+			t.Errorf("chunks[%d].Language = %q, want %q", i, chunk.Language, tt.language)
+		}
+		if chunk.CodeStyle != tt.codeStyle {
+			t.Errorf("chunks[%d].CodeStyle = %q, want %q", i, chunk.CodeStyle, tt.codeStyle)
+		}
+		if !strings.Contains(chunk.Content, tt.contains) {
+			t.Errorf("chunks[%d].Content = %q, 应包含 %q", i, chunk.Content, tt.contains)
+		}
+		if chunk.Section != "安装" {
+			t.Errorf("chunks[%d].Section = %q, want 安装", i, chunk.Section)
+		}
+		if content[chunk.StartPosition:chunk.EndPosition] != chunk.Content {
+			t.Errorf("chunks[%d] 的位置信息与内容不一致", i)
+		}
+	}
+}
+
+// TestFindCodeBlocks_ListContinuation 测试列表项下的缩进内容不被识别为代码块
+func TestFindCodeBlocks_ListContinuation(t *testing.T) {
+	content := "- 第一项\n\n    第一项的补充说明\n\n正文\n"
+	if blocks := findCodeBlocks(content, 0, len(content)); len(blocks) != 0 {
+		t.Errorf("findCodeBlocks() 返回 %d 个代码块, want 0", len(blocks))
+	}
+
+	content = "```go\nfunc main() {}\n"
+	blocks := findCodeBlocks(content, 0, len(content))
+	if len(blocks) != 1 || blocks[0].end != len(content) || blocks[0].language != "go" {
+		t.Errorf("未关闭的围栏代码块应延续到末尾, got %+v", blocks)
+	}
+}
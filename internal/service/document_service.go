@@ -22,6 +22,7 @@ type DocumentService interface {
 	UploadDocument(ctx context.Context, file *multipart.FileHeader, name, docType, category, version, library, description string, tags []string) (*model.Document, error)
 	GetDocument(ctx context.Context, id string) (*model.Document, error)
 	GetDocuments(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
+	GetDocumentsAfter(ctx context.Context, cursor string, size int, filters map[string]interface{}) ([]*model.Document, int64, string, error)
 	GetDocumentVersions(ctx context.Context, documentID string) ([]*model.DocumentVersion, error)
 	GetDocumentByVersion(ctx context.Context, documentID, version string) (*model.DocumentVersion, error)
 	DeleteDocument(ctx context.Context, id string) error
@@ -31,7 +32,8 @@ type DocumentService interface {
 	UpdateDocument(ctx context.Context, id string, updates map[string]interface{}) error
 	UpdateDocumentVersion(ctx context.Context, documentID, oldVersion string, updates map[string]interface{}) error
 	BuildDocumentIndex(ctx context.Context, documentID, version string) error
-	BuildAllMissingIndexes(ctx context.Context) error
+	BuildAllMissingIndexes(ctx context.Context) (*model.IndexReconcileResult, error)
+	ScheduleIndexReconcile(ctx context.Context) (*model.IndexingJob, error)
 }
 
 // documentService 文档服务实现
@@ -42,10 +44,12 @@ type documentService struct {
 	storageService StorageService
 	parserService  DocumentParserService
 	searchService  SearchService
+	jobService     IndexingJobService
 	baseStorageDir string
 }
 
 // NewDocumentService 创建文档服务实例
+// jobService 不为空时，文档解析和索引构建通过持久化的任务队列执行，并注册对应的任务处理函数
 func NewDocumentService(
 	documentRepo repository.DocumentRepository,
 	versionRepo repository.DocumentVersionRepository,
@@ -53,17 +57,25 @@ func NewDocumentService(
 	storageService StorageService,
 	parserService DocumentParserService,
 	searchService SearchService,
+	jobService IndexingJobService,
 	baseStorageDir string,
 ) DocumentService {
-	return &documentService{
+	s := &documentService{
 		documentRepo:   documentRepo,
 		versionRepo:    versionRepo,
 		metadataRepo:   metadataRepo,
 		storageService: storageService,
 		parserService:  parserService,
 		searchService:  searchService,
+		jobService:     jobService,
 		baseStorageDir: baseStorageDir,
 	}
+	if jobService != nil {
+		jobService.RegisterHandler(model.IndexingJobTypeParseDocument, s.runParseDocumentJob)
+		jobService.RegisterHandler(model.IndexingJobTypeBuildIndex, s.runBuildIndexJob)
+		jobService.RegisterHandler(model.IndexingJobTypeReconcileIndexes, s.runReconcileIndexesJob)
+	}
+	return s
 }
 
 // UploadDocument 上传文档
@@ -189,9 +201,21 @@ func (s *documentService) UploadDocument(ctx context.Context, file *multipart.Fi
 	log.Printf("DEBUG: 文档版本记录创建成功 - 文档ID: %s, 版本: %s, 版本记录ID: %s\n",
 		documentID, version, documentVersion.ID)
 
-	// 异步处理文档解析
+	// 异步处理文档解析：加入任务队列，服务重启后任务不会丢失
 	log.Printf("DEBUG: 开始异步处理文档解析 - 文档ID: %s, 版本: %s, 文件路径: %s\n", documentID, version, filePath)
-	go s.processDocumentWithFile(documentID, version, filePath)
+	if s.jobService == nil {
+		go s.processDocumentWithFile(documentID, version, filePath)
+	} else if _, err := s.jobService.Enqueue(ctx, &model.IndexingJob{
+		Type:       model.IndexingJobTypeParseDocument,
+		Priority:   model.IndexingJobPriorityParse,
+		DocumentID: documentID,
+		Version:    version,
+		FilePath:   filePath,
+	}); err != nil {
+		log.Printf("WARNING: 创建文档解析任务失败 - 文档ID: %s, 版本: %s, 错误: %v", documentID, version, err)
+		s.versionRepo.UpdateStatus(ctx, documentID, version, model.DocumentStatusFailed)
+		return nil, fmt.Errorf("failed to enqueue document processing: %v", err)
+	}
 
 	return document, nil
 }
@@ -226,7 +250,56 @@ func (s *documentService) GetDocuments(ctx context.Context, page, size int, filt
 		return nil, 0, err
 	}
 
-	// 为每个文档更新为最新版本
+	s.applyLatestVersions(ctx, documents)
+	return documents, total, nil
+}
+
+// GetDocumentsAfter 使用游标获取文档列表，返回下一页的游标，没有更多文档时为空
+func (s *documentService) GetDocumentsAfter(ctx context.Context, cursor string, size int, filters map[string]interface{}) ([]*model.Document, int64, string, error) {
+	fingerprint := DocumentCursorFingerprint(filters)
+
+	var after *model.Cursor
+	if cursor != "" {
+		decoded, err := model.DecodeCursor(cursor, fingerprint)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		after = decoded
+	}
+
+	documents, total, err := s.documentRepo.ListAfter(ctx, after, size, filters)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	nextCursor := NextDocumentCursor(documents, size, fingerprint)
+	s.applyLatestVersions(ctx, documents)
+	return documents, total, nextCursor, nil
+}
+
+// NextDocumentCursor 根据本页最后一个文档生成下一页的游标，本页不满时返回空
+func NextDocumentCursor(documents []*model.Document, size int, fingerprint string) string {
+	if len(documents) == 0 || len(documents) < size {
+		return ""
+	}
+
+	last := documents[len(documents)-1]
+	createdAt := last.CreatedAt
+	cursor := &model.Cursor{
+		CreatedAt: &createdAt,
+		ID:        last.ID,
+		Query:     fingerprint,
+	}
+	return cursor.Encode()
+}
+
+// DocumentCursorFingerprint 返回文档列表游标的查询指纹
+func DocumentCursorFingerprint(filters map[string]interface{}) string {
+	return cursorFingerprint(filters)
+}
+
+// applyLatestVersions 将文档的相关字段更新为最新版本的数据
+func (s *documentService) applyLatestVersions(ctx context.Context, documents []*model.Document) {
 	for _, doc := range documents {
 		latestVersion, err := s.versionRepo.GetLatestVersion(ctx, doc.ID)
 		if err == nil && latestVersion != nil {
@@ -240,8 +313,6 @@ func (s *documentService) GetDocuments(ctx context.Context, page, size int, filt
 			doc.UpdatedAt = latestVersion.UpdatedAt
 		}
 	}
-
-	return documents, total, nil
 }
 
 // GetDocumentVersions 获取文档版本列表
@@ -267,6 +338,11 @@ func (s *documentService) DeleteDocument(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to delete document files: %v", err)
 	}
 
+	// 先删除搜索索引以更新语料统计和拼写词表，数据库级联删除不会更新统计
+	if err := s.searchService.DeleteIndex(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete search index: %v", err)
+	}
+
 	// 删除文档记录
 	if err := s.documentRepo.Delete(ctx, id); err != nil {
 		return err
@@ -303,6 +379,11 @@ func (s *documentService) DeleteDocumentVersion(ctx context.Context, documentID,
 		return fmt.Errorf("failed to delete version file: %v", err)
 	}
 
+	// 先删除该版本的搜索索引以更新语料统计和拼写词表
+	if err := s.searchService.DeleteIndexByVersion(ctx, documentID, version); err != nil {
+		return fmt.Errorf("failed to delete search index: %v", err)
+	}
+
 	// 删除文档版本记录
 	if err := s.versionRepo.Delete(ctx, docVersion.ID); err != nil {
 		return err
@@ -436,68 +517,67 @@ func (s *documentService) processDocument(documentID string) {
 	log.Printf("DEBUG: 文档处理完成 - 文档ID: %s\n", documentID)
 }
 
-// processDocumentWithFile 处理带指定文件路径的文档（用于版本处理）
+// processDocumentWithFile 处理带指定文件路径的文档（用于版本处理），未启用任务队列时使用
 func (s *documentService) processDocumentWithFile(documentID, version, filePath string) {
 	log.Printf("DEBUG: 进入processDocumentWithFile函数 - 文档ID: %s, 版本: %s, 文件路径: %s\n", documentID, version, filePath)
 	ctx := context.Background()
 
+	if err := s.parseDocumentVersion(ctx, documentID, version, filePath, true); err != nil {
+		log.Printf("DEBUG: 文档处理失败 - 文档ID: %s, 版本: %s, 错误: %v\n", documentID, version, err)
+		return
+	}
+
+	// 构建搜索索引（这是修复搜索功能的关键）
+	log.Printf("DEBUG: 开始构建搜索索引 - 文档ID: %s, 版本: %s\n", documentID, version)
+	if err := s.BuildDocumentIndex(ctx, documentID, version); err != nil {
+		log.Printf("DEBUG: 构建搜索索引失败 - 文档ID: %s, 版本: %s, 错误: %v\n", documentID, version, err)
+		// 不返回错误，因为文档解析已经成功，只是索引构建失败
+	} else {
+		log.Printf("DEBUG: 搜索索引构建成功 - 文档ID: %s, 版本: %s\n", documentID, version)
+	}
+
+	log.Printf("DEBUG: 文档处理完成 - 文档ID: %s, 版本: %s\n", documentID, version)
+}
+
+// parseDocumentVersion 解析文档版本的文件，保存解析后的内容和元数据
+// markFailed 为 true 时解析失败会将文档和版本标记为失败
+func (s *documentService) parseDocumentVersion(ctx context.Context, documentID, version, filePath string, markFailed bool) error {
 	// 获取文档信息
-	log.Printf("DEBUG: 获取文档信息 - 文档ID: %s\n", documentID)
 	document, err := s.documentRepo.GetByID(ctx, documentID)
 	if err != nil {
-		log.Printf("DEBUG: 获取文档信息失败 - 文档ID: %s, 错误: %v\n", documentID, err)
-		return
+		log.Printf("WARNING: 获取文档信息失败 - 文档ID: %s, 错误: %v", documentID, err)
+		return fmt.Errorf("failed to get document: %v", err)
 	}
-	log.Printf("DEBUG: 成功获取文档信息 - 文档ID: %s, 名称: %s, 版本: %s, 状态: %s\n", documentID, document.Name, document.Version, document.Status)
 
 	// 根据文件扩展名确定文档类型，而不是使用原始文档的类型
 	fileType := s.detectFileTypeFromFile(filePath)
-	log.Printf("DEBUG: 检测到文件类型 - 文档ID: %s, 文件路径: %s, 检测类型: %s, 原始类型: %s\n", documentID, filePath, fileType, document.Type)
-
-	// 解析文档
-	log.Printf("DEBUG: 开始解析文档 - 文档ID: %s, 文件路径: %s, 类型: %s\n", documentID, filePath, fileType)
-
-	// 添加文档类型特定的诊断日志
-	switch document.Type {
-	case model.DocumentTypePDF:
-		log.Printf("DEBUG: 检测到PDF文档，将使用PDF解析器 - 文档ID: %s\n", documentID)
-	case model.DocumentTypeDocx:
-		log.Printf("DEBUG: 检测到DOCX文档，将使用DOCX解析器 - 文档ID: %s\n", documentID)
-	default:
-		log.Printf("DEBUG: 检测到其他类型文档 - 文档ID: %s, 类型: %s\n", documentID, document.Type)
-	}
 
 	content, metadata, err := s.parserService.ParseDocument(ctx, filePath, fileType)
 	if err != nil {
-		log.Printf("DEBUG: 解析文档失败 - 文档ID: %s, 错误: %v\n", documentID, err)
-		// 更新文档状态为失败
-		s.documentRepo.Update(ctx, documentID, map[string]interface{}{
-			"status": model.DocumentStatusFailed,
-		})
-		s.versionRepo.UpdateStatus(ctx, documentID, version, model.DocumentStatusFailed)
-		return
+		log.Printf("WARNING: 解析文档失败 - 文档ID: %s, 版本: %s, 类型: %s, 错误: %v", documentID, version, fileType, err)
+		// 不再重试时更新文档状态为失败
+		if markFailed {
+			s.documentRepo.Update(ctx, documentID, map[string]interface{}{
+				"status": model.DocumentStatusFailed,
+			})
+			s.versionRepo.UpdateStatus(ctx, documentID, version, model.DocumentStatusFailed)
+		}
+		return fmt.Errorf("failed to parse document: %v", err)
 	}
-	log.Printf("DEBUG: 文档解析成功 - 文档ID: %s, 内容长度: %d, 元数据键数量: %d\n", documentID, len(content), len(metadata))
-	log.Printf("DEBUG: PDF解析后数据存储位置:\n")
-	log.Printf("  - 原始PDF文件: %s\n", filePath)
-	log.Printf("  - 文档内容(数据库): documents表.content字段, 文档ID: %s\n", documentID)
-	log.Printf("  - 版本内容(数据库): document_versions表.content字段, 文档ID: %s, 版本: %s\n", documentID, version)
-	log.Printf("  - 元数据(数据库): document_metadata表.metadata字段, 文档ID: %s\n", documentID)
+	log.Printf("INFO: 文档解析成功 - 文档ID: %s, 名称: %s, 版本: %s, 类型: %s, 内容长度: %d, 元数据键数量: %d",
+		documentID, document.Name, version, fileType, len(content), len(metadata))
 
 	// 更新文档内容
-	log.Printf("DEBUG: 更新文档内容和状态 - 文档ID: %s\n", documentID)
 	s.documentRepo.Update(ctx, documentID, map[string]interface{}{
 		"content": content,
 		"status":  model.DocumentStatusCompleted,
 	})
 
 	// 更新文档版本内容
-	log.Printf("DEBUG: 更新文档版本内容和状态 - 文档ID: %s, 版本: %s\n", documentID, version)
 	s.versionRepo.UpdateContent(ctx, documentID, version, content, model.DocumentStatusCompleted)
 
 	// 保存元数据
 	if len(metadata) > 0 {
-		log.Printf("DEBUG: 保存文档元数据 - 文档ID: %s\n", documentID)
 		docMetadata := &model.DocumentMetadata{
 			ID:         uuid.New().String(),
 			DocumentID: documentID,
@@ -507,17 +587,37 @@ func (s *documentService) processDocumentWithFile(documentID, version, filePath
 		}
 		s.metadataRepo.Create(ctx, docMetadata)
 	}
+	return nil
+}
 
-	// 构建搜索索引（这是修复搜索功能的关键）
-	log.Printf("DEBUG: 开始构建搜索索引 - 文档ID: %s, 版本: %s\n", documentID, version)
-	if err := s.BuildDocumentIndex(ctx, documentID, version); err != nil {
-		log.Printf("DEBUG: 构建搜索索引失败 - 文档ID: %s, 版本: %s, 错误: %v\n", documentID, version, err)
-		// 不返回错误，因为文档解析已经成功，只是索引构建失败
-	} else {
-		log.Printf("DEBUG: 搜索索引构建成功 - 文档ID: %s, 版本: %s\n", documentID, version)
+// runParseDocumentJob 执行文档解析任务，解析成功后将索引构建任务加入队列
+func (s *documentService) runParseDocumentJob(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+	ReportJobProgress(ctx, 0, 1)
+	if err := s.parseDocumentVersion(ctx, job.DocumentID, job.Version, job.FilePath, job.IsLastAttempt()); err != nil {
+		return nil, err
 	}
+	ReportJobProgress(ctx, 1, 1)
 
-	log.Printf("DEBUG: 文档处理完成 - 文档ID: %s, 版本: %s\n", documentID, version)
+	indexJob, err := s.jobService.Enqueue(ctx, &model.IndexingJob{
+		Type:       model.IndexingJobTypeBuildIndex,
+		Priority:   model.IndexingJobPriorityIndex,
+		DocumentID: job.DocumentID,
+		Version:    job.Version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"index_job_id": indexJob.ID}, nil
+}
+
+// runBuildIndexJob 执行索引构建任务
+func (s *documentService) runBuildIndexJob(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+	return s.searchService.ReindexVersion(ctx, job.DocumentID, job.Version)
+}
+
+// runReconcileIndexesJob 执行索引对账任务
+func (s *documentService) runReconcileIndexesJob(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+	return s.BuildAllMissingIndexes(ctx)
 }
 
 // isValidDocumentType 验证文档类型是否有效
@@ -577,21 +677,20 @@ func (s *documentService) BuildDocumentIndex(ctx context.Context, documentID str
 	return nil
 }
 
-// BuildAllMissingIndexes 为所有缺少索引的文档版本构建搜索索引
-func (s *documentService) BuildAllMissingIndexes(ctx context.Context) error {
-	log.Printf("DEBUG: 开始为所有缺少索引的文档版本构建搜索索引")
-
+// BuildAllMissingIndexes 对账所有已完成文档版本的搜索索引
+// 每个版本按分块内容哈希增量重建：缺失的分块补建，过期的分块删除，内容未变化的分块复用已有向量
+func (s *documentService) BuildAllMissingIndexes(ctx context.Context) (*model.IndexReconcileResult, error) {
 	// 获取所有文档，然后筛选出状态为completed的版本
 	documents, _, err := s.documentRepo.List(ctx, 1, 1000, map[string]interface{}{})
 	if err != nil {
-		return fmt.Errorf("failed to get documents: %v", err)
+		return nil, fmt.Errorf("failed to get documents: %v", err)
 	}
 
 	var versions []*model.DocumentVersion
 	for _, doc := range documents {
 		docVersions, err := s.versionRepo.GetByDocumentID(ctx, doc.ID)
 		if err != nil {
-			log.Printf("DEBUG: 获取文档版本失败 - 文档ID: %s, 错误: %v", doc.ID, err)
+			log.Printf("WARNING: 获取文档版本失败 - 文档ID: %s, 错误: %v", doc.ID, err)
 			continue
 		}
 
@@ -603,37 +702,41 @@ func (s *documentService) BuildAllMissingIndexes(ctx context.Context) error {
 		}
 	}
 
-	log.Printf("DEBUG: 找到 %d 个已完成的文档版本", len(versions))
-
-	successCount := 0
-	failCount := 0
-
-	for _, version := range versions {
-		// 检查是否已经有搜索索引
-		indexes, err := s.searchService.GetIndexingStatus(ctx, version.DocumentID)
+	result := &model.IndexReconcileResult{}
+	for i, version := range versions {
+		ReportJobProgress(ctx, i, len(versions))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Versions++
+		// 任务进度按版本计算，单个版本内按分块上报的进度不写入任务
+		built, err := s.searchService.ReindexVersion(withoutJobProgress(ctx), version.DocumentID, version.Version)
 		if err != nil {
-			log.Printf("DEBUG: 检查文档索引状态失败 - 文档ID: %s, 版本: %s, 错误: %v", version.DocumentID, version.Version, err)
-			failCount++
+			log.Printf("WARNING: 对账搜索索引失败 - 文档ID: %s, 版本: %s, 错误: %v", version.DocumentID, version.Version, err)
+			result.Failed++
 			continue
 		}
-
-		// 如果没有索引或索引数量为0，则构建索引
-		if indexedCount, ok := indexes["indexed"].(int64); !ok || indexedCount == 0 {
-			log.Printf("DEBUG: 为文档版本构建搜索索引 - 文档ID: %s, 版本: %s", version.DocumentID, version.Version)
-			if err := s.BuildDocumentIndex(ctx, version.DocumentID, version.Version); err != nil {
-				log.Printf("DEBUG: 构建搜索索引失败 - 文档ID: %s, 版本: %s, 错误: %v", version.DocumentID, version.Version, err)
-				failCount++
-			} else {
-				log.Printf("DEBUG: 成功构建搜索索引 - 文档ID: %s, 版本: %s", version.DocumentID, version.Version)
-				successCount++
-			}
-		} else {
-			log.Printf("DEBUG: 文档版本已有搜索索引 - 文档ID: %s, 版本: %s, 索引数量: %d", version.DocumentID, version.Version, indexedCount)
+		if built.HasChanges() {
+			result.Changed++
 		}
+		result.Add(built)
 	}
+	ReportJobProgress(ctx, len(versions), len(versions))
 
-	log.Printf("DEBUG: 搜索索引构建完成 - 成功: %d, 失败: %d", successCount, failCount)
-	return nil
+	log.Printf("INFO: 搜索索引对账完成 - 版本: %d, 有变化: %d, 失败: %d, 新增分块: %d, 删除分块: %d, 复用向量: %d, 重新生成向量: %d",
+		result.Versions, result.Changed, result.Failed, result.Added, result.Removed, result.Reused, result.Embedded)
+	return result, nil
+}
+
+// ScheduleIndexReconcile 将索引对账任务加入队列，已有待执行的对账任务时返回已有任务
+func (s *documentService) ScheduleIndexReconcile(ctx context.Context) (*model.IndexingJob, error) {
+	if s.jobService == nil {
+		return nil, fmt.Errorf("indexing job queue is not configured")
+	}
+	return s.jobService.Enqueue(ctx, &model.IndexingJob{
+		Type:     model.IndexingJobTypeReconcileIndexes,
+		Priority: model.IndexingJobPriorityReconcile,
+	})
 }
 
 // isValidDocumentCategory 验证文档分类是否有效
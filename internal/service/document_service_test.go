@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// memoryIndexRepository 基于内存的搜索索引仓库，只实现删除文档和重建索引时用到的方法
+type memoryIndexRepository struct {
+	repository.SearchIndexRepository
+	indices []*model.SearchIndex
+}
+
+func (r *memoryIndexRepository) GetByDocumentID(ctx context.Context, documentID string) ([]*model.SearchIndex, error) {
+	return r.find(func(index *model.SearchIndex) bool { return index.DocumentID == documentID }), nil
+}
+
+func (r *memoryIndexRepository) GetByDocumentIDAndVersion(ctx context.Context, documentID, version string) ([]*model.SearchIndex, error) {
+	return r.find(func(index *model.SearchIndex) bool { return index.DocumentID == documentID && index.Version == version }), nil
+}
+
+func (r *memoryIndexRepository) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	r.remove(func(index *model.SearchIndex) bool { return index.DocumentID == documentID })
+	return nil
+}
+
+func (r *memoryIndexRepository) DeleteByDocumentIDAndVersion(ctx context.Context, documentID, version string) error {
+	r.remove(func(index *model.SearchIndex) bool { return index.DocumentID == documentID && index.Version == version })
+	return nil
+}
+
+func (r *memoryIndexRepository) CreateBatch(ctx context.Context, indices []*model.SearchIndex) error {
+	r.indices = append(r.indices, indices...)
+	return nil
+}
+
+func (r *memoryIndexRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	return nil
+}
+
+func (r *memoryIndexRepository) DeleteByIDs(ctx context.Context, ids []string) error {
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	r.remove(func(index *model.SearchIndex) bool { return deleted[index.ID] })
+	return nil
+}
+
+func (r *memoryIndexRepository) GetVectorsByContentHashes(ctx context.Context, hashes []string, embeddingModel string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (r *memoryIndexRepository) find(match func(*model.SearchIndex) bool) []*model.SearchIndex {
+	var found []*model.SearchIndex
+	for _, index := range r.indices {
+		if match(index) {
+			found = append(found, index)
+		}
+	}
+	return found
+}
+
+func (r *memoryIndexRepository) remove(match func(*model.SearchIndex) bool) {
+	kept := r.indices[:0]
+	for _, index := range r.indices {
+		if !match(index) {
+			kept = append(kept, index)
+		}
+	}
+	r.indices = kept
+}
+
+// fakeDocumentRepository 只保存一个文档的文档仓库
+type fakeDocumentRepository struct {
+	repository.DocumentRepository
+	document *model.Document
+}
+
+func (r *fakeDocumentRepository) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	if r.document == nil || r.document.ID != id {
+		return nil, repository.ErrRecordNotFound
+	}
+	return r.document, nil
+}
+
+func (r *fakeDocumentRepository) List(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.Document, int64, error) {
+	if r.document == nil {
+		return nil, 0, nil
+	}
+	return []*model.Document{r.document}, 1, nil
+}
+
+func (r *fakeDocumentRepository) Delete(ctx context.Context, id string) error {
+	r.document = nil
+	return nil
+}
+
+// fakeDocumentVersionRepository 基于内存的文档版本仓库
+type fakeDocumentVersionRepository struct {
+	repository.DocumentVersionRepository
+	versions []*model.DocumentVersion
+}
+
+func (r *fakeDocumentVersionRepository) GetByDocumentIDAndVersion(ctx context.Context, documentID, version string) (*model.DocumentVersion, error) {
+	for _, docVersion := range r.versions {
+		if docVersion.DocumentID == documentID && docVersion.Version == version {
+			return docVersion, nil
+		}
+	}
+	return nil, repository.ErrRecordNotFound
+}
+
+func (r *fakeDocumentVersionRepository) GetByDocumentID(ctx context.Context, documentID string) ([]*model.DocumentVersion, error) {
+	var found []*model.DocumentVersion
+	for _, docVersion := range r.versions {
+		if docVersion.DocumentID == documentID {
+			found = append(found, docVersion)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeDocumentVersionRepository) Delete(ctx context.Context, id string) error {
+	kept := r.versions[:0]
+	for _, docVersion := range r.versions {
+		if docVersion.ID != id {
+			kept = append(kept, docVersion)
+		}
+	}
+	r.versions = kept
+	return nil
+}
+
+func (r *fakeDocumentVersionRepository) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	r.versions = nil
+	return nil
+}
+
+// fakeDocumentMetadataRepository 不保存数据的文档元数据仓库
+type fakeDocumentMetadataRepository struct {
+	repository.DocumentMetadataRepository
+}
+
+func (r *fakeDocumentMetadataRepository) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	return nil
+}
+
+// newIndexedDocumentService 创建包含一个已索引文档（版本 1.0 和 2.0）的文档服务
+func newIndexedDocumentService(t *testing.T, statsRepo *fakeSearchStatsRepository) (DocumentService, *memoryIndexRepository) {
+	t.Helper()
+	dir := t.TempDir()
+	versions := []*model.DocumentVersion{
+		{ID: "ver-1", DocumentID: "doc-1", Version: "1.0", FilePath: filepath.Join(dir, "v1.md")},
+		{ID: "ver-2", DocumentID: "doc-1", Version: "2.0", FilePath: filepath.Join(dir, "v2.md")},
+	}
+	for _, docVersion := range versions {
+		if err := os.WriteFile(docVersion.FilePath, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	indexRepo := &memoryIndexRepository{indices: []*model.SearchIndex{
+		{ID: "idx-1", DocumentID: "doc-1", Version: "1.0", Section: "Setup", Content: "configure kubernetes ingress"},
+		{ID: "idx-2", DocumentID: "doc-1", Version: "2.0", Content: "kubernetes operator"},
+	}}
+	search := &searchService{indexRepo: indexRepo, statsRepo: statsRepo, config: DefaultSearchConfig()}
+	// 与建立索引时一样增量更新语料统计
+	search.updateStatistics(context.Background(), indexRepo.indices, nil)
+
+	documentService := NewDocumentService(
+		&fakeDocumentRepository{document: &model.Document{ID: "doc-1", FilePath: filepath.Join(dir, "document.md")}},
+		&fakeDocumentVersionRepository{versions: versions},
+		&fakeDocumentMetadataRepository{},
+		nil, nil, search, nil, dir,
+	)
+	return documentService, indexRepo
+}
+
+// TestDeleteDocumentUpdatesStatistics 测试删除文档和文档版本后语料统计恢复到建立索引前的值
+func TestDeleteDocumentUpdatesStatistics(t *testing.T) {
+	statsRepo := &fakeSearchStatsRepository{docFreqs: map[string]int64{"kubernetes": 5, "gin": 3}, chunkCount: 8}
+	documentService, indexRepo := newIndexedDocumentService(t, statsRepo)
+	if statsRepo.docFreqs["kubernetes"] != 7 || statsRepo.chunkCount != 10 {
+		t.Fatalf("stats after indexing = %v, %d", statsRepo.docFreqs, statsRepo.chunkCount)
+	}
+
+	if err := documentService.DeleteDocumentVersion(context.Background(), "doc-1", "1.0"); err != nil {
+		t.Fatalf("DeleteDocumentVersion() error = %v", err)
+	}
+	if len(indexRepo.indices) != 1 || statsRepo.docFreqs["kubernetes"] != 6 || statsRepo.docFreqs["ingress"] != 0 || statsRepo.chunkCount != 9 {
+		t.Errorf("stats after deleting version = %v, %d", statsRepo.docFreqs, statsRepo.chunkCount)
+	}
+
+	if err := documentService.DeleteDocument(context.Background(), "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	want := map[string]int64{"kubernetes": 5, "gin": 3}
+	if len(indexRepo.indices) != 0 || statsRepo.chunkCount != 8 || len(statsRepo.docFreqs) != len(want) {
+		t.Fatalf("stats after deleting document = %v, %d, want %v, 8", statsRepo.docFreqs, statsRepo.chunkCount, want)
+	}
+	for term, freq := range want {
+		if statsRepo.docFreqs[term] != freq {
+			t.Errorf("doc freq of %s = %d, want %d", term, statsRepo.docFreqs[term], freq)
+		}
+	}
+}
+
+// recordingJobProgress 记录每次上报的任务进度
+type recordingJobProgress struct {
+	reports [][2]int
+}
+
+func (p *recordingJobProgress) report(done, total int) {
+	p.reports = append(p.reports, [2]int{done, total})
+}
+
+// newReconcileDocumentService 创建包含两个已完成但未建立索引的文档版本的文档服务
+func newReconcileDocumentService(embeddingService EmbeddingService) (DocumentService, *memoryIndexRepository) {
+	document := &model.Document{ID: "doc-1", Name: "部署指南"}
+	versionRepo := &fakeDocumentVersionRepository{versions: []*model.DocumentVersion{
+		{ID: "ver-1", DocumentID: "doc-1", Version: "1.0", Status: model.DocumentStatusCompleted,
+			Content: "# 安装\n使用 Docker 安装\n\n# 配置\n修改配置文件\n\n# 升级\n替换镜像版本\n"},
+		{ID: "ver-2", DocumentID: "doc-1", Version: "2.0", Status: model.DocumentStatusCompleted,
+			Content: "# 安装\n使用 Helm 安装\n\n# 配置\n修改 values.yaml\n\n# 监控\n接入 Prometheus\n"},
+	}}
+	documentRepo := &fakeDocumentRepository{document: document}
+	indexRepo := &memoryIndexRepository{}
+	search := &searchService{
+		indexRepo:        indexRepo,
+		documentRepo:     documentRepo,
+		versionRepo:      versionRepo,
+		embeddingService: embeddingService,
+		config:           DefaultSearchConfig(),
+	}
+	documentService := NewDocumentService(documentRepo, versionRepo, &fakeDocumentMetadataRepository{}, nil, nil, search, nil, "")
+	return documentService, indexRepo
+}
+
+// TestBuildAllMissingIndexesProgress 测试索引对账任务的进度按版本上报，不被单个版本内按分块上报的进度覆盖
+func TestBuildAllMissingIndexesProgress(t *testing.T) {
+	documentService, indexRepo := newReconcileDocumentService(&fakeEmbeddingProvider{})
+	progress := &recordingJobProgress{}
+	ctx := context.WithValue(context.Background(), jobProgressKey{}, jobProgressReporter(progress))
+
+	result, err := documentService.BuildAllMissingIndexes(ctx)
+	if err != nil {
+		t.Fatalf("BuildAllMissingIndexes() error = %v", err)
+	}
+	if result.Versions != 2 || result.Failed != 0 || len(indexRepo.indices) != 6 {
+		t.Fatalf("result = %+v, indices = %d, want 2 versions and 6 indices", result, len(indexRepo.indices))
+	}
+
+	if len(progress.reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	previous := 0
+	for _, report := range progress.reports {
+		done, total := report[0], report[1]
+		if total != 2 {
+			t.Errorf("progress %d/%d, want total 2 versions", done, total)
+		}
+		if done < previous {
+			t.Errorf("progress went backwards: %v", progress.reports)
+			break
+		}
+		previous = done
+	}
+	if previous != 2 {
+		t.Errorf("final progress = %d, want 2", previous)
+	}
+}
@@ -18,12 +18,14 @@ import (
 type MockSearchService struct {
 	mock.Mock
 	BuildIndexFunc           func(ctx context.Context, documentID, version string) error
+	ReindexVersionFunc       func(ctx context.Context, documentID, version string) (*model.IndexBuildResult, error)
 	BuildIndexBatchFunc      func(ctx context.Context, indices []*model.SearchIndex) error
 	SearchFunc               func(ctx context.Context, request *model.SearchRequest) (*model.SearchResponse, error)
 	GetIndexingStatusFunc    func(ctx context.Context, documentID string) (map[string]interface{}, error)
 	DeleteIndexFunc          func(ctx context.Context, documentID string) error
 	DeleteIndexByVersionFunc func(ctx context.Context, documentID, version string) error
 	ClearCacheFunc           func() error
+	EnsureStatisticsFunc     func(ctx context.Context) error
 }
 
 func (m *MockSearchService) BuildIndex(ctx context.Context, documentID, version string) error {
@@ -34,6 +36,17 @@ func (m *MockSearchService) BuildIndex(ctx context.Context, documentID, version
 	return args.Error(0)
 }
 
+func (m *MockSearchService) ReindexVersion(ctx context.Context, documentID, version string) (*model.IndexBuildResult, error) {
+	if m.ReindexVersionFunc != nil {
+		return m.ReindexVersionFunc(ctx, documentID, version)
+	}
+	args := m.Called(ctx, documentID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IndexBuildResult), args.Error(1)
+}
+
 func (m *MockSearchService) BuildIndexBatch(ctx context.Context, indices []*model.SearchIndex) error {
 	if m.BuildIndexBatchFunc != nil {
 		return m.BuildIndexBatchFunc(ctx, indices)
@@ -53,6 +66,14 @@ func (m *MockSearchService) Search(ctx context.Context, request *model.SearchReq
 	return args.Get(0).(*model.SearchResponse), args.Error(1)
 }
 
+func (m *MockSearchService) FindSimilar(ctx context.Context, request *model.SimilarRequest) (*model.SearchResponse, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SearchResponse), args.Error(1)
+}
+
 func (m *MockSearchService) GetIndexingStatus(ctx context.Context, documentID string) (map[string]interface{}, error) {
 	if m.GetIndexingStatusFunc != nil {
 		return m.GetIndexingStatusFunc(ctx, documentID)
@@ -88,14 +109,23 @@ func (m *MockSearchService) ClearCache() error {
 	return args.Error(0)
 }
 
+func (m *MockSearchService) EnsureStatistics(ctx context.Context) error {
+	if m.EnsureStatisticsFunc != nil {
+		return m.EnsureStatisticsFunc(ctx)
+	}
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 // MockDocumentRepository 模拟DocumentRepository
 type MockDocumentRepository struct {
 	mock.Mock
-	CreateFunc  func(ctx context.Context, document *model.Document) error
-	GetByIDFunc func(ctx context.Context, id string) (*model.Document, error)
-	ListFunc    func(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
-	UpdateFunc  func(ctx context.Context, id string, updates map[string]interface{}) error
-	DeleteFunc  func(ctx context.Context, id string) error
+	CreateFunc    func(ctx context.Context, document *model.Document) error
+	GetByIDFunc   func(ctx context.Context, id string) (*model.Document, error)
+	ListFunc      func(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
+	ListAfterFunc func(ctx context.Context, after *model.Cursor, size int, filters map[string]interface{}) ([]*model.Document, int64, error)
+	UpdateFunc    func(ctx context.Context, id string, updates map[string]interface{}) error
+	DeleteFunc    func(ctx context.Context, id string) error
 }
 
 func (m *MockDocumentRepository) Create(ctx context.Context, document *model.Document) error {
@@ -125,6 +155,14 @@ func (m *MockDocumentRepository) List(ctx context.Context, page, size int, filte
 	return args.Get(0).([]*model.Document), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockDocumentRepository) ListAfter(ctx context.Context, after *model.Cursor, size int, filters map[string]interface{}) ([]*model.Document, int64, error) {
+	if m.ListAfterFunc != nil {
+		return m.ListAfterFunc(ctx, after, size, filters)
+	}
+	args := m.Called(ctx, after, size, filters)
+	return args.Get(0).([]*model.Document), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockDocumentRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
 	if m.UpdateFunc != nil {
 		return m.UpdateFunc(ctx, id, updates)
@@ -246,6 +284,14 @@ func (m *MockDocumentVersionRepository) Count(ctx context.Context, documentID st
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockDocumentVersionRepository) GetLibraryVersions(ctx context.Context, library string) ([]*model.LibraryVersion, error) {
+	args := m.Called(ctx, library)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.LibraryVersion), args.Error(1)
+}
+
 func (m *MockDocumentVersionRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -497,6 +543,7 @@ func TestDocumentService_UploadDocumentSuccess(t *testing.T) {
 		mockStorage,
 		nil,                    // parser service
 		new(MockSearchService), // Mock for search service
+		nil,                    // job service
 		"/test/storage",
 	)
 
@@ -555,6 +602,7 @@ func TestDocumentService_UploadDocument_DuplicateVersion(t *testing.T) {
 		mockStorage,
 		nil,
 		new(MockSearchService),
+		nil,
 		"/test/storage",
 	)
 
@@ -589,6 +637,7 @@ func TestDocumentService_UploadDocument_InvalidType(t *testing.T) {
 		mockStorage,
 		nil,
 		new(MockSearchService),
+		nil,
 		"/test/storage",
 	)
 
@@ -644,6 +693,7 @@ func TestDocumentService_GetDocumentWithVersions(t *testing.T) {
 		new(MockStorageService),
 		nil,
 		new(MockSearchService),
+		nil,
 		"/test/storage",
 	)
 
@@ -680,6 +730,7 @@ func TestDocumentService_DeleteDocument(t *testing.T) {
 		new(MockStorageService),
 		nil,
 		new(MockSearchService),
+		nil,
 		"/test/storage",
 	)
 
@@ -709,6 +760,7 @@ func TestDocumentService_DeleteDocumentError(t *testing.T) {
 		new(MockStorageService),
 		nil,
 		new(MockSearchService),
+		nil,
 		"/test/storage",
 	)
 
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// EmbeddingBatchConfig 批量嵌入配置
+type EmbeddingBatchConfig struct {
+	BatchSize         int           // 每次请求嵌入服务的文本数量
+	Concurrency       int           // 同时进行的请求数量
+	RequestsPerSecond float64       // 每秒允许的请求数，不大于0时不限流
+	Burst             int           // 令牌桶容量，允许的突发请求数
+	MaxRetries        int           // 限流（429）或服务端错误（5xx）时的最大重试次数
+	RetryBaseDelay    time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	RetryMaxDelay     time.Duration // 重试等待时间的上限
+	BreakerThreshold  int           // 连续失败多少次后打开断路器
+	BreakerTimeout    time.Duration // 断路器打开后多长时间允许再次尝试
+}
+
+// DefaultEmbeddingBatchConfig 返回默认的批量嵌入配置
+func DefaultEmbeddingBatchConfig() *EmbeddingBatchConfig {
+	return &EmbeddingBatchConfig{
+		BatchSize:         64,
+		Concurrency:       2,
+		RequestsPerSecond: 5,
+		Burst:             5,
+		MaxRetries:        3,
+		RetryBaseDelay:    500 * time.Millisecond,
+		RetryMaxDelay:     30 * time.Second,
+		BreakerThreshold:  5,
+		BreakerTimeout:    30 * time.Second,
+	}
+}
+
+// NewEmbeddingBatchConfigFromEnv 从环境变量创建批量嵌入配置
+func NewEmbeddingBatchConfigFromEnv() *EmbeddingBatchConfig {
+	config := DefaultEmbeddingBatchConfig()
+	config.BatchSize = getEnvInt("EMBEDDING_BATCH_SIZE", config.BatchSize)
+	config.Concurrency = getEnvInt("EMBEDDING_CONCURRENCY", config.Concurrency)
+	config.RequestsPerSecond = getEnvFloat("EMBEDDING_RATE_LIMIT", config.RequestsPerSecond)
+	config.Burst = getEnvInt("EMBEDDING_RATE_BURST", config.Burst)
+	config.MaxRetries = getEnvInt("EMBEDDING_MAX_RETRIES", config.MaxRetries)
+	config.RetryBaseDelay = time.Duration(getEnvInt("EMBEDDING_RETRY_BASE_DELAY_MS", int(config.RetryBaseDelay/time.Millisecond))) * time.Millisecond
+	config.RetryMaxDelay = time.Duration(getEnvInt("EMBEDDING_RETRY_MAX_SECONDS", int(config.RetryMaxDelay/time.Second))) * time.Second
+	config.BreakerThreshold = getEnvInt("EMBEDDING_BREAKER_THRESHOLD", config.BreakerThreshold)
+	config.BreakerTimeout = time.Duration(getEnvInt("EMBEDDING_BREAKER_TIMEOUT_SECONDS", int(config.BreakerTimeout/time.Second))) * time.Second
+	return config
+}
+
+// retryDelay 计算第 attempts 次尝试失败后的重试等待时间（指数退避）
+func (c *EmbeddingBatchConfig) retryDelay(attempts int) time.Duration {
+	delay := c.RetryBaseDelay
+	for i := 1; i < attempts && delay < c.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.RetryMaxDelay {
+		delay = c.RetryMaxDelay
+	}
+	return delay
+}
+
+// batchEmbeddingService 在嵌入服务之上提供分批、并发、限流、重试和断路器保护
+type batchEmbeddingService struct {
+	provider EmbeddingService
+	config   *EmbeddingBatchConfig
+	limiter  *tokenBucket
+	breaker  *CircuitBreaker
+}
+
+// NewBatchEmbeddingService 创建批量嵌入服务实例，breaker 为 nil 时按配置创建断路器
+func NewBatchEmbeddingService(provider EmbeddingService, config *EmbeddingBatchConfig, breaker *CircuitBreaker) EmbeddingService {
+	if config == nil {
+		config = DefaultEmbeddingBatchConfig()
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if breaker == nil {
+		breaker = NewCircuitBreaker("embedding", config.BreakerThreshold, config.BreakerTimeout)
+	}
+	return &batchEmbeddingService{
+		provider: provider,
+		config:   config,
+		limiter:  newTokenBucket(config.RequestsPerSecond, config.Burst),
+		breaker:  breaker,
+	}
+}
+
+// GenerateEmbedding 生成单个文本的嵌入向量
+func (s *batchEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// ModelName 返回底层嵌入服务的模型名称
+func (s *batchEmbeddingService) ModelName() string {
+	return s.provider.ModelName()
+}
+
+// GenerateEmbeddings 按批次并发生成嵌入向量，任一批次失败时取消其余批次并返回错误
+func (s *batchEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(contents))
+	if len(contents) == 0 {
+		return embeddings, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	starts := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	workers := s.config.Concurrency
+	if batches := (len(contents) + s.config.BatchSize - 1) / s.config.BatchSize; workers > batches {
+		workers = batches
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := start + s.config.BatchSize
+				if end > len(contents) {
+					end = len(contents)
+				}
+				batch, err := s.embedBatch(ctx, contents[start:end])
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("failed to embed texts %d-%d: %w", start, end-1, err)
+						cancel()
+					})
+					continue
+				}
+				copy(embeddings[start:end], batch)
+			}
+		}()
+	}
+
+dispatch:
+	for start := 0; start < len(contents); start += s.config.BatchSize {
+		select {
+		case starts <- start:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(starts)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// embedBatch 在断路器保护下生成一批文本的嵌入向量，限流和服务端错误按指数退避重试
+func (s *batchEmbeddingService) embedBatch(ctx context.Context, contents []string) ([][]float32, error) {
+	var embeddings [][]float32
+	err := s.breaker.Execute(func() error {
+		var err error
+		embeddings, err = s.embedWithRetry(ctx, contents)
+		if err != nil && ctx.Err() != nil {
+			// 取消不是嵌入服务的故障，不计入断路器失败次数
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if embeddings == nil {
+		return nil, ctx.Err()
+	}
+	return embeddings, nil
+}
+
+// embedWithRetry 请求嵌入服务，可重试的错误在等待后重试，最多重试 MaxRetries 次
+func (s *batchEmbeddingService) embedWithRetry(ctx context.Context, contents []string) ([][]float32, error) {
+	for attempt := 1; ; attempt++ {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		embeddings, err := s.provider.GenerateEmbeddings(ctx, contents)
+		if err == nil {
+			if len(embeddings) != len(contents) {
+				return nil, fmt.Errorf("embedding service returned %d vectors for %d texts", len(embeddings), len(contents))
+			}
+			return embeddings, nil
+		}
+		if attempt > s.config.MaxRetries || !retryableEmbeddingError(err) {
+			return nil, err
+		}
+
+		delay := s.config.retryDelay(attempt)
+		log.Printf("Embedding request failed (attempt %d), retrying in %v: %v", attempt, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableEmbeddingError 判断嵌入服务错误是否可以重试
+func retryableEmbeddingError(err error) bool {
+	var statusErr *EmbeddingStatusError
+	return errors.As(err, &statusErr) && statusErr.Retryable()
+}
+
+// tokenBucket 令牌桶限流器，nil 表示不限流
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // 每秒补充的令牌数
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// newTokenBucket 创建令牌桶，rate 不大于0时返回 nil（不限流）
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait 等待并取走一个令牌，ctx 取消时返回错误
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return ctx.Err()
+	}
+	for {
+		delay := b.reserve()
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 有可用令牌时取走一个并返回0，否则返回距离下一个令牌可用的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
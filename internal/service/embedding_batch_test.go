@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEmbeddingProvider 记录请求的嵌入服务，按顺序返回预设的错误，向量的第一个元素为文本长度
+type fakeEmbeddingProvider struct {
+	mutex   sync.Mutex
+	errs    []error
+	batches [][]string
+}
+
+func (p *fakeEmbeddingProvider) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (p *fakeEmbeddingProvider) ModelName() string {
+	return "fake"
+}
+
+func (p *fakeEmbeddingProvider) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.batches = append(p.batches, contents)
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	embeddings := make([][]float32, len(contents))
+	for i, content := range contents {
+		embeddings[i] = []float32{float32(len(content))}
+	}
+	return embeddings, nil
+}
+
+func testEmbeddingBatchConfig() *EmbeddingBatchConfig {
+	return &EmbeddingBatchConfig{
+		BatchSize:        2,
+		Concurrency:      2,
+		MaxRetries:       2,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerTimeout:   time.Minute,
+	}
+}
+
+// TestBatchEmbeddingServiceOrder 测试分批生成的向量与输入文本一一对应
+func TestBatchEmbeddingServiceOrder(t *testing.T) {
+	provider := &fakeEmbeddingProvider{}
+	service := NewBatchEmbeddingService(provider, testEmbeddingBatchConfig(), nil)
+
+	contents := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	embeddings, err := service.GenerateEmbeddings(context.Background(), contents)
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	for i, content := range contents {
+		if embeddings[i][0] != float32(len(content)) {
+			t.Errorf("embeddings[%d] = %v, want vector of %q", i, embeddings[i], content)
+		}
+	}
+	if len(provider.batches) != 3 {
+		t.Errorf("requests = %d, want 3", len(provider.batches))
+	}
+}
+
+// TestBatchEmbeddingServiceRetry 测试限流和服务端错误会重试，客户端错误不重试
+func TestBatchEmbeddingServiceRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		wantErr  bool
+		requests int
+	}{
+		{
+			name:     "429后成功",
+			errs:     []error{&EmbeddingStatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("rate limited")}},
+			requests: 2,
+		},
+		{
+			name: "5xx超过重试次数",
+			errs: []error{
+				&EmbeddingStatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")},
+				&EmbeddingStatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")},
+				&EmbeddingStatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")},
+			},
+			wantErr:  true,
+			requests: 3,
+		},
+		{
+			name:     "400不重试",
+			errs:     []error{&EmbeddingStatusError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}},
+			wantErr:  true,
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeEmbeddingProvider{errs: tt.errs}
+			service := NewBatchEmbeddingService(provider, testEmbeddingBatchConfig(), nil)
+
+			_, err := service.GenerateEmbeddings(context.Background(), []string{"a"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateEmbeddings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(provider.batches) != tt.requests {
+				t.Errorf("requests = %d, want %d", len(provider.batches), tt.requests)
+			}
+		})
+	}
+}
+
+// TestBatchEmbeddingServiceBreaker 测试连续失败后断路器打开，不再请求嵌入服务
+func TestBatchEmbeddingServiceBreaker(t *testing.T) {
+	provider := &fakeEmbeddingProvider{errs: []error{errors.New("connection refused")}}
+	config := testEmbeddingBatchConfig()
+	service := NewBatchEmbeddingService(provider, config, NewCircuitBreaker("embedding", 1, time.Minute))
+
+	if _, err := service.GenerateEmbeddings(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("GenerateEmbeddings() error = nil, want provider error")
+	}
+	_, err := service.GenerateEmbeddings(context.Background(), []string{"b"})
+	var openErr *CircuitBreakerOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("GenerateEmbeddings() error = %v, want CircuitBreakerOpenError", err)
+	}
+	if len(provider.batches) != 1 {
+		t.Errorf("requests = %d, want 1", len(provider.batches))
+	}
+}
+
+// TestTokenBucket 测试令牌桶在突发容量用完后限制请求速率
+func TestTokenBucket(t *testing.T) {
+	if newTokenBucket(0, 1) != nil {
+		t.Error("newTokenBucket(0) should disable rate limiting")
+	}
+
+	bucket := newTokenBucket(1, 2)
+	if bucket.reserve() != 0 || bucket.reserve() != 0 {
+		t.Fatal("burst tokens should be available immediately")
+	}
+	if delay := bucket.reserve(); delay <= 0 {
+		t.Errorf("reserve() after burst = %v, want positive delay", delay)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with canceled context error = %v, want context.Canceled", err)
+	}
+}
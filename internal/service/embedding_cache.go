@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// 嵌入向量缓存的监控指标
+var (
+	embeddingCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "embedding_cache_requests_total",
+			Help: "Embedding cache lookups by result (hit or miss).",
+		},
+		[]string{"model", "result"},
+	)
+	embeddingCacheEvictions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "embedding_cache_evictions_total",
+			Help: "Embedding cache entries evicted by TTL or size limit.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(embeddingCacheRequests)
+	prometheus.MustRegister(embeddingCacheEvictions)
+}
+
+// EmbeddingCacheConfig 嵌入向量缓存配置
+type EmbeddingCacheConfig struct {
+	Enabled         bool          // 是否使用缓存
+	TTL             time.Duration // 超过该时间未使用的向量被淘汰
+	MaxEntries      int64         // 缓存的最大向量数量，超过时淘汰最久未使用的向量，不大于0时不限制
+	CleanupInterval time.Duration // 淘汰过期向量的间隔
+}
+
+// DefaultEmbeddingCacheConfig 返回默认的嵌入向量缓存配置
+func DefaultEmbeddingCacheConfig() *EmbeddingCacheConfig {
+	return &EmbeddingCacheConfig{
+		Enabled:         true,
+		TTL:             30 * 24 * time.Hour,
+		MaxEntries:      500000,
+		CleanupInterval: time.Hour,
+	}
+}
+
+// NewEmbeddingCacheConfigFromEnv 从环境变量创建嵌入向量缓存配置
+func NewEmbeddingCacheConfigFromEnv() *EmbeddingCacheConfig {
+	config := DefaultEmbeddingCacheConfig()
+	config.Enabled = getEnv("EMBEDDING_CACHE_ENABLED", "true") != "false"
+	config.TTL = time.Duration(getEnvInt("EMBEDDING_CACHE_TTL_DAYS", int(config.TTL/(24*time.Hour)))) * 24 * time.Hour
+	config.MaxEntries = int64(getEnvInt("EMBEDDING_CACHE_MAX_ENTRIES", int(config.MaxEntries)))
+	config.CleanupInterval = time.Duration(getEnvInt("EMBEDDING_CACHE_CLEANUP_MINUTES", int(config.CleanupInterval/time.Minute))) * time.Minute
+	return config
+}
+
+// EmbeddingCacheService 带持久化缓存的嵌入服务
+type EmbeddingCacheService interface {
+	EmbeddingService
+	// Cleanup 淘汰长期未使用和超出数量上限的缓存向量，返回淘汰的数量
+	Cleanup(ctx context.Context) (int64, error)
+	// RunCleanup 按配置的间隔定期淘汰缓存向量，直到 ctx 取消
+	RunCleanup(ctx context.Context)
+}
+
+// cachedEmbeddingService 在任意嵌入服务之前按（模型，规范化文本的SHA-256）缓存向量
+type cachedEmbeddingService struct {
+	provider EmbeddingService
+	repo     repository.EmbeddingCacheRepository
+	config   *EmbeddingCacheConfig
+}
+
+// NewCachedEmbeddingService 创建带持久化缓存的嵌入服务实例
+func NewCachedEmbeddingService(provider EmbeddingService, repo repository.EmbeddingCacheRepository, config *EmbeddingCacheConfig) EmbeddingCacheService {
+	if config == nil {
+		config = DefaultEmbeddingCacheConfig()
+	}
+	return &cachedEmbeddingService{
+		provider: provider,
+		repo:     repo,
+		config:   config,
+	}
+}
+
+// GenerateEmbedding 生成单个文本的嵌入向量，优先使用缓存
+func (s *cachedEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// ModelName 返回底层嵌入服务的模型名称
+func (s *cachedEmbeddingService) ModelName() string {
+	return s.provider.ModelName()
+}
+
+// GenerateEmbeddings 批量生成嵌入向量：命中缓存的文本直接返回，其余文本去重后调用嵌入服务并写入缓存
+// 缓存读写失败时只记录日志，不影响向量生成
+func (s *cachedEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	if !s.config.Enabled || len(contents) == 0 {
+		return s.provider.GenerateEmbeddings(ctx, contents)
+	}
+	embeddingModel := s.provider.ModelName()
+
+	hashes := make([]string, len(contents))
+	positions := make(map[string][]int, len(contents))
+	var unique []string
+	for i, content := range contents {
+		hash := embeddingCacheKey(content)
+		hashes[i] = hash
+		if _, ok := positions[hash]; !ok {
+			unique = append(unique, hash)
+		}
+		positions[hash] = append(positions[hash], i)
+	}
+
+	cached, err := s.repo.Get(ctx, embeddingModel, unique)
+	if err != nil {
+		log.Printf("WARNING: Failed to read embedding cache: %v", err)
+		cached = nil
+	}
+
+	embeddings := make([][]float32, len(contents))
+	var hits, missing []string
+	for _, hash := range unique {
+		var vector []float32
+		if err := json.Unmarshal([]byte(cached[hash]), &vector); err != nil || len(vector) == 0 {
+			missing = append(missing, hash)
+			continue
+		}
+		hits = append(hits, hash)
+		for _, i := range positions[hash] {
+			embeddings[i] = vector
+		}
+	}
+	embeddingCacheRequests.WithLabelValues(embeddingModel, "hit").Add(float64(len(hits)))
+	embeddingCacheRequests.WithLabelValues(embeddingModel, "miss").Add(float64(len(missing)))
+	s.touch(embeddingModel, hits)
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	missingContents := make([]string, len(missing))
+	for i, hash := range missing {
+		missingContents[i] = contents[positions[hash][0]]
+	}
+	generated, err := s.provider.GenerateEmbeddings(ctx, missingContents)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*model.EmbeddingCacheEntry, 0, len(missing))
+	now := time.Now()
+	for i, hash := range missing {
+		for _, position := range positions[hash] {
+			embeddings[position] = generated[i]
+		}
+		vectorJSON, err := json.Marshal(generated[i])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &model.EmbeddingCacheEntry{
+			Model:      embeddingModel,
+			TextHash:   hash,
+			Vector:     string(vectorJSON),
+			Dimension:  len(generated[i]),
+			LastUsedAt: now,
+		})
+	}
+	if err := s.repo.Save(ctx, entries); err != nil {
+		log.Printf("WARNING: Failed to write embedding cache: %v", err)
+	}
+	return embeddings, nil
+}
+
+// touch 异步记录缓存命中，不阻塞向量生成
+func (s *cachedEmbeddingService) touch(embeddingModel string, hashes []string) {
+	if len(hashes) == 0 {
+		return
+	}
+	go func() {
+		if err := s.repo.Touch(context.Background(), embeddingModel, hashes); err != nil {
+			log.Printf("Failed to record embedding cache hits: %v", err)
+		}
+	}()
+}
+
+// Cleanup 淘汰超过 TTL 未使用的向量，再按最近使用时间淘汰超出数量上限的向量
+func (s *cachedEmbeddingService) Cleanup(ctx context.Context) (int64, error) {
+	var evicted int64
+	if s.config.TTL > 0 {
+		deleted, err := s.repo.DeleteUnusedBefore(ctx, time.Now().Add(-s.config.TTL))
+		if err != nil {
+			return evicted, err
+		}
+		evicted += deleted
+	}
+	if s.config.MaxEntries > 0 {
+		deleted, err := s.repo.TrimToSize(ctx, s.config.MaxEntries)
+		if err != nil {
+			return evicted, err
+		}
+		evicted += deleted
+	}
+	embeddingCacheEvictions.Add(float64(evicted))
+	return evicted, nil
+}
+
+// RunCleanup 定期淘汰缓存向量
+func (s *cachedEmbeddingService) RunCleanup(ctx context.Context) {
+	if !s.config.Enabled || s.config.CleanupInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			evicted, err := s.Cleanup(ctx)
+			if err != nil {
+				log.Printf("Failed to clean up embedding cache: %v", err)
+			} else if evicted > 0 {
+				log.Printf("已淘汰 %d 条嵌入向量缓存", evicted)
+			}
+		}
+	}
+}
+
+// embeddingCacheKey 计算规范化文本（去除首尾空白并合并连续空白）的SHA-256
+func embeddingCacheKey(content string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(content), " ")))
+	return hex.EncodeToString(sum[:])
+}
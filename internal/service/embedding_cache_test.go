@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// fakeEmbeddingCacheRepository 基于内存的嵌入向量缓存仓库
+type fakeEmbeddingCacheRepository struct {
+	mutex   sync.Mutex
+	entries map[string]*model.EmbeddingCacheEntry
+	getErr  error
+}
+
+func newFakeEmbeddingCacheRepository() *fakeEmbeddingCacheRepository {
+	return &fakeEmbeddingCacheRepository{entries: make(map[string]*model.EmbeddingCacheEntry)}
+}
+
+func (r *fakeEmbeddingCacheRepository) Get(ctx context.Context, embeddingModel string, hashes []string) (map[string]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.getErr != nil {
+		return nil, r.getErr
+	}
+	vectors := make(map[string]string)
+	for _, hash := range hashes {
+		if entry, ok := r.entries[embeddingModel+"/"+hash]; ok {
+			vectors[hash] = entry.Vector
+		}
+	}
+	return vectors, nil
+}
+
+func (r *fakeEmbeddingCacheRepository) Save(ctx context.Context, entries []*model.EmbeddingCacheEntry) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, entry := range entries {
+		r.entries[entry.Model+"/"+entry.TextHash] = entry
+	}
+	return nil
+}
+
+func (r *fakeEmbeddingCacheRepository) Touch(ctx context.Context, embeddingModel string, hashes []string) error {
+	return nil
+}
+
+func (r *fakeEmbeddingCacheRepository) DeleteUnusedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var deleted int64
+	for key, entry := range r.entries {
+		if entry.LastUsedAt.Before(before) {
+			delete(r.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *fakeEmbeddingCacheRepository) TrimToSize(ctx context.Context, maxEntries int64) (int64, error) {
+	return 0, nil
+}
+
+// TestCachedEmbeddingService 测试命中缓存的文本不再调用嵌入服务，重复文本只生成一次
+func TestCachedEmbeddingService(t *testing.T) {
+	provider := &fakeEmbeddingProvider{}
+	repo := newFakeEmbeddingCacheRepository()
+	service := NewCachedEmbeddingService(provider, repo, nil)
+	ctx := context.Background()
+
+	embeddings, err := service.GenerateEmbeddings(ctx, []string{"alpha", "beta", "alpha"})
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	if len(provider.batches) != 1 || len(provider.batches[0]) != 2 {
+		t.Fatalf("batches = %v, want one request with 2 unique texts", provider.batches)
+	}
+	if embeddings[0][0] != 5 || embeddings[1][0] != 4 || embeddings[2][0] != 5 {
+		t.Errorf("embeddings = %v, want vectors matching input order", embeddings)
+	}
+
+	// 空白不同的相同文本命中缓存
+	embeddings, err = service.GenerateEmbeddings(ctx, []string{" alpha ", "gamma"})
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	if len(provider.batches) != 2 || len(provider.batches[1]) != 1 || provider.batches[1][0] != "gamma" {
+		t.Fatalf("batches = %v, want second request with only gamma", provider.batches)
+	}
+	if embeddings[0][0] != 5 || embeddings[1][0] != 5 {
+		t.Errorf("embeddings = %v, want cached alpha and generated gamma", embeddings)
+	}
+}
+
+// TestCachedEmbeddingServiceCacheError 测试缓存不可用时直接调用嵌入服务
+func TestCachedEmbeddingServiceCacheError(t *testing.T) {
+	provider := &fakeEmbeddingProvider{}
+	repo := newFakeEmbeddingCacheRepository()
+	repo.getErr = errors.New("connection refused")
+	service := NewCachedEmbeddingService(provider, repo, nil)
+
+	embedding, err := service.GenerateEmbedding(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding() error = %v", err)
+	}
+	if embedding[0] != 5 || len(provider.batches) != 1 {
+		t.Errorf("embedding = %v, requests = %d, want generated by provider", embedding, len(provider.batches))
+	}
+}
+
+// TestCachedEmbeddingServiceCleanup 测试淘汰超过 TTL 未使用的缓存向量
+func TestCachedEmbeddingServiceCleanup(t *testing.T) {
+	repo := newFakeEmbeddingCacheRepository()
+	repo.entries["fake/old"] = &model.EmbeddingCacheEntry{Model: "fake", TextHash: "old", LastUsedAt: time.Now().Add(-48 * time.Hour)}
+	repo.entries["fake/new"] = &model.EmbeddingCacheEntry{Model: "fake", TextHash: "new", LastUsedAt: time.Now()}
+	service := NewCachedEmbeddingService(&fakeEmbeddingProvider{}, repo, &EmbeddingCacheConfig{Enabled: true, TTL: 24 * time.Hour})
+
+	evicted, err := service.Cleanup(context.Background())
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if evicted != 1 || len(repo.entries) != 1 || repo.entries["fake/new"] == nil {
+		t.Errorf("evicted = %d, entries = %v, want only new entry kept", evicted, repo.entries)
+	}
+}
+
+// TestEmbeddingCacheKey 测试缓存键忽略空白差异
+func TestEmbeddingCacheKey(t *testing.T) {
+	if embeddingCacheKey("hello  world\n") != embeddingCacheKey(" hello world") {
+		t.Error("embeddingCacheKey() should ignore whitespace differences")
+	}
+	if embeddingCacheKey("hello world") == embeddingCacheKey("Hello world") {
+		t.Error("embeddingCacheKey() should be case sensitive")
+	}
+}
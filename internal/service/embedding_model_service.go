@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// reembedBatchSize 重新生成向量时每批处理的分块数量，每批之后保存并上报一次任务进度
+const reembedBatchSize = 256
+
+// EmbeddingModelService 嵌入模型登记服务接口
+type EmbeddingModelService interface {
+	// Sync 登记嵌入服务当前的模型并设为活动模型，补齐旧分块的模型信息，返回活动模型和之前的活动模型
+	Sync(ctx context.Context, dimension int) (active, previous *model.EmbeddingModel, err error)
+	// GetStatus 获取登记的模型、各模型的分块数量和需要重新生成向量的分块数量
+	GetStatus(ctx context.Context) (*model.EmbeddingModelStatus, error)
+	// ScheduleReembed 将使用活动模型重新生成向量的任务加入队列，已有待执行的任务时返回已有任务
+	ScheduleReembed(ctx context.Context) (*model.IndexingJob, error)
+	// BuildVocabulary 从语料的检索词统计生成离线特征哈希嵌入使用的词表统计
+	BuildVocabulary(ctx context.Context, minDocFreq int64, limit int) (*model.HashingVocabulary, error)
+}
+
+// embeddingModelService 嵌入模型登记服务实现
+type embeddingModelService struct {
+	repo             repository.EmbeddingModelRepository
+	indexRepo        repository.SearchIndexRepository
+	statsRepo        repository.SearchStatsRepository
+	embeddingService EmbeddingService
+	jobService       IndexingJobService
+}
+
+// NewEmbeddingModelService 创建嵌入模型登记服务实例，jobService 不为空时注册重新生成向量任务的处理函数
+func NewEmbeddingModelService(repo repository.EmbeddingModelRepository, indexRepo repository.SearchIndexRepository, statsRepo repository.SearchStatsRepository, embeddingService EmbeddingService, jobService IndexingJobService) EmbeddingModelService {
+	s := &embeddingModelService{
+		repo:             repo,
+		indexRepo:        indexRepo,
+		statsRepo:        statsRepo,
+		embeddingService: embeddingService,
+		jobService:       jobService,
+	}
+	if jobService != nil {
+		jobService.RegisterHandler(model.IndexingJobTypeReembedIndexes, s.runReembedJob)
+	}
+	return s
+}
+
+// Sync 登记嵌入服务当前的模型并设为活动模型
+func (s *embeddingModelService) Sync(ctx context.Context, dimension int) (*model.EmbeddingModel, *model.EmbeddingModel, error) {
+	if dimension <= 0 {
+		return nil, nil, fmt.Errorf("unknown embedding dimension")
+	}
+	name := s.embeddingService.ModelName()
+
+	previous, err := s.repo.GetActive(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get active embedding model: %v", err)
+	}
+	active, err := s.repo.Activate(ctx, name, dimension)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to activate embedding model: %v", err)
+	}
+
+	backfilled, err := s.indexRepo.BackfillEmbeddingModels(ctx, name, dimension)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to backfill embedding models: %v", err)
+	}
+	if backfilled > 0 {
+		log.Printf("已为 %d 条搜索索引补齐嵌入模型信息", backfilled)
+	}
+	if previous != nil && previous.Name == name && previous.Dimension == dimension {
+		previous = nil
+	}
+	return active, previous, nil
+}
+
+// GetStatus 获取嵌入模型状态
+func (s *embeddingModelService) GetStatus(ctx context.Context) (*model.EmbeddingModelStatus, error) {
+	status := &model.EmbeddingModelStatus{}
+	var err error
+	if status.Active, err = s.repo.GetActive(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get active embedding model: %v", err)
+	}
+	if status.Models, err = s.repo.List(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list embedding models: %v", err)
+	}
+	if status.Usage, err = s.indexRepo.CountByEmbeddingModel(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count chunks by embedding model: %v", err)
+	}
+	if status.StaleChunks, err = s.indexRepo.CountStaleEmbeddings(ctx, s.embeddingService.ModelName()); err != nil {
+		return nil, fmt.Errorf("failed to count stale chunks: %v", err)
+	}
+	return status, nil
+}
+
+// ScheduleReembed 将重新生成向量的任务加入队列
+func (s *embeddingModelService) ScheduleReembed(ctx context.Context) (*model.IndexingJob, error) {
+	if s.jobService == nil {
+		return nil, fmt.Errorf("indexing job queue is not configured")
+	}
+	return s.jobService.Enqueue(ctx, &model.IndexingJob{
+		Type:     model.IndexingJobTypeReembedIndexes,
+		Priority: model.IndexingJobPriorityReembed,
+	})
+}
+
+// BuildVocabulary 生成词表统计，只保留文档频率不低于 minDocFreq 的前 limit 个检索词以控制文件大小
+// 未保留的检索词在嵌入时按最稀有的词计算IDF
+func (s *embeddingModelService) BuildVocabulary(ctx context.Context, minDocFreq int64, limit int) (*model.HashingVocabulary, error) {
+	corpus, err := s.statsRepo.GetCorpusStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get corpus stats: %v", err)
+	}
+	if corpus.ChunkCount <= 0 {
+		return nil, fmt.Errorf("corpus stats are empty, index documents first")
+	}
+	stats, err := s.statsRepo.ListTopTerms(ctx, minDocFreq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list terms: %v", err)
+	}
+
+	vocabulary := &model.HashingVocabulary{
+		Documents: corpus.ChunkCount,
+		Terms:     make(map[string]int64, len(stats)),
+	}
+	for _, stat := range stats {
+		vocabulary.Terms[stat.Term] = stat.DocFreq
+	}
+	return vocabulary, nil
+}
+
+// runReembedJob 执行重新生成向量任务：按ID顺序分批读取不是由活动模型生成向量的分块，生成向量后写回
+// 已写回的分块不再属于待处理范围，任务失败重试时从剩余的分块继续
+func (s *embeddingModelService) runReembedJob(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+	embeddingModel := s.embeddingService.ModelName()
+	total, err := s.indexRepo.CountStaleEmbeddings(ctx, embeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stale chunks: %v", err)
+	}
+	result := &model.ReembedResult{Model: embeddingModel, Total: int(total)}
+	ReportJobProgress(ctx, 0, result.Total)
+
+	done := 0
+	afterID := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, err := s.indexRepo.ListStaleEmbeddings(ctx, embeddingModel, afterID, reembedBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list stale chunks: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		afterID = batch[len(batch)-1].ID
+
+		// 内容为空的分块无法生成向量，跳过
+		pending := make([]*model.SearchIndex, 0, len(batch))
+		contents := make([]string, 0, len(batch))
+		for _, index := range batch {
+			if strings.TrimSpace(index.Content) == "" {
+				result.Skipped++
+				continue
+			}
+			pending = append(pending, index)
+			contents = append(contents, index.Content)
+		}
+
+		if len(pending) > 0 {
+			embeddings, err := s.embeddingService.GenerateEmbeddings(ctx, contents)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+			}
+			for i, index := range pending {
+				setIndexVector(index, embeddings[i], embeddings[i], embeddingModel)
+			}
+			if err := s.indexRepo.UpdateEmbeddings(ctx, pending); err != nil {
+				return nil, fmt.Errorf("failed to save embeddings: %v", err)
+			}
+			result.Embedded += len(pending)
+		}
+
+		done += len(batch)
+		if done > result.Total {
+			result.Total = done
+		}
+		ReportJobProgress(ctx, done, result.Total)
+	}
+
+	log.Printf("重新生成向量完成 - 模型: %s, 分块: %d, 已生成: %d, 跳过: %d", embeddingModel, result.Total, result.Embedded, result.Skipped)
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// fakeReembedIndexRepository 基于内存的搜索索引仓库，只实现重新生成向量用到的方法
+type fakeReembedIndexRepository struct {
+	repository.SearchIndexRepository
+	indices map[string]*model.SearchIndex
+}
+
+func newFakeReembedIndexRepository(indices ...*model.SearchIndex) *fakeReembedIndexRepository {
+	repo := &fakeReembedIndexRepository{indices: make(map[string]*model.SearchIndex)}
+	for _, index := range indices {
+		repo.indices[index.ID] = index
+	}
+	return repo
+}
+
+func (r *fakeReembedIndexRepository) stale(embeddingModel string) []*model.SearchIndex {
+	var stale []*model.SearchIndex
+	for _, index := range r.indices {
+		if index.EmbeddingModel != embeddingModel {
+			stale = append(stale, index)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].ID < stale[j].ID })
+	return stale
+}
+
+func (r *fakeReembedIndexRepository) CountStaleEmbeddings(ctx context.Context, embeddingModel string) (int64, error) {
+	return int64(len(r.stale(embeddingModel))), nil
+}
+
+func (r *fakeReembedIndexRepository) ListStaleEmbeddings(ctx context.Context, embeddingModel, afterID string, limit int) ([]*model.SearchIndex, error) {
+	var batch []*model.SearchIndex
+	for _, index := range r.stale(embeddingModel) {
+		if index.ID > afterID && len(batch) < limit {
+			copied := *index
+			batch = append(batch, &copied)
+		}
+	}
+	return batch, nil
+}
+
+func (r *fakeReembedIndexRepository) UpdateEmbeddings(ctx context.Context, indices []*model.SearchIndex) error {
+	for _, index := range indices {
+		stored := r.indices[index.ID]
+		stored.Vector = index.Vector
+		stored.EmbeddingModel = index.EmbeddingModel
+		stored.EmbeddingDimension = index.EmbeddingDimension
+	}
+	return nil
+}
+
+// TestRunReembedJob 测试重新生成其他模型的向量，内容为空的分块跳过
+func TestRunReembedJob(t *testing.T) {
+	repo := newFakeReembedIndexRepository(
+		&model.SearchIndex{ID: "a", Content: "alpha", EmbeddingModel: "previous-model"},
+		&model.SearchIndex{ID: "b", Content: "beta", EmbeddingModel: model.FallbackEmbeddingModel},
+		&model.SearchIndex{ID: "c", Content: " ", EmbeddingModel: "previous-model"},
+		&model.SearchIndex{ID: "d", Content: "delta", EmbeddingModel: "fake"},
+	)
+	provider := &fakeEmbeddingProvider{}
+	service := NewEmbeddingModelService(nil, repo, nil, provider, nil).(*embeddingModelService)
+
+	result, err := service.runReembedJob(context.Background(), &model.IndexingJob{})
+	if err != nil {
+		t.Fatalf("runReembedJob() error = %v", err)
+	}
+	reembed := result.(*model.ReembedResult)
+	if reembed.Total != 3 || reembed.Embedded != 2 || reembed.Skipped != 1 {
+		t.Errorf("result = %+v, want total 3, embedded 2, skipped 1", reembed)
+	}
+	for _, id := range []string{"a", "b"} {
+		if index := repo.indices[id]; index.EmbeddingModel != "fake" || index.EmbeddingDimension != 1 {
+			t.Errorf("index %s = %+v, want re-embedded with fake model", id, index)
+		}
+	}
+	if len(provider.batches) != 1 {
+		t.Errorf("requests = %d, want 1", len(provider.batches))
+	}
+}
+
+// TestRunReembedJobError 测试嵌入服务失败时任务返回错误，未处理的分块保持原状
+func TestRunReembedJobError(t *testing.T) {
+	repo := newFakeReembedIndexRepository(
+		&model.SearchIndex{ID: "a", Content: "alpha", EmbeddingModel: "previous-model"},
+	)
+	provider := &fakeEmbeddingProvider{errs: []error{errors.New("connection refused")}}
+	service := NewEmbeddingModelService(nil, repo, nil, provider, nil).(*embeddingModelService)
+
+	if _, err := service.runReembedJob(context.Background(), &model.IndexingJob{}); err == nil {
+		t.Fatal("runReembedJob() error = nil, want embedding error")
+	}
+	if repo.indices["a"].EmbeddingModel != "previous-model" {
+		t.Errorf("embedding model = %s, want previous-model", repo.indices["a"].EmbeddingModel)
+	}
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 
@@ -13,6 +15,32 @@ import (
 // EmbeddingService 嵌入向量服务接口
 type EmbeddingService interface {
 	GenerateEmbedding(ctx context.Context, content string) ([]float32, error)
+	// GenerateEmbeddings 批量生成嵌入向量，返回的向量与 contents 一一对应
+	GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error)
+	// ModelName 返回生成向量的模型名称，记录在搜索索引中，不同模型的向量不能相互比较
+	ModelName() string
+}
+
+// ErrEmbeddingUnavailable 嵌入服务不可用，无法生成查询向量或内容向量
+var ErrEmbeddingUnavailable = errors.New("embedding service is not available")
+
+// EmbeddingStatusError 嵌入服务返回的HTTP错误
+type EmbeddingStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *EmbeddingStatusError) Error() string {
+	return fmt.Sprintf("embedding service returned status %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *EmbeddingStatusError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 判断错误是否可以重试：限流（429）和服务端错误（5xx）
+func (e *EmbeddingStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
 }
 
 // openAIEmbeddingService OpenAI 嵌入向量服务实现
@@ -54,37 +82,70 @@ func NewOpenAIEmbeddingService(apiKey, modelStr string) EmbeddingService {
 
 // GenerateEmbedding 生成文本的嵌入向量
 func (s *openAIEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
-	// 检查内容是否为空
-	if strings.TrimSpace(content) == "" {
-		return nil, fmt.Errorf("content is empty")
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
 	}
+	return embeddings[0], nil
+}
 
-	// 截断过长的内容，OpenAI API 有输入限制
-	if len(content) > 8192 {
-		content = content[:8192]
-		log.Printf("Warning: Content truncated to 8192 characters for embedding generation")
-	}
+// GenerateEmbeddings 在一次请求中生成多个文本的嵌入向量
+func (s *openAIEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	input := make([]string, len(contents))
+	for i, content := range contents {
+		// 检查内容是否为空
+		if strings.TrimSpace(content) == "" {
+			return nil, fmt.Errorf("content %d is empty", i)
+		}
 
-	// 创建嵌入请求
-	req := openai.EmbeddingRequest{
-		Input: []string{content},
-		Model: s.model,
+		// 截断过长的内容，OpenAI API 有输入限制；需要完整内容时由 longTextEmbeddingService 切分后再调用
+		if truncated, ok := truncateEmbeddingText(content, windowTokenLimit(openAIMaxInputTokens)); ok {
+			content = truncated
+			log.Printf("Warning: Content truncated to %d estimated tokens for embedding generation", estimateEmbeddingTokens(content))
+		}
+		input[i] = content
 	}
 
 	// 调用 OpenAI API
-	resp, err := s.client.CreateEmbeddings(ctx, req)
+	resp, err := s.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: input,
+		Model: s.model,
+	})
 	if err != nil {
-		log.Printf("Error generating embedding: %v", err)
-		return nil, fmt.Errorf("failed to generate embedding: %v", err)
+		return nil, openAIEmbeddingError(err)
 	}
 
-	// 检查响应
-	if len(resp.Data) == 0 {
-		return nil, fmt.Errorf("no embedding data returned")
+	// 按响应中的序号对应输入文本
+	embeddings := make([][]float32, len(contents))
+	for _, data := range resp.Data {
+		if data.Index >= 0 && data.Index < len(embeddings) {
+			embeddings[data.Index] = data.Embedding
+		}
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("no embedding data returned for content %d", i)
+		}
 	}
+	return embeddings, nil
+}
+
+// ModelName 返回 OpenAI 嵌入模型名称
+func (s *openAIEmbeddingService) ModelName() string {
+	return string(s.model)
+}
 
-	// 返回嵌入向量
-	return resp.Data[0].Embedding, nil
+// openAIEmbeddingError 将 OpenAI 客户端的HTTP错误转换为 EmbeddingStatusError，以便判断是否重试
+func openAIEmbeddingError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		return &EmbeddingStatusError{StatusCode: apiErr.HTTPStatusCode, Err: err}
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) && requestErr.HTTPStatusCode > 0 {
+		return &EmbeddingStatusError{StatusCode: requestErr.HTTPStatusCode, Err: err}
+	}
+	return fmt.Errorf("failed to generate embedding: %w", err)
 }
 
 // mockEmbeddingService 模拟嵌入向量服务实现（用于测试或当 OpenAI 服务不可用时）
@@ -127,3 +188,21 @@ func (s *mockEmbeddingService) GenerateEmbedding(ctx context.Context, content st
 
 	return vector, nil
 }
+
+// ModelName 返回模拟嵌入模型名称
+func (s *mockEmbeddingService) ModelName() string {
+	return "mock-hash-384"
+}
+
+// GenerateEmbeddings 批量生成模拟的文本嵌入向量
+func (s *mockEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(contents))
+	for i, content := range contents {
+		embedding, err := s.GenerateEmbedding(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("content %d: %w", i, err)
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
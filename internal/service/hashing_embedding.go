@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// projectionNonZeros 稀疏随机投影中每个哈希桶映射到的输出维度数量
+const projectionNonZeros = 8
+
+// wordNGramDecay 词 n-gram 每增加一个词时权重的衰减系数
+const wordNGramDecay = 0.5
+
+// HashingEmbeddingConfig 离线特征哈希嵌入配置
+type HashingEmbeddingConfig struct {
+	Dimension      int    // 输出向量维度
+	Buckets        int    // 哈希桶数量，大于 Dimension 时先哈希到桶再稀疏随机投影到 Dimension 维，否则直接哈希到 Dimension 维
+	WordNGrams     int    // 词 n-gram 的最大长度
+	CharNGramMin   int    // 字符 n-gram 的最小长度
+	CharNGramMax   int    // 字符 n-gram 的最大长度，不大于0时不使用字符 n-gram
+	Seed           uint64 // 哈希和随机投影的种子，修改后需要重新生成向量
+	VocabularyPath string // 词表统计文件路径，为空时所有检索词的IDF相同
+}
+
+// DefaultHashingEmbeddingConfig 返回默认的离线特征哈希嵌入配置
+func DefaultHashingEmbeddingConfig() *HashingEmbeddingConfig {
+	return &HashingEmbeddingConfig{
+		Dimension:    512,
+		WordNGrams:   2,
+		CharNGramMin: 3,
+		CharNGramMax: 5,
+		Seed:         42,
+	}
+}
+
+// NewHashingEmbeddingConfigFromEnv 从环境变量创建离线特征哈希嵌入配置
+func NewHashingEmbeddingConfigFromEnv() *HashingEmbeddingConfig {
+	config := DefaultHashingEmbeddingConfig()
+	config.Dimension = getEnvInt("HASHING_EMBEDDING_DIMENSION", config.Dimension)
+	config.Buckets = getEnvInt("HASHING_EMBEDDING_BUCKETS", config.Buckets)
+	config.WordNGrams = getEnvInt("HASHING_EMBEDDING_WORD_NGRAMS", config.WordNGrams)
+	config.CharNGramMin = getEnvInt("HASHING_EMBEDDING_CHAR_NGRAM_MIN", config.CharNGramMin)
+	config.CharNGramMax = getEnvInt("HASHING_EMBEDDING_CHAR_NGRAM_MAX", config.CharNGramMax)
+	config.Seed = uint64(getEnvInt("HASHING_EMBEDDING_SEED", int(config.Seed)))
+	config.VocabularyPath = getEnv("HASHING_EMBEDDING_VOCABULARY", "")
+	return config
+}
+
+// LoadHashingVocabulary 从文件加载词表统计
+func LoadHashingVocabulary(path string) (*model.HashingVocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %v", err)
+	}
+	var vocabulary model.HashingVocabulary
+	if err := json.Unmarshal(data, &vocabulary); err != nil {
+		return nil, fmt.Errorf("failed to parse vocabulary: %v", err)
+	}
+	if vocabulary.Documents <= 0 || len(vocabulary.Terms) == 0 {
+		return nil, fmt.Errorf("vocabulary is empty")
+	}
+	return &vocabulary, nil
+}
+
+// hashingEmbeddingService 离线特征哈希嵌入服务实现，不依赖网络
+// 文本切分为检索词后，词 n-gram 和词内字符 n-gram 按 TF-IDF 加权并带符号地哈希到固定维度，
+// 字符 n-gram 使拼写相近和词形变化的词得到相近的向量
+type hashingEmbeddingService struct {
+	config     *HashingEmbeddingConfig
+	vocabulary *model.HashingVocabulary
+	buckets    int
+	modelName  string
+}
+
+// NewHashingEmbeddingService 创建离线特征哈希嵌入服务实例，配置了词表统计文件时加载词表用于IDF加权
+func NewHashingEmbeddingService(config *HashingEmbeddingConfig) (EmbeddingService, error) {
+	if config == nil {
+		config = DefaultHashingEmbeddingConfig()
+	}
+	if config.Dimension <= 0 {
+		return nil, fmt.Errorf("invalid hashing embedding dimension: %d", config.Dimension)
+	}
+	if config.WordNGrams <= 0 && config.CharNGramMax <= 0 {
+		return nil, fmt.Errorf("hashing embedding requires word or character n-grams")
+	}
+	if config.CharNGramMax > 0 && (config.CharNGramMin <= 0 || config.CharNGramMin > config.CharNGramMax) {
+		return nil, fmt.Errorf("invalid character n-gram range: %d-%d", config.CharNGramMin, config.CharNGramMax)
+	}
+
+	var vocabulary *model.HashingVocabulary
+	if config.VocabularyPath != "" {
+		var err error
+		if vocabulary, err = LoadHashingVocabulary(config.VocabularyPath); err != nil {
+			return nil, err
+		}
+	}
+	return newHashingEmbeddingService(config, vocabulary), nil
+}
+
+// newHashingEmbeddingService 使用已加载的词表创建离线特征哈希嵌入服务
+func newHashingEmbeddingService(config *HashingEmbeddingConfig, vocabulary *model.HashingVocabulary) *hashingEmbeddingService {
+	s := &hashingEmbeddingService{
+		config:     config,
+		vocabulary: vocabulary,
+		buckets:    config.Dimension,
+	}
+	if config.Buckets > config.Dimension {
+		s.buckets = config.Buckets
+	}
+
+	// 模型名称包含所有影响向量的参数，参数或词表变化后旧向量被识别为其他模型的向量
+	name := fmt.Sprintf("hashing-d%d", config.Dimension)
+	if s.buckets != config.Dimension {
+		name += fmt.Sprintf("-b%d", s.buckets)
+	}
+	name += fmt.Sprintf("-w%d", config.WordNGrams)
+	if config.CharNGramMax > 0 {
+		name += fmt.Sprintf("-c%d-%d", config.CharNGramMin, config.CharNGramMax)
+	}
+	name += fmt.Sprintf("-s%d", config.Seed)
+	if vocabulary != nil {
+		name += "-v" + vocabularyFingerprint(vocabulary)
+	}
+	s.modelName = name
+	return s
+}
+
+// GenerateEmbedding 生成文本的特征哈希向量
+func (s *hashingEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is empty")
+	}
+
+	features := s.features(content)
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	// 按固定顺序累加，保证相同文本得到完全相同的向量
+	sort.Strings(names)
+
+	buckets := make([]float64, s.buckets)
+	for _, name := range names {
+		hash := s.hash(name)
+		weight := features[name]
+		if hash>>63 == 1 {
+			weight = -weight
+		}
+		buckets[hash%uint64(s.buckets)] += weight
+	}
+
+	vector := buckets
+	if s.buckets != s.config.Dimension {
+		vector = s.project(buckets)
+	}
+	return normalizeVector(vector), nil
+}
+
+// GenerateEmbeddings 批量生成特征哈希向量
+func (s *hashingEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(contents))
+	for i, content := range contents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embedding, err := s.GenerateEmbedding(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("content %d: %w", i, err)
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// ModelName 返回包含参数和词表指纹的模型名称
+func (s *hashingEmbeddingService) ModelName() string {
+	return s.modelName
+}
+
+// features 提取文本的加权特征：词 n-gram 的权重为 (1+log(tf))*IDF，多词 n-gram 的IDF取各词的平均值并按长度衰减，
+// 每个检索词的权重再平均分配到其字符 n-gram 上；文本中没有检索词时（如只有符号）使用原文的字符 n-gram
+func (s *hashingEmbeddingService) features(content string) map[string]float64 {
+	features := make(map[string]float64)
+	tokens := tokenize(content)
+	if len(tokens) == 0 {
+		s.addCharNGrams(features, strings.Join(strings.Fields(strings.ToLower(content)), " "), 1)
+		return features
+	}
+
+	idfs := make([]float64, len(tokens))
+	for i, token := range tokens {
+		idfs[i] = s.idf(token)
+	}
+
+	for n := 1; n <= s.config.WordNGrams; n++ {
+		counts := make(map[string]int)
+		idfSums := make(map[string]float64)
+		for i := 0; i+n <= len(tokens); i++ {
+			gram := strings.Join(tokens[i:i+n], " ")
+			counts[gram]++
+			if counts[gram] == 1 {
+				for _, idf := range idfs[i : i+n] {
+					idfSums[gram] += idf
+				}
+			}
+		}
+		decay := math.Pow(wordNGramDecay, float64(n-1))
+		for gram, count := range counts {
+			weight := (1 + math.Log(float64(count))) * idfSums[gram] / float64(n) * decay
+			features["w:"+gram] += weight
+			if n == 1 {
+				s.addCharNGrams(features, gram, weight)
+			}
+		}
+	}
+
+	// 只使用字符 n-gram 时仍需为每个检索词计算权重
+	if s.config.WordNGrams <= 0 {
+		counts := termFrequencies(tokens)
+		for term, count := range counts {
+			s.addCharNGrams(features, term, (1+math.Log(float64(count)))*s.idf(term))
+		}
+	}
+	return features
+}
+
+// addCharNGrams 将权重平均分配到带边界标记的文本的字符 n-gram 上
+func (s *hashingEmbeddingService) addCharNGrams(features map[string]float64, text string, weight float64) {
+	if s.config.CharNGramMax <= 0 || text == "" {
+		return
+	}
+	runes := []rune("<" + text + ">")
+	var grams []string
+	for n := s.config.CharNGramMin; n <= s.config.CharNGramMax; n++ {
+		for i := 0; i+n <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+n]))
+		}
+	}
+	if len(grams) == 0 {
+		// 比最小 n-gram 还短的文本整体作为一个特征
+		grams = append(grams, string(runes))
+	}
+	share := weight / float64(len(grams))
+	for _, gram := range grams {
+		features["c:"+gram] += share
+	}
+}
+
+// idf 计算检索词的IDF，没有词表时为1；词表中没有的词视为只出现在0个分块中
+func (s *hashingEmbeddingService) idf(term string) float64 {
+	if s.vocabulary == nil {
+		return 1
+	}
+	documents := float64(s.vocabulary.Documents)
+	docFreq := float64(s.vocabulary.Terms[term])
+	return math.Log((documents+1)/(docFreq+1)) + 1
+}
+
+// hash 计算特征的带种子哈希值，最高位作为符号，其余部分用于选择哈希桶
+func (s *hashingEmbeddingService) hash(feature string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	return splitMix64(h.Sum64() ^ s.config.Seed)
+}
+
+// project 将哈希桶稀疏随机投影到输出维度：每个桶以随机符号加到 projectionNonZeros 个随机维度上
+func (s *hashingEmbeddingService) project(buckets []float64) []float64 {
+	vector := make([]float64, s.config.Dimension)
+	for bucket, value := range buckets {
+		if value == 0 {
+			continue
+		}
+		state := splitMix64(s.config.Seed ^ uint64(bucket+1)*0x9e3779b97f4a7c15)
+		for j := 0; j < projectionNonZeros; j++ {
+			state = splitMix64(state)
+			if state>>63 == 1 {
+				vector[state%uint64(s.config.Dimension)] -= value
+			} else {
+				vector[state%uint64(s.config.Dimension)] += value
+			}
+		}
+	}
+	return vector
+}
+
+// splitMix64 对64位整数做一次 SplitMix64 混合，用作确定性的伪随机数
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// normalizeVector 将向量L2归一化并转换为 float32，零向量保持为零
+func normalizeVector(vector []float64) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	result := make([]float32, len(vector))
+	if norm == 0 {
+		return result
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vector {
+		result[i] = float32(v / norm)
+	}
+	return result
+}
+
+// vocabularyFingerprint 计算词表内容的短指纹，词表变化时模型名称随之变化
+func vocabularyFingerprint(vocabulary *model.HashingVocabulary) string {
+	terms := make([]string, 0, len(vocabulary.Terms))
+	for term := range vocabulary.Terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", vocabulary.Documents)
+	for _, term := range terms {
+		fmt.Fprintf(h, "%s\t%d\n", term, vocabulary.Terms[term])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// fallbackEmbedder 为内容为空的分块生成备用向量，不使用词表
+var fallbackEmbedder = newHashingEmbeddingService(&HashingEmbeddingConfig{
+	Dimension:    fallbackVectorDimension,
+	WordNGrams:   2,
+	CharNGramMin: 3,
+	CharNGramMax: 5,
+	Seed:         42,
+}, nil)
+
+// fallbackEmbedding 生成备用向量，内容为空时返回零向量
+func fallbackEmbedding(content string) []float32 {
+	vector, err := fallbackEmbedder.GenerateEmbedding(context.Background(), content)
+	if err != nil {
+		return make([]float32, fallbackVectorDimension)
+	}
+	return vector
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// cosine 计算两个已归一化向量的余弦相似度
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func mustHashingEmbedding(t *testing.T, service EmbeddingService, content string) []float32 {
+	t.Helper()
+	vector, err := service.GenerateEmbedding(context.Background(), content)
+	if err != nil {
+		t.Fatalf("GenerateEmbedding(%q) error = %v", content, err)
+	}
+	return vector
+}
+
+// TestHashingEmbeddingDeterministic 测试相同文本得到相同的归一化向量，空文本返回错误
+func TestHashingEmbeddingDeterministic(t *testing.T) {
+	service, err := NewHashingEmbeddingService(nil)
+	if err != nil {
+		t.Fatalf("NewHashingEmbeddingService() error = %v", err)
+	}
+
+	a := mustHashingEmbedding(t, service, "Deploy the service with Kubernetes")
+	b := mustHashingEmbedding(t, service, "Deploy the service with Kubernetes")
+	if len(a) != 512 {
+		t.Fatalf("dimension = %d, want 512", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("vectors differ at %d: %v != %v", i, a[i], b[i])
+		}
+	}
+	if norm := cosine(a, a); norm < 0.999 || norm > 1.001 {
+		t.Errorf("norm = %v, want 1", norm)
+	}
+	if vector := mustHashingEmbedding(t, service, "-> ::"); cosine(vector, vector) < 0.999 {
+		t.Error("text without tokens should still produce a normalized vector")
+	}
+	if _, err := service.GenerateEmbedding(context.Background(), "  "); err == nil {
+		t.Error("GenerateEmbedding() error = nil, want error for empty content")
+	}
+}
+
+// TestHashingEmbeddingSimilarity 测试共享词和词形变化的文本比无关文本更相似
+func TestHashingEmbeddingSimilarity(t *testing.T) {
+	service, _ := NewHashingEmbeddingService(nil)
+	query := mustHashingEmbedding(t, service, "configure database connection pool")
+	related := mustHashingEmbedding(t, service, "How to configure the connection pool of the database")
+	inflected := mustHashingEmbedding(t, service, "configuring databases connections")
+	unrelated := mustHashingEmbedding(t, service, "render charts in the browser")
+
+	if cosine(query, related) <= cosine(query, unrelated) {
+		t.Errorf("related similarity %v should exceed unrelated %v", cosine(query, related), cosine(query, unrelated))
+	}
+	if cosine(query, inflected) <= cosine(query, unrelated) {
+		t.Errorf("inflected similarity %v should exceed unrelated %v", cosine(query, inflected), cosine(query, unrelated))
+	}
+}
+
+// TestHashingEmbeddingVocabulary 测试词表使稀有词比常见词对相似度的贡献更大，且词表改变模型名称
+func TestHashingEmbeddingVocabulary(t *testing.T) {
+	vocabulary := &model.HashingVocabulary{
+		Documents: 1000,
+		Terms:     map[string]int64{"the": 990, "guide": 900, "pgvector": 3, "redis": 5},
+	}
+	data, _ := json.Marshal(vocabulary)
+	path := filepath.Join(t.TempDir(), "vocabulary.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultHashingEmbeddingConfig()
+	config.CharNGramMax = 0
+	plain, _ := NewHashingEmbeddingService(config)
+	config.VocabularyPath = path
+	weighted, err := NewHashingEmbeddingService(config)
+	if err != nil {
+		t.Fatalf("NewHashingEmbeddingService() error = %v", err)
+	}
+	if plain.ModelName() == weighted.ModelName() || !strings.Contains(weighted.ModelName(), "-v") {
+		t.Errorf("model names = %s, %s, want vocabulary fingerprint", plain.ModelName(), weighted.ModelName())
+	}
+
+	// 查询与两段文本各共享一个词：没有词表时相似度相同，有词表时共享稀有词的文本更相似
+	query, rare, common := "pgvector guide", "pgvector redis", "guide redis"
+	for _, tt := range []struct {
+		service  EmbeddingService
+		wantRare bool
+	}{{plain, false}, {weighted, true}} {
+		q := mustHashingEmbedding(t, tt.service, query)
+		rareSim := cosine(q, mustHashingEmbedding(t, tt.service, rare))
+		commonSim := cosine(q, mustHashingEmbedding(t, tt.service, common))
+		if got := rareSim > commonSim+1e-6; got != tt.wantRare {
+			t.Errorf("%s: rare %v, common %v, want rare preferred = %v", tt.service.ModelName(), rareSim, commonSim, tt.wantRare)
+		}
+	}
+
+	config.VocabularyPath = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := NewHashingEmbeddingService(config); err == nil {
+		t.Error("NewHashingEmbeddingService() error = nil, want error for missing vocabulary")
+	}
+}
+
+// TestHashingEmbeddingProjection 测试哈希桶多于维度时随机投影到配置的维度
+func TestHashingEmbeddingProjection(t *testing.T) {
+	config := DefaultHashingEmbeddingConfig()
+	config.Dimension = 64
+	config.Buckets = 1 << 16
+	service, err := NewHashingEmbeddingService(config)
+	if err != nil {
+		t.Fatalf("NewHashingEmbeddingService() error = %v", err)
+	}
+	if !strings.Contains(service.ModelName(), "-b65536") {
+		t.Errorf("model name = %s, want bucket count", service.ModelName())
+	}
+
+	a := mustHashingEmbedding(t, service, "vector index tuning")
+	b := mustHashingEmbedding(t, service, "tuning the vector index")
+	c := mustHashingEmbedding(t, service, "user login page")
+	if len(a) != 64 {
+		t.Fatalf("dimension = %d, want 64", len(a))
+	}
+	if cosine(a, b) <= cosine(a, c) {
+		t.Errorf("similar %v should exceed unrelated %v after projection", cosine(a, b), cosine(a, c))
+	}
+}
+
+// TestBuildVocabulary 测试从语料统计生成词表，过滤低频词
+func TestBuildVocabulary(t *testing.T) {
+	statsRepo := &fakeSearchStatsRepository{
+		docFreqs:   map[string]int64{"index": 40, "vector": 12, "typo": 1},
+		chunkCount: 50,
+	}
+	service := NewEmbeddingModelService(nil, nil, statsRepo, &fakeEmbeddingProvider{}, nil)
+
+	vocabulary, err := service.BuildVocabulary(context.Background(), 2, 0)
+	if err != nil {
+		t.Fatalf("BuildVocabulary() error = %v", err)
+	}
+	if vocabulary.Documents != 50 || len(vocabulary.Terms) != 2 || vocabulary.Terms["index"] != 40 {
+		t.Errorf("vocabulary = %+v, want 50 documents and terms index, vector", vocabulary)
+	}
+
+	statsRepo.chunkCount = 0
+	if _, err := service.BuildVocabulary(context.Background(), 2, 0); err == nil {
+		t.Error("BuildVocabulary() error = nil, want error for empty corpus")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
 )
 
 // HealthStatus 表示健康状态
@@ -183,3 +185,36 @@ func (c *StorageHealthCheck) Check(ctx context.Context) ComponentHealth {
 func (c *StorageHealthCheck) Name() string {
 	return "storage"
 }
+
+// VectorSearchHealthCheck 向量检索健康检查，报告启动时选择的向量检索方式
+type VectorSearchHealthCheck struct {
+	status *model.VectorSearchStatus
+}
+
+// NewVectorSearchHealthCheck 创建向量检索健康检查
+func NewVectorSearchHealthCheck(status *model.VectorSearchStatus) *VectorSearchHealthCheck {
+	return &VectorSearchHealthCheck{status: status}
+}
+
+// Check 执行检查
+// 内存计算是可用的回退方式，不影响就绪状态，只在消息中说明原因
+func (c *VectorSearchHealthCheck) Check(ctx context.Context) ComponentHealth {
+	message := "向量检索方式未检测"
+	if c.status != nil && c.status.Mode == model.VectorSearchModePgvector {
+		message = fmt.Sprintf("向量检索使用pgvector（维度: %d, 索引: %s）", c.status.Dimension, c.status.IndexType)
+	} else if c.status != nil {
+		message = fmt.Sprintf("向量检索使用内存计算: %s", c.status.Reason)
+	}
+
+	return ComponentHealth{
+		Name:      c.Name(),
+		Status:    HealthStatusHealthy,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+// Name 返回组件名称
+func (c *VectorSearchHealthCheck) Name() string {
+	return "vector_search"
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
 )
 
 // MockHealthCheck 模拟健康检查器，用于测试
@@ -398,3 +401,46 @@ func TestStorageHealthCheck_Name(t *testing.T) {
 		t.Errorf("名称 = %s, expected 'storage'", name)
 	}
 }
+
+// TestVectorSearchHealthCheck 测试向量检索检查器报告检索方式
+func TestVectorSearchHealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      *model.VectorSearchStatus
+		wantMessage string
+	}{
+		{
+			name:        "pgvector",
+			status:      &model.VectorSearchStatus{Mode: model.VectorSearchModePgvector, IndexType: "hnsw", Dimension: 384},
+			wantMessage: "pgvector",
+		},
+		{
+			name:        "内存计算",
+			status:      &model.VectorSearchStatus{Mode: model.VectorSearchModeInMemory, IndexType: "none", Reason: "pgvector扩展未安装"},
+			wantMessage: "pgvector扩展未安装",
+		},
+		{
+			name:        "未检测",
+			status:      nil,
+			wantMessage: "未检测",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := NewVectorSearchHealthCheck(tt.status)
+			health := check.Check(context.Background())
+
+			// 内存计算是可用的回退方式，不应影响就绪状态
+			if health.Status != HealthStatusHealthy {
+				t.Errorf("状态 = %s, expected %s", health.Status, HealthStatusHealthy)
+			}
+			if !strings.Contains(health.Message, tt.wantMessage) {
+				t.Errorf("消息 = %s, 应包含 %s", health.Message, tt.wantMessage)
+			}
+			if health.Name != "vector_search" {
+				t.Errorf("名称 = %s, expected 'vector_search'", health.Name)
+			}
+		})
+	}
+}
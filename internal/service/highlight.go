@@ -0,0 +1,258 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+const (
+	highlightFragmentSize = 160 // 单个命中片段的最大字符数
+	maxHighlightFragments = 3   // 每个结果最多返回的命中片段数
+)
+
+// matchSpan 查询词在分块内容中的命中范围（字节偏移）
+type matchSpan struct {
+	term  string
+	start int
+	end   int
+}
+
+// wordSpan 分块内容中的英文单词及其范围（字节偏移）
+type wordSpan struct {
+	text  string
+	start int
+	end   int
+}
+
+// buildHighlights 在分块内容中查找查询词，返回命中片段
+// baseChar 为分块在原文档中的起始字符位置，返回的偏移均相对原文档
+func buildHighlights(content string, baseChar int, leaves []*model.QueryNode) []model.Highlight {
+	matches := findMatches(content, leaves)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	// 相距较近的命中合并到同一个片段
+	type matchGroup struct {
+		start, end int
+		matches    []matchSpan
+		terms      map[string]bool
+	}
+	var groups []*matchGroup
+	for _, match := range matches {
+		if n := len(groups); n > 0 && utf8.RuneCountInString(content[groups[n-1].start:match.end]) <= highlightFragmentSize {
+			group := groups[n-1]
+			if match.end > group.end {
+				group.end = match.end
+			}
+			group.matches = append(group.matches, match)
+			group.terms[match.term] = true
+			continue
+		}
+		groups = append(groups, &matchGroup{
+			start:   match.start,
+			end:     match.end,
+			matches: []matchSpan{match},
+			terms:   map[string]bool{match.term: true},
+		})
+	}
+
+	// 优先选择命中不同查询词最多的片段，再按命中次数和位置排序
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].terms) != len(groups[j].terms) {
+			return len(groups[i].terms) > len(groups[j].terms)
+		}
+		return len(groups[i].matches) > len(groups[j].matches)
+	})
+	if len(groups) > maxHighlightFragments {
+		groups = groups[:maxHighlightFragments]
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].start < groups[j].start
+	})
+
+	offsets := newCharOffsets(content, baseChar)
+	highlights := make([]model.Highlight, 0, len(groups))
+	prevEnd := 0
+
+	for i, group := range groups {
+		// 在命中范围两侧补充上下文，不与相邻片段重叠
+		nextStart := len(content)
+		if i+1 < len(groups) {
+			nextStart = groups[i+1].start
+		}
+		padding := highlightFragmentSize - utf8.RuneCountInString(content[group.start:group.end])
+		start := retreatRunes(content, group.start, prevEnd, padding/2)
+		end := advanceRunes(content, group.end, nextStart, padding-utf8.RuneCountInString(content[start:group.start]))
+		start, end = trimRange(content, start, end)
+		prevEnd = end
+
+		highlight := model.Highlight{
+			Fragment: content[start:end],
+			Start:    offsets.at(start),
+			End:      offsets.at(end),
+			Matches:  make([]model.MatchRange, len(group.matches)),
+		}
+		for j, match := range group.matches {
+			highlight.Matches[j] = model.MatchRange{
+				Term:  match.term,
+				Start: offsets.at(match.start),
+				End:   offsets.at(match.end),
+			}
+		}
+		highlights = append(highlights, highlight)
+	}
+
+	return highlights
+}
+
+// findMatches 查找所有查询词的命中范围，按位置排序并去除重叠
+func findMatches(content string, leaves []*model.QueryNode) []matchSpan {
+	var matches []matchSpan
+	var words []wordSpan
+
+	for _, leaf := range leaves {
+		text := strings.TrimSpace(leaf.Text)
+		if text == "" {
+			continue
+		}
+
+		// 中文不切分单词，与关键词检索一致按子串匹配
+		if containsHan(text) {
+			matches = append(matches, findSubstrings(content, text)...)
+			continue
+		}
+
+		terms := tokenize(text)
+		if len(terms) == 0 {
+			continue
+		}
+		if words == nil {
+			words = wordSpans(content)
+		}
+		for i := 0; i+len(terms) <= len(words); i++ {
+			if matchWords(words[i:i+len(terms)], terms, leaf.Prefix) {
+				matches = append(matches, matchSpan{term: text, start: words[i].start, end: words[i+len(terms)-1].end})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+
+	// 重叠的命中只保留最先出现且最长的一个
+	var result []matchSpan
+	for _, match := range matches {
+		if n := len(result); n > 0 && match.start < result[n-1].end {
+			continue
+		}
+		result = append(result, match)
+	}
+	return result
+}
+
+// matchWords 判断连续的单词是否与查询词一致，prefix 为 true 时最后一个词按前缀匹配
+func matchWords(words []wordSpan, terms []string, prefix bool) bool {
+	for i, term := range terms {
+		if prefix && i == len(terms)-1 {
+			if !strings.HasPrefix(words[i].text, term) {
+				return false
+			}
+		} else if words[i].text != term {
+			return false
+		}
+	}
+	return true
+}
+
+// findSubstrings 忽略大小写查找子串的所有出现位置
+func findSubstrings(content, text string) []matchSpan {
+	lowerContent := strings.ToLower(content)
+	lowerText := strings.ToLower(text)
+	// 转换大小写改变了字节长度时无法对应原文位置，退化为区分大小写匹配
+	if len(lowerContent) != len(content) || len(lowerText) != len(text) {
+		lowerContent, lowerText = content, text
+	}
+
+	var matches []matchSpan
+	for pos := 0; pos < len(lowerContent); {
+		idx := strings.Index(lowerContent[pos:], lowerText)
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+		matches = append(matches, matchSpan{term: text, start: start, end: start + len(lowerText)})
+		pos = start + len(lowerText)
+	}
+	return matches
+}
+
+// wordSpans 按连续的字母数字（不含汉字）切分单词，与 tokenize 的英文切分规则一致
+func wordSpans(content string) []wordSpan {
+	var words []wordSpan
+	start := -1
+
+	flush := func(end int) {
+		if start >= 0 {
+			words = append(words, wordSpan{text: strings.ToLower(content[start:end]), start: start, end: end})
+			start = -1
+		}
+	}
+
+	for i, r := range content {
+		if (unicode.IsLetter(r) || unicode.IsDigit(r)) && !unicode.Is(unicode.Han, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(content))
+
+	return words
+}
+
+// charOffsets 将分块内的字节偏移换算为原文档中的字符偏移，要求按递增顺序查询
+type charOffsets struct {
+	content string
+	pos     int
+	chars   int
+}
+
+// newCharOffsets 创建字符偏移换算器，baseChar 为分块在原文档中的起始字符位置
+func newCharOffsets(content string, baseChar int) *charOffsets {
+	return &charOffsets{content: content, chars: baseChar}
+}
+
+// at 返回字节偏移对应的原文档字符偏移
+func (c *charOffsets) at(pos int) int {
+	if pos < c.pos {
+		return c.chars - utf8.RuneCountInString(c.content[pos:c.pos])
+	}
+	c.chars += utf8.RuneCountInString(c.content[c.pos:pos])
+	c.pos = pos
+	return c.chars
+}
+
+// chunkStartChar 从搜索结果元数据中获取分块在原文档中的起始字符位置
+// 旧索引没有记录字符位置时使用起始位置代替
+func chunkStartChar(metadata map[string]interface{}) int {
+	for _, key := range []string{"start_char", "start_position"} {
+		switch value := metadata[key].(type) {
+		case float64:
+			return int(value)
+		case int:
+			return value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestFindMatches 测试查询词命中位置的查找
+func TestFindMatches(t *testing.T) {
+	content := "Configure the Connection Pool. connection pooling 和连接池配置, config"
+
+	tests := []struct {
+		name   string
+		leaves []*model.QueryNode
+		want   []string
+	}{
+		{"关键词按单词匹配且忽略大小写", []*model.QueryNode{{Type: model.QueryNodeTerm, Text: "connection"}}, []string{"Connection", "connection"}},
+		{"短语要求单词相邻", []*model.QueryNode{{Type: model.QueryNodePhrase, Text: "connection pool"}}, []string{"Connection Pool"}},
+		{"前缀匹配", []*model.QueryNode{{Type: model.QueryNodeTerm, Text: "conf", Prefix: true}}, []string{"Configure", "config"}},
+		{"前缀短语", []*model.QueryNode{{Type: model.QueryNodeTerm, Text: "connection pool", Prefix: true}}, []string{"Connection Pool", "connection pooling"}},
+		{"中文按子串匹配", []*model.QueryNode{{Type: model.QueryNodeTerm, Text: "连接池"}}, []string{"连接池"}},
+		{
+			"重叠的命中只保留较长的一个",
+			[]*model.QueryNode{{Type: model.QueryNodeTerm, Text: "pool"}, {Type: model.QueryNodePhrase, Text: "connection pool"}},
+			[]string{"Connection Pool"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := findMatches(content, tt.leaves)
+			var got []string
+			for _, match := range matches {
+				got = append(got, content[match.start:match.end])
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("findMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildHighlights 测试命中片段的偏移相对原文档计算
+func TestBuildHighlights(t *testing.T) {
+	document := "# 简介\n这是前言。\n\n使用 gorm 的连接池配置 MaxOpenConns。"
+	chunkStart := strings.Index(document, "使用")
+	chunk := document[chunkStart:]
+	baseChar := utf8.RuneCountInString(document[:chunkStart])
+
+	leaves := []*model.QueryNode{
+		{Type: model.QueryNodeTerm, Text: "gorm"},
+		{Type: model.QueryNodeTerm, Text: "连接池"},
+	}
+	highlights := buildHighlights(chunk, baseChar, leaves)
+	if len(highlights) != 1 {
+		t.Fatalf("buildHighlights() 返回 %d 个片段, want 1", len(highlights))
+	}
+
+	runes := []rune(document)
+	highlight := highlights[0]
+	if string(runes[highlight.Start:highlight.End]) != highlight.Fragment {
+		t.Errorf("片段偏移 [%d, %d) 与片段内容 %q 不一致", highlight.Start, highlight.End, highlight.Fragment)
+	}
+	if len(highlight.Matches) != 2 {
+		t.Fatalf("Matches = %+v, want 2 个命中", highlight.Matches)
+	}
+	for i, want := range []string{"gorm", "连接池"} {
+		match := highlight.Matches[i]
+		if got := string(runes[match.Start:match.End]); got != want || match.Term != want {
+			t.Errorf("Matches[%d] = %q (term %q), want %q", i, got, match.Term, want)
+		}
+	}
+}
+
+// TestBuildHighlights_Fragments 测试片段数量限制、长度限制和不重叠
+func TestBuildHighlights_Fragments(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+	content := "alpha " + filler + "beta gamma " + filler + "alpha " + filler + "alpha " + filler + "alpha"
+	leaves := []*model.QueryNode{
+		{Type: model.QueryNodeTerm, Text: "alpha"},
+		{Type: model.QueryNodeTerm, Text: "beta"},
+		{Type: model.QueryNodeTerm, Text: "gamma"},
+	}
+
+	highlights := buildHighlights(content, 0, leaves)
+	if len(highlights) != maxHighlightFragments {
+		t.Fatalf("buildHighlights() 返回 %d 个片段, want %d", len(highlights), maxHighlightFragments)
+	}
+
+	// 同时命中 beta 和 gamma 的片段应被选中
+	foundBeta := false
+	for i, highlight := range highlights {
+		if n := utf8.RuneCountInString(highlight.Fragment); n > highlightFragmentSize {
+			t.Errorf("highlights[%d] 长度 %d 超过 %d", i, n, highlightFragmentSize)
+		}
+		if content[highlight.Start:highlight.End] != highlight.Fragment {
+			t.Errorf("highlights[%d] 偏移与内容不一致", i)
+		}
+		if i > 0 && highlight.Start < highlights[i-1].End {
+			t.Errorf("highlights[%d] 与前一个片段重叠", i)
+		}
+		for _, match := range highlight.Matches {
+			if match.Start < highlight.Start || match.End > highlight.End {
+				t.Errorf("highlights[%d] 的命中 %+v 不在片段范围内", i, match)
+			}
+			if match.Term == "beta" {
+				foundBeta = true
+			}
+		}
+	}
+	if !foundBeta {
+		t.Error("命中多个查询词的片段应优先返回")
+	}
+
+	if got := buildHighlights(content, 0, []*model.QueryNode{{Type: model.QueryNodeTerm, Text: "missing"}}); got != nil {
+		t.Errorf("没有命中时应返回 nil, got %v", got)
+	}
+}
+
+// TestChunkStartChar 测试从元数据获取分块起始字符位置
+func TestChunkStartChar(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		want     int
+	}{
+		{"使用字符位置", map[string]interface{}{"start_char": float64(12), "start_position": 30}, 12},
+		{"旧索引使用起始位置", map[string]interface{}{"start_position": 30}, 30},
+		{"没有位置信息", map[string]interface{}{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkStartChar(tt.metadata); got != tt.want {
+				t.Errorf("chunkStartChar() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
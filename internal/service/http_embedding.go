@@ -0,0 +1,352 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP 嵌入服务的预设
+const (
+	HTTPEmbeddingPresetOllama = "ollama" // Ollama /api/embed
+	HTTPEmbeddingPresetTEI    = "tei"    // HuggingFace Text Embeddings Inference /embed
+	HTTPEmbeddingPresetCustom = "custom" // 完全由配置指定请求和响应格式
+)
+
+// HTTPEmbeddingConfig 通用HTTP嵌入服务配置
+// 路径使用以点分隔的字段名，"$" 表示整个JSON，"*" 匹配数组的每个元素，数字表示数组下标，
+// 如 OpenAI 格式的响应向量路径为 "$.data.*.embedding"
+type HTTPEmbeddingConfig struct {
+	Preset      string                 // 预设名称，用于生成模型名称
+	URL         string                 // 嵌入接口地址
+	Model       string                 // 模型名称，配置了 ModelPath 时写入请求体
+	ModelName   string                 // 登记的嵌入模型名称，为空时由预设和模型生成
+	APIKey      string                 // 不为空时作为 Bearer 令牌发送
+	Headers     map[string]string      // 额外的请求头
+	RequestBody map[string]interface{} // 请求体模板，输入文本和模型名称写入其中
+	InputPath   string                 // 请求体中输入文本的路径
+	ModelPath   string                 // 请求体中模型名称的路径，为空时不写入
+	VectorPath  string                 // 响应中向量的路径，结果可以是一个向量或向量数组
+	BatchSize   int                    // 每次请求的文本数量，为1时输入为单个字符串，否则为字符串数组
+	Timeout     time.Duration          // 单次请求的超时时间
+}
+
+// NewHTTPEmbeddingPreset 返回预设的HTTP嵌入服务配置
+func NewHTTPEmbeddingPreset(preset string) (*HTTPEmbeddingConfig, error) {
+	config := &HTTPEmbeddingConfig{
+		Preset:      preset,
+		RequestBody: map[string]interface{}{},
+		Timeout:     30 * time.Second,
+	}
+	switch preset {
+	case HTTPEmbeddingPresetOllama:
+		config.URL = "http://localhost:11434/api/embed"
+		config.Model = "nomic-embed-text"
+		config.InputPath = "input"
+		config.ModelPath = "model"
+		config.VectorPath = "embeddings"
+		config.BatchSize = 32
+	case HTTPEmbeddingPresetTEI:
+		// TEI 默认每次请求最多32个文本，超长文本由服务端截断
+		config.URL = "http://localhost:8080/embed"
+		config.RequestBody["truncate"] = true
+		config.InputPath = "inputs"
+		config.VectorPath = "$"
+		config.BatchSize = 32
+	case HTTPEmbeddingPresetCustom:
+		config.InputPath = "input"
+		config.VectorPath = "embedding"
+		config.BatchSize = 1
+	default:
+		return nil, fmt.Errorf("unknown http embedding preset: %s", preset)
+	}
+	return config, nil
+}
+
+// NewHTTPEmbeddingConfigFromEnv 从环境变量创建HTTP嵌入服务配置，未设置的项使用预设的值
+func NewHTTPEmbeddingConfigFromEnv() (*HTTPEmbeddingConfig, error) {
+	config, err := NewHTTPEmbeddingPreset(getEnv("HTTP_EMBEDDING_PRESET", HTTPEmbeddingPresetCustom))
+	if err != nil {
+		return nil, err
+	}
+	config.URL = getEnv("HTTP_EMBEDDING_URL", config.URL)
+	config.Model = getEnv("HTTP_EMBEDDING_MODEL", config.Model)
+	config.ModelName = getEnv("HTTP_EMBEDDING_MODEL_NAME", config.ModelName)
+	config.APIKey = getEnv("HTTP_EMBEDDING_API_KEY", config.APIKey)
+	config.InputPath = getEnv("HTTP_EMBEDDING_INPUT_PATH", config.InputPath)
+	config.ModelPath = getEnv("HTTP_EMBEDDING_MODEL_PATH", config.ModelPath)
+	config.VectorPath = getEnv("HTTP_EMBEDDING_VECTOR_PATH", config.VectorPath)
+	config.BatchSize = getEnvInt("HTTP_EMBEDDING_BATCH_SIZE", config.BatchSize)
+	config.Timeout = time.Duration(getEnvInt("HTTP_EMBEDDING_TIMEOUT_SECONDS", int(config.Timeout/time.Second))) * time.Second
+
+	if headers := getEnv("HTTP_EMBEDDING_HEADERS", ""); headers != "" {
+		if err := json.Unmarshal([]byte(headers), &config.Headers); err != nil {
+			return nil, fmt.Errorf("invalid HTTP_EMBEDDING_HEADERS, expected JSON object: %v", err)
+		}
+	}
+	if body := getEnv("HTTP_EMBEDDING_REQUEST_BODY", ""); body != "" {
+		var template map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &template); err != nil {
+			return nil, fmt.Errorf("invalid HTTP_EMBEDDING_REQUEST_BODY, expected JSON object: %v", err)
+		}
+		for key, value := range template {
+			config.RequestBody[key] = value
+		}
+	}
+	return config, nil
+}
+
+// httpEmbeddingService 通过任意HTTP JSON接口生成嵌入向量
+type httpEmbeddingService struct {
+	config     *HTTPEmbeddingConfig
+	inputPath  []string
+	modelPath  []string
+	vectorPath []string
+	client     *http.Client
+	modelName  string
+}
+
+// NewHTTPEmbeddingService 创建通用HTTP嵌入服务实例
+func NewHTTPEmbeddingService(config *HTTPEmbeddingConfig) (EmbeddingService, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("http embedding url is required")
+	}
+	inputPath := splitJSONPath(config.InputPath)
+	if len(inputPath) == 0 {
+		return nil, fmt.Errorf("http embedding input path is required")
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+
+	modelName := config.ModelName
+	if modelName == "" {
+		modelName = config.Preset + "/" + config.Model
+		if config.Model == "" {
+			modelName = config.Preset + "@" + config.URL
+		}
+	}
+
+	return &httpEmbeddingService{
+		config:     config,
+		inputPath:  inputPath,
+		modelPath:  splitJSONPath(config.ModelPath),
+		vectorPath: splitJSONPath(config.VectorPath),
+		client:     &http.Client{Timeout: config.Timeout},
+		modelName:  modelName,
+	}, nil
+}
+
+// GenerateEmbedding 生成单个文本的嵌入向量
+func (s *httpEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is empty")
+	}
+	embeddings, err := s.request(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings 按 BatchSize 分批请求嵌入接口，返回的向量与输入顺序一致
+func (s *httpEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(contents))
+	for start := 0; start < len(contents); start += s.config.BatchSize {
+		end := start + s.config.BatchSize
+		if end > len(contents) {
+			end = len(contents)
+		}
+		batch, err := s.request(ctx, contents[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+// ModelName 返回嵌入模型名称
+func (s *httpEmbeddingService) ModelName() string {
+	return s.modelName
+}
+
+// request 发送一次嵌入请求，HTTP错误转换为 EmbeddingStatusError 以便判断是否重试
+func (s *httpEmbeddingService) request(ctx context.Context, contents []string) ([][]float32, error) {
+	body := cloneJSONObject(s.config.RequestBody)
+	var input interface{} = contents
+	if s.config.BatchSize == 1 {
+		input = contents[0]
+	}
+	setJSONPath(body, s.inputPath, input)
+	if len(s.modelPath) > 0 && s.config.Model != "" {
+		setJSONPath(body, s.modelPath, s.config.Model)
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	}
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &EmbeddingStatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("%s", bytes.TrimSpace(message))}
+	}
+
+	var result interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %v", err)
+	}
+	value, err := getJSONPath(result, s.vectorPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vectors in embedding response: %v", err)
+	}
+	embeddings, err := jsonVectors(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vectors in embedding response: %v", err)
+	}
+	if len(embeddings) != len(contents) {
+		return nil, fmt.Errorf("embedding response contains %d vectors, want %d", len(embeddings), len(contents))
+	}
+	return embeddings, nil
+}
+
+// splitJSONPath 将路径切分为字段名，"$" 和空路径表示整个JSON
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// setJSONPath 在JSON对象中按路径写入值，中间的对象不存在时创建
+func setJSONPath(object map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			object[key] = child
+		}
+		object = child
+	}
+	object[path[len(path)-1]] = value
+}
+
+// getJSONPath 按路径读取JSON中的值，"*" 将数组的每个元素按剩余路径读取后组成数组
+func getJSONPath(value interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	key, rest := path[0], path[1:]
+
+	switch current := value.(type) {
+	case map[string]interface{}:
+		child, ok := current[key]
+		if !ok {
+			return nil, fmt.Errorf("field %q not found", key)
+		}
+		return getJSONPath(child, rest)
+	case []interface{}:
+		if key == "*" {
+			items := make([]interface{}, len(current))
+			for i, item := range current {
+				child, err := getJSONPath(item, rest)
+				if err != nil {
+					return nil, err
+				}
+				items[i] = child
+			}
+			return items, nil
+		}
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(current) {
+			return nil, fmt.Errorf("invalid array index %q", key)
+		}
+		return getJSONPath(current[index], rest)
+	default:
+		return nil, fmt.Errorf("cannot read %q from %T", key, value)
+	}
+}
+
+// jsonVectors 将JSON数组转换为向量列表，数字数组视为单个向量
+func jsonVectors(value interface{}) ([][]float32, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array, got %T", value)
+	}
+	if len(items) > 0 {
+		if _, isNumber := items[0].(float64); isNumber {
+			vector, err := jsonVector(items)
+			if err != nil {
+				return nil, err
+			}
+			return [][]float32{vector}, nil
+		}
+	}
+
+	vectors := make([][]float32, len(items))
+	for i, item := range items {
+		values, ok := item.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("vector %d: expected array, got %T", i, item)
+		}
+		vector, err := jsonVector(values)
+		if err != nil {
+			return nil, fmt.Errorf("vector %d: %v", i, err)
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// jsonVector 将JSON数字数组转换为向量
+func jsonVector(values []interface{}) ([]float32, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("vector is empty")
+	}
+	vector := make([]float32, len(values))
+	for i, value := range values {
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("element %d is %T, not a number", i, value)
+		}
+		vector[i] = float32(number)
+	}
+	return vector, nil
+}
+
+// cloneJSONObject 深拷贝请求体模板，避免并发请求相互修改
+func cloneJSONObject(object map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if child, ok := value.(map[string]interface{}); ok {
+			value = cloneJSONObject(child)
+		}
+		clone[key] = value
+	}
+	return clone
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHTTPEmbeddingServiceOllama 测试 Ollama 预设的请求格式和分批
+func TestHTTPEmbeddingServiceOllama(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, body)
+		inputs := body["input"].([]interface{})
+		embeddings := make([][]float32, len(inputs))
+		for i, input := range inputs {
+			embeddings[i] = []float32{float32(len(input.(string))), 1}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"model": body["model"], "embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	config, _ := NewHTTPEmbeddingPreset(HTTPEmbeddingPresetOllama)
+	config.URL = server.URL
+	config.Model = "bge-m3"
+	config.BatchSize = 2
+	service, err := NewHTTPEmbeddingService(config)
+	if err != nil {
+		t.Fatalf("NewHTTPEmbeddingService() error = %v", err)
+	}
+
+	embeddings, err := service.GenerateEmbeddings(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	if len(requests) != 2 || requests[0]["model"] != "bge-m3" {
+		t.Fatalf("requests = %v, want 2 requests with model bge-m3", requests)
+	}
+	for i, want := range []float32{1, 2, 3} {
+		if embeddings[i][0] != want {
+			t.Errorf("embedding %d = %v, want first element %v", i, embeddings[i], want)
+		}
+	}
+	if service.ModelName() != "ollama/bge-m3" {
+		t.Errorf("ModelName() = %s, want ollama/bge-m3", service.ModelName())
+	}
+}
+
+// TestHTTPEmbeddingServiceTEI 测试 TEI 预设：响应本身是向量数组
+func TestHTTPEmbeddingServiceTEI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Inputs   []string `json:"inputs"`
+			Truncate bool     `json:"truncate"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Truncate || len(body.Inputs) != 2 {
+			t.Errorf("request = %+v, want 2 inputs with truncate", body)
+		}
+		w.Write([]byte(`[[0.1,0.2],[0.3,0.4]]`))
+	}))
+	defer server.Close()
+
+	config, _ := NewHTTPEmbeddingPreset(HTTPEmbeddingPresetTEI)
+	config.URL = server.URL
+	service, _ := NewHTTPEmbeddingService(config)
+
+	embeddings, err := service.GenerateEmbeddings(context.Background(), []string{"x", "y"})
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[1][1] != 0.4 {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+}
+
+// TestHTTPEmbeddingServiceCustom 测试自定义的请求体模板、请求头和嵌套的向量路径
+func TestHTTPEmbeddingServiceCustom(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		params := body["params"].(map[string]interface{})
+		if params["text"] != "hello" || params["normalize"] != true {
+			t.Errorf("request = %v, want text inside template", body)
+		}
+		w.Write([]byte(`{"result":{"items":[{"vector":[1,2,3]}]}}`))
+	}))
+	defer server.Close()
+
+	service, err := NewHTTPEmbeddingService(&HTTPEmbeddingConfig{
+		Preset:      HTTPEmbeddingPresetCustom,
+		URL:         server.URL,
+		ModelName:   "encoder-v2",
+		APIKey:      "token",
+		Headers:     map[string]string{"X-Api-Key": "secret"},
+		RequestBody: map[string]interface{}{"params": map[string]interface{}{"normalize": true}},
+		InputPath:   "params.text",
+		VectorPath:  "$.result.items.*.vector",
+		BatchSize:   1,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPEmbeddingService() error = %v", err)
+	}
+
+	embedding, err := service.GenerateEmbedding(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding() error = %v", err)
+	}
+	if len(embedding) != 3 || embedding[2] != 3 || service.ModelName() != "encoder-v2" {
+		t.Errorf("embedding = %v, model = %s", embedding, service.ModelName())
+	}
+}
+
+// TestHTTPEmbeddingServiceErrors 测试服务端错误可重试，响应格式不符时返回错误
+func TestHTTPEmbeddingServiceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		response  string
+		retryable bool
+	}{
+		{"overloaded", http.StatusServiceUnavailable, "model loading", true},
+		{"bad request", http.StatusBadRequest, "input too long", false},
+		{"missing field", http.StatusOK, `{"data":[]}`, false},
+		{"vector count mismatch", http.StatusOK, `{"embeddings":[[1],[2]]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			config, _ := NewHTTPEmbeddingPreset(HTTPEmbeddingPresetOllama)
+			config.URL = server.URL
+			service, _ := NewHTTPEmbeddingService(config)
+
+			_, err := service.GenerateEmbedding(context.Background(), "text")
+			if err == nil {
+				t.Fatal("GenerateEmbedding() error = nil, want error")
+			}
+			if retryableEmbeddingError(err) != tt.retryable {
+				t.Errorf("retryable = %v, want %v (err = %v)", !tt.retryable, tt.retryable, err)
+			}
+			var statusErr *EmbeddingStatusError
+			if tt.status != http.StatusOK && (!errors.As(err, &statusErr) || !strings.Contains(err.Error(), tt.response)) {
+				t.Errorf("error = %v, want status error with response message", err)
+			}
+		})
+	}
+}
+
+// TestHTTPEmbeddingConfigFromEnv 测试环境变量覆盖预设
+func TestHTTPEmbeddingConfigFromEnv(t *testing.T) {
+	t.Setenv("HTTP_EMBEDDING_PRESET", "tei")
+	t.Setenv("HTTP_EMBEDDING_URL", "http://tei:80/embed")
+	t.Setenv("HTTP_EMBEDDING_HEADERS", `{"X-Tenant":"docs"}`)
+	t.Setenv("HTTP_EMBEDDING_REQUEST_BODY", `{"normalize":true}`)
+
+	config, err := NewHTTPEmbeddingConfigFromEnv()
+	if err != nil {
+		t.Fatalf("NewHTTPEmbeddingConfigFromEnv() error = %v", err)
+	}
+	if config.URL != "http://tei:80/embed" || config.InputPath != "inputs" || config.Headers["X-Tenant"] != "docs" {
+		t.Errorf("config = %+v", config)
+	}
+	if config.RequestBody["normalize"] != true || config.RequestBody["truncate"] != true {
+		t.Errorf("request body = %v, want template merged with preset", config.RequestBody)
+	}
+
+	t.Setenv("HTTP_EMBEDDING_HEADERS", "X-Tenant: docs")
+	if _, err := NewHTTPEmbeddingConfigFromEnv(); err == nil {
+		t.Error("NewHTTPEmbeddingConfigFromEnv() error = nil, want error for invalid headers")
+	}
+	t.Setenv("HTTP_EMBEDDING_PRESET", "unknown")
+	if _, err := NewHTTPEmbeddingConfigFromEnv(); err == nil {
+		t.Error("NewHTTPEmbeddingConfigFromEnv() error = nil, want error for unknown preset")
+	}
+}
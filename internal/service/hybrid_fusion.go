@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// 混合搜索融合方式
+const (
+	HybridModeRRF      = "rrf"      // 倒数排名融合，只依赖排名，不受得分尺度影响
+	HybridModeWeighted = "weighted" // 各自得分最小-最大归一化后线性加权
+)
+
+// hybridParams 混合搜索融合参数
+type hybridParams struct {
+	mode  string
+	alpha float64 // 语义搜索的权重
+	rrfK  int
+}
+
+// hybridExplanation 混合搜索融合明细，写入搜索结果的元数据
+type hybridExplanation struct {
+	Mode          string   `json:"mode"`
+	KeywordRank   int      `json:"keyword_rank,omitempty"` // 在关键词搜索结果中的排名，从1开始，0表示未命中
+	SemanticRank  int      `json:"semantic_rank,omitempty"`
+	KeywordScore  *float32 `json:"keyword_score,omitempty"`
+	SemanticScore *float32 `json:"semantic_score,omitempty"`
+	Score         float64  `json:"score"`
+}
+
+// hybridParamsFor 合并请求参数和服务端默认配置
+func (s *searchService) hybridParamsFor(request *model.SearchRequest) (hybridParams, error) {
+	params := hybridParams{
+		mode:  s.config.HybridMode,
+		alpha: s.config.HybridAlpha,
+		rrfK:  s.config.RRFK,
+	}
+
+	if request.HybridMode != "" {
+		params.mode = request.HybridMode
+	}
+	if request.HybridAlpha != nil {
+		params.alpha = *request.HybridAlpha
+	}
+	if request.RRFK != nil {
+		params.rrfK = *request.RRFK
+	}
+
+	if params.mode != HybridModeRRF && params.mode != HybridModeWeighted {
+		return params, fmt.Errorf("invalid hybrid_mode: %s", params.mode)
+	}
+	if params.alpha < 0 || params.alpha > 1 {
+		return params, fmt.Errorf("hybrid_alpha must be between 0 and 1, got %v", params.alpha)
+	}
+	if params.rrfK < 1 {
+		return params, fmt.Errorf("rrf_k must be positive, got %d", params.rrfK)
+	}
+
+	return params, nil
+}
+
+// hybridCandidates 关键词搜索和语义搜索各自召回最多 window 个候选结果，返回按融合得分排序的结果
+// 嵌入服务不可用时只融合关键词搜索结果，并返回 degraded 为 true
+func (s *searchService) hybridCandidates(ctx context.Context, request *model.SearchRequest, query *parsedQuery, window int) ([]*model.SearchIndex, int64, map[string]*bm25Explanation, map[string]*hybridExplanation, bool, error) {
+	params, err := s.hybridParamsFor(request)
+	if err != nil {
+		return nil, 0, nil, nil, false, err
+	}
+
+	// 关键词搜索
+	keywordIndices, keywordTotal, explanations, err := s.keywordCandidates(ctx, request, query, window, window)
+	if err != nil {
+		return nil, 0, nil, nil, false, fmt.Errorf("keyword search failed: %v", err)
+	}
+
+	// 语义搜索，嵌入服务不可用时降级为只使用关键词搜索结果
+	degraded := false
+	semanticIndices, semanticTotal, err := s.semanticCandidates(ctx, request, query, window, window)
+	if errors.Is(err, ErrEmbeddingUnavailable) {
+		log.Printf("WARNING: Semantic search unavailable, hybrid search degraded to keyword only: %v", err)
+		semanticIndices, semanticTotal, degraded = nil, 0, true
+	} else if err != nil {
+		return nil, 0, nil, nil, false, fmt.Errorf("semantic search failed: %w", err)
+	}
+
+	fused, fusions := fuseResults(keywordIndices, semanticIndices, params)
+
+	// 使用两个搜索结果中较大的总数
+	total := keywordTotal
+	if semanticTotal > total {
+		total = semanticTotal
+	}
+
+	return fused, total, explanations, fusions, degraded, nil
+}
+
+// fuseResults 融合关键词和语义搜索结果（均已按得分降序排列），返回按融合得分降序排列的结果
+func fuseResults(keywordIndices, semanticIndices []*model.SearchIndex, params hybridParams) ([]*model.SearchIndex, map[string]*hybridExplanation) {
+	fusions := make(map[string]*hybridExplanation)
+	resultMap := make(map[string]*model.SearchIndex)
+	var results []*model.SearchIndex
+
+	add := func(index *model.SearchIndex) *hybridExplanation {
+		fusion, ok := fusions[index.ID]
+		if !ok {
+			fusion = &hybridExplanation{Mode: params.mode}
+			fusions[index.ID] = fusion
+			resultMap[index.ID] = index
+			results = append(results, index)
+		}
+		return fusion
+	}
+
+	keywordNorm := minMaxNormalizer(keywordIndices)
+	semanticNorm := minMaxNormalizer(semanticIndices)
+	keywordWeight := 1 - params.alpha
+	semanticWeight := params.alpha
+
+	for i, index := range keywordIndices {
+		score := index.Score
+		fusion := add(index)
+		fusion.KeywordRank = i + 1
+		fusion.KeywordScore = &score
+		fusion.Score += fusionContribution(params, keywordWeight, i+1, keywordNorm(score))
+	}
+	for i, index := range semanticIndices {
+		score := index.Score
+		fusion := add(index)
+		fusion.SemanticRank = i + 1
+		fusion.SemanticScore = &score
+		fusion.Score += fusionContribution(params, semanticWeight, i+1, semanticNorm(score))
+	}
+
+	for id, index := range resultMap {
+		index.Score = float32(fusions[id].Score)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	return results, fusions
+}
+
+// fusionContribution 计算单个结果列表对融合得分的贡献
+// RRF 得分乘以 k+1，使两个列表都排第一的结果得分为1
+func fusionContribution(params hybridParams, weight float64, rank int, normalized float64) float64 {
+	if params.mode == HybridModeRRF {
+		return weight * float64(params.rrfK+1) / float64(params.rrfK+rank)
+	}
+	return weight * normalized
+}
+
+// minMaxNormalizer 返回将得分最小-最大归一化到 [0, 1] 的函数，所有得分相同时归一化为1
+func minMaxNormalizer(indices []*model.SearchIndex) func(float32) float64 {
+	if len(indices) == 0 {
+		return func(float32) float64 { return 0 }
+	}
+
+	minScore, maxScore := indices[0].Score, indices[0].Score
+	for _, index := range indices[1:] {
+		if index.Score < minScore {
+			minScore = index.Score
+		}
+		if index.Score > maxScore {
+			maxScore = index.Score
+		}
+	}
+
+	if maxScore == minScore {
+		return func(float32) float64 { return 1 }
+	}
+	return func(score float32) float64 {
+		return float64(score-minScore) / float64(maxScore-minScore)
+	}
+}
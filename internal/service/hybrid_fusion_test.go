@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// fakeHybridIndexRepository 返回固定的关键词和向量搜索结果
+type fakeHybridIndexRepository struct {
+	repository.SearchIndexRepository
+	keyword  []*model.SearchIndex
+	semantic []*model.SearchIndex
+}
+
+func (r *fakeHybridIndexRepository) SearchByQuery(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.keyword, int64(len(r.keyword)), nil
+}
+
+func (r *fakeHybridIndexRepository) SearchByVector(ctx context.Context, vector string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.semantic, int64(len(r.semantic)), nil
+}
+
+// newScoredIndices 按顺序创建带得分的搜索索引
+func newScoredIndices(ids []string, scores []float32) []*model.SearchIndex {
+	indices := make([]*model.SearchIndex, len(ids))
+	for i, id := range ids {
+		indices[i] = &model.SearchIndex{ID: id, Score: scores[i]}
+	}
+	return indices
+}
+
+// resultIDs 返回结果的ID列表
+func resultIDs(indices []*model.SearchIndex) []string {
+	ids := make([]string, len(indices))
+	for i, index := range indices {
+		ids[i] = index.ID
+	}
+	return ids
+}
+
+// TestFuseResults 测试混合搜索结果融合
+func TestFuseResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   hybridParams
+		keyword  []*model.SearchIndex
+		semantic []*model.SearchIndex
+		want     []string
+	}{
+		{
+			name:     "RRF 两边都命中的结果排在前面，同分按ID排序",
+			params:   hybridParams{mode: HybridModeRRF, alpha: 0.5, rrfK: 60},
+			keyword:  newScoredIndices([]string{"a", "b", "c"}, []float32{9, 5, 1}),
+			semantic: newScoredIndices([]string{"c", "d"}, []float32{0.9, 0.8}),
+			want:     []string{"c", "a", "b", "d"},
+		},
+		{
+			name:     "RRF 不受得分尺度影响",
+			params:   hybridParams{mode: HybridModeRRF, alpha: 0.5, rrfK: 60},
+			keyword:  newScoredIndices([]string{"a"}, []float32{1000}),
+			semantic: newScoredIndices([]string{"b"}, []float32{0.01}),
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "加权模式 alpha=0 只使用关键词得分",
+			params:   hybridParams{mode: HybridModeWeighted, alpha: 0, rrfK: 60},
+			keyword:  newScoredIndices([]string{"a", "b"}, []float32{0.9, 0.1}),
+			semantic: newScoredIndices([]string{"b", "c"}, []float32{0.9, 0.1}),
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "加权模式 alpha=1 只使用语义得分",
+			params:   hybridParams{mode: HybridModeWeighted, alpha: 1, rrfK: 60},
+			keyword:  newScoredIndices([]string{"a", "b"}, []float32{0.9, 0.1}),
+			semantic: newScoredIndices([]string{"c", "b"}, []float32{0.9, 0.1}),
+			want:     []string{"c", "a", "b"},
+		},
+		{
+			name:     "加权模式归一化不同尺度的得分",
+			params:   hybridParams{mode: HybridModeWeighted, alpha: 0.5, rrfK: 60},
+			keyword:  newScoredIndices([]string{"a", "b"}, []float32{100, 50}),
+			semantic: newScoredIndices([]string{"b", "a"}, []float32{0.9, 0.1}),
+			want:     []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, fusions := fuseResults(tt.keyword, tt.semantic, tt.params)
+			got := resultIDs(results)
+			if len(got) != len(tt.want) {
+				t.Fatalf("fuseResults() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("fuseResults() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+			for _, result := range results {
+				if fusions[result.ID] == nil || float64(result.Score) != float64(float32(fusions[result.ID].Score)) {
+					t.Errorf("结果 %s 的融合明细与得分不一致", result.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestFuseResults_RRFTopScore 测试两个列表都排第一的结果RRF得分为1
+func TestFuseResults_RRFTopScore(t *testing.T) {
+	params := hybridParams{mode: HybridModeRRF, alpha: 0.3, rrfK: 60}
+	results, fusions := fuseResults(
+		newScoredIndices([]string{"a"}, []float32{1}),
+		newScoredIndices([]string{"a"}, []float32{1}),
+		params,
+	)
+
+	if len(results) != 1 || results[0].Score < 0.999 || results[0].Score > 1.001 {
+		t.Errorf("得分 = %v, want 1", results[0].Score)
+	}
+	if fusions["a"].KeywordRank != 1 || fusions["a"].SemanticRank != 1 {
+		t.Errorf("排名明细 = %+v, want 都为1", fusions["a"])
+	}
+}
+
+// TestHybridParamsFor 测试混合搜索参数与默认配置的合并和校验
+func TestHybridParamsFor(t *testing.T) {
+	s := &searchService{config: DefaultSearchConfig()}
+	alpha := 0.8
+	invalidAlpha := 1.5
+	k := 10
+	invalidK := 0
+
+	tests := []struct {
+		name    string
+		request *model.SearchRequest
+		want    hybridParams
+		wantErr bool
+	}{
+		{"使用默认配置", &model.SearchRequest{}, hybridParams{mode: HybridModeRRF, alpha: 0.5, rrfK: 60}, false},
+		{"请求参数覆盖默认配置", &model.SearchRequest{HybridMode: HybridModeWeighted, HybridAlpha: &alpha, RRFK: &k}, hybridParams{mode: HybridModeWeighted, alpha: 0.8, rrfK: 10}, false},
+		{"无效的融合方式", &model.SearchRequest{HybridMode: "max"}, hybridParams{}, true},
+		{"无效的alpha", &model.SearchRequest{HybridAlpha: &invalidAlpha}, hybridParams{}, true},
+		{"无效的k", &model.SearchRequest{RRFK: &invalidK}, hybridParams{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.hybridParamsFor(tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hybridParamsFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("hybridParamsFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHybridCandidatesDegraded 测试嵌入服务失败时混合搜索降级为关键词结果，纯语义搜索返回错误
+func TestHybridCandidatesDegraded(t *testing.T) {
+	repo := &fakeHybridIndexRepository{
+		keyword:  []*model.SearchIndex{{ID: "kw-1", Content: "connection pool"}},
+		semantic: []*model.SearchIndex{{ID: "vec-1", Content: "database pool"}},
+	}
+	provider := &fakeEmbeddingProvider{errs: []error{errors.New("connection refused"), errors.New("connection refused")}}
+	s := &searchService{indexRepo: repo, embeddingService: provider, config: DefaultSearchConfig()}
+	query, err := parseSearchQuery("connection pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &model.SearchRequest{Query: "connection pool", SearchType: "hybrid"}
+
+	results, _, _, _, degraded, err := s.hybridCandidates(context.Background(), request, query, 10)
+	if err != nil {
+		t.Fatalf("hybridCandidates() error = %v", err)
+	}
+	if !degraded || len(results) != 1 || results[0].ID != "kw-1" {
+		t.Errorf("results = %v, degraded = %v, want keyword results only and degraded", results, degraded)
+	}
+
+	if _, _, err := s.semanticCandidates(context.Background(), request, query, 10, 10); !errors.Is(err, ErrEmbeddingUnavailable) {
+		t.Errorf("semanticCandidates() error = %v, want ErrEmbeddingUnavailable", err)
+	}
+}
+
+// TestHybridCandidatesExclusion 测试混合搜索的语义结果也遵守排除条件
+func TestHybridCandidatesExclusion(t *testing.T) {
+	repo := &fakeHybridIndexRepository{
+		keyword: []*model.SearchIndex{{ID: "kw-1", Content: "foo only"}},
+		semantic: []*model.SearchIndex{
+			{ID: "vec-1", Content: "foo together with bar"},
+			{ID: "vec-2", Section: "Bar", Content: "similar text"},
+			{ID: "vec-3", Content: "foo alternatives"},
+		},
+	}
+	s := &searchService{indexRepo: repo, embeddingService: &fakeEmbeddingProvider{}, config: DefaultSearchConfig()}
+	query, err := parseSearchQuery("foo -bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, total, _, _, _, err := s.hybridCandidates(context.Background(), &model.SearchRequest{Query: "foo -bar", SearchType: "hybrid"}, query, 10)
+	if err != nil {
+		t.Fatalf("hybridCandidates() error = %v", err)
+	}
+	for _, result := range results {
+		if matchesConstraint(&model.QueryNode{Type: model.QueryNodeTerm, Text: "bar"}, result.Section+"\n"+result.Content) {
+			t.Errorf("result %s contains excluded term bar", result.ID)
+		}
+	}
+	if len(results) != 2 || total != 1 {
+		t.Errorf("results = %d, total = %d, want kw-1 and vec-3", len(results), total)
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// fallbackVectorDimension 嵌入服务不可用时生成的备用向量维度，这类向量不参与复用
+const fallbackVectorDimension = model.FallbackEmbeddingDimension
+
+// embeddingProgressStep 生成向量时每次提交给嵌入服务的分块数，每步之后上报一次任务进度
+const embeddingProgressStep = 256
+
+// indexUpdate 内容未变化、需要原地更新位置或元数据的分块
+type indexUpdate struct {
+	old     *model.SearchIndex
+	desired *model.SearchIndex
+}
+
+// indexPlan 重建索引时分块的变化
+type indexPlan struct {
+	unchanged int                  // 保持不变的分块数量
+	updates   []indexUpdate        // 原地更新的分块
+	added     []*model.SearchIndex // 需要新写入的分块
+	removed   []*model.SearchIndex // 需要删除的分块
+}
+
+// contentHash 计算分块内容的SHA-256
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReindexVersion 增量重建文档版本的索引：内容未变化的分块保留原有向量，新分块优先复用其他版本中相同内容的向量
+func (s *searchService) ReindexVersion(ctx context.Context, documentID, version string) (*model.IndexBuildResult, error) {
+	docVersion, err := s.versionRepo.GetByDocumentIDAndVersion(ctx, documentID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document version: %v", err)
+	}
+	if docVersion.Status != model.DocumentStatusCompleted {
+		return nil, fmt.Errorf("document is not ready for indexing, status: %s", docVersion.Status)
+	}
+	document, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %v", err)
+	}
+
+	chunker := newDocumentChunker(s.config.ChunkSize, s.config.ChunkOverlap)
+	chunks := chunker.Chunk(docVersion.Content, document.Name)
+	desired := make([]*model.SearchIndex, 0, len(chunks))
+	for _, chunk := range chunks {
+		desired = append(desired, s.buildChunkIndex(document, docVersion, chunk))
+	}
+
+	existing, err := s.indexRepo.GetByDocumentIDAndVersion(ctx, documentID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing indices: %v", err)
+	}
+
+	// 没有嵌入服务时只建立关键词索引，模型名称为空
+	embeddingModel := ""
+	if s.embeddingService != nil {
+		embeddingModel = s.embeddingService.ModelName()
+	}
+	plan := planIndexChanges(existing, desired, embeddingModel)
+	result := &model.IndexBuildResult{
+		Added:   len(plan.added),
+		Removed: len(plan.removed),
+		Updated: len(plan.updates),
+		Reused:  plan.unchanged + len(plan.updates),
+	}
+	// 进度按分块计算，保留和原地更新的分块无需生成向量，直接计为已完成
+	if err := s.assignVectors(ctx, plan.added, embeddingModel, result, len(desired)-len(plan.added), len(desired)); err != nil {
+		return nil, err
+	}
+
+	// 先写入新分块再删除旧分块，失败时不会丢失原有索引
+	if err := s.indexRepo.CreateBatch(ctx, plan.added); err != nil {
+		return nil, fmt.Errorf("failed to create index: %v", err)
+	}
+	added, removed := plan.added, plan.removed
+	for _, update := range plan.updates {
+		if err := s.indexRepo.Update(ctx, update.old.ID, map[string]interface{}{
+			"section":        update.desired.Section,
+			"content_type":   update.desired.ContentType,
+			"metadata":       update.desired.Metadata,
+			"start_position": update.desired.StartPosition,
+			"end_position":   update.desired.EndPosition,
+			"content_hash":   update.desired.ContentHash,
+		}); err != nil {
+			s.updateStatistics(ctx, added, nil)
+			return nil, fmt.Errorf("failed to update index %s: %v", update.old.ID, err)
+		}
+		added = append(added, update.desired)
+		removed = append(removed, update.old)
+	}
+	ids := make([]string, 0, len(plan.removed))
+	for _, index := range plan.removed {
+		ids = append(ids, index.ID)
+	}
+	if err := s.indexRepo.DeleteByIDs(ctx, ids); err != nil {
+		s.updateStatistics(ctx, added, removed[len(plan.removed):])
+		return nil, fmt.Errorf("failed to delete stale indices: %v", err)
+	}
+	s.updateStatistics(ctx, added, removed)
+
+	log.Printf("Reindexed document %s version %s: %d chunks, added %d, removed %d, updated %d, reused %d, embedded %d",
+		documentID, version, len(desired), result.Added, result.Removed, result.Updated, result.Reused, result.Embedded)
+	return result, nil
+}
+
+// planIndexChanges 按内容哈希比较已有分块和新分块
+// 内容相同的分块保留原有记录（位置或元数据变化时原地更新），其余已有分块删除，新分块写入
+// 向量为备用向量或不是由活动嵌入模型 embeddingModel 生成的已有分块不保留，以便重新生成向量；
+// embeddingModel 为空（没有嵌入服务）时无法重新生成向量，已有分块按内容保留
+func planIndexChanges(existing, desired []*model.SearchIndex, embeddingModel string) *indexPlan {
+	plan := &indexPlan{}
+	pool := make(map[string][]*model.SearchIndex)
+	for _, index := range existing {
+		if _, ok := reusableVector(index.Vector); embeddingModel != "" && (!ok || index.EmbeddingModel != embeddingModel) {
+			plan.removed = append(plan.removed, index)
+			continue
+		}
+		hash := index.ContentHash
+		if hash == "" {
+			hash = contentHash(index.Content)
+		}
+		pool[hash] = append(pool[hash], index)
+	}
+
+	for _, index := range desired {
+		candidates := pool[index.ContentHash]
+		if len(candidates) == 0 {
+			plan.added = append(plan.added, index)
+			continue
+		}
+
+		// 相同内容有多个分块时优先匹配完全相同的分块
+		match := 0
+		for i, candidate := range candidates {
+			if indexUnchanged(candidate, index) {
+				match = i
+				break
+			}
+		}
+		old := candidates[match]
+		pool[index.ContentHash] = append(candidates[:match:match], candidates[match+1:]...)
+
+		if indexUnchanged(old, index) {
+			plan.unchanged++
+			continue
+		}
+		index.ID = old.ID
+		index.Vector = old.Vector
+		index.EmbeddingModel = old.EmbeddingModel
+		index.EmbeddingDimension = old.EmbeddingDimension
+		plan.updates = append(plan.updates, indexUpdate{old: old, desired: index})
+	}
+
+	for _, candidates := range pool {
+		plan.removed = append(plan.removed, candidates...)
+	}
+	return plan
+}
+
+// indexUnchanged 判断已有分块与新分块的存储内容是否一致
+func indexUnchanged(old, desired *model.SearchIndex) bool {
+	return old.ContentHash == desired.ContentHash &&
+		old.Section == desired.Section &&
+		old.ContentType == desired.ContentType &&
+		old.StartPosition == desired.StartPosition &&
+		old.EndPosition == desired.EndPosition &&
+		sameJSON(old.Metadata, desired.Metadata)
+}
+
+// sameJSON 判断两个JSON字符串的内容是否相同，忽略格式差异（jsonb 读出时会重新格式化）
+func sameJSON(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// reusableVector 解析已存储的向量，备用向量和空向量不可复用
+func reusableVector(vectorJSON string) ([]float32, bool) {
+	var vector []float32
+	if err := json.Unmarshal([]byte(vectorJSON), &vector); err != nil {
+		return nil, false
+	}
+	if len(vector) == 0 || len(vector) == fallbackVectorDimension {
+		return nil, false
+	}
+	return vector, true
+}
+
+// assignVectors 为新分块设置向量：优先复用任意文档版本中相同内容、由活动嵌入模型 embeddingModel 生成的向量，其余分块按批调用嵌入服务生成
+// 嵌入服务失败时返回错误，由索引任务重试，不写入备用向量
+// done 和 total 为上报任务进度时已完成的分块数和分块总数，ctx 取消时停止生成并返回错误
+func (s *searchService) assignVectors(ctx context.Context, indices []*model.SearchIndex, embeddingModel string, result *model.IndexBuildResult, done, total int) error {
+	ReportJobProgress(ctx, done, total)
+	if len(indices) == 0 {
+		return nil
+	}
+	// 没有嵌入服务时只建立关键词索引，分块使用备用向量且不写入 pgvector 列
+	if s.embeddingService == nil {
+		for _, index := range indices {
+			setIndexVector(index, fallbackEmbedding(index.Content), nil, model.FallbackEmbeddingModel)
+		}
+		ReportJobProgress(ctx, total, total)
+		return nil
+	}
+
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, index := range indices {
+		if !seen[index.ContentHash] {
+			seen[index.ContentHash] = true
+			hashes = append(hashes, index.ContentHash)
+		}
+	}
+	stored, err := s.indexRepo.GetVectorsByContentHashes(ctx, hashes, embeddingModel)
+	if err != nil {
+		log.Printf("WARNING: Failed to look up reusable vectors, embedding all new chunks: %v", err)
+		stored = nil
+	}
+
+	// 相同内容只生成一次向量，本次生成的向量也被相同内容的其他分块复用
+	var pending []*model.SearchIndex
+	queued := make(map[string]bool)
+	for _, index := range indices {
+		if _, ok := reusableVector(stored[index.ContentHash]); ok || queued[index.ContentHash] {
+			continue
+		}
+		if strings.TrimSpace(index.Content) == "" {
+			continue
+		}
+		queued[index.ContentHash] = true
+		pending = append(pending, index)
+	}
+
+	generated := make(map[string][]float32, len(pending))
+	for start := 0; start < len(pending); start += embeddingProgressStep {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ReportJobProgress(ctx, done+start*len(indices)/len(pending), total)
+
+		end := start + embeddingProgressStep
+		if end > len(pending) {
+			end = len(pending)
+		}
+		contents := make([]string, end-start)
+		for i, index := range pending[start:end] {
+			contents[i] = index.Content
+		}
+		embeddings, err := s.embeddingService.GenerateEmbeddings(ctx, contents)
+		if err != nil {
+			return fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		for i, index := range pending[start:end] {
+			generated[index.ContentHash] = embeddings[i]
+		}
+	}
+
+	embedded := make(map[string]bool, len(generated))
+	for _, index := range indices {
+		if vector, ok := generated[index.ContentHash]; ok {
+			setIndexVector(index, vector, vector, embeddingModel)
+			if embedded[index.ContentHash] {
+				result.Reused++
+			} else {
+				embedded[index.ContentHash] = true
+				result.Embedded++
+			}
+			continue
+		}
+		if vector, ok := reusableVector(stored[index.ContentHash]); ok {
+			setIndexVector(index, vector, vector, embeddingModel)
+			result.Reused++
+			continue
+		}
+		// 空内容无需调用嵌入服务
+		setIndexVector(index, fallbackEmbedding(index.Content), nil, model.FallbackEmbeddingModel)
+	}
+	ReportJobProgress(ctx, total, total)
+	return nil
+}
+
+// setIndexVector 设置分块的向量和生成向量的嵌入模型，vector 以JSON字符串存储，embedding 为空时不写入 pgvector 列
+func setIndexVector(index *model.SearchIndex, vector, embedding []float32, embeddingModel string) {
+	vectorJSON, err := json.Marshal(vector)
+	if err != nil {
+		log.Printf("Error marshaling vector to JSON: %v", err)
+		vectorJSON = []byte("[]")
+	}
+	index.Vector = string(vectorJSON)
+	index.EmbeddingModel = embeddingModel
+	index.EmbeddingDimension = len(vector)
+	if embedding != nil {
+		index.Embedding = embedding
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// testVectorJSON 生成指定维度的向量JSON字符串
+func testVectorJSON(dimension int) string {
+	data, _ := json.Marshal(make([]float32, dimension))
+	return string(data)
+}
+
+// testEmbeddingModel 测试用的活动嵌入模型
+const testEmbeddingModel = "test-model"
+
+// testChunkIndex 构造测试用的分块索引
+func testChunkIndex(id, content string, start int, vector string) *model.SearchIndex {
+	return &model.SearchIndex{
+		EmbeddingModel: testEmbeddingModel,
+		ID:             id,
+		Content:        content,
+		ContentType:    "text",
+		Section:        "Intro",
+		Metadata:       `{"chunk_index": 0}`,
+		StartPosition:  start,
+		EndPosition:    start + len(content),
+		ContentHash:    contentHash(content),
+		Vector:         vector,
+	}
+}
+
+// TestPlanIndexChanges 测试按内容哈希比较分块
+func TestPlanIndexChanges(t *testing.T) {
+	embedded := testVectorJSON(8)
+	existing := []*model.SearchIndex{
+		testChunkIndex("old-1", "unchanged chunk", 0, embedded),
+		testChunkIndex("old-2", "moved chunk", 20, embedded),
+		testChunkIndex("old-3", "deleted chunk", 40, embedded),
+		testChunkIndex("old-4", "fallback chunk", 60, testVectorJSON(fallbackVectorDimension)),
+		testChunkIndex("old-5", "legacy chunk", 100, embedded),
+	}
+	// jsonb 读出时格式不同，内容相同视为未变化
+	existing[0].Metadata = `{"chunk_index":0}`
+	// 旧数据没有内容哈希时按内容匹配，并原地补齐哈希
+	existing[4].ContentHash = ""
+
+	desired := []*model.SearchIndex{
+		testChunkIndex("new-1", "unchanged chunk", 0, ""),
+		testChunkIndex("new-2", "moved chunk", 30, ""),
+		testChunkIndex("new-3", "fallback chunk", 60, ""),
+		testChunkIndex("new-4", "added chunk", 80, ""),
+		testChunkIndex("new-5", "legacy chunk", 100, ""),
+	}
+
+	plan := planIndexChanges(existing, desired, testEmbeddingModel)
+
+	if plan.unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", plan.unchanged)
+	}
+	if len(plan.updates) != 2 || plan.updates[0].old.ID != "old-2" || plan.updates[1].old.ID != "old-5" {
+		t.Fatalf("updates = %+v, want old-2 and old-5", plan.updates)
+	}
+	for _, update := range plan.updates {
+		if update.desired.ID != update.old.ID || update.desired.Vector != embedded {
+			t.Errorf("updated chunk %s should keep the existing ID and vector", update.old.ID)
+		}
+	}
+
+	addedIDs := map[string]bool{}
+	for _, index := range plan.added {
+		addedIDs[index.ID] = true
+	}
+	if len(addedIDs) != 2 || !addedIDs["new-3"] || !addedIDs["new-4"] {
+		t.Errorf("added = %v, want new-3 and new-4", addedIDs)
+	}
+
+	removedIDs := map[string]bool{}
+	for _, index := range plan.removed {
+		removedIDs[index.ID] = true
+	}
+	if len(removedIDs) != 2 || !removedIDs["old-3"] || !removedIDs["old-4"] {
+		t.Errorf("removed = %v, want old-3 and old-4", removedIDs)
+	}
+}
+
+// TestPlanIndexChangesDuplicateContent 测试相同内容的多个分块优先匹配完全相同的分块
+func TestPlanIndexChangesDuplicateContent(t *testing.T) {
+	embedded := testVectorJSON(8)
+	existing := []*model.SearchIndex{
+		testChunkIndex("old-1", "repeated", 0, embedded),
+		testChunkIndex("old-2", "repeated", 50, embedded),
+	}
+	desired := []*model.SearchIndex{
+		testChunkIndex("new-1", "repeated", 50, ""),
+	}
+
+	plan := planIndexChanges(existing, desired, testEmbeddingModel)
+
+	if plan.unchanged != 1 || len(plan.updates) != 0 || len(plan.added) != 0 {
+		t.Errorf("plan = %+v, want one unchanged chunk", plan)
+	}
+	if len(plan.removed) != 1 || plan.removed[0].ID != "old-1" {
+		t.Errorf("removed = %+v, want old-1", plan.removed)
+	}
+}
+
+// TestPlanIndexChangesModelSwitch 测试切换嵌入模型后其他模型生成的分块不保留
+func TestPlanIndexChangesModelSwitch(t *testing.T) {
+	embedded := testVectorJSON(8)
+	existing := []*model.SearchIndex{
+		testChunkIndex("old-1", "same model", 0, embedded),
+		testChunkIndex("old-2", "other model", 20, embedded),
+	}
+	existing[1].EmbeddingModel = "previous-model"
+	desired := []*model.SearchIndex{
+		testChunkIndex("new-1", "same model", 0, ""),
+		testChunkIndex("new-2", "other model", 20, ""),
+	}
+
+	plan := planIndexChanges(existing, desired, testEmbeddingModel)
+
+	if plan.unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", plan.unchanged)
+	}
+	if len(plan.added) != 1 || plan.added[0].ID != "new-2" {
+		t.Errorf("added = %+v, want new-2", plan.added)
+	}
+	if len(plan.removed) != 1 || plan.removed[0].ID != "old-2" {
+		t.Errorf("removed = %+v, want old-2", plan.removed)
+	}
+}
+
+// TestReusableVector 测试可复用向量的判断
+func TestReusableVector(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector string
+		want   bool
+	}{
+		{"embedding", testVectorJSON(8), true},
+		{"fallback", testVectorJSON(fallbackVectorDimension), false},
+		{"empty", "[]", false},
+		{"invalid", "not json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := reusableVector(tt.vector); got != tt.want {
+				t.Errorf("reusableVector(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReindexVersionWithoutEmbeddingService 测试没有嵌入服务时只建立关键词索引，再次重建时保留已有分块
+func TestReindexVersionWithoutEmbeddingService(t *testing.T) {
+	documentRepo := &fakeDocumentRepository{document: &model.Document{ID: "doc-1", Name: "部署指南"}}
+	versionRepo := &fakeDocumentVersionRepository{versions: []*model.DocumentVersion{
+		{ID: "ver-1", DocumentID: "doc-1", Version: "1.0", Status: model.DocumentStatusCompleted,
+			Content: "# 安装\n使用 Docker 安装\n\n# 配置\n修改配置文件\n"},
+	}}
+	indexRepo := &memoryIndexRepository{}
+	s := &searchService{
+		indexRepo:        indexRepo,
+		documentRepo:     documentRepo,
+		versionRepo:      versionRepo,
+		embeddingService: nil,
+		config:           DefaultSearchConfig(),
+	}
+
+	result, err := s.ReindexVersion(context.Background(), "doc-1", "1.0")
+	if err != nil {
+		t.Fatalf("ReindexVersion() error = %v", err)
+	}
+	if result.Added != 2 || result.Embedded != 0 || len(indexRepo.indices) != 2 {
+		t.Fatalf("result = %+v, indices = %d, want 2 added without embedding", result, len(indexRepo.indices))
+	}
+	for _, index := range indexRepo.indices {
+		if index.EmbeddingModel != model.FallbackEmbeddingModel || len(index.Embedding) != 0 {
+			t.Errorf("index %s embedding model = %q, embedding = %v, want fallback vector only", index.ID, index.EmbeddingModel, index.Embedding)
+		}
+	}
+
+	result, err = s.ReindexVersion(context.Background(), "doc-1", "1.0")
+	if err != nil {
+		t.Fatalf("second ReindexVersion() error = %v", err)
+	}
+	if result.HasChanges() {
+		t.Errorf("second reindex result = %+v, want no changes", result)
+	}
+}
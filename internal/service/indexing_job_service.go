@@ -0,0 +1,455 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// ErrIndexingJobNotFound 索引任务不存在
+var ErrIndexingJobNotFound = errors.New("indexing job not found")
+
+// ErrIndexingJobState 索引任务的当前状态不允许该操作
+var ErrIndexingJobState = errors.New("indexing job state does not allow this operation")
+
+// IndexingJobHandler 执行一种类型的索引任务，返回的结果会以JSON格式保存到任务中
+// 处理函数应通过 ReportJobProgress 上报进度，并在 ctx 取消后尽快返回
+type IndexingJobHandler func(ctx context.Context, job *model.IndexingJob) (interface{}, error)
+
+// IndexingJobService 索引任务队列服务接口
+type IndexingJobService interface {
+	// RegisterHandler 注册任务类型的处理函数，需在 Start 之前调用
+	RegisterHandler(jobType model.IndexingJobType, handler IndexingJobHandler)
+	// Enqueue 将任务加入队列，同类型、同文档版本已有待执行任务时返回已有任务
+	Enqueue(ctx context.Context, job *model.IndexingJob) (*model.IndexingJob, error)
+	// GetJob 获取任务
+	GetJob(ctx context.Context, id string) (*model.IndexingJob, error)
+	// ListJobs 获取任务列表，支持按 status、type、document_id、version 过滤
+	ListJobs(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.IndexingJob, int64, error)
+	// RetryJob 重新执行失败或已取消的任务
+	RetryJob(ctx context.Context, id string) (*model.IndexingJob, error)
+	// CancelJob 取消待执行或执行中的任务
+	CancelJob(ctx context.Context, id string) (*model.IndexingJob, error)
+	// Start 启动工作协程池
+	Start(ctx context.Context)
+	// Stop 停止工作协程池，执行中的任务会放回队列
+	Stop()
+}
+
+// IndexingJobConfig 索引任务队列配置
+type IndexingJobConfig struct {
+	Workers           int           // 工作协程数量
+	PollInterval      time.Duration // 队列为空时的轮询间隔
+	HeartbeatInterval time.Duration // 执行中任务上报心跳和进度的间隔
+	StaleTimeout      time.Duration // 心跳超过该时间未更新的任务视为工作进程已退出，重新放回队列
+	MaxAttempts       int           // 任务默认的最大尝试次数
+	RetryBaseDelay    time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	RetryMaxDelay     time.Duration // 重试等待时间的上限
+}
+
+// DefaultIndexingJobConfig 返回默认的索引任务队列配置
+func DefaultIndexingJobConfig() *IndexingJobConfig {
+	return &IndexingJobConfig{
+		Workers:           2,
+		PollInterval:      2 * time.Second,
+		HeartbeatInterval: 5 * time.Second,
+		StaleTimeout:      2 * time.Minute,
+		MaxAttempts:       3,
+		RetryBaseDelay:    10 * time.Second,
+		RetryMaxDelay:     10 * time.Minute,
+	}
+}
+
+// NewIndexingJobConfigFromEnv 从环境变量创建索引任务队列配置
+func NewIndexingJobConfigFromEnv() *IndexingJobConfig {
+	config := DefaultIndexingJobConfig()
+	config.Workers = getEnvInt("INDEX_JOB_WORKERS", config.Workers)
+	config.MaxAttempts = getEnvInt("INDEX_JOB_MAX_ATTEMPTS", config.MaxAttempts)
+	config.PollInterval = time.Duration(getEnvInt("INDEX_JOB_POLL_INTERVAL_MS", int(config.PollInterval/time.Millisecond))) * time.Millisecond
+	config.StaleTimeout = time.Duration(getEnvInt("INDEX_JOB_STALE_TIMEOUT_SECONDS", int(config.StaleTimeout/time.Second))) * time.Second
+	config.RetryBaseDelay = time.Duration(getEnvInt("INDEX_JOB_RETRY_BASE_SECONDS", int(config.RetryBaseDelay/time.Second))) * time.Second
+	config.RetryMaxDelay = time.Duration(getEnvInt("INDEX_JOB_RETRY_MAX_SECONDS", int(config.RetryMaxDelay/time.Second))) * time.Second
+	return config
+}
+
+// retryDelay 计算第 attempts 次尝试失败后的重试等待时间（指数退避）
+func (c *IndexingJobConfig) retryDelay(attempts int) time.Duration {
+	delay := c.RetryBaseDelay
+	for i := 1; i < attempts && delay < c.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.RetryMaxDelay {
+		delay = c.RetryMaxDelay
+	}
+	return delay
+}
+
+// jobProgressKey 任务进度在 context 中的键
+type jobProgressKey struct{}
+
+// jobProgress 执行中任务的进度，由处理函数更新、心跳协程读取
+type jobProgress struct {
+	done  atomic.Int64
+	total atomic.Int64
+}
+
+// jobProgressReporter 接收任务进度上报
+type jobProgressReporter interface {
+	report(done, total int)
+}
+
+// report 记录任务进度
+func (p *jobProgress) report(done, total int) {
+	p.done.Store(int64(done))
+	p.total.Store(int64(total))
+}
+
+// ReportJobProgress 上报当前任务的进度，ctx 不属于索引任务时不做任何处理
+func ReportJobProgress(ctx context.Context, done, total int) {
+	if reporter, ok := ctx.Value(jobProgressKey{}).(jobProgressReporter); ok {
+		reporter.report(done, total)
+	}
+}
+
+// withoutJobProgress 返回不上报任务进度的 context
+// 任务中嵌套的步骤按自己的单位上报进度时使用，避免覆盖外层任务的进度
+func withoutJobProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jobProgressKey{}, nil)
+}
+
+// indexingJobService 基于PostgreSQL的索引任务队列实现
+type indexingJobService struct {
+	repo     repository.IndexingJobRepository
+	config   *IndexingJobConfig
+	workerID string
+	handlers map[model.IndexingJobType]IndexingJobHandler
+	wake     chan struct{}
+
+	mutex   sync.Mutex
+	running map[string]context.CancelFunc // 本进程执行中任务的取消函数
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewIndexingJobService 创建索引任务队列服务实例
+func NewIndexingJobService(repo repository.IndexingJobRepository, config *IndexingJobConfig) IndexingJobService {
+	if config == nil {
+		config = DefaultIndexingJobConfig()
+	}
+	hostname, _ := os.Hostname()
+	return &indexingJobService{
+		repo:     repo,
+		config:   config,
+		workerID: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		handlers: make(map[model.IndexingJobType]IndexingJobHandler),
+		wake:     make(chan struct{}, 1),
+		running:  make(map[string]context.CancelFunc),
+	}
+}
+
+// RegisterHandler 注册任务类型的处理函数
+func (s *indexingJobService) RegisterHandler(jobType model.IndexingJobType, handler IndexingJobHandler) {
+	s.handlers[jobType] = handler
+}
+
+// Enqueue 将任务加入队列
+func (s *indexingJobService) Enqueue(ctx context.Context, job *model.IndexingJob) (*model.IndexingJob, error) {
+	existing, err := s.repo.FindPending(ctx, job.Type, job.DocumentID, job.Version)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check pending jobs: %v", err)
+	}
+
+	job.Status = model.IndexingJobStatusPending
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = s.config.MaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create indexing job: %v", err)
+	}
+	s.notify()
+	return job, nil
+}
+
+// GetJob 获取任务
+func (s *indexingJobService) GetJob(ctx context.Context, id string) (*model.IndexingJob, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIndexingJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get indexing job: %v", err)
+	}
+	return job, nil
+}
+
+// ListJobs 获取任务列表
+func (s *indexingJobService) ListJobs(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.IndexingJob, int64, error) {
+	return s.repo.List(ctx, page, size, filters)
+}
+
+// RetryJob 重新执行失败或已取消的任务
+func (s *indexingJobService) RetryJob(ctx context.Context, id string) (*model.IndexingJob, error) {
+	ok, err := s.repo.Retry(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry indexing job: %v", err)
+	}
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: job is %s", ErrIndexingJobState, job.Status)
+	}
+	s.notify()
+	return job, nil
+}
+
+// CancelJob 取消待执行或执行中的任务
+// 本进程执行的任务立即中断，其他进程执行的任务在下一次心跳时中断
+func (s *indexingJobService) CancelJob(ctx context.Context, id string) (*model.IndexingJob, error) {
+	ok, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel indexing job: %v", err)
+	}
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: job is %s", ErrIndexingJobState, job.Status)
+	}
+
+	s.mutex.Lock()
+	if cancel, running := s.running[id]; running {
+		cancel()
+	}
+	s.mutex.Unlock()
+	return job, nil
+}
+
+// Start 启动工作协程池，并回收工作进程退出时遗留的执行中任务
+func (s *indexingJobService) Start(ctx context.Context) {
+	s.ctx, s.stop = context.WithCancel(ctx)
+
+	s.recoverStale()
+	s.wg.Add(1)
+	go s.recoverLoop()
+
+	for i := 0; i < s.config.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	log.Printf("Indexing job queue started with %d workers (worker ID: %s)", s.config.Workers, s.workerID)
+}
+
+// Stop 停止工作协程池并等待执行中的任务返回
+func (s *indexingJobService) Stop() {
+	if s.stop == nil {
+		return
+	}
+	s.stop()
+	s.wg.Wait()
+}
+
+// notify 唤醒一个空闲的工作协程
+func (s *indexingJobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker 工作协程：循环领取并执行任务，队列为空时等待唤醒或轮询
+func (s *indexingJobService) worker() {
+	defer s.wg.Done()
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		job, err := s.repo.ClaimNext(s.ctx, s.workerID)
+		if err != nil && s.ctx.Err() == nil {
+			log.Printf("Failed to claim indexing job: %v", err)
+		}
+		if job != nil {
+			s.run(job)
+			continue
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// recoverLoop 定期回收心跳超时的任务
+func (s *indexingJobService) recoverLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.config.StaleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverStale()
+		}
+	}
+}
+
+// recoverStale 回收心跳超时的任务
+func (s *indexingJobService) recoverStale() {
+	recovered, err := s.repo.RequeueStale(s.ctx, time.Now().Add(-s.config.StaleTimeout))
+	if err != nil {
+		log.Printf("Failed to recover stale indexing jobs: %v", err)
+		return
+	}
+	if recovered > 0 {
+		log.Printf("Recovered %d stale indexing jobs", recovered)
+		s.notify()
+	}
+}
+
+// run 执行一个已领取的任务，并根据结果结束、重试或放回队列
+func (s *indexingJobService) run(job *model.IndexingJob) {
+	handler, ok := s.handlers[job.Type]
+	if !ok {
+		s.finish(job, model.IndexingJobStatusFailed, "", fmt.Sprintf("no handler for job type %s", job.Type))
+		return
+	}
+
+	progress := &jobProgress{}
+	ctx, cancel := context.WithCancel(context.WithValue(s.ctx, jobProgressKey{}, progress))
+	defer cancel()
+	s.mutex.Lock()
+	s.running[job.ID] = cancel
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.running, job.ID)
+		s.mutex.Unlock()
+	}()
+
+	// 心跳协程定期保存进度，任务在其他进程被取消时中断执行
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(s.config.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !s.heartbeat(job, progress) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	log.Printf("Running indexing job %s (%s, document: %s, version: %s, attempt %d/%d)",
+		job.ID, job.Type, job.DocumentID, job.Version, job.Attempts, job.MaxAttempts)
+	result, err := runIndexingJobHandler(ctx, handler, job)
+	cancel()
+	<-heartbeatDone
+	s.heartbeat(job, progress)
+
+	switch {
+	case err == nil:
+		resultJSON := ""
+		if result != nil {
+			if data, marshalErr := json.Marshal(result); marshalErr == nil {
+				resultJSON = string(data)
+			}
+		}
+		s.finish(job, model.IndexingJobStatusSucceeded, resultJSON, "")
+	case s.ctx.Err() != nil:
+		// 服务停止，任务放回队列，重启后继续执行；中断不是任务失败，不计入尝试次数
+		s.requeue(job, "worker stopped before the job finished")
+	case job.IsLastAttempt():
+		s.finish(job, model.IndexingJobStatusFailed, "", err.Error())
+	default:
+		// 任务已被取消时状态不再是 running，重新排队不会生效
+		s.reschedule(job, time.Now().Add(s.config.retryDelay(job.Attempts)), err.Error())
+	}
+}
+
+// runIndexingJobHandler 执行任务处理函数，处理函数 panic 时作为错误返回
+func runIndexingJobHandler(ctx context.Context, handler IndexingJobHandler, job *model.IndexingJob) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat 保存任务的心跳和进度，任务已不属于本进程（被取消或回收）时返回 false
+func (s *indexingJobService) heartbeat(job *model.IndexingJob, progress *jobProgress) bool {
+	ok, err := s.repo.Heartbeat(context.Background(), job.ID, s.workerID, int(progress.done.Load()), int(progress.total.Load()))
+	if err != nil {
+		log.Printf("Failed to update indexing job %s heartbeat: %v", job.ID, err)
+		return true
+	}
+	return ok
+}
+
+// finish 结束任务
+func (s *indexingJobService) finish(job *model.IndexingJob, status model.IndexingJobStatus, result, lastError string) {
+	ok, err := s.repo.Finish(context.Background(), job.ID, s.workerID, status, result, lastError)
+	if err != nil {
+		log.Printf("Failed to finish indexing job %s: %v", job.ID, err)
+		return
+	}
+	if ok {
+		log.Printf("Indexing job %s %s %s", job.ID, status, lastError)
+	}
+}
+
+// requeue 将被中断的任务立即放回队列，并退还本次执行计入的尝试次数
+func (s *indexingJobService) requeue(job *model.IndexingJob, lastError string) {
+	ok, err := s.repo.Requeue(context.Background(), job.ID, s.workerID, lastError)
+	if err != nil {
+		log.Printf("Failed to requeue indexing job %s: %v", job.ID, err)
+		return
+	}
+	if ok {
+		log.Printf("Indexing job %s requeued: %s", job.ID, lastError)
+	}
+}
+
+// reschedule 将任务放回队列，在 runAt 之后重试
+func (s *indexingJobService) reschedule(job *model.IndexingJob, runAt time.Time, lastError string) {
+	ok, err := s.repo.Reschedule(context.Background(), job.ID, s.workerID, runAt, lastError)
+	if err != nil {
+		log.Printf("Failed to reschedule indexing job %s: %v", job.ID, err)
+		return
+	}
+	if ok {
+		log.Printf("Indexing job %s will retry at %s: %s", job.ID, runAt.Format(time.RFC3339), lastError)
+	}
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// fakeIndexingJobRepository 基于内存的索引任务仓库
+type fakeIndexingJobRepository struct {
+	mutex sync.Mutex
+	jobs  map[string]*model.IndexingJob
+}
+
+func newFakeIndexingJobRepository(jobs ...*model.IndexingJob) *fakeIndexingJobRepository {
+	repo := &fakeIndexingJobRepository{jobs: make(map[string]*model.IndexingJob)}
+	for _, job := range jobs {
+		repo.jobs[job.ID] = job
+	}
+	return repo
+}
+
+func (r *fakeIndexingJobRepository) Create(ctx context.Context, job *model.IndexingJob) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if job.ID == "" {
+		job.ID = generateID()
+	}
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *fakeIndexingJobRepository) GetByID(ctx context.Context, id string) (*model.IndexingJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (r *fakeIndexingJobRepository) List(ctx context.Context, page, size int, filters map[string]interface{}) ([]*model.IndexingJob, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeIndexingJobRepository) FindPending(ctx context.Context, jobType model.IndexingJobType, documentID, version string) (*model.IndexingJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, job := range r.jobs {
+		if job.Type == jobType && job.DocumentID == documentID && job.Version == version && job.Status == model.IndexingJobStatusPending {
+			return job, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIndexingJobRepository) ClaimNext(ctx context.Context, workerID string) (*model.IndexingJob, error) {
+	return nil, nil
+}
+
+func (r *fakeIndexingJobRepository) Heartbeat(ctx context.Context, id, workerID string, done, total int) (bool, error) {
+	return r.updateRunning(id, func(job *model.IndexingJob) {
+		job.ProgressDone = done
+		job.ProgressTotal = total
+	}), nil
+}
+
+func (r *fakeIndexingJobRepository) Finish(ctx context.Context, id, workerID string, status model.IndexingJobStatus, result, lastError string) (bool, error) {
+	return r.updateRunning(id, func(job *model.IndexingJob) {
+		job.Status = status
+		job.Result = result
+		job.LastError = lastError
+	}), nil
+}
+
+func (r *fakeIndexingJobRepository) Reschedule(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error) {
+	return r.updateRunning(id, func(job *model.IndexingJob) {
+		job.Status = model.IndexingJobStatusPending
+		job.RunAt = runAt
+		job.LastError = lastError
+	}), nil
+}
+
+func (r *fakeIndexingJobRepository) Requeue(ctx context.Context, id, workerID string, lastError string) (bool, error) {
+	return r.updateRunning(id, func(job *model.IndexingJob) {
+		job.Status = model.IndexingJobStatusPending
+		job.RunAt = time.Now()
+		job.LastError = lastError
+		if job.Attempts > 0 {
+			job.Attempts--
+		}
+	}), nil
+}
+
+func (r *fakeIndexingJobRepository) updateRunning(id string, update func(job *model.IndexingJob)) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.Status != model.IndexingJobStatusRunning {
+		return false
+	}
+	update(job)
+	return true
+}
+
+func (r *fakeIndexingJobRepository) Cancel(ctx context.Context, id string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.IsFinished() {
+		return false, nil
+	}
+	job.Status = model.IndexingJobStatusCancelled
+	return true, nil
+}
+
+func (r *fakeIndexingJobRepository) Retry(ctx context.Context, id string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || (job.Status != model.IndexingJobStatusFailed && job.Status != model.IndexingJobStatusCancelled) {
+		return false, nil
+	}
+	job.Status = model.IndexingJobStatusPending
+	job.Attempts = 0
+	return true, nil
+}
+
+func (r *fakeIndexingJobRepository) RequeueStale(ctx context.Context, heartbeatBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+// newTestIndexingJobService 创建未启动工作协程的索引任务服务
+func newTestIndexingJobService(repo *fakeIndexingJobRepository) *indexingJobService {
+	config := DefaultIndexingJobConfig()
+	config.HeartbeatInterval = time.Hour
+	s := NewIndexingJobService(repo, config).(*indexingJobService)
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	return s
+}
+
+// runningJob 构造已被领取的任务
+func runningJob(id string, attempts, maxAttempts int) *model.IndexingJob {
+	return &model.IndexingJob{
+		ID:          id,
+		Type:        model.IndexingJobTypeBuildIndex,
+		Status:      model.IndexingJobStatusRunning,
+		Attempts:    attempts,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// TestIndexingJobRetryDelay 测试重试的指数退避
+func TestIndexingJobRetryDelay(t *testing.T) {
+	config := &IndexingJobConfig{RetryBaseDelay: 10 * time.Second, RetryMaxDelay: time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := config.retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// TestIndexingJobRun 测试任务执行结果对应的状态变化
+func TestIndexingJobRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		err        error
+		wantStatus model.IndexingJobStatus
+	}{
+		{"成功", 1, nil, model.IndexingJobStatusSucceeded},
+		{"失败后重试", 1, errors.New("embedding timeout"), model.IndexingJobStatusPending},
+		{"最后一次失败", 3, errors.New("embedding timeout"), model.IndexingJobStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := runningJob("job-1", tt.attempts, 3)
+			repo := newFakeIndexingJobRepository(job)
+			s := newTestIndexingJobService(repo)
+			s.RegisterHandler(model.IndexingJobTypeBuildIndex, func(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+				ReportJobProgress(ctx, 4, 4)
+				return map[string]int{"added": 4}, tt.err
+			})
+
+			claimed := *job
+			s.run(&claimed)
+
+			stored, _ := repo.GetByID(context.Background(), "job-1")
+			if stored.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", stored.Status, tt.wantStatus)
+			}
+			if stored.ProgressDone != 4 || stored.ProgressTotal != 4 {
+				t.Errorf("progress = %d/%d, want 4/4", stored.ProgressDone, stored.ProgressTotal)
+			}
+			if tt.err == nil && stored.Result != `{"added":4}` {
+				t.Errorf("result = %s, want {\"added\":4}", stored.Result)
+			}
+			if tt.wantStatus == model.IndexingJobStatusPending && !stored.RunAt.After(time.Now()) {
+				t.Errorf("retry should be scheduled in the future, got %v", stored.RunAt)
+			}
+		})
+	}
+}
+
+// TestIndexingJobStopRequeues 测试服务停止时中断的任务放回队列，且不消耗尝试次数
+func TestIndexingJobStopRequeues(t *testing.T) {
+	job := runningJob("job-1", 2, 3)
+	repo := newFakeIndexingJobRepository(job)
+	s := newTestIndexingJobService(repo)
+
+	s.RegisterHandler(model.IndexingJobTypeBuildIndex, func(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+		s.stop()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	claimed := *job
+	s.run(&claimed)
+
+	stored, _ := repo.GetByID(context.Background(), "job-1")
+	if stored.Status != model.IndexingJobStatusPending {
+		t.Fatalf("status = %s, want pending", stored.Status)
+	}
+	if stored.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", stored.Attempts)
+	}
+	if stored.RunAt.After(time.Now()) {
+		t.Errorf("interrupted job should run again immediately, got %v", stored.RunAt)
+	}
+
+	// 下次领取时尝试次数回到 2，不是最后一次尝试，失败后仍会重试
+	stored.Status = model.IndexingJobStatusRunning
+	stored.Attempts++
+	if stored.IsLastAttempt() {
+		t.Errorf("job should not be on its last attempt after a restart")
+	}
+}
+
+// TestIndexingJobCancelRunning 测试取消执行中的任务会中断处理函数
+func TestIndexingJobCancelRunning(t *testing.T) {
+	job := runningJob("job-1", 1, 3)
+	repo := newFakeIndexingJobRepository(job)
+	s := newTestIndexingJobService(repo)
+
+	started := make(chan struct{})
+	s.RegisterHandler(model.IndexingJobTypeBuildIndex, func(ctx context.Context, job *model.IndexingJob) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		claimed := *job
+		s.run(&claimed)
+		close(done)
+	}()
+
+	<-started
+	if _, err := s.CancelJob(context.Background(), "job-1"); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cancelled job handler did not return")
+	}
+
+	stored, _ := repo.GetByID(context.Background(), "job-1")
+	if stored.Status != model.IndexingJobStatusCancelled {
+		t.Errorf("status = %s, want cancelled", stored.Status)
+	}
+
+	if _, err := s.CancelJob(context.Background(), "job-1"); !errors.Is(err, ErrIndexingJobState) {
+		t.Errorf("cancelling a finished job error = %v, want ErrIndexingJobState", err)
+	}
+}
+
+// TestIndexingJobEnqueueDeduplicates 测试同一文档版本的待执行任务不会重复创建
+func TestIndexingJobEnqueueDeduplicates(t *testing.T) {
+	repo := newFakeIndexingJobRepository()
+	s := newTestIndexingJobService(repo)
+
+	first, err := s.Enqueue(context.Background(), &model.IndexingJob{Type: model.IndexingJobTypeBuildIndex, DocumentID: "doc", Version: "1.0"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if first.Status != model.IndexingJobStatusPending || first.MaxAttempts != s.config.MaxAttempts {
+		t.Errorf("enqueued job = %+v, want pending with default max attempts", first)
+	}
+
+	second, err := s.Enqueue(context.Background(), &model.IndexingJob{Type: model.IndexingJobTypeBuildIndex, DocumentID: "doc", Version: "1.0"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("second enqueue created job %s, want existing %s", second.ID, first.ID)
+	}
+}
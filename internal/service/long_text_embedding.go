@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 超长文本的处理策略
+const (
+	LongTextStrategyTruncate = "truncate" // 只使用第一个窗口
+	LongTextStrategyMean     = "mean"     // 各窗口向量的平均值
+	LongTextStrategyWeighted = "weighted" // 按窗口的token数加权平均
+)
+
+// longTextSafetyRatio token数为估算值，窗口只使用模型上限的这一比例
+const longTextSafetyRatio = 0.9
+
+// maxTextPieceRunes 切分时一段连续字母、数字或空白的最大字符数，保证超长的无空格文本也能切分
+const maxTextPieceRunes = 64
+
+// openAIMaxInputTokens OpenAI 嵌入模型单个输入的token上限
+const openAIMaxInputTokens = 8191
+
+// defaultEmbeddingModelLimits 常见嵌入模型的输入token上限，模型名称与 ModelName 一致
+var defaultEmbeddingModelLimits = map[string]int{
+	"text-embedding-ada-002":   openAIMaxInputTokens,
+	"text-embedding-3-small":   openAIMaxInputTokens,
+	"text-embedding-3-large":   openAIMaxInputTokens,
+	"ollama/nomic-embed-text":  2048,
+	"ollama/bge-m3":            8192,
+	"ollama/mxbai-embed-large": 512,
+	"ollama/all-minilm":        256,
+}
+
+// LongTextModelConfig 单个嵌入模型的超长文本配置
+type LongTextModelConfig struct {
+	MaxTokens int    // 模型单个输入的token上限
+	Strategy  string // 超过上限时的处理策略
+}
+
+// LongTextEmbeddingConfig 超长文本嵌入配置
+type LongTextEmbeddingConfig struct {
+	MaxTokens int                            // 没有单独配置且不在内置列表中的模型的token上限
+	Strategy  string                         // 没有单独配置策略的模型使用的处理策略
+	Models    map[string]LongTextModelConfig // 按模型名称单独配置，未设置的字段使用默认值
+}
+
+// DefaultLongTextEmbeddingConfig 返回默认的超长文本嵌入配置
+func DefaultLongTextEmbeddingConfig() *LongTextEmbeddingConfig {
+	return &LongTextEmbeddingConfig{
+		MaxTokens: openAIMaxInputTokens,
+		Strategy:  LongTextStrategyWeighted,
+		Models:    map[string]LongTextModelConfig{},
+	}
+}
+
+// NewLongTextEmbeddingConfigFromEnv 从环境变量创建超长文本嵌入配置
+// EMBEDDING_LONG_TEXT_MODELS 格式为逗号分隔的“模型=token上限[:策略]”，如 "ollama/bge-m3=8192:mean,tei@http://tei/embed=512"
+func NewLongTextEmbeddingConfigFromEnv() *LongTextEmbeddingConfig {
+	config := DefaultLongTextEmbeddingConfig()
+	config.MaxTokens = getEnvInt("EMBEDDING_MAX_TOKENS", config.MaxTokens)
+	config.Strategy = getEnv("EMBEDDING_LONG_TEXT_STRATEGY", config.Strategy)
+
+	for _, entry := range strings.Split(getEnv("EMBEDDING_LONG_TEXT_MODELS", ""), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		separator := strings.LastIndex(entry, "=")
+		if separator <= 0 {
+			log.Printf("Warning: invalid EMBEDDING_LONG_TEXT_MODELS entry: %s", entry)
+			continue
+		}
+		limit, strategy, _ := strings.Cut(entry[separator+1:], ":")
+		maxTokens, err := strconv.Atoi(limit)
+		if err != nil || maxTokens <= 0 {
+			log.Printf("Warning: invalid token limit in EMBEDDING_LONG_TEXT_MODELS entry: %s", entry)
+			continue
+		}
+		config.Models[entry[:separator]] = LongTextModelConfig{MaxTokens: maxTokens, Strategy: strategy}
+	}
+	return config
+}
+
+// resolve 获取模型的超长文本配置：单独配置优先，其次为内置的模型上限，最后使用默认值
+func (c *LongTextEmbeddingConfig) resolve(embeddingModel string) LongTextModelConfig {
+	resolved := c.Models[embeddingModel]
+	if resolved.MaxTokens <= 0 {
+		resolved.MaxTokens = defaultEmbeddingModelLimits[embeddingModel]
+	}
+	if resolved.MaxTokens <= 0 {
+		resolved.MaxTokens = c.MaxTokens
+	}
+	if resolved.Strategy == "" {
+		resolved.Strategy = c.Strategy
+	}
+	switch resolved.Strategy {
+	case LongTextStrategyTruncate, LongTextStrategyMean, LongTextStrategyWeighted:
+	default:
+		log.Printf("Warning: unknown long text strategy %q for embedding model %s, using %s", resolved.Strategy, embeddingModel, LongTextStrategyWeighted)
+		resolved.Strategy = LongTextStrategyWeighted
+	}
+	return resolved
+}
+
+// longTextEmbeddingService 将超过模型token上限的文本按句子边界切分为多个窗口，
+// 分别生成向量后按配置的策略合并为一个向量，或只使用第一个窗口
+type longTextEmbeddingService struct {
+	provider  EmbeddingService
+	maxTokens int
+	strategy  string
+}
+
+// NewLongTextEmbeddingService 创建支持超长文本的嵌入服务实例，按底层服务的模型名称选择token上限和处理策略
+func NewLongTextEmbeddingService(provider EmbeddingService, config *LongTextEmbeddingConfig) EmbeddingService {
+	if config == nil {
+		config = DefaultLongTextEmbeddingConfig()
+	}
+	resolved := config.resolve(provider.ModelName())
+	return &longTextEmbeddingService{
+		provider:  provider,
+		maxTokens: windowTokenLimit(resolved.MaxTokens),
+		strategy:  resolved.Strategy,
+	}
+}
+
+// GenerateEmbedding 生成单个文本的嵌入向量
+func (s *longTextEmbeddingService) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// ModelName 返回底层嵌入服务的模型名称，合并后的向量与单个窗口的向量处于同一空间
+func (s *longTextEmbeddingService) ModelName() string {
+	return s.provider.ModelName()
+}
+
+// GenerateEmbeddings 将所有文本的窗口合并为一次批量请求，再按文本合并各窗口的向量
+func (s *longTextEmbeddingService) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	var inputs []string
+	var windowTokens []int
+	offsets := make([]int, len(contents)+1)
+	for i, content := range contents {
+		windows := []embeddingWindow{{Text: content, Tokens: 1}}
+		if estimateEmbeddingTokens(content) > s.maxTokens {
+			if split := splitEmbeddingWindows(content, s.maxTokens); len(split) > 0 {
+				windows = split
+			}
+			if s.strategy == LongTextStrategyTruncate && len(windows) > 1 {
+				log.Printf("Warning: Content truncated to the first of %d windows for embedding generation", len(windows))
+				windows = windows[:1]
+			}
+		}
+		for _, window := range windows {
+			inputs = append(inputs, window.Text)
+			windowTokens = append(windowTokens, window.Tokens)
+		}
+		offsets[i+1] = len(inputs)
+	}
+
+	windowEmbeddings, err := s.provider.GenerateEmbeddings(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(windowEmbeddings) != len(inputs) {
+		return nil, fmt.Errorf("embedding service returned %d vectors for %d windows", len(windowEmbeddings), len(inputs))
+	}
+
+	embeddings := make([][]float32, len(contents))
+	for i := range contents {
+		start, end := offsets[i], offsets[i+1]
+		if end-start == 1 {
+			embeddings[i] = windowEmbeddings[start]
+			continue
+		}
+		pooled, err := poolEmbeddings(windowEmbeddings[start:end], windowTokens[start:end], s.strategy == LongTextStrategyWeighted)
+		if err != nil {
+			return nil, fmt.Errorf("content %d: %w", i, err)
+		}
+		embeddings[i] = pooled
+	}
+	return embeddings, nil
+}
+
+// poolEmbeddings 合并窗口向量并L2归一化，weighted 为 true 时按窗口的token数加权
+func poolEmbeddings(vectors [][]float32, tokens []int, weighted bool) ([]float32, error) {
+	pooled := make([]float64, len(vectors[0]))
+	for i, vector := range vectors {
+		if len(vector) != len(pooled) {
+			return nil, fmt.Errorf("window vectors have different dimensions: %d and %d", len(pooled), len(vector))
+		}
+		weight := 1.0
+		if weighted && tokens[i] > 0 {
+			weight = float64(tokens[i])
+		}
+		for j, v := range vector {
+			pooled[j] += weight * float64(v)
+		}
+	}
+	return normalizeVector(pooled), nil
+}
+
+// windowTokenLimit 按安全比例计算窗口的token上限，至少为1
+func windowTokenLimit(maxTokens int) int {
+	limit := int(float64(maxTokens) * longTextSafetyRatio)
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// embeddingWindow 切分出的文本窗口
+type embeddingWindow struct {
+	Text   string
+	Tokens int // 估算的token数
+}
+
+// textPiece 估算token数时的最小切分单位，以在原文中的结束字节位置表示
+type textPiece struct {
+	end      int
+	tokens   int
+	boundary bool // 是否在句末标点或换行处结束，窗口优先在此处切分
+}
+
+// estimateEmbeddingTokens 估算文本在BPE分词下的token数，估算值偏大以保证窗口不超过模型上限
+func estimateEmbeddingTokens(text string) int {
+	tokens := 0
+	for _, piece := range scanTextPieces(text) {
+		tokens += piece.tokens
+	}
+	return tokens
+}
+
+// splitEmbeddingWindows 将文本切分为token数不超过 maxTokens 的窗口，只在字符边界切分，
+// 窗口已超过一半时优先在最后一个句末标点或换行处切分
+func splitEmbeddingWindows(text string, maxTokens int) []embeddingWindow {
+	pieces := scanTextPieces(text)
+	prefix := make([]int, len(pieces)+1)
+	for i, piece := range pieces {
+		prefix[i+1] = prefix[i] + piece.tokens
+	}
+	startOf := func(i int) int {
+		if i == 0 {
+			return 0
+		}
+		return pieces[i-1].end
+	}
+
+	var windows []embeddingWindow
+	addWindow := func(from, to int) {
+		if content := strings.TrimSpace(text[startOf(from):pieces[to-1].end]); content != "" {
+			windows = append(windows, embeddingWindow{Text: content, Tokens: prefix[to] - prefix[from]})
+		}
+	}
+
+	start, lastBoundary := 0, -1
+	for i := 0; i < len(pieces); i++ {
+		if prefix[i+1]-prefix[start] > maxTokens && i > start {
+			cut := i
+			if lastBoundary >= start && prefix[lastBoundary+1]-prefix[start] >= maxTokens/2 {
+				cut = lastBoundary + 1
+			}
+			addWindow(start, cut)
+			start, lastBoundary = cut, -1
+			i = cut - 1
+			continue
+		}
+		if pieces[i].boundary {
+			lastBoundary = i
+		}
+	}
+	if start < len(pieces) {
+		addWindow(start, len(pieces))
+	}
+	return windows
+}
+
+// truncateEmbeddingText 在字符边界截断文本，使估算的token数不超过 maxTokens
+func truncateEmbeddingText(text string, maxTokens int) (string, bool) {
+	if estimateEmbeddingTokens(text) <= maxTokens {
+		return text, false
+	}
+	windows := splitEmbeddingWindows(text, maxTokens)
+	if len(windows) == 0 {
+		return text, false
+	}
+	return windows[0].Text, true
+}
+
+// 估算token数时的字符类别
+const (
+	pieceSpace = iota
+	pieceNewline
+	pieceASCIILetter
+	pieceDigit
+	pieceHan
+	pieceLetter
+	pieceSymbol
+)
+
+// textPieceClass 判断字符的类别
+func textPieceClass(r rune) int {
+	switch {
+	case r == '\n':
+		return pieceNewline
+	case unicode.IsSpace(r):
+		return pieceSpace
+	case r < unicode.MaxASCII && unicode.IsLetter(r):
+		return pieceASCIILetter
+	case unicode.IsDigit(r):
+		return pieceDigit
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return pieceHan
+	case unicode.IsLetter(r) || unicode.IsMark(r):
+		return pieceLetter
+	default:
+		return pieceSymbol
+	}
+}
+
+// textPieceTokens 估算一段同类字符的token数
+// 英文短词约为1个token，长词约每4个字母1个token；数字每3位1个token；汉字等约每字1.5个token；其他符号每个1个token
+func textPieceTokens(class, runes int) int {
+	switch class {
+	case pieceSpace:
+		if runes == 1 {
+			return 0 // 单个空格与后面的词合并为一个token
+		}
+		return (runes + 3) / 4
+	case pieceNewline:
+		return 1
+	case pieceASCIILetter:
+		if runes <= 6 {
+			return 1
+		}
+		return (runes + 3) / 4
+	case pieceDigit:
+		return (runes + 2) / 3
+	case pieceHan:
+		return (runes*3 + 1) / 2
+	case pieceLetter:
+		return (runes + 1) / 2
+	default:
+		return runes
+	}
+}
+
+// isSentenceEnd 判断字符是否为句末标点
+func isSentenceEnd(r rune) bool {
+	return strings.ContainsRune(".!?;。！？；", r)
+}
+
+// scanTextPieces 将文本切分为同类字符组成的片段，每个片段最多 maxTextPieceRunes 个字符，
+// 汉字每两个字一个片段，符号每个一个片段
+func scanTextPieces(text string) []textPiece {
+	var pieces []textPiece
+	class, runes := -1, 0
+	var last rune
+	for offset, r := range text {
+		current := textPieceClass(r)
+		limit := maxTextPieceRunes
+		switch current {
+		case pieceHan:
+			limit = 2
+		case pieceSymbol:
+			limit = 1
+		}
+		if runes > 0 && (current != class || runes >= limit) {
+			pieces = append(pieces, textPiece{
+				end:      offset,
+				tokens:   textPieceTokens(class, runes),
+				boundary: class == pieceNewline || (class == pieceSymbol && isSentenceEnd(last)),
+			})
+			runes = 0
+		}
+		class = current
+		runes++
+		last = r
+	}
+	if runes > 0 {
+		pieces = append(pieces, textPiece{
+			end:      len(text),
+			tokens:   textPieceTokens(class, runes),
+			boundary: class == pieceNewline || (class == pieceSymbol && isSentenceEnd(last)),
+		})
+	}
+	return pieces
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// windowEmbeddingProvider 记录请求的窗口，向量为窗口中 alpha 和 beta 出现的次数
+type windowEmbeddingProvider struct {
+	inputs []string
+}
+
+func (p *windowEmbeddingProvider) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{content})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (p *windowEmbeddingProvider) GenerateEmbeddings(ctx context.Context, contents []string) ([][]float32, error) {
+	p.inputs = append(p.inputs, contents...)
+	embeddings := make([][]float32, len(contents))
+	for i, content := range contents {
+		embeddings[i] = []float32{float32(strings.Count(content, "alpha")), float32(strings.Count(content, "beta"))}
+	}
+	return embeddings, nil
+}
+
+func (p *windowEmbeddingProvider) ModelName() string {
+	return "window-model"
+}
+
+// TestSplitEmbeddingWindows 测试窗口不超过上限、不切断多字节字符并优先在句末切分
+func TestSplitEmbeddingWindows(t *testing.T) {
+	text := strings.Repeat("向量检索需要把文档切分为较短的片段。", 20) + "\n" + strings.Repeat("Configure the connection pool. ", 20)
+	windows := splitEmbeddingWindows(text, 50)
+	if len(windows) < 2 {
+		t.Fatalf("windows = %d, want several", len(windows))
+	}
+
+	var joined strings.Builder
+	for i, window := range windows {
+		if !utf8.ValidString(window.Text) {
+			t.Errorf("window %d is not valid UTF-8", i)
+		}
+		if window.Tokens > 50 || estimateEmbeddingTokens(window.Text) > 50 {
+			t.Errorf("window %d tokens = %d, want <= 50", i, window.Tokens)
+		}
+		if i < len(windows)-1 && !strings.HasSuffix(window.Text, "。") && !strings.HasSuffix(window.Text, ".") {
+			t.Errorf("window %d = %q, want cut at sentence end", i, window.Text)
+		}
+		joined.WriteString(window.Text)
+	}
+	strip := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	if strip(joined.String()) != strip(text) {
+		t.Error("windows should cover the whole text")
+	}
+
+	// 没有空格和标点的超长文本也能切分
+	for _, window := range splitEmbeddingWindows(strings.Repeat("x", 5000), 100) {
+		if window.Tokens > 100 {
+			t.Errorf("window tokens = %d, want <= 100", window.Tokens)
+		}
+	}
+}
+
+// TestEstimateEmbeddingTokens 测试token估算
+func TestEstimateEmbeddingTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello world", 2},
+		{"configuration", 4},
+		{"1234567", 3},
+		{"向量检索", 6},
+		{"a.b", 3},
+	}
+	for _, tt := range tests {
+		if got := estimateEmbeddingTokens(tt.text); got != tt.want {
+			t.Errorf("estimateEmbeddingTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestLongTextEmbeddingService 测试短文本原样请求，长文本按策略截断或合并窗口向量
+func TestLongTextEmbeddingService(t *testing.T) {
+	long := strings.Repeat("alpha. ", 30) + strings.Repeat("beta. ", 10)
+
+	newService := func(strategy string) (EmbeddingService, *windowEmbeddingProvider) {
+		provider := &windowEmbeddingProvider{}
+		config := DefaultLongTextEmbeddingConfig()
+		config.Models["window-model"] = LongTextModelConfig{MaxTokens: 50, Strategy: strategy}
+		return NewLongTextEmbeddingService(provider, config), provider
+	}
+
+	service, provider := newService(LongTextStrategyWeighted)
+	embeddings, err := service.GenerateEmbeddings(context.Background(), []string{"alpha beta", long})
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings() error = %v", err)
+	}
+	if provider.inputs[0] != "alpha beta" || embeddings[0][0] != 1 || embeddings[0][1] != 1 {
+		t.Errorf("short text should be sent unchanged, got %q -> %v", provider.inputs[0], embeddings[0])
+	}
+	if len(provider.inputs) < 3 {
+		t.Fatalf("inputs = %d, want long text split into windows", len(provider.inputs))
+	}
+	// 合并后的向量归一化，alpha 占多数
+	weighted := embeddings[1]
+	if norm := math.Hypot(float64(weighted[0]), float64(weighted[1])); math.Abs(norm-1) > 1e-6 || weighted[0] <= weighted[1] {
+		t.Errorf("weighted embedding = %v, want normalized and dominated by alpha", weighted)
+	}
+
+	service, _ = newService(LongTextStrategyMean)
+	mean, err := service.GenerateEmbedding(context.Background(), long)
+	if err != nil {
+		t.Fatalf("GenerateEmbedding() error = %v", err)
+	}
+	if math.Abs(float64(mean[0]-weighted[0])) < 1e-6 {
+		t.Errorf("mean %v should differ from weighted %v when windows differ in length", mean, weighted)
+	}
+
+	service, provider = newService(LongTextStrategyTruncate)
+	truncated, err := service.GenerateEmbedding(context.Background(), long)
+	if err != nil {
+		t.Fatalf("GenerateEmbedding() error = %v", err)
+	}
+	if len(provider.inputs) != 1 || truncated[1] != 0 || truncated[0] == 0 {
+		t.Errorf("inputs = %d, embedding = %v, want only the first window", len(provider.inputs), truncated)
+	}
+}
+
+// TestLongTextEmbeddingConfigResolve 测试按模型选择token上限和策略
+func TestLongTextEmbeddingConfigResolve(t *testing.T) {
+	t.Setenv("EMBEDDING_LONG_TEXT_STRATEGY", "mean")
+	t.Setenv("EMBEDDING_LONG_TEXT_MODELS", "ollama/bge-m3:latest=4096:truncate, tei@http://tei/embed=512, broken")
+	config := NewLongTextEmbeddingConfigFromEnv()
+
+	tests := []struct {
+		model string
+		want  LongTextModelConfig
+	}{
+		{"ollama/bge-m3:latest", LongTextModelConfig{MaxTokens: 4096, Strategy: LongTextStrategyTruncate}},
+		{"tei@http://tei/embed", LongTextModelConfig{MaxTokens: 512, Strategy: LongTextStrategyMean}},
+		{"ollama/mxbai-embed-large", LongTextModelConfig{MaxTokens: 512, Strategy: LongTextStrategyMean}},
+		{"custom-model", LongTextModelConfig{MaxTokens: openAIMaxInputTokens, Strategy: LongTextStrategyMean}},
+	}
+	for _, tt := range tests {
+		if got := config.resolve(tt.model); got != tt.want {
+			t.Errorf("resolve(%s) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+	if len(config.Models) != 2 {
+		t.Errorf("models = %v, want invalid entry skipped", config.Models)
+	}
+}
+
+// TestTruncateEmbeddingText 测试截断不会切断多字节字符
+func TestTruncateEmbeddingText(t *testing.T) {
+	text := strings.Repeat("中文内容", 5000)
+	truncated, ok := truncateEmbeddingText(text, 1000)
+	if !ok || !utf8.ValidString(truncated) || estimateEmbeddingTokens(truncated) > 1000 {
+		t.Errorf("truncated = %d bytes, ok = %v", len(truncated), ok)
+	}
+	if short, ok := truncateEmbeddingText("short", 1000); ok || short != "short" {
+		t.Errorf("short text should not be truncated")
+	}
+}
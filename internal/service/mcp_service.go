@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -25,6 +29,8 @@ const (
 	DefaultSearchResultLength = 1000
 	// SearchResultMaxLength 搜索结果最大长度限制（字符数）
 	SearchResultMaxLength = 2000
+	// DefaultSearchPerGroup 搜索结果按文档分组时每组默认返回的片段数量
+	DefaultSearchPerGroup = 2
 )
 
 // MCPService MCP服务接口
@@ -67,26 +73,32 @@ type mcpService struct {
 	documentService DocumentService
 	versionRepo     repository.DocumentVersionRepository
 	indexRepo       repository.SearchIndexRepository
+	analytics       SearchAnalyticsService
 }
 
+// mcpCallerKey 上下文中保存MCP调用方（API密钥ID）的键
+type mcpCallerKey struct{}
+
 // NewMCPService 创建MCP服务实例
-func NewMCPService(db *gorm.DB, searchService SearchService, documentService DocumentService, versionRepo repository.DocumentVersionRepository, indexRepo repository.SearchIndexRepository) MCPService {
+func NewMCPService(db *gorm.DB, searchService SearchService, documentService DocumentService, versionRepo repository.DocumentVersionRepository, indexRepo repository.SearchIndexRepository, analytics SearchAnalyticsService) MCPService {
 	return &mcpService{
 		db:              db,
 		searchService:   searchService,
 		documentService: documentService,
 		versionRepo:     versionRepo,
 		indexRepo:       indexRepo,
+		analytics:       analytics,
 	}
 }
 
 // HandleRequest 处理MCP请求
 func (s *mcpService) HandleRequest(ctx context.Context, req *model.MCPRequest, apiKey string) (*model.MCPResponse, error) {
 	// 验证API密钥
-	_, err := s.ValidateAPIKey(apiKey)
+	key, err := s.ValidateAPIKey(apiKey)
 	if err != nil {
 		return s.createErrorResponse(req.ID, req.Method, -32600, "Invalid API key", nil)
 	}
+	ctx = context.WithValue(ctx, mcpCallerKey{}, key.ID)
 
 	switch req.Method {
 	case "initialize":
@@ -100,6 +112,12 @@ func (s *mcpService) HandleRequest(ctx context.Context, req *model.MCPRequest, a
 	}
 }
 
+// mcpCaller 返回上下文中的MCP调用方（API密钥ID），未经 HandleRequest 时为空
+func mcpCaller(ctx context.Context) string {
+	caller, _ := ctx.Value(mcpCallerKey{}).(string)
+	return caller
+}
+
 // Initialize 初始化MCP连接
 func (s *mcpService) Initialize(ctx context.Context, params *model.MCPInitializeParams) (*model.MCPInitializeResult, error) {
 	log.Printf("Initializing MCP connection for client: %s %s", params.ClientInfo.Name, params.ClientInfo.Version)
@@ -131,7 +149,7 @@ func (s *mcpService) ListTools(ctx context.Context, params *model.MCPToolListPar
 				"properties": map[string]interface{}{
 					"query": map[string]interface{}{
 						"type":        "string",
-						"description": "搜索查询关键词（多个关键词用空格分隔，可提高搜索精度）",
+						"description": "搜索查询。多个关键词用空格分隔；\"...\" 表示短语；支持 AND、OR、NOT（或 -关键词）和括号；以 * 结尾表示前缀匹配；支持字段限定符 library:、version:、section:、type:、content_type:、language:、document_id:，如 \"connection pool\" -deprecated library:gorm",
 					},
 					"types": map[string]interface{}{
 						"type": "array",
@@ -142,16 +160,54 @@ func (s *mcpService) ListTools(ctx context.Context, params *model.MCPToolListPar
 					},
 					"version": map[string]interface{}{
 						"type":        "string",
-						"description": "文档版本过滤器",
+						"description": "文档版本过滤器：精确版本号（如 1.2.3），语义化版本范围（如 >=1.2 <2.0、~1.4、^3、1.x），或 latest（最高版本）、latest-stable（最高正式版本），范围和别名按库分别解析为满足条件的最高版本",
+					},
+					"content_type": map[string]interface{}{
+						"type":        "string",
+						"description": "内容类型过滤器，text 为正文，code 为代码块",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "代码语言过滤器，如 go, python, bash，仅匹配代码块",
 					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
-						"description": "返回结果数量限制，默认为10",
+						"description": "返回结果数量限制（分组时为返回的组数），默认为10",
+					},
+					"hybrid_mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{HybridModeRRF, HybridModeWeighted},
+						"description": "关键词和语义搜索结果的融合方式：rrf（倒数排名融合）或 weighted（归一化加权），默认使用服务端配置",
+					},
+					"hybrid_alpha": map[string]interface{}{
+						"type":        "number",
+						"description": "语义搜索的权重（0-1），关键词搜索的权重为 1-hybrid_alpha，默认使用服务端配置",
+					},
+					"rrf_k": map[string]interface{}{
+						"type":        "integer",
+						"description": "RRF 的平滑常数 k，越大排名靠后的结果影响越大，默认使用服务端配置",
 					},
 					"content_length": map[string]interface{}{
 						"type":        "integer",
 						"description": fmt.Sprintf("每个搜索结果的内容片段最大字符数，默认为%d，最大为%d", DefaultSearchResultLength, SearchResultMaxLength),
 					},
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "分页游标，传入上一次搜索返回的下一页游标以获取后续结果，其他参数需与上一次搜索一致",
+					},
+					"auto_correct": map[string]interface{}{
+						"type":        "boolean",
+						"description": "关键词命中过少且有拼写建议时，自动使用纠正后的查询重新搜索，默认为false",
+					},
+					"group_by": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{model.GroupByDocument, model.GroupByLibrary, "none"},
+						"description": "结果分组方式：document_id（默认，按文档折叠，避免同一文档的片段占满结果）、library（按库折叠）或 none（不分组）。分组时 limit 为返回的组数",
+					},
+					"per_group": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("分组时每组返回的片段数量，默认为%d，最大为20", DefaultSearchPerGroup),
+					},
 				},
 				"required": []string{"query"},
 			},
@@ -174,6 +230,10 @@ func (s *mcpService) ListTools(ctx context.Context, params *model.MCPToolListPar
 						"type":        "integer",
 						"description": "每页数量，默认为10",
 					},
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "分页游标，传入上一次返回的下一页游标以获取后续文档版本，传入后忽略 page",
+					},
 				},
 				"required": []string{"library"},
 			},
@@ -216,6 +276,55 @@ func (s *mcpService) ListTools(ctx context.Context, params *model.MCPToolListPar
 				"required": []string{"document_id"},
 			},
 		},
+		{
+			Name:        "find_similar",
+			Description: "查找与某个搜索结果片段或文档相似的内容（more like this），默认排除同一文档中的片段，可用于从一个有用的结果扩展到相关资料而无需重新构造查询",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "源片段的搜索索引ID（search_documents 返回的文档ID），优先于 document_id",
+					},
+					"document_id": map[string]interface{}{
+						"type":        "string",
+						"description": "源文档ID，使用该文档版本全部片段的平均向量查找相似内容",
+					},
+					"source_version": map[string]interface{}{
+						"type":        "string",
+						"description": "源文档版本号（仅在提供 document_id 时有效，未指定时使用最新版本）",
+					},
+					"library": map[string]interface{}{
+						"type":        "string",
+						"description": "结果所属库过滤器",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "结果版本过滤器：精确版本号、语义化版本范围或 latest、latest-stable",
+					},
+					"content_type": map[string]interface{}{
+						"type":        "string",
+						"description": "结果内容类型过滤器，text 为正文，code 为代码块",
+					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "结果代码语言过滤器，如 go, python, bash",
+					},
+					"include_same_document": map[string]interface{}{
+						"type":        "boolean",
+						"description": "是否包含与源同一文档中的片段，默认为false",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "返回结果数量限制，默认为10，最大为100",
+					},
+					"content_length": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("每个结果的内容片段最大字符数，默认为%d，最大为%d", DefaultSearchResultLength, SearchResultMaxLength),
+					},
+				},
+			},
+		},
 	}
 
 	return &model.MCPToolListResult{
@@ -232,6 +341,8 @@ func (s *mcpService) CallTool(ctx context.Context, params *model.MCPToolCallPara
 		return s.getDocumentsByLibraryTool(ctx, params.Arguments)
 	case "get_document_content":
 		return s.getDocumentContentTool(ctx, params.Arguments)
+	case "find_similar":
+		return s.findSimilarTool(ctx, params.Arguments)
 	default:
 		return &model.MCPToolResult{
 			Content: []interface{}{
@@ -442,6 +553,47 @@ func (s *mcpService) handleCallTool(ctx context.Context, req *model.MCPRequest)
 	return s.createSuccessResponse(req.ID, "tools/call", result)
 }
 
+// matchCharRange 返回查询词在分块内容中第一次出现的位置，chunkStart 为分块在原文档中的起始字符位置，
+// 返回的位置均为原文档中的字符偏移；找不到查询词时返回整个分块的范围
+func matchCharRange(content, query string, chunkStart int) (int, int) {
+	if query != "" {
+		// strings.ToLower 逐字符转换，不改变字符数，可以直接在小写内容上计算字符偏移
+		contentLower := strings.ToLower(content)
+		if matchIndex := strings.Index(contentLower, strings.ToLower(query)); matchIndex != -1 {
+			start := chunkStart + utf8.RuneCountInString(contentLower[:matchIndex])
+			return start, start + utf8.RuneCountInString(query)
+		}
+	}
+	return chunkStart, chunkStart + utf8.RuneCountInString(content)
+}
+
+// parseHybridArgs 解析混合搜索的融合参数，参数类型或取值不合法时返回说明参数及允许范围的错误信息
+func parseHybridArgs(args map[string]interface{}, request *model.SearchRequest) string {
+	if value, exists := args["hybrid_mode"]; exists {
+		mode, ok := value.(string)
+		if !ok || (mode != HybridModeRRF && mode != HybridModeWeighted) {
+			return fmt.Sprintf("hybrid_mode 只能为 %s 或 %s", HybridModeRRF, HybridModeWeighted)
+		}
+		request.HybridMode = mode
+	}
+	if value, exists := args["hybrid_alpha"]; exists {
+		alpha, ok := value.(float64)
+		if !ok || math.IsNaN(alpha) || alpha < 0 || alpha > 1 {
+			return "hybrid_alpha 必须是 0 到 1 之间的数字"
+		}
+		request.HybridAlpha = &alpha
+	}
+	if value, exists := args["rrf_k"]; exists {
+		rrfK, ok := value.(float64)
+		if !ok || rrfK < 1 || rrfK != math.Trunc(rrfK) || rrfK > math.MaxInt32 {
+			return "rrf_k 必须是大于等于 1 的整数"
+		}
+		k := int(rrfK)
+		request.RRFK = &k
+	}
+	return ""
+}
+
 // searchDocumentsTool 搜索文档工具
 func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]interface{}) (*model.MCPToolResult, error) {
 	// 解析参数
@@ -468,6 +620,8 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 	}
 
 	version, _ := args["version"].(string)
+	contentType, _ := args["content_type"].(string)
+	language, _ := args["language"].(string)
 
 	limit := 10 // 默认限制
 	if limitArg, ok := args["limit"].(float64); ok {
@@ -495,6 +649,12 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 	if version != "" {
 		filters["version"] = version
 	}
+	if contentType != "" {
+		filters["content_type"] = contentType
+	}
+	if language != "" {
+		filters["language"] = language
+	}
 
 	searchRequest := &model.SearchRequest{
 		Query:      query,
@@ -503,9 +663,87 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 		Size:       limit,
 		SearchType: "hybrid", // 默认使用混合搜索
 	}
+	searchRequest.Cursor, _ = args["cursor"].(string)
+	searchRequest.AutoCorrect, _ = args["auto_correct"].(bool)
+	if message := parseHybridArgs(args, searchRequest); message != "" {
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: message,
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// 默认按文档分组，使结果覆盖更多不同的来源
+	groupBy, _ := args["group_by"].(string)
+	switch groupBy {
+	case "":
+		searchRequest.GroupBy = model.GroupByDocument
+	case "none":
+	default:
+		if !model.IsValidGroupBy(groupBy) {
+			return &model.MCPToolResult{
+				Content: []interface{}{
+					model.MCPTextContent{
+						Type: "text",
+						Text: "group_by 只能为 document_id、library 或 none",
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		searchRequest.GroupBy = groupBy
+	}
+	if searchRequest.GroupBy != "" {
+		searchRequest.PerGroup = DefaultSearchPerGroup
+		if perGroup, ok := args["per_group"].(float64); ok && perGroup >= 1 && perGroup <= 20 {
+			searchRequest.PerGroup = int(perGroup)
+		}
+	}
 
 	// 调用搜索服务
+	start := time.Now()
 	searchResult, err := s.searchService.Search(ctx, searchRequest)
+	if s.analytics != nil {
+		s.analytics.RecordSearch(model.SearchSourceMCP, mcpCaller(ctx), searchRequest, searchResult, err, time.Since(start))
+	}
+	var parseErr *QueryParseError
+	if errors.As(err, &parseErr) {
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: fmt.Sprintf("查询语法错误（位置 %d）: %s", parseErr.Position, parseErr.Message),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if errors.Is(err, ErrInvalidVersionRange) {
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: fmt.Sprintf("版本范围无效: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if errors.Is(err, model.ErrInvalidCursor) {
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: "游标无效或与当前查询不匹配，请使用与上一次搜索相同的参数",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
 	if err != nil {
 		return &model.MCPToolResult{
 			Content: []interface{}{
@@ -521,23 +759,8 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 	// 转换搜索结果为MCP格式
 	var documents []model.MCPSearchDocument
 	for _, item := range searchResult.Items {
-		// 计算关键词在文档内容中的实际位置
-		actualStartPos := 0
-		actualEndPos := len(item.Content)
-
-		if query != "" && item.Content != "" {
-			// 在内容中查找查询词的位置
-			queryLower := strings.ToLower(query)
-			contentLower := strings.ToLower(item.Content)
-
-			// 查找第一个匹配位置
-			matchIndex := strings.Index(contentLower, queryLower)
-			if matchIndex != -1 {
-				// 找到匹配，计算精确的起始和结束位置
-				actualStartPos = matchIndex
-				actualEndPos = matchIndex + len(query)
-			}
-		}
+		// 计算关键词在文档内容中的实际位置（字符偏移）
+		actualStartPos, actualEndPos := matchCharRange(item.Content, query, chunkStartChar(item.Metadata))
 
 		// 获取文档名称（优先使用Title，如果Title为空则使用Section）
 		documentName := item.Title
@@ -545,6 +768,8 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 			documentName = item.Section
 		}
 
+		codeLanguage, _ := item.Metadata["language"].(string)
+
 		documents = append(documents, model.MCPSearchDocument{
 			ID:            item.ID,         // 搜索索引ID，每个代码片段的唯一标识
 			DocumentID:    item.DocumentID, // 文档ID，用于获取完整文档
@@ -557,18 +782,53 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 			Snippet:       item.Snippet, // 包含查询关键词的上下文片段
 			ContentType:   item.ContentType,
 			Section:       item.Section,
+			Language:      codeLanguage,
 			StartPosition: actualStartPos, // 关键词在内容中的实际起始位置
 			EndPosition:   actualEndPos,   // 关键词在内容中的实际结束位置
 		})
 	}
 
 	// 构造结果文本
-	resultText := fmt.Sprintf("搜索查询: %s\n找到 %d 个相关文档:\n\n", query, len(documents))
+	resultText := fmt.Sprintf("搜索查询: %s\n", query)
+	if searchResult.Degraded {
+		resultText += "注意: 嵌入服务不可用，本次结果仅基于关键词匹配\n"
+	}
+	if searchResult.CorrectedQuery != "" {
+		resultText += fmt.Sprintf("原查询结果过少，已自动纠正为: %s\n", searchResult.CorrectedQuery)
+	} else if searchResult.SuggestedQuery != "" {
+		resultText += fmt.Sprintf("您是不是要找: %s（可设置 auto_correct=true 自动使用纠正后的查询）\n", searchResult.SuggestedQuery)
+	}
+	// 分组时在每组第一个片段前显示组信息
+	groupStarts := make(map[int]model.SearchResultGroup, len(searchResult.Groups))
+	position := 0
+	for _, group := range searchResult.Groups {
+		groupStarts[position] = group
+		position += len(group.Items)
+	}
+	if len(searchResult.Groups) > 0 {
+		resultText += fmt.Sprintf("找到 %d 个相关来源（共 %d 个匹配片段），当前返回 %d 个来源的 %d 个片段:\n\n", searchResult.Total, searchResult.TotalHits, len(searchResult.Groups), len(documents))
+	} else {
+		resultText += fmt.Sprintf("找到 %d 个相关文档:\n\n", len(documents))
+	}
 	totalTokens := 0
 	for i, doc := range documents {
+		if group, ok := groupStarts[i]; ok {
+			key := group.Key
+			if key == "" {
+				key = "未知"
+			}
+			resultText += fmt.Sprintf("== 来源: %s (最高相关度: %.2f, 命中片段: %d) ==\n", key, group.Score, group.Hits)
+		}
 		resultText += fmt.Sprintf("%d. %s (版本: %s, 类型: %s)\n", i+1, doc.Name, doc.Version, doc.Type)
 		resultText += fmt.Sprintf("   文档ID: %s\n", doc.ID) // 添加文档ID，方便后续调用get_document_content
+		resultText += fmt.Sprintf("   所属文档: %s\n", doc.DocumentID)
 		resultText += fmt.Sprintf("   所属库: %s\n", doc.Library)
+		if doc.Section != "" {
+			resultText += fmt.Sprintf("   章节: %s\n", doc.Section)
+		}
+		if doc.Language != "" {
+			resultText += fmt.Sprintf("   代码语言: %s\n", doc.Language)
+		}
 		resultText += fmt.Sprintf("   相关度: %.2f\n", doc.Score)
 		// 显示位置信息
 		if doc.StartPosition > 0 || doc.EndPosition > 0 {
@@ -583,9 +843,108 @@ func (s *mcpService) searchDocumentsTool(ctx context.Context, args map[string]in
 		totalTokens += tokens
 		resultText += fmt.Sprintf("   内容片段: %s\n   估算Token数: %d\n\n", truncatedSnippet, tokens)
 	}
-	// 记录总Token使用情况
+	if searchResult.NextCursor != "" {
+		resultText += fmt.Sprintf("下一页游标: %s\n提示: 使用 cursor 参数并保持其他参数不变可以获取后续结果\n", searchResult.NextCursor)
+	}
+	// 记录总Token使用情况，没有结果时平均值为0
+	avgTokens := 0
+	if len(documents) > 0 {
+		avgTokens = totalTokens / len(documents)
+	}
 	log.Printf("INFO: Search results - totalDocuments: %d, totalEstimatedTokens: %d, avgTokensPerDoc: %d",
-		len(documents), totalTokens, totalTokens/len(documents))
+		len(documents), totalTokens, avgTokens)
+
+	return &model.MCPToolResult{
+		Content: []interface{}{
+			model.MCPTextContent{
+				Type: "text",
+				Text: resultText,
+			},
+		},
+		IsError: false,
+	}, nil
+}
+
+// findSimilarTool 查找相似内容工具
+func (s *mcpService) findSimilarTool(ctx context.Context, args map[string]interface{}) (*model.MCPToolResult, error) {
+	request := &model.SimilarRequest{Filters: make(map[string]interface{})}
+	request.IndexID, _ = args["id"].(string)
+	request.DocumentID, _ = args["document_id"].(string)
+	request.SourceVersion, _ = args["source_version"].(string)
+	request.IncludeSameDocument, _ = args["include_same_document"].(bool)
+	if request.IndexID == "" && request.DocumentID == "" {
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: "id 和 document_id 至少需要提供一个",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	for _, key := range []string{"library", "version", "content_type", "language"} {
+		if value, ok := args[key].(string); ok && value != "" {
+			request.Filters[key] = value
+		}
+	}
+
+	request.Size = 10
+	if limitArg, ok := args["limit"].(float64); ok && limitArg > 0 {
+		request.Size = int(limitArg)
+	}
+	resultContentLength := DefaultSearchResultLength
+	if contentLengthArg, ok := args["content_length"].(float64); ok && contentLengthArg > 0 {
+		resultContentLength = int(contentLengthArg)
+		if resultContentLength > SearchResultMaxLength {
+			resultContentLength = SearchResultMaxLength
+		}
+	}
+
+	similar, err := s.searchService.FindSimilar(ctx, request)
+	if err != nil {
+		text := fmt.Sprintf("查找相似内容失败: %v", err)
+		switch {
+		case errors.Is(err, ErrSimilarSourceNotFound):
+			text = "源片段或文档不存在，或文档尚未建立索引"
+		case errors.Is(err, ErrInvalidVersionRange):
+			text = fmt.Sprintf("版本范围无效: %v", err)
+		}
+		return &model.MCPToolResult{
+			Content: []interface{}{
+				model.MCPTextContent{
+					Type: "text",
+					Text: text,
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	source := request.IndexID
+	if source == "" {
+		source = request.DocumentID
+	}
+	resultText := fmt.Sprintf("与 %s 相似的内容，找到 %d 个结果:\n\n", source, len(similar.Items))
+	for i, item := range similar.Items {
+		name := item.Title
+		if name == "" {
+			name = item.Section
+		}
+		resultText += fmt.Sprintf("%d. %s (版本: %s, 类型: %s)\n", i+1, name, item.Version, item.ContentType)
+		resultText += fmt.Sprintf("   文档ID: %s\n", item.ID)
+		resultText += fmt.Sprintf("   所属文档: %s\n", item.DocumentID)
+		resultText += fmt.Sprintf("   所属库: %s\n", item.Library)
+		if item.Section != "" {
+			resultText += fmt.Sprintf("   章节: %s\n", item.Section)
+		}
+		if language, _ := item.Metadata["language"].(string); language != "" {
+			resultText += fmt.Sprintf("   代码语言: %s\n", language)
+		}
+		resultText += fmt.Sprintf("   相似度: %.2f\n", item.Score)
+		snippet := s.truncateText(item.Snippet, resultContentLength)
+		resultText += fmt.Sprintf("   内容片段: %s\n   估算Token数: %d\n\n", snippet, s.estimateTokens(snippet))
+	}
 
 	return &model.MCPToolResult{
 		Content: []interface{}{
@@ -624,7 +983,27 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 		size = int(sizeArg)
 	}
 
-	log.Printf("DEBUG: getDocumentsByLibraryTool - library=%s, page=%d, size=%d", library, page, size)
+	// 游标与库名称绑定，防止用于其他库
+	cursorToken, _ := args["cursor"].(string)
+	fingerprint := cursorFingerprint(map[string]interface{}{"library": library})
+	var after *model.Cursor
+	if cursorToken != "" {
+		decoded, err := model.DecodeCursor(cursorToken, fingerprint)
+		if err != nil {
+			return &model.MCPToolResult{
+				Content: []interface{}{
+					model.MCPTextContent{
+						Type: "text",
+						Text: "游标无效或与当前库不匹配",
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		after = decoded
+	}
+
+	log.Printf("DEBUG: getDocumentsByLibraryTool - library=%s, page=%d, size=%d, cursor=%t", library, page, size, after != nil)
 
 	// 首先获取该库的所有文档
 	filters := map[string]interface{}{}
@@ -655,14 +1034,21 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 		allVersions = append(allVersions, versions...)
 	}
 
+	// 按创建时间倒序排列，保证分页顺序稳定
+	sortVersionsForCursor(allVersions)
+
 	// 计算总数和分页
 	total := int64(len(allVersions))
 	totalPages := (total + int64(size) - 1) / int64(size)
 
-	// 计算偏移量
+	// 计算偏移量，使用游标时从游标之后的第一个版本开始
 	startIndex := (page - 1) * size
+	if after != nil {
+		startIndex = versionsAfter(allVersions, after)
+		page = after.Offset/size + 1
+	}
 	endIndex := startIndex + size
-	if startIndex >= len(allVersions) {
+	if after == nil && startIndex >= len(allVersions) {
 		return &model.MCPToolResult{
 			Content: []interface{}{
 				model.MCPTextContent{
@@ -678,8 +1064,17 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 	}
 
 	// 获取当前页的版本
+	if startIndex > endIndex {
+		startIndex = endIndex
+	}
 	pageVersions := allVersions[startIndex:endIndex]
 
+	// 序号从已返回的数量开始
+	returned := (page - 1) * size
+	if after != nil {
+		returned = after.Offset
+	}
+
 	log.Printf("DEBUG: getDocumentsByLibraryTool - totalVersions=%d, pageVersions=%d, totalPages=%d", total, len(pageVersions), totalPages)
 
 	// 构造结果文本
@@ -690,14 +1085,14 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 		doc, err := s.documentService.GetDocument(ctx, version.DocumentID)
 		if err != nil {
 			log.Printf("WARNING: getDocumentsByLibraryTool - Failed to get document %s: %v", version.DocumentID, err)
-			resultText += fmt.Sprintf("%d. 文档版本\n", (page-1)*size+i+1)
+			resultText += fmt.Sprintf("%d. 文档版本\n", returned+i+1)
 			resultText += fmt.Sprintf("   版本ID: %s\n", version.ID)
 			resultText += fmt.Sprintf("   版本号: %s\n", version.Version)
 			resultText += fmt.Sprintf("   状态: %s\n", version.Status)
 			resultText += fmt.Sprintf("   文件大小: %d bytes\n", version.FileSize)
 			resultText += fmt.Sprintf("   文档信息获取失败\n\n")
 		} else {
-			resultText += fmt.Sprintf("%d. %s (版本: %s)\n", (page-1)*size+i+1, doc.Name, version.Version)
+			resultText += fmt.Sprintf("%d. %s (版本: %s)\n", returned+i+1, doc.Name, version.Version)
 			resultText += fmt.Sprintf("   文档ID: %s\n", doc.ID)
 			resultText += fmt.Sprintf("   版本ID: %s\n", version.ID)
 			resultText += fmt.Sprintf("   版本号: %s\n", version.Version)
@@ -712,8 +1107,17 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 	// 添加分页信息提示
 	if totalPages > 1 {
 		resultText += fmt.Sprintf("\n分页信息: 共 %d 页，当前第 %d 页\n", totalPages, page)
-		if page < int(totalPages) {
-			resultText += fmt.Sprintf("提示: 可以使用 page=%d 查看下一页\n", page+1)
+		if endIndex < len(allVersions) && len(pageVersions) > 0 {
+			last := pageVersions[len(pageVersions)-1]
+			createdAt := last.CreatedAt
+			nextCursor := &model.Cursor{
+				CreatedAt: &createdAt,
+				ID:        last.ID,
+				Offset:    returned + len(pageVersions),
+				Query:     fingerprint,
+			}
+			resultText += fmt.Sprintf("下一页游标: %s\n", nextCursor.Encode())
+			resultText += fmt.Sprintf("提示: 可以使用 cursor 参数或 page=%d 查看下一页\n", page+1)
 		}
 	}
 
@@ -730,6 +1134,29 @@ func (s *mcpService) getDocumentsByLibraryTool(ctx context.Context, args map[str
 	}, nil
 }
 
+// sortVersionsForCursor 按创建时间倒序排列文档版本，创建时间相同时按ID倒序，与文档列表的游标顺序一致
+func sortVersionsForCursor(versions []*model.DocumentVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].CreatedAt.Equal(versions[j].CreatedAt) {
+			return versions[i].CreatedAt.After(versions[j].CreatedAt)
+		}
+		return versions[i].ID > versions[j].ID
+	})
+}
+
+// versionsAfter 返回游标之后第一个版本的下标，versions 须已按 sortVersionsForCursor 排序
+func versionsAfter(versions []*model.DocumentVersion, after *model.Cursor) int {
+	if after.CreatedAt == nil {
+		return 0
+	}
+	return sort.Search(len(versions), func(i int) bool {
+		if !versions[i].CreatedAt.Equal(*after.CreatedAt) {
+			return versions[i].CreatedAt.Before(*after.CreatedAt)
+		}
+		return versions[i].ID < after.ID
+	})
+}
+
 // getDocumentContentTool 获取文档内容工具
 func (s *mcpService) getDocumentContentTool(ctx context.Context, args map[string]interface{}) (*model.MCPToolResult, error) {
 	// 解析参数 - document_id 可以是文档ID或版本ID
@@ -748,6 +1175,11 @@ func (s *mcpService) getDocumentContentTool(ctx context.Context, args map[string
 
 	version, _ := args["version"].(string)
 
+	// 搜索后获取片段或文档内容视为对搜索结果的点击
+	if s.analytics != nil {
+		s.analytics.RecordClick(mcpCaller(ctx), documentID)
+	}
+
 	// 解析内容长度限制参数，默认使用 DefaultContentMaxLength
 	maxContentLength := DefaultContentMaxLength
 	if contentLengthArg, ok := args["content_length"].(float64); ok {
@@ -791,9 +1223,9 @@ func (s *mcpService) getDocumentContentTool(ctx context.Context, args map[string
 		// 成功作为搜索索引ID获取（片段）
 		log.Printf("DEBUG: Retrieved search index by ID: %s (fragment content)", documentID)
 		content := docIndex.Content
-		originalLength := len(content)
+		originalLength := utf8.RuneCountInString(content)
 
-		// 确定要获取的内容范围
+		// 确定要获取的内容范围（字符位置）
 		actualStartPos := 0
 		actualEndPos := originalLength
 
@@ -824,7 +1256,8 @@ func (s *mcpService) getDocumentContentTool(ctx context.Context, args map[string
 		if extractEnd > originalLength {
 			extractEnd = originalLength
 		}
-		extractedContent := content[actualStartPos:extractEnd]
+		startByte := advanceRunes(content, 0, len(content), actualStartPos)
+		extractedContent := content[startByte:advanceRunes(content, startByte, len(content), extractEnd-actualStartPos)]
 
 		// 检查内容长度并记录警告
 		if len(extractedContent) > WarningContentLength {
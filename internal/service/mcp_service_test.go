@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestSearchDocumentsToolRejectsInvalidHybridArgs 测试搜索工具拒绝不合法的混合搜索融合参数
+func TestSearchDocumentsToolRejectsInvalidHybridArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		argument string
+	}{
+		{name: "未知融合方式", args: map[string]interface{}{"hybrid_mode": "max"}, argument: "hybrid_mode"},
+		{name: "融合方式类型错误", args: map[string]interface{}{"hybrid_mode": 1.0}, argument: "hybrid_mode"},
+		{name: "权重小于0", args: map[string]interface{}{"hybrid_alpha": -0.1}, argument: "hybrid_alpha"},
+		{name: "权重大于1", args: map[string]interface{}{"hybrid_alpha": 1.5}, argument: "hybrid_alpha"},
+		{name: "权重类型错误", args: map[string]interface{}{"hybrid_alpha": "0.5"}, argument: "hybrid_alpha"},
+		{name: "k为0", args: map[string]interface{}{"rrf_k": 0.0}, argument: "rrf_k"},
+		{name: "k为负数", args: map[string]interface{}{"rrf_k": -60.0}, argument: "rrf_k"},
+		{name: "k不是整数", args: map[string]interface{}{"rrf_k": 2.5}, argument: "rrf_k"},
+	}
+
+	service := &mcpService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["query"] = "connection pool"
+			result, err := service.searchDocumentsTool(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("searchDocumentsTool() error = %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("searchDocumentsTool() IsError = false, want true")
+			}
+			text := result.Content[0].(model.MCPTextContent).Text
+			if !strings.Contains(text, tt.argument) {
+				t.Errorf("error message %q does not name %s", text, tt.argument)
+			}
+		})
+	}
+}
+
+// TestParseHybridArgs 测试合法的融合参数写入搜索请求
+func TestParseHybridArgs(t *testing.T) {
+	request := &model.SearchRequest{}
+	args := map[string]interface{}{"hybrid_mode": HybridModeWeighted, "hybrid_alpha": 0.0, "rrf_k": 60.0}
+	if message := parseHybridArgs(args, request); message != "" {
+		t.Fatalf("parseHybridArgs() = %q, want no error", message)
+	}
+	if request.HybridMode != HybridModeWeighted {
+		t.Errorf("HybridMode = %q, want %q", request.HybridMode, HybridModeWeighted)
+	}
+	if request.HybridAlpha == nil || *request.HybridAlpha != 0 {
+		t.Errorf("HybridAlpha = %v, want 0", request.HybridAlpha)
+	}
+	if request.RRFK == nil || *request.RRFK != 60 {
+		t.Errorf("RRFK = %v, want 60", request.RRFK)
+	}
+
+	empty := &model.SearchRequest{}
+	if message := parseHybridArgs(map[string]interface{}{}, empty); message != "" {
+		t.Fatalf("parseHybridArgs() = %q, want no error", message)
+	}
+	if empty.HybridMode != "" || empty.HybridAlpha != nil || empty.RRFK != nil {
+		t.Errorf("omitted arguments should keep server defaults, got %+v", empty)
+	}
+}
+
+// TestMatchCharRange 测试查询词位置按字符计算，不受多字节字符影响
+func TestMatchCharRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		query     string
+		wantStart int
+		wantEnd   int
+	}{
+		{name: "英文", content: "use the Connection pool", query: "connection", wantStart: 108, wantEnd: 118},
+		{name: "中文前缀", content: "配置连接池 connection pool", query: "connection pool", wantStart: 106, wantEnd: 121},
+		{name: "中文查询", content: "修改配置文件后重启服务", query: "重启", wantStart: 107, wantEnd: 109},
+		{name: "未找到时返回整个分块", content: "配置文件", query: "docker", wantStart: 100, wantEnd: 104},
+		{name: "空查询", content: "配置文件", query: "", wantStart: 100, wantEnd: 104},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := matchCharRange(tt.content, tt.query, 100)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("matchCharRange() = (%d, %d), want (%d, %d)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// vectorConstraint 返回语义搜索结果也必须满足的条件：排除条件、短语和用 AND 连接的条件。
+// 并列的普通关键词只用于生成查询向量，不作为必须满足的条件；没有这类条件时返回 nil
+func vectorConstraint(node *model.QueryNode) *model.QueryNode {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Type {
+	case model.QueryNodeNot, model.QueryNodePhrase:
+		return node
+	case model.QueryNodeAnd:
+		// 肯定条件多于一个说明是查询中显式的 AND，全部条件都必须满足；
+		// 否则是并列的关键词与排除条件，只保留各子节点中必须满足的部分
+		positives := 0
+		for _, child := range node.Children {
+			if child.Type != model.QueryNodeNot {
+				positives++
+			}
+		}
+		if positives > 1 {
+			return node
+		}
+		var children []*model.QueryNode
+		for _, child := range node.Children {
+			if constraint := vectorConstraint(child); constraint != nil {
+				children = append(children, constraint)
+			}
+		}
+		return combineConstraints(model.QueryNodeAnd, children)
+	case model.QueryNodeOr:
+		// 任意一个分支没有必须满足的条件时，整个 OR 都不构成约束
+		children := make([]*model.QueryNode, 0, len(node.Children))
+		for _, child := range node.Children {
+			constraint := vectorConstraint(child)
+			if constraint == nil {
+				return nil
+			}
+			children = append(children, constraint)
+		}
+		return combineConstraints(model.QueryNodeOr, children)
+	}
+	return nil
+}
+
+// combineConstraints 合并子条件，只有一个子条件时直接返回
+func combineConstraints(nodeType model.QueryNodeType, children []*model.QueryNode) *model.QueryNode {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return &model.QueryNode{Type: nodeType, Children: children}
+}
+
+// matchesConstraint 判断文本是否满足查询条件，关键词和短语的匹配规则与命中片段一致
+func matchesConstraint(node *model.QueryNode, text string) bool {
+	switch node.Type {
+	case model.QueryNodeAnd:
+		for _, child := range node.Children {
+			if !matchesConstraint(child, text) {
+				return false
+			}
+		}
+		return true
+	case model.QueryNodeOr:
+		for _, child := range node.Children {
+			if matchesConstraint(child, text) {
+				return true
+			}
+		}
+		return len(node.Children) == 0
+	case model.QueryNodeNot:
+		// 与关键词检索一致，格式不正确的 NOT 不构成条件
+		if len(node.Children) != 1 {
+			return true
+		}
+		return !matchesConstraint(node.Children[0], text)
+	}
+
+	if strings.TrimSpace(node.Text) == "" {
+		return true
+	}
+	return len(findMatches(text, []*model.QueryNode{node})) > 0
+}
+
+// filterByConstraint 过滤不满足查询条件的结果（匹配章节标题和内容），返回保留的结果和被过滤的数量
+func filterByConstraint(indices []*model.SearchIndex, constraint *model.QueryNode) ([]*model.SearchIndex, int) {
+	if constraint == nil {
+		return indices, 0
+	}
+	kept := make([]*model.SearchIndex, 0, len(indices))
+	for _, index := range indices {
+		if matchesConstraint(constraint, index.Section+"\n"+index.Content) {
+			kept = append(kept, index)
+		}
+	}
+	return kept, len(indices) - len(kept)
+}
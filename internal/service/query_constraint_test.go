@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestVectorConstraint 测试从查询中提取语义搜索结果必须满足的条件
+func TestVectorConstraint(t *testing.T) {
+	tests := []struct {
+		query   string
+		content string
+		want    bool
+	}{
+		// 并列的普通关键词不构成条件
+		{"connection pool", "database settings", true},
+		{"foo -bar", "foo and bar", false},
+		{"foo -bar", "unrelated text", true},
+		{"foo NOT bar", "BAR in upper case", false},
+		{`"connection pool"`, "pool of connections", false},
+		{`"connection pool" timeout`, "pool of connections", true},
+		{`"connection pool"`, "the connection pool size", true},
+		{"gin AND middleware", "gin router", false},
+		{"gin AND middleware", "gin middleware chain", true},
+		{"(gin OR echo) AND middleware -deprecated", "echo middleware", true},
+		{"(gin OR echo) AND middleware -deprecated", "echo middleware (deprecated)", false},
+		{"conf* -config*", "configuration file", false},
+		{"连接池 -废弃", "连接池已废弃", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery() error = %v", err)
+			}
+			constraint := vectorConstraint(parsed.Root)
+			got := constraint == nil || matchesConstraint(constraint, tt.content)
+			if got != tt.want {
+				t.Errorf("constraint %+v on %q = %v, want %v", constraint, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterByConstraint 测试过滤时同时匹配章节标题和内容
+func TestFilterByConstraint(t *testing.T) {
+	parsed, _ := parseSearchQuery("pool -deprecated")
+	indices := []*model.SearchIndex{
+		{ID: "kept", Section: "Pooling", Content: "pool size"},
+		{ID: "section", Section: "Deprecated APIs", Content: "pool size"},
+		{ID: "content", Content: "this option is deprecated"},
+	}
+
+	kept, removed := filterByConstraint(indices, vectorConstraint(parsed.Root))
+	if removed != 2 || len(kept) != 1 || kept[0].ID != "kept" {
+		t.Errorf("kept = %v, removed = %d, want only kept", kept, removed)
+	}
+}
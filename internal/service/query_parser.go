@@ -0,0 +1,491 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// queryFieldFilters 查询中的字段限定符与过滤条件键的对应关系
+var queryFieldFilters = map[string]string{
+	"library":      "library",
+	"version":      "version",
+	"section":      "section",
+	"type":         "document_type",
+	"content_type": "content_type",
+	"language":     "language",
+	"document_id":  "document_id",
+	"tag":          "tag",
+}
+
+// QueryParseError 查询语法错误，Position 为出错位置（从0开始的字符序号）
+type QueryParseError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+// Error 实现 error 接口
+func (e *QueryParseError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// parsedQuery 解析后的结构化查询
+type parsedQuery struct {
+	Root    *model.QueryNode       // 全文检索条件
+	Filters map[string]interface{} // 字段限定符转换得到的过滤条件
+}
+
+// Text 返回肯定关键词和短语组成的纯文本，用于语义搜索和生成片段，不包含同义词扩展的词
+func (q *parsedQuery) Text() string {
+	var words []string
+	for _, leaf := range q.Root.PositiveLeaves() {
+		if !leaf.Synonym {
+			words = append(words, leaf.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Keywords 返回肯定关键词列表，前缀匹配的关键词以 * 结尾，不包含同义词扩展的词
+func (q *parsedQuery) Keywords() []string {
+	var keywords []string
+	for _, leaf := range q.Root.PositiveLeaves() {
+		if leaf.Synonym {
+			continue
+		}
+		if leaf.Prefix {
+			keywords = append(keywords, leaf.Text+"*")
+		} else {
+			keywords = append(keywords, leaf.Text)
+		}
+	}
+	return keywords
+}
+
+// SynonymKeywords 返回同义词扩展生成的肯定关键词列表
+func (q *parsedQuery) SynonymKeywords() []string {
+	var keywords []string
+	for _, leaf := range q.Root.PositiveLeaves() {
+		if leaf.Synonym {
+			keywords = append(keywords, leaf.Text)
+		}
+	}
+	return keywords
+}
+
+// MergeFilters 合并请求中的过滤条件，查询中的字段限定符优先
+func (q *parsedQuery) MergeFilters(filters map[string]interface{}) map[string]interface{} {
+	if len(q.Filters) == 0 {
+		return filters
+	}
+	merged := make(map[string]interface{}, len(filters)+len(q.Filters))
+	for key, value := range filters {
+		merged[key] = value
+	}
+	for key, value := range q.Filters {
+		merged[key] = value
+	}
+	return merged
+}
+
+// queryTokenKind 查询词法单元类型
+type queryTokenKind int
+
+const (
+	queryTokenEOF queryTokenKind = iota
+	queryTokenWord
+	queryTokenPhrase
+	queryTokenField
+	queryTokenAnd
+	queryTokenOr
+	queryTokenNot
+	queryTokenLParen
+	queryTokenRParen
+)
+
+// queryToken 查询词法单元
+type queryToken struct {
+	kind   queryTokenKind
+	text   string
+	field  string // 字段限定符名称（仅 queryTokenField）
+	prefix bool   // 是否以 * 结尾（仅 queryTokenWord）
+	pos    int
+}
+
+// describe 返回词法单元在错误信息中的描述
+func (t queryToken) describe() string {
+	switch t.kind {
+	case queryTokenEOF:
+		return "end of query"
+	case queryTokenAnd:
+		return "AND"
+	case queryTokenOr:
+		return "OR"
+	case queryTokenNot:
+		return "NOT"
+	case queryTokenLParen:
+		return "'('"
+	case queryTokenRParen:
+		return "')'"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isQuote 判断是否为引号（包括中文引号）
+func isQuote(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// lexQuery 将查询拆分为词法单元
+func lexQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+
+	// readPhrase 读取从 start 处引号开始的短语，返回短语内容和结束引号之后的位置
+	readPhrase := func(start int) (string, int, error) {
+		for i := start + 1; i < len(runes); i++ {
+			if isQuote(runes[i]) {
+				return string(runes[start+1 : i]), i + 1, nil
+			}
+		}
+		return "", 0, &QueryParseError{Position: start, Message: "unterminated quoted phrase"}
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRParen, pos: i})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, queryToken{kind: queryTokenNot, pos: i})
+			i++
+		case isQuote(r):
+			text, next, err := readPhrase(i)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(text) != "" {
+				tokens = append(tokens, queryToken{kind: queryTokenPhrase, text: strings.TrimSpace(text), pos: i})
+			}
+			i = next
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !isQuote(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				i++
+			}
+			word := string(runes[start:i])
+
+			switch word {
+			case "AND", "&&":
+				tokens = append(tokens, queryToken{kind: queryTokenAnd, pos: start})
+				continue
+			case "OR", "||":
+				tokens = append(tokens, queryToken{kind: queryTokenOr, pos: start})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: queryTokenNot, pos: start})
+				continue
+			}
+
+			// 字段限定符：field:value 或 field:"quoted value"
+			if colon := strings.IndexByte(word, ':'); colon > 0 {
+				field := strings.ToLower(word[:colon])
+				if _, ok := queryFieldFilters[field]; ok {
+					value := word[colon+1:]
+					if value == "" && i < len(runes) && isQuote(runes[i]) {
+						phrase, next, err := readPhrase(i)
+						if err != nil {
+							return nil, err
+						}
+						value, i = phrase, next
+					}
+					value = strings.TrimSpace(value)
+					if value == "" {
+						return nil, &QueryParseError{Position: start, Message: fmt.Sprintf("missing value for field %q", field)}
+					}
+					tokens = append(tokens, queryToken{kind: queryTokenField, field: field, text: value, pos: start})
+					continue
+				}
+			}
+
+			prefix := strings.HasSuffix(word, "*")
+			text := strings.TrimRight(word, "*")
+			// 忽略不包含字母和数字的片段，如单独的标点
+			if strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+				continue
+			}
+			tokens = append(tokens, queryToken{kind: queryTokenWord, text: text, prefix: prefix, pos: start})
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: queryTokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// queryExprKind 解析过程中的表达式类型
+type queryExprKind int
+
+const (
+	queryExprLeaf queryExprKind = iota
+	queryExprField
+	queryExprAnd
+	queryExprOr
+	queryExprNot
+)
+
+// queryExpr 解析过程中的表达式，字段限定符在转换为语法树时提取为过滤条件
+type queryExpr struct {
+	kind     queryExprKind
+	node     *model.QueryNode // 关键词或短语（仅 queryExprLeaf）
+	field    string
+	value    string
+	children []*queryExpr
+	pos      int
+}
+
+// queryParser 结构化查询解析器
+//
+// 语法（优先级从高到低）：
+//
+//	NOT x / -x        排除匹配 x 的结果
+//	x AND y           同时匹配
+//	x OR y            匹配任意一个
+//	x y               并列的条件之间匹配任意一个，但排除条件和字段限定符必须满足
+//
+// "..." 表示短语，以 * 结尾的关键词按前缀匹配，field:value 为字段限定符，括号用于分组
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// parseSearchQuery 解析结构化查询
+func parseSearchQuery(query string) (*parsedQuery, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &queryParser{tokens: tokens}
+	expr, err := parser.parseSequence()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != queryTokenEOF {
+		return nil, &QueryParseError{Position: token.pos, Message: fmt.Sprintf("unexpected %s", token.describe())}
+	}
+	if expr == nil {
+		return nil, &QueryParseError{Position: 0, Message: "query is empty"}
+	}
+
+	parsed := &parsedQuery{Filters: make(map[string]interface{})}
+	root, err := parsed.lower(expr, true)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil || len(root.PositiveLeaves()) == 0 {
+		return nil, &QueryParseError{Position: 0, Message: "query must contain at least one search term that is not negated"}
+	}
+	parsed.Root = root
+
+	return parsed, nil
+}
+
+// peek 返回当前词法单元
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+// next 返回当前词法单元并前进
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != queryTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// startsOperand 判断词法单元能否作为操作数的开始
+func startsOperand(kind queryTokenKind) bool {
+	switch kind {
+	case queryTokenWord, queryTokenPhrase, queryTokenField, queryTokenNot, queryTokenLParen:
+		return true
+	}
+	return false
+}
+
+// parseSequence 解析并列的条件，直到查询结束或遇到右括号
+// 肯定条件之间为 OR 关系，排除条件和字段限定符与其他条件为 AND 关系
+func (p *queryParser) parseSequence() (*queryExpr, error) {
+	var required, optional []*queryExpr
+
+	for startsOperand(p.peek().kind) {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if expr.kind == queryExprNot || expr.kind == queryExprField {
+			required = append(required, expr)
+		} else {
+			optional = append(optional, expr)
+		}
+	}
+
+	if token := p.peek(); token.kind == queryTokenAnd || token.kind == queryTokenOr {
+		return nil, &QueryParseError{Position: token.pos, Message: fmt.Sprintf("missing operand before %s", token.describe())}
+	}
+
+	// 肯定条件放在最前面
+	switch len(optional) {
+	case 0:
+	case 1:
+		required = append([]*queryExpr{optional[0]}, required...)
+	default:
+		required = append([]*queryExpr{{kind: queryExprOr, children: optional, pos: optional[0].pos}}, required...)
+	}
+
+	switch len(required) {
+	case 0:
+		return nil, nil
+	case 1:
+		return required[0], nil
+	}
+	return &queryExpr{kind: queryExprAnd, children: required, pos: required[0].pos}, nil
+}
+
+// parseOr 解析 OR 表达式
+func (p *queryParser) parseOr() (*queryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []*queryExpr{left}
+	for p.peek().kind == queryTokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &queryExpr{kind: queryExprOr, children: children, pos: left.pos}, nil
+}
+
+// parseAnd 解析 AND 表达式
+func (p *queryParser) parseAnd() (*queryExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []*queryExpr{left}
+	for p.peek().kind == queryTokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &queryExpr{kind: queryExprAnd, children: children, pos: left.pos}, nil
+}
+
+// parseUnary 解析 NOT 表达式、括号分组、关键词、短语和字段限定符
+func (p *queryParser) parseUnary() (*queryExpr, error) {
+	token := p.next()
+
+	switch token.kind {
+	case queryTokenNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryExpr{kind: queryExprNot, children: []*queryExpr{operand}, pos: token.pos}, nil
+	case queryTokenLParen:
+		expr, err := p.parseSequence()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != queryTokenRParen {
+			return nil, &QueryParseError{Position: token.pos, Message: "unclosed parenthesis"}
+		}
+		if expr == nil {
+			return nil, &QueryParseError{Position: token.pos, Message: "empty parentheses"}
+		}
+		return expr, nil
+	case queryTokenWord:
+		return &queryExpr{kind: queryExprLeaf, node: &model.QueryNode{Type: model.QueryNodeTerm, Text: token.text, Prefix: token.prefix}, pos: token.pos}, nil
+	case queryTokenPhrase:
+		return &queryExpr{kind: queryExprLeaf, node: &model.QueryNode{Type: model.QueryNodePhrase, Text: token.text}, pos: token.pos}, nil
+	case queryTokenField:
+		return &queryExpr{kind: queryExprField, field: token.field, value: token.text, pos: token.pos}, nil
+	}
+
+	return nil, &QueryParseError{Position: token.pos, Message: fmt.Sprintf("expected a search term but found %s", token.describe())}
+}
+
+// lower 将表达式转换为查询语法树，字段限定符提取为过滤条件
+// required 表示表达式是否处于必须满足的位置，字段限定符只能出现在这些位置
+func (q *parsedQuery) lower(expr *queryExpr, required bool) (*model.QueryNode, error) {
+	switch expr.kind {
+	case queryExprLeaf:
+		return expr.node, nil
+	case queryExprField:
+		if !required {
+			return nil, &QueryParseError{Position: expr.pos, Message: fmt.Sprintf("field qualifier %q cannot be used inside OR or NOT", expr.field)}
+		}
+		key := queryFieldFilters[expr.field]
+		if _, ok := q.Filters[key]; ok {
+			return nil, &QueryParseError{Position: expr.pos, Message: fmt.Sprintf("duplicate field qualifier %q", expr.field)}
+		}
+		q.Filters[key] = expr.value
+		return nil, nil
+	case queryExprNot:
+		child, err := q.lower(expr.children[0], false)
+		if err != nil {
+			return nil, err
+		}
+		return &model.QueryNode{Type: model.QueryNodeNot, Children: []*model.QueryNode{child}}, nil
+	}
+
+	nodeType := model.QueryNodeAnd
+	if expr.kind == queryExprOr {
+		nodeType = model.QueryNodeOr
+		required = false
+	}
+
+	var children []*model.QueryNode
+	for _, childExpr := range expr.children {
+		child, err := q.lower(childExpr, required)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+
+	switch len(children) {
+	case 0:
+		return nil, nil
+	case 1:
+		return children[0], nil
+	}
+	return &model.QueryNode{Type: nodeType, Children: children}, nil
+}
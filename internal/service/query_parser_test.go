@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// formatQueryNode 将查询语法树格式化为便于比较的字符串
+func formatQueryNode(node *model.QueryNode) string {
+	if node == nil {
+		return ""
+	}
+	switch node.Type {
+	case model.QueryNodeTerm:
+		if node.Prefix {
+			return node.Text + "*"
+		}
+		return node.Text
+	case model.QueryNodePhrase:
+		return "\"" + node.Text + "\""
+	case model.QueryNodeNot:
+		return "NOT " + formatQueryNode(node.Children[0])
+	}
+
+	parts := make([]string, len(node.Children))
+	for i, child := range node.Children {
+		parts[i] = formatQueryNode(child)
+	}
+	return "(" + strings.Join(parts, " "+strings.ToUpper(string(node.Type))+" ") + ")"
+}
+
+// TestParseSearchQuery 测试结构化查询解析
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantTree    string
+		wantFilters map[string]interface{}
+	}{
+		{"单个关键词", "gorm", "gorm", map[string]interface{}{}},
+		{"空格分隔的关键词为 OR 关系", "gorm gin", "(gorm OR gin)", map[string]interface{}{}},
+		{"短语和前缀", `"connection pool" conf*`, `("connection pool" OR conf*)`, map[string]interface{}{}},
+		{"中文引号", "“连接 池”", `"连接 池"`, map[string]interface{}{}},
+		{"排除条件必须满足", `"connection pool" -deprecated`, `("connection pool" AND NOT deprecated)`, map[string]interface{}{}},
+		{"AND 优先于 OR", "a OR b AND c", "(a OR (b AND c))", map[string]interface{}{}},
+		{"括号分组", "(a OR b) AND NOT c", "((a OR b) AND NOT c)", map[string]interface{}{}},
+		{"小写运算符作为关键词", "read and write", "(read OR and OR write)", map[string]interface{}{}},
+		{"连字符不在开头时属于关键词", "utf-8", "utf-8", map[string]interface{}{}},
+		{
+			"字段限定符转换为过滤条件",
+			`"connection pool" -deprecated library:gorm version:1.25 section:Transactions`,
+			`("connection pool" AND NOT deprecated)`,
+			map[string]interface{}{"library": "gorm", "version": "1.25", "section": "Transactions"},
+		},
+		{
+			"带引号的字段值和类型映射",
+			`section:"Getting Started" type:markdown content_type:code language:go document_id:abc tag:orm gin`,
+			"gin",
+			map[string]interface{}{"section": "Getting Started", "document_type": "markdown", "content_type": "code", "language": "go", "document_id": "abc", "tag": "orm"},
+		},
+		{"AND 中的字段限定符", "gin AND library:gin", "gin", map[string]interface{}{"library": "gin"}},
+		{"未知字段作为关键词", "std::vector http://example.com", "(std::vector OR http://example.com)", map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery(%q) error = %v", tt.query, err)
+			}
+			if got := formatQueryNode(parsed.Root); got != tt.wantTree {
+				t.Errorf("parseSearchQuery(%q) = %s, want %s", tt.query, got, tt.wantTree)
+			}
+			if !reflect.DeepEqual(parsed.Filters, tt.wantFilters) {
+				t.Errorf("parseSearchQuery(%q).Filters = %v, want %v", tt.query, parsed.Filters, tt.wantFilters)
+			}
+		})
+	}
+}
+
+// TestParseSearchQuery_Errors 测试查询语法错误及出错位置
+func TestParseSearchQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantPosition int
+	}{
+		{"未闭合的引号", `gin "connection pool`, 4},
+		{"未闭合的括号", "(gin OR gorm", 0},
+		{"多余的右括号", "gin)", 3},
+		{"空括号", "gin ()", 4},
+		{"缺少右操作数", "gin AND", 7},
+		{"缺少左操作数", "OR gin", 0},
+		{"字段缺少值", "gin library:", 4},
+		{"OR 中的字段限定符", "gin OR library:gorm", 7},
+		{"否定字段限定符", "gin -library:gorm", 5},
+		{"重复的字段限定符", "gin version:1 version:2", 14},
+		{"只有排除条件", "-deprecated", 0},
+		{"只有字段限定符", "library:gorm", 0},
+		{"位置按字符计算", "中文 AND", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSearchQuery(tt.query)
+			var parseErr *QueryParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("parseSearchQuery(%q) error = %v, want QueryParseError", tt.query, err)
+			}
+			if parseErr.Position != tt.wantPosition {
+				t.Errorf("parseSearchQuery(%q) position = %d, want %d (%s)", tt.query, parseErr.Position, tt.wantPosition, parseErr.Message)
+			}
+		})
+	}
+}
+
+// TestParsedQuery_TextAndKeywords 测试提取肯定关键词
+func TestParsedQuery_TextAndKeywords(t *testing.T) {
+	parsed, err := parseSearchQuery(`"connection pool" conf* -deprecated NOT (legacy OR old) library:gorm`)
+	if err != nil {
+		t.Fatalf("parseSearchQuery() error = %v", err)
+	}
+
+	if got := parsed.Text(); got != "connection pool conf" {
+		t.Errorf("Text() = %q, want %q", got, "connection pool conf")
+	}
+	if got := parsed.Keywords(); !reflect.DeepEqual(got, []string{"connection pool", "conf*"}) {
+		t.Errorf("Keywords() = %v, want [connection pool conf*]", got)
+	}
+
+	merged := parsed.MergeFilters(map[string]interface{}{"library": "gin", "version": "1.0"})
+	if merged["library"] != "gorm" || merged["version"] != "1.0" {
+		t.Errorf("MergeFilters() = %v, 查询中的字段限定符应优先", merged)
+	}
+}
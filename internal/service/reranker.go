@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// 重排序器名称
+const (
+	RerankerNone         = "none"          // 不重排序
+	RerankerHeuristic    = "heuristic"     // 本地启发式重排序
+	RerankerCrossEncoder = "cross-encoder" // 通过HTTP调用交叉编码器模型
+)
+
+// 启发式重排序的特征权重，各特征均归一化到 [0, 1]
+const (
+	heuristicRetrievalWeight   = 0.5                  // 召回阶段的得分
+	heuristicFieldWeight       = 0.2                  // 标题和章节命中查询词的比例
+	heuristicProximityWeight   = 0.2                  // 查询词在内容中的紧密程度
+	heuristicFreshnessWeight   = 0.1                  // 索引的新鲜度
+	heuristicFreshnessHalfLife = 180 * 24 * time.Hour // 新鲜度的半衰期
+)
+
+// ErrRerankerUnavailable 请求的重排序器不存在或未配置
+var ErrRerankerUnavailable = errors.New("reranker is not available")
+
+// searchRerankDuration 重排序耗时
+var searchRerankDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "search_rerank_duration_seconds",
+		Help:    "Search rerank duration in seconds.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"reranker", "status"},
+)
+
+func init() {
+	prometheus.MustRegister(searchRerankDuration)
+}
+
+// Reranker 对召回的前 N 个候选结果重新排序
+type Reranker interface {
+	// Name 返回重排序器名称，用于请求参数和监控指标
+	Name() string
+	// Rerank 为候选结果重新打分，返回与 candidates 一一对应的得分，得分越高越相关
+	Rerank(ctx context.Context, query string, candidates []*model.SearchIndex) ([]float64, error)
+}
+
+// rerankExplanation 重排序明细，写入搜索结果的元数据
+type rerankExplanation struct {
+	Reranker     string  `json:"reranker"`
+	Score        float64 `json:"score"`
+	OriginalRank int     `json:"original_rank"` // 重排序前的排名，从1开始
+}
+
+// newRerankers 根据配置创建可用的重排序器，交叉编码器只在配置了服务地址时可用
+func newRerankers(config *SearchConfig) map[string]Reranker {
+	rerankers := map[string]Reranker{
+		RerankerHeuristic: NewHeuristicReranker(),
+	}
+	if config.RerankURL != "" {
+		rerankers[RerankerCrossEncoder] = NewCrossEncoderReranker(config.RerankURL, config.RerankModel, config.RerankAPIKey, config.RerankTimeout)
+	}
+	return rerankers
+}
+
+// rerankerFor 合并请求参数和服务端默认配置，返回使用的重排序器和参与重排序的候选数量
+// 不重排序时返回 nil
+func (s *searchService) rerankerFor(request *model.SearchRequest) (Reranker, int, error) {
+	name := s.config.Reranker
+	if request.Rerank != "" {
+		name = request.Rerank
+	}
+	topN := s.config.RerankTopN
+	if request.RerankTopN != nil {
+		topN = *request.RerankTopN
+	}
+
+	if name == "" || name == RerankerNone {
+		return nil, 0, nil
+	}
+	if topN < 1 {
+		return nil, 0, fmt.Errorf("rerank_top_n must be positive, got %d", topN)
+	}
+	reranker, ok := s.rerankers[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrRerankerUnavailable, name)
+	}
+	return reranker, topN, nil
+}
+
+// rerankCandidates 对前 topN 个候选结果重排序，返回各结果的重排序明细
+// 重排序后的结果沿用原来的得分序列，保证整体仍按得分降序，游标分页不受影响
+// 重排序失败时保持召回顺序
+func rerankCandidates(ctx context.Context, reranker Reranker, topN int, query string, candidates []*model.SearchIndex) map[string]*rerankExplanation {
+	if reranker == nil || len(candidates) == 0 {
+		return nil
+	}
+	if topN > len(candidates) {
+		topN = len(candidates)
+	}
+	top := candidates[:topN]
+
+	startTime := time.Now()
+	scores, err := reranker.Rerank(ctx, query, top)
+	if err == nil && len(scores) != len(top) {
+		err = fmt.Errorf("reranker returned %d scores for %d candidates", len(scores), len(top))
+	}
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	searchRerankDuration.WithLabelValues(reranker.Name(), status).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		log.Printf("WARNING: Rerank with %s failed, keeping retrieval order: %v", reranker.Name(), err)
+		return nil
+	}
+
+	type rankedCandidate struct {
+		index *model.SearchIndex
+		score float64
+		rank  int
+	}
+	ranked := make([]rankedCandidate, len(top))
+	originalScores := make([]float32, len(top))
+	for i, index := range top {
+		ranked[i] = rankedCandidate{index: index, score: scores[i], rank: i + 1}
+		originalScores[i] = index.Score
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	explanations := make(map[string]*rerankExplanation, len(ranked))
+	for i, item := range ranked {
+		explanations[item.index.ID] = &rerankExplanation{
+			Reranker:     reranker.Name(),
+			Score:        item.score,
+			OriginalRank: item.rank,
+		}
+		item.index.Score = originalScores[i]
+		top[i] = item.index
+	}
+	return explanations
+}
+
+// heuristicReranker 启发式重排序：综合召回得分、标题和章节命中、查询词紧密程度和新鲜度
+type heuristicReranker struct {
+	now func() time.Time
+}
+
+// NewHeuristicReranker 创建启发式重排序器
+func NewHeuristicReranker() Reranker {
+	return &heuristicReranker{now: time.Now}
+}
+
+// Name 返回重排序器名称
+func (r *heuristicReranker) Name() string {
+	return RerankerHeuristic
+}
+
+// Rerank 为候选结果重新打分
+func (r *heuristicReranker) Rerank(ctx context.Context, query string, candidates []*model.SearchIndex) ([]float64, error) {
+	terms := uniqueTokens(tokenize(query))
+
+	// 召回得分在候选结果内最小-最大归一化
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, candidate := range candidates {
+		minScore = math.Min(minScore, float64(candidate.Score))
+		maxScore = math.Max(maxScore, float64(candidate.Score))
+	}
+
+	now := r.now()
+	scores := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		retrieval := 1.0
+		if maxScore > minScore {
+			retrieval = (float64(candidate.Score) - minScore) / (maxScore - minScore)
+		}
+
+		scores[i] = heuristicRetrievalWeight*retrieval +
+			heuristicFieldWeight*fieldMatch(terms, candidateTitle(candidate)+" "+candidate.Section) +
+			heuristicProximityWeight*termProximity(terms, tokenize(candidate.Content)) +
+			heuristicFreshnessWeight*freshness(candidate.UpdatedAt, now)
+	}
+	return scores, nil
+}
+
+// uniqueTokens 去除重复的检索词，保持原有顺序
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	var result []string
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// candidateTitle 从索引元数据中获取文档名称
+func candidateTitle(index *model.SearchIndex) string {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(index.Metadata), &metadata); err != nil {
+		return ""
+	}
+	title, _ := metadata["document_name"].(string)
+	return title
+}
+
+// fieldMatch 返回查询词在字段中出现的比例
+func fieldMatch(terms []string, field string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	present := make(map[string]bool)
+	for _, token := range tokenize(field) {
+		present[token] = true
+	}
+	matched := 0
+	for _, term := range terms {
+		if present[term] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(terms))
+}
+
+// termProximity 查询词在内容中的紧密程度
+// 取包含所有出现过的查询词的最短窗口，得分为 覆盖比例 × 出现的查询词数 / 窗口长度，所有查询词相邻出现时为1
+func termProximity(terms []string, tokens []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	// 统计出现过的查询词
+	present := make(map[string]bool)
+	for _, token := range tokens {
+		if wanted[token] {
+			present[token] = true
+		}
+	}
+	if len(present) == 0 {
+		return 0
+	}
+
+	// 滑动窗口求包含所有出现过的查询词的最短窗口
+	counts := make(map[string]int, len(present))
+	covered, best, left := 0, len(tokens), 0
+	for right, token := range tokens {
+		if !present[token] {
+			continue
+		}
+		if counts[token] == 0 {
+			covered++
+		}
+		counts[token]++
+		for covered == len(present) {
+			if width := right - left + 1; width < best {
+				best = width
+			}
+			if present[tokens[left]] {
+				counts[tokens[left]]--
+				if counts[tokens[left]] == 0 {
+					covered--
+				}
+			}
+			left++
+		}
+	}
+
+	coverage := float64(len(present)) / float64(len(terms))
+	return coverage * float64(len(present)) / float64(best)
+}
+
+// freshness 按半衰期计算的新鲜度，时间未知时为0
+func freshness(updatedAt, now time.Time) float64 {
+	if updatedAt.IsZero() {
+		return 0
+	}
+	age := now.Sub(updatedAt)
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(heuristicFreshnessHalfLife))
+}
+
+// crossEncoderReranker 通过HTTP调用交叉编码器模型重排序
+// 请求和响应格式与 Cohere、Jina 等 rerank 接口兼容
+type crossEncoderReranker struct {
+	endpoint string
+	model    string
+	apiKey   string
+	client   *http.Client
+}
+
+// crossEncoderRequest 交叉编码器重排序请求
+type crossEncoderRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+// crossEncoderResponse 交叉编码器重排序响应
+type crossEncoderResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// NewCrossEncoderReranker 创建交叉编码器重排序器
+func NewCrossEncoderReranker(endpoint, model, apiKey string, timeout time.Duration) Reranker {
+	return &crossEncoderReranker{
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Name 返回重排序器名称
+func (r *crossEncoderReranker) Name() string {
+	return RerankerCrossEncoder
+}
+
+// Rerank 为候选结果重新打分，模型没有返回得分的候选结果排在最后
+func (r *crossEncoderReranker) Rerank(ctx context.Context, query string, candidates []*model.SearchIndex) ([]float64, error) {
+	documents := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		documents[i] = candidate.Content
+		if candidate.Section != "" {
+			documents[i] = candidate.Section + "\n" + candidate.Content
+		}
+	}
+
+	body, err := json.Marshal(crossEncoderRequest{Model: r.model, Query: query, Documents: documents, TopN: len(documents)})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("rerank request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	var result crossEncoderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %v", err)
+	}
+
+	scores := make([]float64, len(candidates))
+	scored := make([]bool, len(candidates))
+	lowest := math.Inf(1)
+	for _, item := range result.Results {
+		if item.Index < 0 || item.Index >= len(candidates) {
+			return nil, fmt.Errorf("rerank response index %d out of range", item.Index)
+		}
+		scores[item.Index] = item.RelevanceScore
+		scored[item.Index] = true
+		lowest = math.Min(lowest, item.RelevanceScore)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("rerank response contains no results")
+	}
+	for i := range scores {
+		if !scored[i] {
+			scores[i] = lowest - 1
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// stubReranker 返回固定得分的重排序器
+type stubReranker struct {
+	scores map[string]float64
+	err    error
+}
+
+func (r *stubReranker) Name() string { return "stub" }
+
+func (r *stubReranker) Rerank(ctx context.Context, query string, candidates []*model.SearchIndex) ([]float64, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	scores := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = r.scores[candidate.ID]
+	}
+	return scores, nil
+}
+
+// TestTermProximity 测试查询词紧密程度
+func TestTermProximity(t *testing.T) {
+	tests := []struct {
+		name    string
+		terms   []string
+		content string
+		want    float64
+	}{
+		{"相邻出现", []string{"connection", "pool"}, "configure the connection pool size", 1},
+		{"间隔出现", []string{"connection", "pool"}, "connection settings for the pool", 0.4},
+		{"只出现一个", []string{"connection", "pool"}, "connection settings", 0.5},
+		{"都未出现", []string{"connection", "pool"}, "router groups", 0},
+		{"取最短窗口", []string{"a", "b"}, "a x x b x a b", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := termProximity(tt.terms, tokenize(tt.content))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("termProximity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHeuristicReranker 测试启发式重排序的各项特征
+func TestHeuristicReranker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	reranker := &heuristicReranker{now: func() time.Time { return now }}
+
+	candidates := []*model.SearchIndex{
+		{ID: "scattered", Score: 1, Content: "pool of workers, see connection docs", UpdatedAt: now},
+		{ID: "section", Score: 1, Content: "set the connection pool size", Section: "Connection Pool", UpdatedAt: now},
+		{ID: "stale", Score: 1, Content: "set the connection pool size", Section: "Connection Pool", UpdatedAt: now.Add(-heuristicFreshnessHalfLife)},
+	}
+	scores, err := reranker.Rerank(context.Background(), "connection pool", candidates)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+
+	if !(scores[1] > scores[2] && scores[2] > scores[0]) {
+		t.Errorf("Rerank() scores = %v, want section > stale > scattered", scores)
+	}
+	if diff := scores[1] - scores[2]; math.Abs(diff-heuristicFreshnessWeight/2) > 1e-9 {
+		t.Errorf("freshness difference = %v, want %v", diff, heuristicFreshnessWeight/2)
+	}
+}
+
+// TestCrossEncoderReranker 测试通过HTTP调用交叉编码器
+func TestCrossEncoderReranker(t *testing.T) {
+	var got crossEncoderRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q, want Bearer secret", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		// 第三个候选结果没有返回得分
+		w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.2}]}`))
+	}))
+	defer server.Close()
+
+	reranker := NewCrossEncoderReranker(server.URL, "bge-reranker", "secret", time.Second)
+	candidates := []*model.SearchIndex{
+		{ID: "a", Content: "gin router"},
+		{ID: "b", Content: "route groups", Section: "Routing"},
+		{ID: "c", Content: "middleware"},
+	}
+	scores, err := reranker.Rerank(context.Background(), "gin routing", candidates)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+
+	if got.Model != "bge-reranker" || got.Query != "gin routing" || got.TopN != 3 {
+		t.Errorf("request = %+v", got)
+	}
+	if strings.Join(got.Documents, "|") != "gin router|Routing\nroute groups|middleware" {
+		t.Errorf("request documents = %q", got.Documents)
+	}
+	if scores[0] != 0.2 || scores[1] != 0.9 || scores[2] >= 0.2 {
+		t.Errorf("Rerank() scores = %v, want [0.2 0.9 <0.2]", scores)
+	}
+}
+
+// TestCrossEncoderReranker_Error 测试重排序服务返回错误
+func TestCrossEncoderReranker_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	reranker := NewCrossEncoderReranker(server.URL, "", "", time.Second)
+	_, err := reranker.Rerank(context.Background(), "gin", []*model.SearchIndex{{ID: "a"}})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Rerank() error = %v, want status 503", err)
+	}
+}
+
+// TestRerankCandidates 测试只重排前 N 个结果且得分保持降序
+func TestRerankCandidates(t *testing.T) {
+	newCandidates := func() []*model.SearchIndex {
+		return []*model.SearchIndex{
+			{ID: "a", Score: 0.9},
+			{ID: "b", Score: 0.8},
+			{ID: "c", Score: 0.7},
+			{ID: "d", Score: 0.6},
+		}
+	}
+
+	candidates := newCandidates()
+	reranker := &stubReranker{scores: map[string]float64{"a": 0.1, "b": 0.3, "c": 0.9, "d": 1}}
+	explanations := rerankCandidates(context.Background(), reranker, 3, "q", candidates)
+
+	var ids []string
+	for i, candidate := range candidates {
+		ids = append(ids, candidate.ID)
+		if i > 0 && candidate.Score > candidates[i-1].Score {
+			t.Errorf("scores not descending: %v > %v", candidate.Score, candidates[i-1].Score)
+		}
+	}
+	if strings.Join(ids, ",") != "c,b,a,d" {
+		t.Errorf("order = %v, want c,b,a,d", ids)
+	}
+	if explanation := explanations["c"]; explanation == nil || explanation.OriginalRank != 3 || explanation.Score != 0.9 {
+		t.Errorf("explanation for c = %+v", explanation)
+	}
+	if _, ok := explanations["d"]; ok {
+		t.Error("candidate outside top N should not be reranked")
+	}
+
+	// 重排序失败时保持召回顺序
+	candidates = newCandidates()
+	if explanations := rerankCandidates(context.Background(), &stubReranker{err: errors.New("timeout")}, 3, "q", candidates); explanations != nil {
+		t.Errorf("explanations = %v, want nil", explanations)
+	}
+	if candidates[0].ID != "a" || candidates[2].ID != "c" {
+		t.Error("failed rerank should keep retrieval order")
+	}
+}
+
+// TestRerankerFor 测试重排序参数的合并
+func TestRerankerFor(t *testing.T) {
+	config := DefaultSearchConfig()
+	config.Reranker = RerankerHeuristic
+	config.RerankTopN = 30
+	s := &searchService{config: config, rerankers: newRerankers(config)}
+
+	reranker, topN, err := s.rerankerFor(&model.SearchRequest{})
+	if err != nil || reranker == nil || reranker.Name() != RerankerHeuristic || topN != 30 {
+		t.Errorf("rerankerFor(default) = %v, %d, %v", reranker, topN, err)
+	}
+
+	n := 5
+	if reranker, _, err := s.rerankerFor(&model.SearchRequest{Rerank: RerankerNone, RerankTopN: &n}); reranker != nil || err != nil {
+		t.Errorf("rerankerFor(none) = %v, %v, want nil", reranker, err)
+	}
+
+	// 未配置服务地址时交叉编码器不可用
+	if _, _, err := s.rerankerFor(&model.SearchRequest{Rerank: RerankerCrossEncoder}); !errors.Is(err, ErrRerankerUnavailable) {
+		t.Errorf("rerankerFor(cross-encoder) error = %v, want ErrRerankerUnavailable", err)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// candidateGroup 按文档或库聚合的候选结果
+type candidateGroup struct {
+	key     string
+	hits    int                  // 候选结果中属于该组的分块数量
+	indices []*model.SearchIndex // 组内得分最高的分块，按候选顺序排列
+}
+
+// groupingFor 返回请求的分组方式和每组分块数量，不分组时分组方式为空
+func (s *searchService) groupingFor(request *model.SearchRequest) (string, int) {
+	if !model.IsValidGroupBy(request.GroupBy) {
+		return "", 0
+	}
+	perGroup := request.PerGroup
+	if perGroup < 1 {
+		perGroup = s.config.GroupPerGroup
+	}
+	if perGroup < 1 {
+		perGroup = 1
+	}
+	return request.GroupBy, perGroup
+}
+
+// groupCandidates 按分组键折叠按得分排列的候选结果，组按各自最高得分的分块出现的顺序排列，每组保留前 perGroup 个分块
+func groupCandidates(indices []*model.SearchIndex, groupBy string, perGroup int) []*candidateGroup {
+	var groups []*candidateGroup
+	byKey := make(map[string]*candidateGroup)
+	for _, index := range indices {
+		key := groupKey(index, groupBy)
+		group, ok := byKey[key]
+		if !ok {
+			group = &candidateGroup{key: key}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.hits++
+		if len(group.indices) < perGroup {
+			group.indices = append(group.indices, index)
+		}
+	}
+	return groups
+}
+
+// groupKey 返回候选结果的分组键：文档ID或元数据中的库名称
+func groupKey(index *model.SearchIndex, groupBy string) string {
+	if groupBy != model.GroupByLibrary {
+		return index.DocumentID
+	}
+	var metadata struct {
+		Library string `json:"document_library"`
+	}
+	if index.Metadata != "" {
+		_ = json.Unmarshal([]byte(index.Metadata), &metadata)
+	}
+	return metadata.Library
+}
+
+// pageGroups 返回游标之后（没有游标时跳过 offset 个组）的一页分组，按每组得分最高的分块定位
+func pageGroups(groups []*candidateGroup, after *model.Cursor, offset, size int) []*candidateGroup {
+	leaders := make([]*model.SearchIndex, len(groups))
+	byLeader := make(map[string]*candidateGroup, len(groups))
+	for i, group := range groups {
+		leaders[i] = group.indices[0]
+		byLeader[leaders[i].ID] = group
+	}
+
+	page := pageAfter(leaders, after, offset, size)
+	result := make([]*candidateGroup, len(page))
+	for i, leader := range page {
+		result[i] = byLeader[leader.ID]
+	}
+	return result
+}
+
+// groupLeaders 返回各组得分最高的分块，用于生成下一页游标
+func groupLeaders(groups []*candidateGroup) []*model.SearchIndex {
+	leaders := make([]*model.SearchIndex, len(groups))
+	for i, group := range groups {
+		leaders[i] = group.indices[0]
+	}
+	return leaders
+}
+
+// flattenGroups 将各组分块按组依次排列
+func flattenGroups(groups []*candidateGroup) []*model.SearchIndex {
+	var indices []*model.SearchIndex
+	for _, group := range groups {
+		indices = append(indices, group.indices...)
+	}
+	return indices
+}
+
+// buildResultGroups 将按组依次排列的搜索结果拆分为分组
+func buildResultGroups(groups []*candidateGroup, results []model.SearchResult) []model.SearchResultGroup {
+	resultGroups := make([]model.SearchResultGroup, 0, len(groups))
+	start := 0
+	for _, group := range groups {
+		end := start + len(group.indices)
+		if end > len(results) {
+			end = len(results)
+		}
+		items := results[start:end]
+		resultGroup := model.SearchResultGroup{
+			Key:   group.key,
+			Hits:  group.hits,
+			Items: items,
+		}
+		if len(items) > 0 {
+			resultGroup.Score = items[0].Score
+		}
+		resultGroups = append(resultGroups, resultGroup)
+		start = end
+	}
+	return resultGroups
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// groupingCandidates 按得分排列的候选结果，doc-1 的分块占据前几名
+func groupingCandidates() []*model.SearchIndex {
+	return []*model.SearchIndex{
+		{ID: "a", DocumentID: "doc-1", Score: 0.9, Metadata: `{"document_library":"gorm"}`},
+		{ID: "b", DocumentID: "doc-1", Score: 0.8, Metadata: `{"document_library":"gorm"}`},
+		{ID: "c", DocumentID: "doc-1", Score: 0.7, Metadata: `{"document_library":"gorm"}`},
+		{ID: "d", DocumentID: "doc-2", Score: 0.6, Metadata: `{"document_library":"gorm"}`},
+		{ID: "e", DocumentID: "doc-3", Score: 0.5, Metadata: `{"document_library":"gin"}`},
+		{ID: "f", DocumentID: "doc-2", Score: 0.4, Metadata: `{"document_library":"gorm"}`},
+	}
+}
+
+// groupSummary 将分组表示为 key:分块ID列表/命中数，便于比较
+func groupSummary(groups []*candidateGroup) string {
+	var parts []string
+	for _, group := range groups {
+		var ids []string
+		for _, index := range group.indices {
+			ids = append(ids, index.ID)
+		}
+		parts = append(parts, group.key+":"+strings.Join(ids, ",")+"/"+strconv.Itoa(group.hits))
+	}
+	return strings.Join(parts, " ")
+}
+
+// TestGroupCandidates 测试按文档和库折叠候选结果
+func TestGroupCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupBy  string
+		perGroup int
+		want     string
+	}{
+		{"按文档", model.GroupByDocument, 2, "doc-1:a,b/3 doc-2:d,f/2 doc-3:e/1"},
+		{"每组一个", model.GroupByDocument, 1, "doc-1:a/3 doc-2:d/2 doc-3:e/1"},
+		{"按库", model.GroupByLibrary, 2, "gorm:a,b/5 gin:e/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := groupSummary(groupCandidates(groupingCandidates(), tt.groupBy, tt.perGroup))
+			if got != tt.want {
+				t.Errorf("groupCandidates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPageGroups 测试按组分页和游标定位
+func TestPageGroups(t *testing.T) {
+	groups := groupCandidates(groupingCandidates(), model.GroupByDocument, 2)
+
+	if got := groupSummary(pageGroups(groups, nil, 0, 2)); got != "doc-1:a,b/3 doc-2:d,f/2" {
+		t.Errorf("first page = %q", got)
+	}
+	// 游标为上一页最后一组得分最高的分块
+	next := nextSearchCursor(groupLeaders(pageGroups(groups, nil, 0, 2)), 0, int64(len(groups)), "q")
+	cursor, err := model.DecodeCursor(next, "q")
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if cursor.ID != "d" || cursor.Offset != 2 {
+		t.Errorf("cursor = %+v, want leader d at offset 2", cursor)
+	}
+	if got := groupSummary(pageGroups(groups, cursor, cursor.Offset, 2)); got != "doc-3:e/1" {
+		t.Errorf("second page = %q, want doc-3:e/1", got)
+	}
+}
+
+// TestBuildResultGroups 测试将按组排列的结果拆分为分组
+func TestBuildResultGroups(t *testing.T) {
+	groups := groupCandidates(groupingCandidates(), model.GroupByDocument, 2)
+	indices := flattenGroups(groups)
+	results := make([]model.SearchResult, len(indices))
+	for i, index := range indices {
+		results[i] = model.SearchResult{ID: index.ID, Score: index.Score}
+	}
+
+	resultGroups := buildResultGroups(groups, results)
+	if len(resultGroups) != 3 {
+		t.Fatalf("len(groups) = %d, want 3", len(resultGroups))
+	}
+	second := resultGroups[1]
+	if second.Key != "doc-2" || second.Score != 0.6 || second.Hits != 2 || len(second.Items) != 2 || second.Items[1].ID != "f" {
+		t.Errorf("second group = %+v, want doc-2 with d and f", second)
+	}
+}
+
+// TestGroupingFor 测试分组参数的默认值
+func TestGroupingFor(t *testing.T) {
+	s := &searchService{config: DefaultSearchConfig()}
+	if groupBy, _ := s.groupingFor(&model.SearchRequest{}); groupBy != "" {
+		t.Errorf("groupingFor() without group_by = %q, want no grouping", groupBy)
+	}
+	if groupBy, perGroup := s.groupingFor(&model.SearchRequest{GroupBy: "library"}); groupBy != "library" || perGroup != 3 {
+		t.Errorf("groupingFor() = %q, %d, want library with default per_group 3", groupBy, perGroup)
+	}
+	if _, perGroup := s.groupingFor(&model.SearchRequest{GroupBy: "document_id", PerGroup: 5}); perGroup != 5 {
+		t.Errorf("groupingFor() per_group = %d, want 5", perGroup)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// semanticAfterMaxBatches 语义搜索从游标位置继续召回时，按查询语法树过滤后结果不足一页时最多召回的批数
+const semanticAfterMaxBatches = 5
+
+// rescoreHead 返回召回顺序中重新打分和重排序的结果数量：关键词搜索为BM25重新打分的候选数量，
+// 语义搜索只有重排序会改变顺序；超出这部分的结果保持召回顺序，翻页时由数据库从游标位置定位
+func (s *searchService) rescoreHead(searchType string, rerankTopN int) int {
+	if searchType == "semantic" {
+		return rerankTopN
+	}
+	return maxInt(s.config.BM25CandidateWindow, rerankTopN)
+}
+
+// recallLast 返回召回顺序中最后的结果：全文检索按 (得分 DESC, ID ASC)，向量检索按 (距离 ASC, ID ASC) 排序
+func recallLast(indices []*model.SearchIndex, searchType string) *model.SearchIndex {
+	var last *model.SearchIndex
+	for _, index := range indices {
+		if last == nil || recallBefore(last, index, searchType) {
+			last = index
+		}
+	}
+	return last
+}
+
+// recallBefore 判断 a 在召回顺序中是否排在 b 之前
+func recallBefore(a, b *model.SearchIndex, searchType string) bool {
+	if a.SortKey != b.SortKey {
+		if searchType == "semantic" {
+			return a.SortKey < b.SortKey
+		}
+		return a.SortKey > b.SortKey
+	}
+	return a.ID < b.ID
+}
+
+// candidatesAfter 按召回顺序从游标位置继续召回 size 个结果（search_after），
+// 关键词搜索的结果按BM25计算得分但保持全文检索的顺序
+func (s *searchService) candidatesAfter(ctx context.Context, request *model.SearchRequest, query *parsedQuery, after *model.Cursor, size int) ([]*model.SearchIndex, int64, map[string]*bm25Explanation, error) {
+	if request.SearchType == "semantic" {
+		indices, total, err := s.semanticAfter(ctx, request, query, after, size)
+		return indices, total, nil, err
+	}
+
+	candidates, total, err := s.indexRepo.SearchByQueryAfter(ctx, query.Root, request.Filters, after, size)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return candidates, total, s.scoreBM25(ctx, query, candidates), nil
+}
+
+// semanticAfter 向量检索从游标位置继续召回 size 个结果，按查询语法树过滤后不足一页时继续向后召回
+func (s *searchService) semanticAfter(ctx context.Context, request *model.SearchRequest, query *parsedQuery, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error) {
+	text := query.Text()
+	if strings.TrimSpace(text) == "" {
+		return []*model.SearchIndex{}, 0, nil
+	}
+	queryVector, err := s.generateQueryVector(ctx, text)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	constraint := vectorConstraint(query.Root)
+	filters := s.vectorFilters(request.Filters)
+	position := *after
+	var results []*model.SearchIndex
+	var total int64
+	for batch := 0; batch < semanticAfterMaxBatches && len(results) < size; batch++ {
+		candidates, batchTotal, err := s.indexRepo.SearchByVectorAfter(ctx, queryVector, filters, &position, size)
+		if err != nil {
+			return nil, 0, err
+		}
+		if batch == 0 {
+			total = batchTotal
+		}
+		kept, _ := filterByConstraint(candidates, constraint)
+		results = append(results, kept...)
+		if len(candidates) < size {
+			break
+		}
+		last := candidates[len(candidates)-1]
+		position = model.Cursor{Rank: last.SortKey, ID: last.ID, Offset: position.Offset + len(candidates)}
+	}
+
+	if len(results) > size {
+		results = results[:size]
+	}
+	for _, result := range results {
+		result.Score = s.calculateRelevanceScore(result, request.Query, "semantic")
+	}
+	return results, total, nil
+}
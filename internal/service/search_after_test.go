@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// fakeKeysetIndexRepository 按召回顺序保存结果，按数据库的排序和游标条件返回结果，并记录每次召回的数量
+type fakeKeysetIndexRepository struct {
+	repository.SearchIndexRepository
+	keyword  []*model.SearchIndex // 按 (SortKey DESC, ID ASC) 排列
+	semantic []*model.SearchIndex // 按 (SortKey ASC, ID ASC) 排列
+	fetched  []int
+}
+
+func (r *fakeKeysetIndexRepository) SearchByQuery(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.page(r.keyword, (page-1)*size, size), int64(len(r.keyword)), nil
+}
+
+func (r *fakeKeysetIndexRepository) SearchByQueryAfter(ctx context.Context, query *model.QueryNode, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error) {
+	start := sort.Search(len(r.keyword), func(i int) bool {
+		index := r.keyword[i]
+		return index.SortKey < after.Rank || (index.SortKey == after.Rank && index.ID > after.ID)
+	})
+	return r.page(r.keyword, start, size), int64(len(r.keyword)), nil
+}
+
+func (r *fakeKeysetIndexRepository) SearchByVector(ctx context.Context, vector string, filters map[string]interface{}, page, size int) ([]*model.SearchIndex, int64, error) {
+	return r.page(r.semantic, (page-1)*size, size), int64(len(r.semantic)), nil
+}
+
+func (r *fakeKeysetIndexRepository) SearchByVectorAfter(ctx context.Context, vector string, filters map[string]interface{}, after *model.Cursor, size int) ([]*model.SearchIndex, int64, error) {
+	start := sort.Search(len(r.semantic), func(i int) bool {
+		index := r.semantic[i]
+		return index.SortKey > after.Rank || (index.SortKey == after.Rank && index.ID > after.ID)
+	})
+	return r.page(r.semantic, start, size), int64(len(r.semantic)), nil
+}
+
+// page 返回结果的副本，避免搜索服务修改得分影响后续请求
+func (r *fakeKeysetIndexRepository) page(rows []*model.SearchIndex, start, size int) []*model.SearchIndex {
+	r.fetched = append(r.fetched, size)
+	var result []*model.SearchIndex
+	for i := start; i < len(rows) && i < start+size; i++ {
+		index := *rows[i]
+		result = append(result, &index)
+	}
+	return result
+}
+
+// newKeysetRows 创建召回顺序的结果，每三个结果的排序值相同以检查按ID定位
+func newKeysetRows(n int, descending bool) []*model.SearchIndex {
+	rows := make([]*model.SearchIndex, n)
+	for i := range rows {
+		key := float64(i/3) * 0.01
+		if descending {
+			key = 1 - key
+		}
+		// 内容中查询词出现的次数与召回顺序无关，使BM25重新打分改变顺序
+		content := strings.Repeat("pool ", 1+(i*7)%5) + fmt.Sprintf("chunk %d", i)
+		rows[i] = &model.SearchIndex{ID: fmt.Sprintf("idx-%02d", i), Content: content, Metadata: "{}", SortKey: key}
+	}
+	return rows
+}
+
+// collectPages 按游标翻页直到没有下一页，返回各页结果的ID
+func collectPages(t *testing.T, s SearchService, searchType string, size int) []string {
+	t.Helper()
+	var ids []string
+	cursor := ""
+	for page := 0; page < 50; page++ {
+		response, err := s.Search(context.Background(), &model.SearchRequest{Query: "pool", SearchType: searchType, Size: size, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Search() page %d error = %v", page, err)
+		}
+		for _, item := range response.Items {
+			ids = append(ids, item.ID)
+		}
+		if response.NextCursor == "" {
+			return ids
+		}
+		cursor = response.NextCursor
+	}
+	t.Fatal("cursor paging did not terminate")
+	return nil
+}
+
+// TestSearchCursorKeyset 测试游标翻页越过重新打分的范围后在数据库中定位，每页召回的数量不随页数增长
+func TestSearchCursorKeyset(t *testing.T) {
+	for _, searchType := range []string{"keyword", "semantic"} {
+		t.Run(searchType, func(t *testing.T) {
+			repo := &fakeKeysetIndexRepository{keyword: newKeysetRows(20, true), semantic: newKeysetRows(20, false)}
+			config := DefaultSearchConfig()
+			config.BM25CandidateWindow = 5
+			s := NewSearchServiceWithConfig(repo, nil, nil, nil, nil, NewMemoryCache(), &fakeEmbeddingProvider{}, true, config)
+
+			ids := collectPages(t, s, searchType, 3)
+			seen := make(map[string]bool)
+			for _, id := range ids {
+				if seen[id] {
+					t.Errorf("result %s returned twice", id)
+				}
+				seen[id] = true
+			}
+			if len(seen) != 20 {
+				t.Errorf("returned %d distinct results, want 20: %v", len(seen), ids)
+			}
+			// 关键词搜索每次最多召回重新打分的5个结果，语义搜索没有重排序时只召回当前页
+			limit := 3
+			if searchType == "keyword" {
+				limit = 5
+			}
+			for _, fetched := range repo.fetched {
+				if fetched > limit {
+					t.Errorf("fetched %v, want every fetch <= %d", repo.fetched, limit)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestSearchPageNumberMatchesCursor 测试页码翻页与游标翻页的结果顺序一致
+func TestSearchPageNumberMatchesCursor(t *testing.T) {
+	repo := &fakeKeysetIndexRepository{keyword: newKeysetRows(20, true)}
+	config := DefaultSearchConfig()
+	config.BM25CandidateWindow = 5
+	s := NewSearchServiceWithConfig(repo, nil, nil, nil, nil, NewMemoryCache(), nil, true, config)
+
+	cursorIDs := collectPages(t, s, "keyword", 4)
+	var pageIDs []string
+	for page := 1; page <= 5; page++ {
+		response, err := s.Search(context.Background(), &model.SearchRequest{Query: "pool", SearchType: "keyword", Page: page, Size: 4})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		for _, item := range response.Items {
+			pageIDs = append(pageIDs, item.ID)
+		}
+	}
+	if strings.Join(cursorIDs, ",") != strings.Join(pageIDs, ",") {
+		t.Errorf("cursor order %v differs from page order %v", cursorIDs, pageIDs)
+	}
+}
+
+// TestRecallLast 测试按召回顺序查找最后的结果
+func TestRecallLast(t *testing.T) {
+	indices := []*model.SearchIndex{
+		{ID: "b", SortKey: 0.5},
+		{ID: "a", SortKey: 0.2},
+		{ID: "c", SortKey: 0.2},
+		{ID: "d", SortKey: 0.9},
+	}
+	if last := recallLast(indices, "keyword"); last.ID != "c" {
+		t.Errorf("keyword recallLast() = %s, want c (lowest score, largest ID)", last.ID)
+	}
+	if last := recallLast(indices, "semantic"); last.ID != "d" {
+		t.Errorf("semantic recallLast() = %s, want d (largest distance)", last.ID)
+	}
+	if last := recallLast(nil, "keyword"); last != nil {
+		t.Errorf("recallLast(nil) = %v, want nil", last)
+	}
+}
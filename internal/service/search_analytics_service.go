@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// SearchAnalyticsService 搜索分析服务接口
+type SearchAnalyticsService interface {
+	// RecordSearch 异步记录一次搜索请求，searchErr 不为空时记录为失败的搜索
+	RecordSearch(source, caller string, request *model.SearchRequest, response *model.SearchResponse, searchErr error, latency time.Duration)
+	// RecordClick 异步记录调用方对搜索结果（搜索索引ID或文档ID）的点击，关联到点击窗口内最近一次返回该结果的搜索
+	RecordClick(caller, targetID string)
+	// GetReport 获取最近 days 天的搜索分析报告，每个列表最多返回 limit 条
+	GetReport(ctx context.Context, days, limit int) (*model.SearchAnalyticsReport, error)
+	// Cleanup 删除超过保留天数的搜索日志和点击，返回删除的搜索日志数量
+	Cleanup(ctx context.Context, retentionDays int) (int64, error)
+	// RetentionDays 返回配置的搜索日志保留天数
+	RetentionDays() int
+}
+
+// SearchAnalyticsConfig 搜索分析配置
+type SearchAnalyticsConfig struct {
+	Enabled           bool          // 是否记录搜索日志
+	RetentionDays     int           // 搜索日志和点击的默认保留天数
+	ClickWindow       time.Duration // 获取文档内容的请求在搜索后多长时间内视为点击
+	LowCTRMinSearches int64         // 低点击率报告只统计搜索次数不少于该值的查询
+}
+
+// DefaultSearchAnalyticsConfig 返回默认的搜索分析配置
+func DefaultSearchAnalyticsConfig() *SearchAnalyticsConfig {
+	return &SearchAnalyticsConfig{
+		Enabled:           true,
+		RetentionDays:     90,
+		ClickWindow:       30 * time.Minute,
+		LowCTRMinSearches: 5,
+	}
+}
+
+// NewSearchAnalyticsConfigFromEnv 从环境变量创建搜索分析配置
+func NewSearchAnalyticsConfigFromEnv() *SearchAnalyticsConfig {
+	config := DefaultSearchAnalyticsConfig()
+	config.Enabled = getEnv("SEARCH_ANALYTICS_ENABLED", "true") != "false"
+	config.RetentionDays = getEnvInt("SEARCH_ANALYTICS_RETENTION_DAYS", config.RetentionDays)
+	config.ClickWindow = time.Duration(getEnvInt("SEARCH_ANALYTICS_CLICK_WINDOW_MINUTES", int(config.ClickWindow/time.Minute))) * time.Minute
+	config.LowCTRMinSearches = int64(getEnvInt("SEARCH_ANALYTICS_LOW_CTR_MIN_SEARCHES", int(config.LowCTRMinSearches)))
+	return config
+}
+
+// searchAnalyticsService 搜索分析服务实现
+type searchAnalyticsService struct {
+	repo   repository.SearchAnalyticsRepository
+	config *SearchAnalyticsConfig
+}
+
+// NewSearchAnalyticsService 创建搜索分析服务实例
+func NewSearchAnalyticsService(repo repository.SearchAnalyticsRepository, config *SearchAnalyticsConfig) SearchAnalyticsService {
+	if config == nil {
+		config = DefaultSearchAnalyticsConfig()
+	}
+	return &searchAnalyticsService{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// RecordSearch 异步记录一次搜索请求，不阻塞搜索响应
+func (s *searchAnalyticsService) RecordSearch(source, caller string, request *model.SearchRequest, response *model.SearchResponse, searchErr error, latency time.Duration) {
+	if !s.config.Enabled || request == nil {
+		return
+	}
+	entry := newSearchQueryLog(source, caller, request, response, searchErr, latency)
+	go func() {
+		if err := s.repo.CreateQueryLog(context.Background(), entry); err != nil {
+			log.Printf("Failed to record search query: %v", err)
+		}
+	}()
+}
+
+// RecordClick 异步记录搜索结果的点击
+func (s *searchAnalyticsService) RecordClick(caller, targetID string) {
+	if !s.config.Enabled || caller == "" || targetID == "" {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		entry, err := s.repo.FindRecentQueryLog(ctx, caller, targetID, time.Now().Add(-s.config.ClickWindow))
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Failed to find search for click: %v", err)
+			}
+			return
+		}
+
+		click := &model.SearchClick{
+			QueryLogID: entry.ID,
+			TargetID:   targetID,
+			Caller:     caller,
+		}
+		click.DocumentID, click.Position = clickPosition(entry, targetID)
+		if err := s.repo.CreateClick(ctx, click); err != nil {
+			log.Printf("Failed to record search click: %v", err)
+		}
+	}()
+}
+
+// GetReport 获取搜索分析报告
+func (s *searchAnalyticsService) GetReport(ctx context.Context, days, limit int) (*model.SearchAnalyticsReport, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	report := &model.SearchAnalyticsReport{Since: since}
+
+	total, zeroResults, clicked, err := s.repo.GetTotals(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count searches: %v", err)
+	}
+	report.TotalSearches = total
+	if total > 0 {
+		report.ZeroResultRate = float64(zeroResults) / float64(total)
+		report.CTR = float64(clicked) / float64(total)
+	}
+
+	if report.BySource, err = s.repo.CountBySource(ctx, since); err != nil {
+		return nil, fmt.Errorf("failed to count searches by source: %v", err)
+	}
+	if report.TopQueries, err = s.repo.GetTopQueries(ctx, since, limit); err != nil {
+		return nil, fmt.Errorf("failed to get top queries: %v", err)
+	}
+	if report.ZeroResultQueries, err = s.repo.GetZeroResultQueries(ctx, since, limit); err != nil {
+		return nil, fmt.Errorf("failed to get zero-result queries: %v", err)
+	}
+	if report.LowCTRQueries, err = s.repo.GetLowCTRQueries(ctx, since, s.config.LowCTRMinSearches, limit); err != nil {
+		return nil, fmt.Errorf("failed to get low-CTR queries: %v", err)
+	}
+	if report.LibraryDemand, err = s.repo.GetLibraryDemand(ctx, since, limit); err != nil {
+		return nil, fmt.Errorf("failed to get library demand: %v", err)
+	}
+	return report, nil
+}
+
+// Cleanup 删除超过保留天数的搜索日志和点击
+func (s *searchAnalyticsService) Cleanup(ctx context.Context, retentionDays int) (int64, error) {
+	return s.repo.DeleteBefore(ctx, time.Now().AddDate(0, 0, -retentionDays))
+}
+
+// RetentionDays 返回配置的搜索日志保留天数
+func (s *searchAnalyticsService) RetentionDays() int {
+	return s.config.RetentionDays
+}
+
+// newSearchQueryLog 根据搜索请求和结果构建搜索日志
+func newSearchQueryLog(source, caller string, request *model.SearchRequest, response *model.SearchResponse, searchErr error, latency time.Duration) *model.SearchQueryLog {
+	entry := &model.SearchQueryLog{
+		Query:           request.Query,
+		NormalizedQuery: normalizeAnalyticsQuery(request.Query),
+		SearchType:      request.SearchType,
+		Filters:         "{}",
+		LatencyMs:       latency.Milliseconds(),
+		Source:          source,
+		Caller:          caller,
+	}
+	if len(request.Filters) > 0 {
+		if data, err := json.Marshal(request.Filters); err == nil {
+			entry.Filters = string(data)
+		}
+	}
+	entry.Library, _ = request.Filters["library"].(string)
+
+	if searchErr != nil {
+		entry.Error = searchErr.Error()
+		return entry
+	}
+	if response == nil {
+		return entry
+	}
+
+	entry.ResultCount = response.Total
+	entry.ResultIDs = make(model.StringArray, 0, len(response.Items))
+	entry.ResultDocumentIDs = make(model.StringArray, 0, len(response.Items))
+	for _, item := range response.Items {
+		entry.ResultIDs = append(entry.ResultIDs, item.ID)
+		entry.ResultDocumentIDs = append(entry.ResultDocumentIDs, item.DocumentID)
+	}
+	if entry.Library == "" && len(response.Items) > 0 {
+		entry.Library = response.Items[0].Library
+	}
+	return entry
+}
+
+// normalizeAnalyticsQuery 规范化查询用于聚合：转为小写并合并空白
+func normalizeAnalyticsQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// clickPosition 返回点击目标所属的文档ID和在搜索结果中的排名（从1开始），优先按搜索索引ID匹配
+func clickPosition(entry *model.SearchQueryLog, targetID string) (string, int) {
+	for i, id := range entry.ResultIDs {
+		if id == targetID {
+			documentID := ""
+			if i < len(entry.ResultDocumentIDs) {
+				documentID = entry.ResultDocumentIDs[i]
+			}
+			return documentID, i + 1
+		}
+	}
+	for i, documentID := range entry.ResultDocumentIDs {
+		if documentID == targetID {
+			return documentID, i + 1
+		}
+	}
+	return "", 0
+}
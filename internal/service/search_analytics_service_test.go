@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestNormalizeAnalyticsQuery 测试查询规范化
+func TestNormalizeAnalyticsQuery(t *testing.T) {
+	tests := map[string]string{
+		"Connection Pool":         "connection pool",
+		"  gorm\tTRANSACTION \n":  "gorm transaction",
+		"":                        "",
+		"\"Exact  Phrase\" -Skip": "\"exact phrase\" -skip",
+	}
+	for query, want := range tests {
+		if got := normalizeAnalyticsQuery(query); got != want {
+			t.Errorf("normalizeAnalyticsQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+// TestNewSearchQueryLog 测试根据搜索请求和结果构建搜索日志
+func TestNewSearchQueryLog(t *testing.T) {
+	request := &model.SearchRequest{
+		Query:      "Connection  Pool",
+		SearchType: "hybrid",
+		Filters:    map[string]interface{}{"version": "1.0"},
+	}
+	response := &model.SearchResponse{
+		Total: 2,
+		Items: []model.SearchResult{
+			{ID: "idx-1", DocumentID: "doc-1", Library: "gorm"},
+			{ID: "idx-2", DocumentID: "doc-2", Library: "sqlx"},
+		},
+	}
+
+	entry := newSearchQueryLog(model.SearchSourceREST, "user-1", request, response, nil, 42*time.Millisecond)
+	if entry.NormalizedQuery != "connection pool" {
+		t.Errorf("NormalizedQuery = %q, want connection pool", entry.NormalizedQuery)
+	}
+	if entry.Library != "gorm" {
+		t.Errorf("Library = %q, want library of the top result", entry.Library)
+	}
+	if entry.ResultCount != 2 || len(entry.ResultIDs) != 2 || entry.ResultDocumentIDs[1] != "doc-2" {
+		t.Errorf("results = %d %v %v, want 2 ordered results", entry.ResultCount, entry.ResultIDs, entry.ResultDocumentIDs)
+	}
+	if entry.Filters != `{"version":"1.0"}` || entry.LatencyMs != 42 || entry.Source != "rest" || entry.Caller != "user-1" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	request.Filters["library"] = "sqlx"
+	entry = newSearchQueryLog(model.SearchSourceMCP, "key-1", request, nil, errors.New("timeout"), 0)
+	if entry.Library != "sqlx" {
+		t.Errorf("Library = %q, want library filter", entry.Library)
+	}
+	if entry.Error != "timeout" || entry.ResultCount != 0 || entry.ResultIDs != nil {
+		t.Errorf("failed search entry = %+v", entry)
+	}
+}
+
+// TestClickPosition 测试点击目标在搜索结果中的排名
+func TestClickPosition(t *testing.T) {
+	entry := &model.SearchQueryLog{
+		ResultIDs:         model.StringArray{"idx-1", "idx-2", "idx-3"},
+		ResultDocumentIDs: model.StringArray{"doc-1", "doc-2", "doc-2"},
+	}
+	tests := []struct {
+		targetID     string
+		wantDocument string
+		wantPosition int
+	}{
+		{"idx-3", "doc-2", 3},
+		{"doc-2", "doc-2", 2},
+		{"doc-9", "", 0},
+	}
+	for _, tt := range tests {
+		documentID, position := clickPosition(entry, tt.targetID)
+		if documentID != tt.wantDocument || position != tt.wantPosition {
+			t.Errorf("clickPosition(%q) = %q, %d, want %q, %d", tt.targetID, documentID, position, tt.wantDocument, tt.wantPosition)
+		}
+	}
+}
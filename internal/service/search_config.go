@@ -0,0 +1,121 @@
+package service
+
+import (
+	"strconv"
+	"time"
+)
+
+// SearchConfig 搜索服务配置
+type SearchConfig struct {
+	// 分块配置
+	ChunkSize    int // 单个分块的最大字符数
+	ChunkOverlap int // 相邻分块之间重叠的字符数
+
+	// BM25配置
+	BM25K1              float64 // 词频饱和参数
+	BM25B               float64 // 文档长度归一化参数
+	BM25CandidateWindow int     // 全文检索召回后参与BM25重排的候选数量
+
+	// 混合搜索配置
+	HybridMode            string  // 融合方式：rrf（倒数排名融合）或 weighted（归一化后加权）
+	HybridAlpha           float64 // 语义搜索的权重，关键词搜索的权重为 1-HybridAlpha
+	RRFK                  int     // RRF 的平滑常数 k
+	HybridCandidateWindow int     // 关键词和语义搜索各自参与融合的候选数量
+
+	// 分面配置
+	FacetLimit int // 每个分面最多返回的取值数量
+
+	// 结果分组配置
+	GroupPerGroup        int // 每组默认返回的分块数量
+	GroupCandidateWindow int // 分组时至少召回的候选数量，候选越多组数统计越完整
+
+	// 拼写建议配置
+	SuggestMinHits     int // 关键词搜索结果少于该数量时返回拼写建议，0 表示不返回
+	SuggestMaxDistance int // 候选词与查询词的最大编辑距离
+
+	// 同义词扩展配置
+	SynonymWeight float64 // 同义词扩展的词在BM25得分中相对原词的权重
+
+	// 重排序配置
+	Reranker      string        // 默认的重排序器：none、heuristic 或 cross-encoder
+	RerankTopN    int           // 参与重排序的候选数量
+	RerankURL     string        // 交叉编码器重排序服务地址，为空时交叉编码器不可用
+	RerankModel   string        // 交叉编码器模型名称
+	RerankAPIKey  string        // 交叉编码器服务的API密钥
+	RerankTimeout time.Duration // 交叉编码器请求超时时间
+}
+
+// DefaultSearchConfig 返回默认的搜索配置
+func DefaultSearchConfig() *SearchConfig {
+	return &SearchConfig{
+		ChunkSize:           1000,
+		ChunkOverlap:        150,
+		BM25K1:              1.2,
+		BM25B:               0.75,
+		BM25CandidateWindow: 200,
+
+		HybridMode:            HybridModeRRF,
+		HybridAlpha:           0.5,
+		RRFK:                  60,
+		HybridCandidateWindow: 100,
+
+		FacetLimit: 20,
+
+		GroupPerGroup:        3,
+		GroupCandidateWindow: 200,
+
+		SuggestMinHits:     3,
+		SuggestMaxDistance: 2,
+
+		SynonymWeight: 0.5,
+
+		Reranker:      RerankerNone,
+		RerankTopN:    50,
+		RerankTimeout: 10 * time.Second,
+	}
+}
+
+// NewSearchConfigFromEnv 从环境变量创建搜索配置
+func NewSearchConfigFromEnv() *SearchConfig {
+	config := DefaultSearchConfig()
+	config.ChunkSize = getEnvInt("SEARCH_CHUNK_SIZE", config.ChunkSize)
+	config.ChunkOverlap = getEnvInt("SEARCH_CHUNK_OVERLAP", config.ChunkOverlap)
+	config.BM25K1 = getEnvFloat("SEARCH_BM25_K1", config.BM25K1)
+	config.BM25B = getEnvFloat("SEARCH_BM25_B", config.BM25B)
+	config.BM25CandidateWindow = getEnvInt("SEARCH_BM25_CANDIDATES", config.BM25CandidateWindow)
+	config.HybridMode = getEnv("SEARCH_HYBRID_MODE", config.HybridMode)
+	config.HybridAlpha = getEnvFloat("SEARCH_HYBRID_ALPHA", config.HybridAlpha)
+	config.RRFK = getEnvInt("SEARCH_RRF_K", config.RRFK)
+	config.HybridCandidateWindow = getEnvInt("SEARCH_HYBRID_CANDIDATES", config.HybridCandidateWindow)
+	config.FacetLimit = getEnvInt("SEARCH_FACET_LIMIT", config.FacetLimit)
+	config.GroupPerGroup = getEnvInt("SEARCH_GROUP_PER_GROUP", config.GroupPerGroup)
+	config.GroupCandidateWindow = getEnvInt("SEARCH_GROUP_CANDIDATES", config.GroupCandidateWindow)
+	config.SuggestMinHits = getEnvInt("SEARCH_SUGGEST_MIN_HITS", config.SuggestMinHits)
+	config.SuggestMaxDistance = getEnvInt("SEARCH_SUGGEST_MAX_DISTANCE", config.SuggestMaxDistance)
+	config.SynonymWeight = getEnvFloat("SEARCH_SYNONYM_WEIGHT", config.SynonymWeight)
+	config.Reranker = getEnv("SEARCH_RERANKER", config.Reranker)
+	config.RerankTopN = getEnvInt("SEARCH_RERANK_TOP_N", config.RerankTopN)
+	config.RerankURL = getEnv("SEARCH_RERANK_URL", config.RerankURL)
+	config.RerankModel = getEnv("SEARCH_RERANK_MODEL", config.RerankModel)
+	config.RerankAPIKey = getEnv("SEARCH_RERANK_API_KEY", config.RerankAPIKey)
+	config.RerankTimeout = time.Duration(getEnvInt("SEARCH_RERANK_TIMEOUT", int(config.RerankTimeout/time.Second))) * time.Second
+	return config
+}
+
+// getEnvInt 获取整数类型的环境变量，解析失败时使用默认值
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat 获取浮点数类型的环境变量，解析失败时使用默认值
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/UniverseHappiness/LAST-doc/internal/model"
 	"github.com/UniverseHappiness/LAST-doc/internal/repository"
@@ -16,25 +21,32 @@ import (
 // SearchService 搜索服务接口
 type SearchService interface {
 	BuildIndex(ctx context.Context, documentID, version string) error
+	ReindexVersion(ctx context.Context, documentID, version string) (*model.IndexBuildResult, error)
 	BuildIndexBatch(ctx context.Context, indices []*model.SearchIndex) error
 	Search(ctx context.Context, request *model.SearchRequest) (*model.SearchResponse, error)
+	FindSimilar(ctx context.Context, request *model.SimilarRequest) (*model.SearchResponse, error)
 	GetIndexingStatus(ctx context.Context, documentID string) (map[string]interface{}, error)
 	DeleteIndex(ctx context.Context, documentID string) error
 	DeleteIndexByVersion(ctx context.Context, documentID, version string) error
 	ClearCache() error
+	EnsureStatistics(ctx context.Context) error
 }
 
 // searchService 搜索服务实现
 type searchService struct {
 	indexRepo        repository.SearchIndexRepository
+	statsRepo        repository.SearchStatsRepository
+	synonyms         SynonymService
 	documentRepo     repository.DocumentRepository
 	versionRepo      repository.DocumentVersionRepository
 	cacheService     CacheService
 	embeddingService EmbeddingService
 	indexingEnabled  bool
+	config           *SearchConfig
+	rerankers        map[string]Reranker
 }
 
-// NewSearchService 创建搜索服务实例
+// NewSearchService 创建搜索服务实例（使用默认搜索配置）
 func NewSearchService(
 	indexRepo repository.SearchIndexRepository,
 	documentRepo repository.DocumentRepository,
@@ -43,46 +55,44 @@ func NewSearchService(
 	embeddingService EmbeddingService,
 	indexingEnabled bool,
 ) SearchService {
+	return NewSearchServiceWithConfig(indexRepo, nil, nil, documentRepo, versionRepo, cacheService, embeddingService, indexingEnabled, DefaultSearchConfig())
+}
+
+// NewSearchServiceWithConfig 使用指定配置创建搜索服务实例
+// statsRepo 为空时BM25使用候选结果估算语料统计，synonymService 为空时不做同义词扩展
+func NewSearchServiceWithConfig(
+	indexRepo repository.SearchIndexRepository,
+	statsRepo repository.SearchStatsRepository,
+	synonymService SynonymService,
+	documentRepo repository.DocumentRepository,
+	versionRepo repository.DocumentVersionRepository,
+	cacheService CacheService,
+	embeddingService EmbeddingService,
+	indexingEnabled bool,
+	config *SearchConfig,
+) SearchService {
+	if config == nil {
+		config = DefaultSearchConfig()
+	}
 	return &searchService{
 		indexRepo:        indexRepo,
+		statsRepo:        statsRepo,
+		synonyms:         synonymService,
 		documentRepo:     documentRepo,
 		versionRepo:      versionRepo,
 		cacheService:     cacheService,
 		embeddingService: embeddingService,
 		indexingEnabled:  indexingEnabled,
+		config:           config,
+		rerankers:        newRerankers(config),
 	}
 }
 
 // BuildIndex 构建文档索引
 func (s *searchService) BuildIndex(ctx context.Context, documentID, version string) error {
-	// 获取文档版本信息
-	docVersion, err := s.versionRepo.GetByDocumentIDAndVersion(ctx, documentID, version)
-	if err != nil {
-		return fmt.Errorf("failed to get document version: %v", err)
-	}
-
-	// 检查文档是否已解析完成
-	if docVersion.Status != model.DocumentStatusCompleted {
-		return fmt.Errorf("document is not ready for indexing, status: %s", docVersion.Status)
-	}
-
-	// 获取文档信息
-	document, err := s.documentRepo.GetByID(ctx, documentID)
-	if err != nil {
-		return fmt.Errorf("failed to get document: %v", err)
-	}
-
-	// 解析文档内容并构建索引（会自动处理更新逻辑）
-	log.Printf("DEBUG: 开始解析文档内容并构建索引 - 文档ID: %s, 版本: %s", documentID, version)
-	indices, err := s.parseAndBuildIndices(document, docVersion)
-	if err != nil {
-		return fmt.Errorf("failed to parse and build indices: %v", err)
-	}
-	log.Printf("DEBUG: 解析完成，生成了 %d 个索引 - 文档ID: %s, 版本: %s", len(indices), documentID, version)
-
-	// parseAndBuildIndices已经处理了索引的删除和创建，这里不需要再创建
-	log.Printf("Successfully built %d indices for document %s version %s", len(indices), documentID, version)
-	return nil
+	// 增量重建索引，内容未变化的分块复用已有向量
+	_, err := s.ReindexVersion(ctx, documentID, version)
+	return err
 }
 
 // BuildIndexBatch 批量构建索引
@@ -90,17 +100,71 @@ func (s *searchService) BuildIndexBatch(ctx context.Context, indices []*model.Se
 	if len(indices) == 0 {
 		return nil
 	}
-	return s.indexRepo.CreateBatch(ctx, indices)
+	if err := s.indexRepo.CreateBatch(ctx, indices); err != nil {
+		return err
+	}
+	s.updateStatistics(ctx, indices, nil)
+	return nil
 }
 
 // Search 执行搜索
 func (s *searchService) Search(ctx context.Context, request *model.SearchRequest) (*model.SearchResponse, error) {
-	// 添加调试日志
-	log.Printf("DEBUG: Search called with query: %s, type: %s, page: %d, size: %d",
-		request.Query, request.SearchType, request.Page, request.Size)
+	// 解析结构化查询，字段限定符转换为过滤条件
+	parsed, err := parseSearchQuery(request.Query)
+	if err != nil {
+		return nil, err
+	}
+	searchRequest := *request
+	searchRequest.Filters = parsed.MergeFilters(request.Filters)
+
+	reranker, rerankTopN, err := s.rerankerFor(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析分页位置：游标优先于页码
+	page, size := pageAndSize(request)
+	fingerprint := searchCursorFingerprint(request)
+	offset := (page - 1) * size
+	var after *model.Cursor
+	if request.Cursor != "" {
+		if after, err = model.DecodeCursor(request.Cursor, fingerprint); err != nil {
+			return nil, err
+		}
+		offset = after.Offset
+	}
+	groupBy, perGroup := s.groupingFor(request)
+	// 关键词和语义搜索不分组时按 search_after 方式翻页：召回顺序的前 head 个结果重新打分和重排序，
+	// 之后的结果保持召回顺序，游标越过前 head 个结果后在数据库中从游标位置继续召回；
+	// 混合搜索的融合结果和分组结果没有数据库中的排序值，只能召回到当前页为止再按偏移量定位
+	keyset := groupBy == "" && request.SearchType != "hybrid"
+	window := offset + size
+	if after != nil && !keyset {
+		// 翻页期间新增的高分结果会排在游标之前，预留一页余量
+		window += size
+	}
+	// 候选窗口至少覆盖参与重排序的结果
+	window = maxInt(window, rerankTopN)
+	// 分组时页码和数量按组计算，召回更多候选以填满每组
+	if groupBy != "" {
+		window = maxInt(window*perGroup, s.config.GroupCandidateWindow)
+	}
+	head := window
+	if keyset {
+		head = s.rescoreHead(request.SearchType, rerankTopN)
+		switch {
+		case after == nil:
+			window = maxInt(window, head)
+		case after.Offset < head:
+			// 游标位于重新打分的范围内，只需召回这部分结果
+			window = head
+		default:
+			window = 0
+		}
+	}
 
 	// 生成缓存键
-	cacheKey := searchCacheKey(request.Query, request.SearchType, request.Filters, request.Page, request.Size)
+	cacheKey := searchRequestCacheKey(request)
 
 	// 尝试从缓存获取结果
 	if cachedResult, found := s.cacheService.Get(cacheKey); found {
@@ -110,71 +174,91 @@ func (s *searchService) Search(ctx context.Context, request *model.SearchRequest
 		}
 	}
 
-	var indices []*model.SearchIndex
+	// 版本范围和 latest 别名解析为各库实际匹配的版本
+	if searchRequest.Filters, err = s.resolveVersionFilter(ctx, searchRequest.Filters); err != nil {
+		return nil, err
+	}
+
+	// 关键词检索使用同义词扩展后的查询，语义搜索只使用原查询
+	var expansions []model.QueryExpansion
+	if request.SearchType != "semantic" {
+		parsed, expansions = s.expandQuery(ctx, parsed, searchRequest.Filters)
+	}
+
+	var candidates []*model.SearchIndex
 	var total int64
-	var err error
+	var explanations map[string]*bm25Explanation
+	var fusions map[string]*hybridExplanation
+	var degraded bool
 
 	startTime := time.Now()
 
-	// 根据搜索类型执行不同的搜索策略
-	switch request.SearchType {
-	case "keyword":
-		keywords := s.extractKeywords(request.Query)
-		indices, total, err = s.indexRepo.SearchByKeywords(ctx, keywords, request.Filters, request.Page, request.Size)
-		// 为每个结果计算相关性得分
-		for _, index := range indices {
-			index.Score = s.calculateRelevanceScore(index, request.Query, "keyword")
-		}
-	case "semantic":
-		// 生成查询向量
-		queryVector := s.generateQueryVector(request.Query)
-		indices, total, err = s.indexRepo.SearchByVector(ctx, queryVector, request.Filters, request.Page, request.Size)
-		// 向量搜索结果已经包含相似度得分，但可以进一步优化
-		for _, index := range indices {
-			index.Score = s.calculateRelevanceScore(index, request.Query, "semantic")
+	// 根据搜索类型召回按得分排序的候选结果，候选窗口至少覆盖到当前页
+	switch {
+	case window == 0:
+		// 游标已越过重新打分的范围，全部结果从游标位置召回
+	case request.SearchType == "semantic":
+		candidates, total, err = s.semanticCandidates(ctx, &searchRequest, parsed, window, head)
+	case request.SearchType == "hybrid":
+		// 混合搜索：关键词搜索和语义搜索各自召回候选结果，融合排序
+		candidates, total, explanations, fusions, degraded, err = s.hybridCandidates(ctx, &searchRequest, parsed, maxInt(s.config.HybridCandidateWindow, window))
+	default:
+		// 默认使用关键词搜索
+		if !keyset {
+			window = maxInt(s.config.BM25CandidateWindow, window)
+			head = window
 		}
-	case "hybrid":
-		// 混合搜索：先关键词搜索，再语义搜索，然后合并结果
-		var keywordIndices []*model.SearchIndex
-		var semanticIndices []*model.SearchIndex
-		var keywordTotal, semanticTotal int64
+		candidates, total, explanations, err = s.keywordCandidates(ctx, &searchRequest, parsed, window, head)
+	}
 
-		// 关键词搜索
-		keywords := s.extractKeywords(request.Query)
-		keywordIndices, keywordTotal, err = s.indexRepo.SearchByKeywords(ctx, keywords, request.Filters, request.Page, request.Size)
-		if err != nil {
-			return nil, fmt.Errorf("keyword search failed: %v", err)
-		}
-		// 为关键词搜索结果计算相关性得分
-		for _, index := range keywordIndices {
-			index.Score = s.calculateRelevanceScore(index, request.Query, "keyword")
-		}
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	rerankings := rerankCandidates(ctx, reranker, rerankTopN, parsed.Text(), candidates)
+	indices := pageAfter(candidates, after, offset, size)
 
-		// 语义搜索
-		queryVector := s.generateQueryVector(request.Query)
-		semanticIndices, semanticTotal, err = s.indexRepo.SearchByVector(ctx, queryVector, request.Filters, request.Page, request.Size)
-		if err != nil {
-			return nil, fmt.Errorf("semantic search failed: %v", err)
-		}
-		// 为语义搜索结果计算相关性得分
-		for _, index := range semanticIndices {
-			index.Score = s.calculateRelevanceScore(index, request.Query, "semantic")
+	// 重新打分的范围在召回顺序中的最后一个结果，翻页越过该范围后从它之后继续召回
+	var boundary *model.SearchIndex
+	if keyset {
+		boundary = recallLast(candidates[:minInt(head, len(candidates))], request.SearchType)
+	}
+	if keyset && after != nil && len(indices) < size {
+		position := after
+		if after.Offset < head {
+			position = nil
+			if boundary != nil && int64(offset+len(indices)) < total {
+				position = &model.Cursor{Rank: boundary.SortKey, ID: boundary.ID, Offset: offset + len(indices)}
+			}
 		}
-
-		// 合并结果
-		indices, total = s.mergeSearchResults(keywordIndices, semanticIndices, keywordTotal, semanticTotal)
-	default:
-		// 默认使用关键词搜索
-		keywords := s.extractKeywords(request.Query)
-		indices, total, err = s.indexRepo.SearchByKeywords(ctx, keywords, request.Filters, request.Page, request.Size)
-		// 为每个结果计算相关性得分
-		for _, index := range indices {
-			index.Score = s.calculateRelevanceScore(index, request.Query, "keyword")
+		if position != nil {
+			tail, tailTotal, tailExplanations, err := s.candidatesAfter(ctx, &searchRequest, parsed, position, size-len(indices))
+			if err != nil {
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
+			indices = append(append([]*model.SearchIndex{}, indices...), tail...)
+			total = maxInt64(total, tailTotal)
+			if explanations == nil {
+				explanations = make(map[string]*bm25Explanation, len(tailExplanations))
+			}
+			for id, explanation := range tailExplanations {
+				explanations[id] = explanation
+			}
 		}
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("search failed: %v", err)
+	// 按文档或库折叠结果，总数为候选结果中的组数，游标按每组得分最高的分块定位
+	hits := total
+	cursorIndices := indices
+	if boundary != nil && len(indices) > 0 && offset+len(indices) == head {
+		// 当前页恰好结束于重新打分的范围时，下一页从该范围在召回顺序中的最后一个结果之后继续
+		cursorIndices = append(append([]*model.SearchIndex{}, indices[:len(indices)-1]...), boundary)
+	}
+	var groups []*candidateGroup
+	if groupBy != "" {
+		allGroups := groupCandidates(candidates, groupBy, perGroup)
+		groups = pageGroups(allGroups, after, offset, size)
+		total = int64(len(allGroups))
+		indices, cursorIndices = flattenGroups(groups), groupLeaders(groups)
 	}
 
 	// 计算搜索耗时
@@ -182,19 +266,72 @@ func (s *searchService) Search(ctx context.Context, request *model.SearchRequest
 	log.Printf("Search completed in %v for query: %s", duration, request.Query)
 
 	// 转换为搜索结果，传递查询词以便在片段中显示上下文
-	log.Printf("DEBUG: Found %d indices, total count: %d", len(indices), total)
-	results := s.convertToSearchResultsWithQuery(indices, request.Query)
-	log.Printf("DEBUG: Converted to %d search results", len(results))
+	results := s.convertToSearchResultsWithQuery(indices, parsed.Text())
+
+	// 生成命中片段，在元数据中附加BM25得分明细、混合搜索融合明细和重排序明细
+	leaves := parsed.Root.PositiveLeaves()
+	for i := range results {
+		results[i].Highlights = buildHighlights(results[i].Content, chunkStartChar(results[i].Metadata), leaves)
+		if explanation, ok := explanations[results[i].ID]; ok {
+			results[i].Metadata["bm25"] = explanation
+		}
+		if fusion, ok := fusions[results[i].ID]; ok {
+			results[i].Metadata["hybrid"] = fusion
+		}
+		if reranking, ok := rerankings[results[i].ID]; ok {
+			results[i].Metadata["rerank"] = reranking
+		}
+	}
 
 	response := &model.SearchResponse{
 		Total: total,
 		Items: results,
 		Page:  request.Page,
 		Size:  request.Size,
+		// 下一页游标
+		NextCursor: nextSearchCursor(cursorIndices, offset, total, fingerprint),
+		Degraded:   degraded,
+	}
+	if request.Explain {
+		response.Expansions = expansions
+	}
+	if groupBy != "" {
+		response.Groups = buildResultGroups(groups, results)
+		response.TotalHits = hits
 	}
 
-	// 智能缓存策略
-	s.applyCacheStrategy(cacheKey, response, duration, request)
+	// 统计分面，语义搜索和混合搜索的匹配范围为满足过滤条件的全部索引
+	if facets := normalizeFacets(request.Facets); len(facets) > 0 {
+		facetQuery := parsed.Root
+		if request.SearchType == "semantic" || request.SearchType == "hybrid" {
+			facetQuery = nil
+		}
+		if counts, err := s.indexRepo.Facets(ctx, facetQuery, searchRequest.Filters, facets, s.config.FacetLimit); err != nil {
+			log.Printf("WARNING: Failed to count search facets: %v", err)
+		} else {
+			response.Facets = counts
+		}
+	}
+
+	// 关键词检索命中过少时根据索引词表给出拼写建议，混合搜索按其中关键词检索的命中数判断
+	keywordHits := hits
+	if request.SearchType == "hybrid" {
+		keywordHits = int64(len(explanations))
+	}
+	if request.SearchType != "semantic" && keywordHits < int64(s.config.SuggestMinHits) {
+		if suggestions, suggested := s.suggestSpelling(ctx, request.Query, parsed); suggested != "" {
+			if request.AutoCorrect {
+				response = s.searchCorrected(ctx, request, suggested, response)
+			}
+			response.Suggestions = suggestions
+			response.SuggestedQuery = suggested
+		}
+	}
+
+	// 智能缓存策略，降级的结果不缓存，嵌入服务恢复后立即返回完整结果
+	if !degraded {
+		s.applyCacheStrategy(cacheKey, response, duration, request)
+	}
 
 	return response, nil
 }
@@ -206,12 +343,28 @@ func (s *searchService) GetIndexingStatus(ctx context.Context, documentID string
 
 // DeleteIndex 删除索引
 func (s *searchService) DeleteIndex(ctx context.Context, documentID string) error {
-	return s.indexRepo.DeleteByDocumentID(ctx, documentID)
+	removed, err := s.indexRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return err
+	}
+	if err := s.indexRepo.DeleteByDocumentID(ctx, documentID); err != nil {
+		return err
+	}
+	s.updateStatistics(ctx, nil, removed)
+	return nil
 }
 
 // DeleteIndexByVersion 删除指定版本的索引
 func (s *searchService) DeleteIndexByVersion(ctx context.Context, documentID, version string) error {
-	return s.indexRepo.DeleteByDocumentIDAndVersion(ctx, documentID, version)
+	removed, err := s.indexRepo.GetByDocumentIDAndVersion(ctx, documentID, version)
+	if err != nil {
+		return err
+	}
+	if err := s.indexRepo.DeleteByDocumentIDAndVersion(ctx, documentID, version); err != nil {
+		return err
+	}
+	s.updateStatistics(ctx, nil, removed)
+	return nil
 }
 
 // ClearCache 清空缓存
@@ -219,263 +372,396 @@ func (s *searchService) ClearCache() error {
 	return s.cacheService.Clear()
 }
 
-// parseAndBuildIndices 解析文档内容并构建索引
-func (s *searchService) parseAndBuildIndices(document *model.Document, docVersion *model.DocumentVersion) ([]*model.SearchIndex, error) {
-	// 直接使用整个文档内容，不再分段处理
-	content := docVersion.Content
-	contentLength := len(content)
+// buildChunkIndex 为单个分块构建索引条目
+func (s *searchService) buildChunkIndex(document *model.Document, docVersion *model.DocumentVersion, chunk documentChunk) *model.SearchIndex {
+	now := time.Now()
+	index := &model.SearchIndex{
+		ID:            generateID(),
+		DocumentID:    document.ID,
+		Version:       docVersion.Version,
+		Content:       chunk.Content,
+		ContentType:   chunk.ContentType,
+		Section:       chunk.Section,
+		Keywords:      "", // 不使用关键词
+		Metadata:      s.buildChunkMetadata(document, docVersion, chunk),
+		StartPosition: chunk.StartChar,
+		EndPosition:   chunk.EndChar,
+		ContentHash:   contentHash(chunk.Content),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
 
-	// 生成向量
-	vectorSlice := s.generateContentVector(content)
-	embeddingSlice := s.generateEmbedding(content) // 生成真实嵌入向量
+	return index
+}
 
-	// 将向量转换为JSON字符串
-	vectorJSON, err := json.Marshal(vectorSlice)
+// keywordCandidates 全文检索召回最多 window 个候选结果，返回按BM25得分排序的结果
+// BM25只统计肯定关键词，排除条件只影响召回
+// 只有前 head 个结果按BM25得分重新排序，之后的结果保持全文检索的召回顺序
+func (s *searchService) keywordCandidates(ctx context.Context, request *model.SearchRequest, query *parsedQuery, window, head int) ([]*model.SearchIndex, int64, map[string]*bm25Explanation, error) {
+	candidates, total, err := s.indexRepo.SearchByQuery(ctx, query.Root, request.Filters, 1, window)
 	if err != nil {
-		log.Printf("Error marshaling vector to JSON: %v", err)
-		vectorJSON = []byte("[]")
+		return nil, 0, nil, err
 	}
 
-	// 计算位置信息：从文档开始到结束
-	startPos := 0
-	endPos := contentLength
+	explanations := s.scoreBM25(ctx, query, candidates)
+	sortByScore(candidates[:minInt(head, len(candidates))])
 
-	// 创建单个索引条目
-	newID := generateID()
-	index := &model.SearchIndex{
-		ID:            newID,
-		DocumentID:    document.ID,
-		Version:       docVersion.Version,
-		Content:       content,
-		ContentType:   "text",
-		Section:       document.Name,
-		Keywords:      "",                 // 不使用关键词
-		Vector:        string(vectorJSON), // 传统向量，以JSON字符串格式存储
-		Metadata:      s.buildMetadataWithPosition(document, docVersion, startPos, endPos),
-		StartPosition: startPos, // 记录起始位置
-		EndPosition:   endPos,   // 记录结束位置
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
+	return candidates, total, explanations, nil
+}
 
-	// 只有在 pgvector 扩展可用且生成了有效嵌入向量时才设置 Embedding 字段
-	if embeddingSlice != nil {
-		index.Embedding = embeddingSlice
+// scoreBM25 按BM25计算结果的得分，返回各结果的得分明细
+func (s *searchService) scoreBM25(ctx context.Context, query *parsedQuery, candidates []*model.SearchIndex) map[string]*bm25Explanation {
+	// 同义词扩展的词按较低的权重参与评分
+	terms := appendWeightedTerms(bm25QueryTerms(query.Keywords()), bm25QueryTerms(query.SynonymKeywords()), s.config.SynonymWeight)
+	scorer := s.newBM25Scorer(ctx, terms, candidates)
+
+	explanations := make(map[string]*bm25Explanation, len(candidates))
+	for _, candidate := range candidates {
+		raw, contributions := scorer.score(terms, indexTokens(candidate))
+		// 归一化到 [0, 1)，与语义搜索的相似度得分保持同一量级
+		candidate.Score = float32(raw / (raw + 1))
+		explanations[candidate.ID] = &bm25Explanation{Score: raw, Terms: contributions}
 	}
+	return explanations
+}
 
-	indices := []*model.SearchIndex{index}
-
-	// 删除该文档版本的所有现有索引
-	if err := s.indexRepo.DeleteByDocumentIDAndVersion(context.Background(), document.ID, docVersion.Version); err != nil {
-		log.Printf("Error deleting existing indices: %v", err)
+// semanticCandidates 向量检索召回前 window 个结果，返回按相似度排序的结果，前 head 个结果按调整后的得分重新排序
+func (s *searchService) semanticCandidates(ctx context.Context, request *model.SearchRequest, query *parsedQuery, window, head int) ([]*model.SearchIndex, int64, error) {
+	// 生成查询向量，只使用肯定关键词，不包含运算符和字段限定符；没有肯定关键词时没有可比较的语义
+	text := query.Text()
+	if strings.TrimSpace(text) == "" {
+		return []*model.SearchIndex{}, 0, nil
 	}
+	queryVector, err := s.generateQueryVector(ctx, text)
+	if err != nil {
+		return nil, 0, err
+	}
+	candidates, total, err := s.indexRepo.SearchByVector(ctx, queryVector, s.vectorFilters(request.Filters), 1, window)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 向量检索不考虑排除条件、短语和 AND，按查询语法树过滤召回结果
+	candidates, removed := filterByConstraint(candidates, vectorConstraint(query.Root))
+	total -= int64(removed)
 
-	// 创建新索引
-	log.Printf("DEBUG: 创建索引 - 文档ID: %s, 版本: %s, 位置: %d-%d", document.ID, docVersion.Version, startPos, endPos)
-	if err := s.indexRepo.Create(context.Background(), index); err != nil {
-		log.Printf("DEBUG: 创建索引失败 - 文档ID: %s, 版本: %s, 错误: %v", document.ID, docVersion.Version, err)
-		return nil, fmt.Errorf("failed to create index: %v", err)
+	// 向量搜索结果已经包含相似度得分，但可以进一步优化
+	for _, candidate := range candidates {
+		candidate.Score = s.calculateRelevanceScore(candidate, request.Query, "semantic")
 	}
-	log.Printf("DEBUG: 创建索引成功 - 文档ID: %s, 版本: %s, 位置: %d-%d", document.ID, docVersion.Version, startPos, endPos)
+	sortByScore(candidates[:minInt(head, len(candidates))])
 
-	log.Printf("Successfully built index for document %s version %s with position %d-%d", document.ID, docVersion.Version, startPos, endPos)
+	return candidates, total, nil
+}
 
-	return indices, nil
+// vectorFilters 在过滤条件上限定活动嵌入模型，语义搜索只比较同一模型生成的向量
+func (s *searchService) vectorFilters(filters map[string]interface{}) map[string]interface{} {
+	if s.embeddingService == nil {
+		return filters
+	}
+	scoped := make(map[string]interface{}, len(filters)+1)
+	for key, value := range filters {
+		scoped[key] = value
+	}
+	scoped["embedding_model"] = s.embeddingService.ModelName()
+	return scoped
 }
 
-// extractKeywords 从查询中提取关键词，严格匹配
-func (s *searchService) extractKeywords(query string) []string {
-	// 去除首尾空格，保持原始大小写
-	query = strings.TrimSpace(query)
-	if query == "" {
-		return []string{}
+// expandQuery 使用全局同义词和过滤条件中所属库的同义词扩展查询，没有扩展时返回原查询
+func (s *searchService) expandQuery(ctx context.Context, query *parsedQuery, filters map[string]interface{}) (*parsedQuery, []model.QueryExpansion) {
+	if s.synonyms == nil {
+		return query, nil
+	}
+	library := ""
+	if value, ok := filters["library"]; ok && value != nil {
+		library = fmt.Sprint(value)
 	}
 
-	// 直接按空格分词，不做复杂处理
-	words := strings.Fields(query)
+	root, expansions := expandSynonyms(query.Root, s.synonyms.Synonyms(ctx, library))
+	if len(expansions) == 0 {
+		return query, nil
+	}
+	for i := range expansions {
+		expansions[i].Weight = s.config.SynonymWeight
+	}
+	expanded := *query
+	expanded.Root = root
+	return &expanded, expansions
+}
 
-	// 过滤空字符串
-	var keywords []string
-	for _, word := range words {
-		if word != "" {
-			keywords = append(keywords, word)
-		}
+// searchCorrected 使用纠正拼写后的查询重新搜索，失败时返回原结果
+func (s *searchService) searchCorrected(ctx context.Context, request *model.SearchRequest, corrected string, original *model.SearchResponse) *model.SearchResponse {
+	correctedRequest := *request
+	correctedRequest.Query = corrected
+	correctedRequest.AutoCorrect = false
+	correctedRequest.Cursor = ""
+
+	response, err := s.Search(ctx, &correctedRequest)
+	if err != nil {
+		log.Printf("WARNING: Search with corrected query %q failed: %v", corrected, err)
+		return original
 	}
 
-	return keywords
+	// 复制一份，避免修改缓存中的结果
+	result := *response
+	result.CorrectedQuery = corrected
+	return &result
 }
 
-// generateQueryVector 生成查询向量
-func (s *searchService) generateQueryVector(query string) string {
-	// 生成真实的嵌入向量
-	embedding := s.generateEmbedding(query)
-	if embedding == nil {
-		// 如果嵌入生成失败，使用备用方法
-		return s.generateFallbackVector(query)
+// resolveVersionFilter 将版本范围和 latest、latest-stable 别名按库解析为满足条件的最高版本
+// 普通版本号不做处理，仍按原样精确匹配
+func (s *searchService) resolveVersionFilter(ctx context.Context, filters map[string]interface{}) (map[string]interface{}, error) {
+	value, ok := filters["version"]
+	if !ok || value == nil {
+		return filters, nil
+	}
+	constraint, err := parseVersionConstraint(fmt.Sprint(value))
+	if err != nil || constraint == nil {
+		return filters, err
 	}
 
-	// 将向量转换为JSON字符串
-	vectorJSON, err := json.Marshal(embedding)
+	library := ""
+	if value, ok := filters["library"]; ok && value != nil {
+		library = fmt.Sprint(value)
+	}
+	rows, err := s.versionRepo.GetLibraryVersions(ctx, library)
 	if err != nil {
-		log.Printf("Error marshaling vector to JSON: %v", err)
-		return "[]"
+		return nil, fmt.Errorf("failed to resolve version %q: %v", value, err)
 	}
-	return string(vectorJSON)
-}
-
-// generateFallbackVector 生成备用向量（当嵌入服务不可用时）
-func (s *searchService) generateFallbackVector(query string) string {
-	// 备用向量生成方法
-	keywords := s.extractKeywords(query)
-	vector := make([]float32, 100) // 假设向量维度为100
 
-	// 简单的哈希映射
-	for _, keyword := range keywords {
-		hash := simpleHash(keyword)
-		// 确保索引为正数
-		index := hash % 100
-		if index < 0 {
-			index = -index
+	// 按库分组，每个库分别解析
+	byLibrary := make(map[string][]*model.LibraryVersion)
+	var libraries []string
+	for _, row := range rows {
+		if _, ok := byLibrary[row.Library]; !ok {
+			libraries = append(libraries, row.Library)
 		}
-		index = index % 100 // 再次确保在范围内
-		vector[index] += 1.0
+		byLibrary[row.Library] = append(byLibrary[row.Library], row)
 	}
+	sort.Strings(libraries)
 
-	// 归一化
-	norm := float32(0)
-	for _, v := range vector {
-		norm += v * v
+	refs := []model.DocumentVersionRef{}
+	for _, lib := range libraries {
+		var versions []string
+		for _, row := range byLibrary[lib] {
+			versions = append(versions, row.Version)
+		}
+		matched := make(map[string]bool)
+		for _, version := range constraint.resolve(versions) {
+			matched[version] = true
+		}
+		for _, row := range byLibrary[lib] {
+			if matched[row.Version] {
+				refs = append(refs, model.DocumentVersionRef{DocumentID: row.DocumentID, Version: row.Version})
+			}
+		}
 	}
-	if norm > 0 {
-		norm = sqrt(norm)
-		for i := range vector {
-			vector[i] /= norm
+
+	resolved := make(map[string]interface{}, len(filters))
+	for key, value := range filters {
+		if key != "version" {
+			resolved[key] = value
 		}
 	}
+	resolved["document_versions"] = refs
+	return resolved, nil
+}
 
-	// 将向量转换为JSON字符串
-	vectorJSON, err := json.Marshal(vector)
-	if err != nil {
-		log.Printf("Error marshaling fallback vector to JSON: %v", err)
-		return "[]"
+// sortByScore 按得分降序排列，得分相同时按ID升序，与游标分页的顺序一致
+func sortByScore(indices []*model.SearchIndex) {
+	sort.SliceStable(indices, func(i, j int) bool {
+		if indices[i].Score != indices[j].Score {
+			return indices[i].Score > indices[j].Score
+		}
+		return indices[i].ID < indices[j].ID
+	})
+}
+
+// normalizeFacets 去除重复和不支持的分面
+func normalizeFacets(facets []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, facet := range facets {
+		facet = strings.TrimSpace(facet)
+		if !model.IsValidFacet(facet) || seen[facet] {
+			continue
+		}
+		seen[facet] = true
+		result = append(result, facet)
 	}
-	return string(vectorJSON)
+	return result
 }
 
-// generateContentVector 生成内容向量
-func (s *searchService) generateContentVector(content string) []float32 {
-	// 生成真实的嵌入向量
-	embedding := s.generateEmbedding(content)
-	if embedding == nil {
-		// 如果嵌入生成失败，使用备用方法
-		return s.generateFallbackContentVector(content)
+// pageAndSize 返回请求的页码和每页数量，无效值使用默认值
+func pageAndSize(request *model.SearchRequest) (int, int) {
+	page, size := request.Page, request.Size
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 10
 	}
-	return embedding
+	return page, size
 }
 
-// generateFallbackContentVector 生成备用内容向量（当嵌入服务不可用时）
-func (s *searchService) generateFallbackContentVector(content string) []float32 {
-	// 备用向量生成方法
-	vector := make([]float32, 100) // 假设向量维度为100
+// pageAfter 返回游标之后（没有游标时跳过 offset 个结果）的一页结果
+// indices 须按 sortByScore 的顺序排列
+func pageAfter(indices []*model.SearchIndex, after *model.Cursor, offset, size int) []*model.SearchIndex {
+	start := offset
+	if after != nil {
+		start = -1
+		// 游标对应的结果仍在候选中时从它之后开始，重排序后得分相同的结果不一定按ID排列
+		for i, index := range indices {
+			if index.ID == after.ID {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			// 得分按索引中的精度比较
+			score := float32(after.Score)
+			start = sort.Search(len(indices), func(i int) bool {
+				return indices[i].Score < score || (indices[i].Score == score && indices[i].ID > after.ID)
+			})
+		}
+	}
 
-	// 简单的哈希映射
-	for i := 0; i < len(vector); i++ {
-		// 基于内容位置生成伪随机值
-		hash := simpleHash(fmt.Sprintf("%s-%d", content, i))
-		// 转换为 -1 到 1 之间的浮点数
-		vector[i] = float32(hash%1000)/500.0 - 1.0
+	if start >= len(indices) {
+		return []*model.SearchIndex{}
+	}
+	end := start + size
+	if end > len(indices) {
+		end = len(indices)
 	}
+	return indices[start:end]
+}
 
-	// 归一化
-	norm := float32(0)
-	for _, v := range vector {
-		norm += v * v
+// nextSearchCursor 根据当前页最后一条结果生成下一页游标，没有更多结果时返回空字符串
+func nextSearchCursor(indices []*model.SearchIndex, offset int, total int64, fingerprint string) string {
+	returned := offset + len(indices)
+	if len(indices) == 0 || int64(returned) >= total {
+		return ""
 	}
-	if norm > 0 {
-		norm = sqrt(norm)
-		for i := range vector {
-			vector[i] /= norm
-		}
+
+	last := indices[len(indices)-1]
+	cursor := &model.Cursor{
+		Score:  float64(last.Score),
+		Rank:   last.SortKey,
+		ID:     last.ID,
+		Offset: returned,
+		Query:  fingerprint,
 	}
+	return cursor.Encode()
+}
 
-	return vector
+// searchCursorFingerprint 返回搜索游标的查询指纹，只包含影响结果集合和排序的参数
+func searchCursorFingerprint(request *model.SearchRequest) string {
+	key := *request
+	key.Page, key.Size, key.Cursor, key.Facets, key.AutoCorrect, key.Explain = 0, 0, "", nil, false, false
+	return cursorFingerprint(key)
 }
 
-// generateEmbedding 生成真实嵌入向量
-func (s *searchService) generateEmbedding(content string) []float32 {
-	// 如果内容为空，返回 nil
-	if strings.TrimSpace(content) == "" {
-		return nil
-	}
+// cursorFingerprint 计算游标的查询指纹
+func cursorFingerprint(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
 
-	// 使用嵌入服务生成向量
-	ctx := context.Background()
-	embedding, err := s.embeddingService.GenerateEmbedding(ctx, content)
-	if err != nil {
-		log.Printf("Error generating embedding with service: %v, falling back to mock service", err)
+// minInt 返回两个整数中较小的一个
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
 
-		// 如果嵌入服务失败，使用模拟服务
-		mockService := NewMockEmbeddingService()
-		embedding, err = mockService.GenerateEmbedding(ctx, content)
-		if err != nil {
-			log.Printf("Error generating embedding with mock service: %v", err)
-			return nil
-		}
+// maxInt64 返回两个整数中较大的一个
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
 	}
+	return b
+}
 
-	return embedding
+// maxInt 返回两个整数中较大的一个
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
-// mergeSearchResults 合并搜索结果
-func (s *searchService) mergeSearchResults(keywordIndices, semanticIndices []*model.SearchIndex, keywordTotal, semanticTotal int64) ([]*model.SearchIndex, int64) {
-	// 高级结果合并策略，使用加权评分
-	// 关键词搜索权重 0.6，语义搜索权重 0.4
+// extractKeywords 从查询中提取关键词
+// 按空白拆分，双引号（包括中文引号）内的内容作为一个短语保留，以 * 结尾的关键词表示前缀匹配
+func (s *searchService) extractKeywords(query string) []string {
+	// 去除首尾空格，保持原始大小写
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []string{}
+	}
 
-	// 创建结果映射，避免重复
-	resultMap := make(map[string]*model.SearchIndex)
+	var keywords []string
+	var current strings.Builder
+	inPhrase := false
 
-	// 添加关键词搜索结果
-	for _, idx := range keywordIndices {
-		// 关键词搜索结果使用权重 0.6
-		idx.Score = idx.Score * 0.6
-		resultMap[idx.ID] = idx
+	flush := func() {
+		if word := strings.TrimSpace(current.String()); word != "" {
+			keywords = append(keywords, word)
+		}
+		current.Reset()
 	}
 
-	// 添加语义搜索结果
-	for _, idx := range semanticIndices {
-		if existing, ok := resultMap[idx.ID]; ok {
-			// 如果已存在，合并分数，语义搜索权重 0.4
-			existing.Score = existing.Score + (idx.Score * 0.4)
-		} else {
-			// 语义搜索结果使用权重 0.4
-			idx.Score = idx.Score * 0.4
-			resultMap[idx.ID] = idx
+	for _, r := range query {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			flush()
+			inPhrase = !inPhrase
+		case !inPhrase && unicode.IsSpace(r):
+			flush()
+		default:
+			current.WriteRune(r)
 		}
 	}
+	flush()
+
+	return keywords
+}
 
-	// 转换为切片
-	var results []*model.SearchIndex
-	for _, idx := range resultMap {
-		results = append(results, idx)
+// generateQueryVector 生成查询向量（JSON字符串），嵌入服务不可用时返回错误，不使用备用向量
+func (s *searchService) generateQueryVector(ctx context.Context, query string) (string, error) {
+	embedding, err := s.generateEmbedding(ctx, query)
+	if err != nil {
+		return "", err
 	}
 
-	// 按分数排序
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[i].Score < results[j].Score {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	// 将向量转换为JSON字符串
+	vectorJSON, err := json.Marshal(embedding)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal query vector: %v", err)
 	}
+	return string(vectorJSON), nil
+}
 
-	// 计算总数
-	// 使用两个搜索结果中较大的总数
-	total := keywordTotal
-	if semanticTotal > keywordTotal {
-		total = semanticTotal
+// generateContentVector 生成内容向量，嵌入服务不可用时返回错误，不使用备用向量
+func (s *searchService) generateContentVector(ctx context.Context, content string) ([]float32, error) {
+	return s.generateEmbedding(ctx, content)
+}
+
+// generateEmbedding 使用嵌入服务生成向量，失败时返回包装了 ErrEmbeddingUnavailable 的错误
+func (s *searchService) generateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is empty")
+	}
+	if s.embeddingService == nil {
+		return nil, ErrEmbeddingUnavailable
 	}
 
-	return results, total
+	embedding, err := s.embeddingService.GenerateEmbedding(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEmbeddingUnavailable, err)
+	}
+	return embedding, nil
 }
 
 // calculateRelevanceScore 计算相关性得分
@@ -484,22 +770,8 @@ func (s *searchService) calculateRelevanceScore(index *model.SearchIndex, query
 
 	switch searchType {
 	case "keyword":
-		// 关键词搜索：基于匹配度和出现频率计算得分
-		keywords := s.extractKeywords(query)
-		content := strings.ToLower(index.Content)
-		matchCount := 0
-
-		for _, keyword := range keywords {
-			keywordLower := strings.ToLower(keyword)
-			count := strings.Count(content, keywordLower)
-			if count > 0 {
-				matchCount++
-			}
-		}
-
-		if len(keywords) > 0 {
-			score = float32(matchCount) / float32(len(keywords))
-		}
+		// 关键词搜索：使用全文检索的 ts_rank_cd 得分
+		score = index.Score
 
 	case "semantic":
 		// 语义搜索：使用向量相似度作为得分
@@ -519,16 +791,14 @@ func (s *searchService) convertToSearchResultsWithQuery(indices []*model.SearchI
 	var results []model.SearchResult
 
 	for _, idx := range indices {
-		// 计算片段在原始文档中的位置
-		startPos := 0
-		endPos := len(idx.Content)
-
-		// 如果搜索索引中存储了位置信息，使用它
-		if idx.StartPosition > 0 {
-			startPos = idx.StartPosition
-		}
-		if idx.EndPosition > 0 && idx.EndPosition <= len(idx.Content) {
-			endPos = idx.EndPosition
+		// 计算分块在原始文档中的位置
+		startPos := idx.StartPosition
+		endPos := idx.EndPosition
+
+		// 旧索引没有存储位置信息时，按整篇文档处理
+		if endPos <= startPos {
+			startPos = 0
+			endPos = utf8.RuneCountInString(idx.Content)
 		}
 
 		// 生成内容片段，优先显示包含查询词的上下文
@@ -873,16 +1143,36 @@ func (s *searchService) buildSimpleMetadata(document *model.Document, docVersion
 	return string(metadataJSON)
 }
 
-// buildMetadataWithPosition 构建包含位置信息的元数据
-func (s *searchService) buildMetadataWithPosition(document *model.Document, docVersion *model.DocumentVersion, startPos, endPos int) string {
+// buildChunkMetadata 构建分块的元数据，包含位置和章节信息
+func (s *searchService) buildChunkMetadata(document *model.Document, docVersion *model.DocumentVersion, chunk documentChunk) string {
+	headingPath := chunk.HeadingPath
+	if headingPath == nil {
+		headingPath = []string{}
+	}
+
 	metadata := map[string]interface{}{
 		"document_name":    document.Name,
 		"document_type":    document.Type,
 		"document_library": document.Library,
 		"version":          docVersion.Version,
-		"start_position":   float64(startPos), // JSON中数字默认为float64
-		"end_position":     float64(endPos),
-		"content_length":   float64(endPos - startPos),
+		"start_position":   float64(chunk.StartChar), // JSON中数字默认为float64，位置均为字符偏移
+		"end_position":     float64(chunk.EndChar),
+		"start_char":       float64(chunk.StartChar),
+		"content_length":   float64(chunk.EndChar - chunk.StartChar),
+		"chunk_index":      float64(chunk.Index),
+		"heading_path":     headingPath,
+		"heading_level":    float64(chunk.HeadingLevel),
+	}
+
+	// 代码分块记录语言和所属标题，便于按语言过滤
+	if chunk.ContentType == "code" {
+		heading := ""
+		if len(headingPath) > 0 {
+			heading = headingPath[len(headingPath)-1]
+		}
+		metadata["language"] = chunk.Language
+		metadata["heading"] = heading
+		metadata["code_style"] = chunk.CodeStyle
 	}
 
 	// 使用json.Marshal进行正确的JSON序列化
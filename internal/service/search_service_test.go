@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestExtractKeywords 测试查询关键词提取
+func TestExtractKeywords(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"空查询", "   ", []string{}},
+		{"按空白拆分", "gin  router\tgroup", []string{"gin", "router", "group"}},
+		{"保留短语", `"http server" config`, []string{"http server", "config"}},
+		{"中文引号", "“快速 开始” 安装", []string{"快速 开始", "安装"}},
+		{"前缀", "conf* gin", []string{"conf*", "gin"}},
+		{"未闭合的引号", `"hello world`, []string{"hello world"}},
+	}
+
+	s := &searchService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.extractKeywords(tt.query)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("extractKeywords(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeFacets 测试分面去重和过滤
+func TestNormalizeFacets(t *testing.T) {
+	tests := []struct {
+		name   string
+		facets []string
+		want   []string
+	}{
+		{"空列表", nil, nil},
+		{"保持顺序并去重", []string{"version", "library", "version"}, []string{"version", "library"}},
+		{"忽略不支持的分面", []string{"tags", "author", " content_type "}, []string{"tags", "content_type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeFacets(tt.facets)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("normalizeFacets(%v) = %v, want %v", tt.facets, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPageAfter 测试游标分页在结果变化时不重复也不遗漏
+func TestPageAfter(t *testing.T) {
+	indices := []*model.SearchIndex{
+		{ID: "a", Score: 0.9},
+		{ID: "b", Score: 0.7},
+		{ID: "c", Score: 0.7},
+		{ID: "d", Score: 0.5},
+		{ID: "e", Score: 0.3},
+	}
+	sortByScore(indices)
+
+	// 第一页最后一条为 b，之后插入了得分更高的 x
+	shifted := append([]*model.SearchIndex{{ID: "x", Score: 0.95}}, indices...)
+	sortByScore(shifted)
+
+	tests := []struct {
+		name    string
+		indices []*model.SearchIndex
+		after   *model.Cursor
+		offset  int
+		want    []string
+	}{
+		{"第一页", indices, nil, 0, []string{"a", "b"}},
+		{"按偏移量", indices, nil, 2, []string{"c", "d"}},
+		{"同分按ID继续", indices, &model.Cursor{Score: 0.7, ID: "b"}, 2, []string{"c", "d"}},
+		{"结果前插入新文档", shifted, &model.Cursor{Score: 0.7, ID: "b"}, 2, []string{"c", "d"}},
+		{"游标记录已删除", indices, &model.Cursor{Score: 0.6, ID: "z"}, 2, []string{"d", "e"}},
+		{"超出范围", indices, &model.Cursor{Score: 0.1, ID: "z"}, 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, index := range pageAfter(tt.indices, tt.after, tt.offset, 2) {
+				got = append(got, index.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("pageAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNextSearchCursor 测试下一页游标的生成和解析
+func TestNextSearchCursor(t *testing.T) {
+	page := []*model.SearchIndex{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.7}}
+
+	if got := nextSearchCursor(page, 0, 2, "q"); got != "" {
+		t.Errorf("nextSearchCursor() on last page = %q, want empty", got)
+	}
+	if got := nextSearchCursor(nil, 0, 5, "q"); got != "" {
+		t.Errorf("nextSearchCursor() on empty page = %q, want empty", got)
+	}
+
+	token := nextSearchCursor(page, 2, 10, "q")
+	cursor, err := model.DecodeCursor(token, "q")
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if cursor.ID != "b" || float32(cursor.Score) != 0.7 || cursor.Offset != 4 {
+		t.Errorf("DecodeCursor() = %+v, want ID b, score 0.7, offset 4", cursor)
+	}
+}
+
+// TestSearchCursorFingerprint 测试查询指纹只与影响结果的参数有关
+func TestSearchCursorFingerprint(t *testing.T) {
+	base := &model.SearchRequest{Query: "gin router", SearchType: "keyword", Page: 1, Size: 10}
+	paged := &model.SearchRequest{Query: "gin router", SearchType: "keyword", Page: 3, Size: 20, Cursor: "abc", Facets: []string{"version"}}
+	other := &model.SearchRequest{Query: "gin router", SearchType: "semantic", Page: 1, Size: 10}
+
+	if searchCursorFingerprint(base) != searchCursorFingerprint(paged) {
+		t.Error("fingerprint should ignore page, size, cursor and facets")
+	}
+	if searchCursorFingerprint(base) == searchCursorFingerprint(other) {
+		t.Error("fingerprint should change with search type")
+	}
+}
+
+// TestBuildChunkIndexCharPositions 测试索引记录的位置为字符偏移，包含多字节字符时与字节偏移不同
+func TestBuildChunkIndexCharPositions(t *testing.T) {
+	content := "# 安装\n使用 Docker 安装服务\n\n# 配置\n修改配置文件后重启\n"
+	document := &model.Document{ID: "doc-1", Name: "部署指南"}
+	docVersion := &model.DocumentVersion{Version: "v1"}
+	runes := []rune(content)
+
+	s := &searchService{}
+	chunks := newDocumentChunker(1000, 100).Chunk(content, document.Name)
+	if len(chunks) != 2 {
+		t.Fatalf("Chunk() 返回 %d 个分块, want 2", len(chunks))
+	}
+	for i, chunk := range chunks {
+		index := s.buildChunkIndex(document, docVersion, chunk)
+		if got := string(runes[index.StartPosition:index.EndPosition]); got != index.Content {
+			t.Errorf("index[%d] 按字符位置截取 = %q, want %q", i, got, index.Content)
+		}
+
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(index.Metadata), &metadata); err != nil {
+			t.Fatalf("metadata unmarshal error = %v", err)
+		}
+		if got := int(metadata["start_position"].(float64)); got != index.StartPosition {
+			t.Errorf("metadata start_position = %d, want %d", got, index.StartPosition)
+		}
+		if got := int(metadata["content_length"].(float64)); got != utf8.RuneCountInString(index.Content) {
+			t.Errorf("metadata content_length = %d, want %d", got, utf8.RuneCountInString(index.Content))
+		}
+	}
+	if chunks[1].StartPosition == chunks[1].StartChar {
+		t.Errorf("测试内容应包含多字节字符，使字节偏移与字符偏移不同")
+	}
+}
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 版本别名
+const (
+	VersionLatest       = "latest"        // 最高版本，包括预发布版本
+	VersionLatestStable = "latest-stable" // 最高的正式版本
+)
+
+// ErrInvalidVersionRange 版本范围无法解析
+var ErrInvalidVersionRange = errors.New("invalid version range")
+
+// semVersion 语义化版本号，缺少的部分按0处理，构建元数据被忽略
+type semVersion struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// partialVersion 范围中可能不完整的版本号，如 1、1.2、1.x
+type partialVersion struct {
+	version  semVersion
+	parts    int  // 指定了数字的段数（0~3）
+	wildcard bool // 是否包含 x、X 或 * 通配符
+}
+
+// parsePartialVersion 解析可能不完整的版本号，支持 v 前缀、预发布标识和构建元数据
+func parsePartialVersion(s string) (partialVersion, bool) {
+	s = strings.TrimSpace(s)
+	if s != "" && (s[0] == 'v' || s[0] == 'V') {
+		s = s[1:]
+	}
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var p partialVersion
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		if i+1 == len(s) {
+			return p, false
+		}
+		p.version.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+
+	fields := strings.Split(s, ".")
+	if s == "" || len(fields) > 3 {
+		return p, false
+	}
+
+	var nums [3]int
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			p.wildcard = true
+			continue
+		}
+		// 通配符之后不能再出现数字，如 1.x.3
+		if p.wildcard {
+			return p, false
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || field[0] == '+' {
+			return p, false
+		}
+		nums[i] = n
+		p.parts++
+	}
+	if p.wildcard && p.version.prerelease != nil {
+		return p, false
+	}
+
+	p.version.major, p.version.minor, p.version.patch = nums[0], nums[1], nums[2]
+	return p, true
+}
+
+// parseSemVersion 宽松解析文档的版本号，如 v1、1.2、1.2.3-rc.1，不允许通配符
+func parseSemVersion(s string) (semVersion, bool) {
+	p, ok := parsePartialVersion(s)
+	if !ok || p.parts == 0 || p.wildcard {
+		return semVersion{}, false
+	}
+	return p.version, true
+}
+
+// compare 比较两个版本，返回 -1、0 或 1
+func (v semVersion) compare(o semVersion) int {
+	if c := compareInt(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.patch, o.patch); c != 0 {
+		return c
+	}
+
+	// 有预发布标识的版本低于对应的正式版本
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		if c := comparePrerelease(v.prerelease[i], o.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.prerelease), len(o.prerelease))
+}
+
+// sameRelease 判断两个版本的主版本号、次版本号和修订号是否相同
+func (v semVersion) sameRelease(o semVersion) bool {
+	return v.major == o.major && v.minor == o.minor && v.patch == o.patch
+}
+
+// comparePrerelease 比较预发布标识，数字标识按数值比较且低于字母标识
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInt 比较两个整数，返回 -1、0 或 1
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// versionComparator 单个版本比较条件
+type versionComparator struct {
+	op      string // ">=", ">", "<=", "<" 或 "="
+	version semVersion
+}
+
+// matches 判断版本是否满足比较条件
+func (c versionComparator) matches(v semVersion) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+// versionRange 版本范围，外层为 || 分隔的可选条件，内层的比较条件需同时满足
+type versionRange [][]versionComparator
+
+// contains 判断版本是否在范围内
+// 与 npm 一致，预发布版本只在条件中明确指定了同一版本的预发布时匹配
+func (r versionRange) contains(v semVersion) bool {
+	for _, set := range r {
+		if comparatorsMatch(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// comparatorsMatch 判断版本是否满足一组比较条件
+func comparatorsMatch(set []versionComparator, v semVersion) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if len(v.prerelease) == 0 {
+		return true
+	}
+	for _, c := range set {
+		if len(c.version.prerelease) > 0 && c.version.sameRelease(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVersionOperator 判断是否为单独的比较运算符，如 ">= 1.2" 中的 ">="
+func isVersionOperator(s string) bool {
+	switch s {
+	case ">=", ">", "<=", "<", "=", "~", "^":
+		return true
+	}
+	return false
+}
+
+// parseVersionRange 解析版本范围，语法与 npm 一致：
+//
+//	>=1.2 <2.0        同时满足
+//	1.2 || 1.4        满足任意一个
+//	~1.4              >=1.4.0 <1.5.0
+//	^3                >=3.0.0 <4.0.0（主版本号为0时锁定次版本号）
+//	1.x、1.2.*        通配符
+//	1.2 - 1.5         闭区间
+func parseVersionRange(s string) (versionRange, error) {
+	var r versionRange
+
+	for _, part := range strings.Split(s, "||") {
+		fields := strings.FieldsFunc(part, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w %q: empty condition", ErrInvalidVersionRange, s)
+		}
+
+		var set []versionComparator
+		for i := 0; i < len(fields); i++ {
+			token := fields[i]
+
+			// 运算符与版本号之间有空格
+			if isVersionOperator(token) {
+				if i+1 >= len(fields) {
+					return nil, fmt.Errorf("%w %q: missing version after %q", ErrInvalidVersionRange, s, token)
+				}
+				i++
+				token += fields[i]
+			}
+
+			// 连字符区间
+			if i+2 < len(fields) && fields[i+1] == "-" {
+				comparators, err := hyphenRange(token, fields[i+2])
+				if err != nil {
+					return nil, fmt.Errorf("%w %q: %v", ErrInvalidVersionRange, s, err)
+				}
+				set = append(set, comparators...)
+				i += 2
+				continue
+			}
+
+			comparators, err := expandComparator(token)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidVersionRange, s, err)
+			}
+			set = append(set, comparators...)
+		}
+		r = append(r, set)
+	}
+
+	return r, nil
+}
+
+// expandComparator 将带运算符的版本条件展开为比较条件
+func expandComparator(token string) ([]versionComparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(token, candidate) {
+			op = candidate
+			break
+		}
+	}
+
+	p, ok := parsePartialVersion(token[len(op):])
+	if !ok {
+		return nil, fmt.Errorf("malformed version %q", token[len(op):])
+	}
+	v := p.version
+	all := []versionComparator{{op: ">=", version: semVersion{}}}
+	none := []versionComparator{{op: "<", version: semVersion{}}}
+
+	// next 返回指定段加1后的版本，用作范围的上界
+	next := func(parts int) semVersion {
+		switch parts {
+		case 1:
+			return semVersion{major: v.major + 1}
+		case 2:
+			return semVersion{major: v.major, minor: v.minor + 1}
+		}
+		return semVersion{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+	between := func(upper semVersion) []versionComparator {
+		return []versionComparator{{op: ">=", version: v}, {op: "<", version: upper}}
+	}
+
+	switch op {
+	case "", "=":
+		if p.parts == 0 {
+			return all, nil
+		}
+		if p.parts == 3 {
+			return []versionComparator{{op: "=", version: v}}, nil
+		}
+		return between(next(p.parts)), nil
+	case "~":
+		if p.parts == 0 {
+			return all, nil
+		}
+		if p.parts == 1 {
+			return between(next(1)), nil
+		}
+		return between(next(2)), nil
+	case "^":
+		switch {
+		case p.parts == 0:
+			return all, nil
+		case v.major > 0 || p.parts == 1:
+			return between(next(1)), nil
+		case v.minor > 0 || p.parts == 2:
+			return between(next(2)), nil
+		}
+		return between(next(3)), nil
+	case ">":
+		if p.parts == 0 {
+			return none, nil
+		}
+		if p.parts == 3 {
+			return []versionComparator{{op: ">", version: v}}, nil
+		}
+		return []versionComparator{{op: ">=", version: next(p.parts)}}, nil
+	case ">=":
+		if p.parts == 0 {
+			return all, nil
+		}
+		return []versionComparator{{op: ">=", version: v}}, nil
+	case "<":
+		if p.parts == 0 {
+			return none, nil
+		}
+		return []versionComparator{{op: "<", version: v}}, nil
+	}
+
+	// <=
+	if p.parts == 0 {
+		return all, nil
+	}
+	if p.parts == 3 {
+		return []versionComparator{{op: "<=", version: v}}, nil
+	}
+	return []versionComparator{{op: "<", version: next(p.parts)}}, nil
+}
+
+// hyphenRange 展开连字符区间，上界不完整时包含该段内的所有版本，如 1.2 - 1.5 包含 1.5.9
+func hyphenRange(lower, upper string) ([]versionComparator, error) {
+	low, err := expandComparator(">=" + lower)
+	if err != nil {
+		return nil, err
+	}
+	high, err := expandComparator("<=" + upper)
+	if err != nil {
+		return nil, err
+	}
+	return append(low, high...), nil
+}
+
+// versionConstraint 版本过滤条件：版本范围或 latest、latest-stable 别名
+type versionConstraint struct {
+	latest     bool // 取最高版本
+	stableOnly bool // 排除预发布版本
+	rng        versionRange
+}
+
+// parseVersionConstraint 解析版本过滤条件，普通版本号（如 1.2）返回 nil，仍按原样精确匹配
+func parseVersionConstraint(s string) (*versionConstraint, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case VersionLatest:
+		return &versionConstraint{latest: true}, nil
+	case VersionLatestStable:
+		return &versionConstraint{latest: true, stableOnly: true}, nil
+	}
+	if !isVersionRange(s) {
+		return nil, nil
+	}
+
+	rng, err := parseVersionRange(s)
+	if err != nil {
+		return nil, err
+	}
+	return &versionConstraint{rng: rng}, nil
+}
+
+// isVersionRange 判断版本过滤条件是否为范围，包含运算符、空格或通配符
+func isVersionRange(s string) bool {
+	if strings.ContainsAny(s, "<>=~^|*, \t") {
+		return true
+	}
+	for _, field := range strings.Split(s, ".") {
+		if field == "x" || field == "X" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 返回满足条件的最高版本，可能对应多个写法不同的版本号（如 1.2 和 v1.2.0）
+// 无法解析为语义化版本的版本号不参与比较
+func (c *versionConstraint) resolve(versions []string) []string {
+	type candidate struct {
+		raw     string
+		version semVersion
+	}
+
+	var candidates []candidate
+	for _, raw := range versions {
+		v, ok := parseSemVersion(raw)
+		if !ok {
+			continue
+		}
+		if c.latest {
+			if c.stableOnly && len(v.prerelease) > 0 {
+				continue
+			}
+		} else if !c.rng.contains(v) {
+			continue
+		}
+		candidates = append(candidates, candidate{raw: raw, version: v})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].version.compare(candidates[j].version) > 0
+	})
+
+	var result []string
+	for _, cand := range candidates {
+		if cand.version.compare(candidates[0].version) != 0 {
+			break
+		}
+		result = append(result, cand.raw)
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestSemVersionCompare 测试语义化版本排序
+func TestSemVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"2", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.2", "1.0.0-alpha.10", -1},
+		{"1.0.0-alpha.1", "1.0.0-beta", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, okA := parseSemVersion(tt.a)
+			b, okB := parseSemVersion(tt.b)
+			if !okA || !okB {
+				t.Fatalf("parseSemVersion(%q, %q) failed", tt.a, tt.b)
+			}
+			if got := a.compare(b); got != tt.want {
+				t.Errorf("compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "latest", "1.2.3.4", "1.x", "abc", "1.-2"} {
+		if _, ok := parseSemVersion(invalid); ok {
+			t.Errorf("parseSemVersion(%q) should fail", invalid)
+		}
+	}
+}
+
+// TestVersionRangeContains 测试版本范围匹配
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		rng   string
+		match []string
+		miss  []string
+	}{
+		{">=1.2 <2.0", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0", "2.0.0-rc.1"}},
+		{">= 1.2, < 2", []string{"1.5.0"}, []string{"2.1.0"}},
+		{"~1.4", []string{"1.4.0", "1.4.9"}, []string{"1.3.9", "1.5.0"}},
+		{"~1.4.2", []string{"1.4.2", "1.4.7"}, []string{"1.4.1", "1.5.0"}},
+		{"^3", []string{"3.0.0", "3.9.1"}, []string{"2.9.9", "4.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"1.x", []string{"1.0.0", "1.8.2"}, []string{"2.0.0"}},
+		{"1.2.* || 1.4", []string{"1.2.5", "1.4.1"}, []string{"1.3.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"1.2 - 1.5", []string{"1.2.0", "1.5.9"}, []string{"1.6.0"}},
+		{">=1.0.0-beta.1 <2", []string{"1.0.0-beta.2", "1.5.0"}, []string{"1.0.0-alpha", "1.1.0-rc.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			rng, err := parseVersionRange(tt.rng)
+			if err != nil {
+				t.Fatalf("parseVersionRange(%q) error = %v", tt.rng, err)
+			}
+			for _, version := range tt.match {
+				v, _ := parseSemVersion(version)
+				if !rng.contains(v) {
+					t.Errorf("%q should contain %s", tt.rng, version)
+				}
+			}
+			for _, version := range tt.miss {
+				v, _ := parseSemVersion(version)
+				if rng.contains(v) {
+					t.Errorf("%q should not contain %s", tt.rng, version)
+				}
+			}
+		})
+	}
+}
+
+// TestParseVersionConstraint 测试版本过滤条件的识别和错误
+func TestParseVersionConstraint(t *testing.T) {
+	for _, exact := range []string{"1.2", "v2.0.1", "2023-10"} {
+		if c, err := parseVersionConstraint(exact); c != nil || err != nil {
+			t.Errorf("parseVersionConstraint(%q) = %v, %v, want exact match", exact, c, err)
+		}
+	}
+
+	for _, invalid := range []string{">=abc", "^", "1.2 ||", "~1.x.3"} {
+		if _, err := parseVersionConstraint(invalid); !errors.Is(err, ErrInvalidVersionRange) {
+			t.Errorf("parseVersionConstraint(%q) error = %v, want ErrInvalidVersionRange", invalid, err)
+		}
+	}
+}
+
+// TestVersionConstraintResolve 测试解析为满足条件的最高版本
+func TestVersionConstraintResolve(t *testing.T) {
+	versions := []string{"1.2.0", "v1.4.2", "1.4.2", "2.0.0", "2.1.0-rc.1", "nightly"}
+	tests := []struct {
+		constraint string
+		want       []string
+	}{
+		{"latest", []string{"2.1.0-rc.1"}},
+		{"LATEST-STABLE", []string{"2.0.0"}},
+		{"^1.2", []string{"v1.4.2", "1.4.2"}},
+		{"~1.2", []string{"1.2.0"}},
+		{">=3", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := parseVersionConstraint(tt.constraint)
+			if err != nil || c == nil {
+				t.Fatalf("parseVersionConstraint(%q) = %v, %v", tt.constraint, c, err)
+			}
+			got := c.resolve(versions)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestResolveVersionFilter 测试按库分别解析版本过滤条件
+func TestResolveVersionFilter(t *testing.T) {
+	versionRepo := new(MockDocumentVersionRepository)
+	versionRepo.On("GetLibraryVersions", context.Background(), "").Return([]*model.LibraryVersion{
+		{DocumentID: "gin-doc", Library: "gin", Version: "1.9.1"},
+		{DocumentID: "gin-doc", Library: "gin", Version: "1.10.0"},
+		{DocumentID: "gorm-guide", Library: "gorm", Version: "1.25.4"},
+		{DocumentID: "gorm-api", Library: "gorm", Version: "1.25.4"},
+		{DocumentID: "gorm-guide", Library: "gorm", Version: "2.0.0-beta"},
+	}, nil)
+	s := &searchService{versionRepo: versionRepo}
+
+	filters, err := s.resolveVersionFilter(context.Background(), map[string]interface{}{"version": "latest-stable", "content_type": "code"})
+	if err != nil {
+		t.Fatalf("resolveVersionFilter() error = %v", err)
+	}
+	if _, ok := filters["version"]; ok {
+		t.Error("version filter should be replaced")
+	}
+	if filters["content_type"] != "code" {
+		t.Error("other filters should be kept")
+	}
+
+	refs, _ := filters["document_versions"].([]model.DocumentVersionRef)
+	want := []model.DocumentVersionRef{
+		{DocumentID: "gin-doc", Version: "1.10.0"},
+		{DocumentID: "gorm-guide", Version: "1.25.4"},
+		{DocumentID: "gorm-api", Version: "1.25.4"},
+	}
+	if len(refs) != len(want) {
+		t.Fatalf("document_versions = %v, want %v", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Errorf("document_versions[%d] = %v, want %v", i, refs[i], want[i])
+		}
+	}
+
+	// 普通版本号保持精确匹配，不查询版本列表
+	exact := map[string]interface{}{"version": "1.9.1"}
+	if got, err := s.resolveVersionFilter(context.Background(), exact); err != nil || got["version"] != "1.9.1" {
+		t.Errorf("resolveVersionFilter() = %v, %v, want unchanged", got, err)
+	}
+	versionRepo.AssertNumberOfCalls(t, "GetLibraryVersions", 1)
+}
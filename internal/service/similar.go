@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// 相似内容查询的错误
+var (
+	ErrSimilarSourceRequired = errors.New("index_id or document_id is required")
+	ErrSimilarSourceNotFound = errors.New("similar source not found")
+)
+
+// similarSourceContentLimit 源分块都没有向量时，用于生成向量的源内容最大长度
+const similarSourceContentLimit = 8000
+
+// FindSimilar 根据源分块或源文档版本的向量查找相似分块，默认排除与源同一文档的分块
+func (s *searchService) FindSimilar(ctx context.Context, request *model.SimilarRequest) (*model.SearchResponse, error) {
+	sources, err := s.similarSources(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	vector := averageVectors(activeModelSources(sources, s.embeddingService))
+	if vector == nil {
+		// 源分块都没有活动模型生成的向量时根据内容生成，嵌入服务不可用时返回错误
+		if vector, err = s.generateContentVector(ctx, joinSourceContent(sources, similarSourceContentLimit)); err != nil {
+			return nil, err
+		}
+	}
+	vectorJSON, err := json.Marshal(vector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal source vector: %v", err)
+	}
+
+	filters, err := s.resolveVersionFilter(ctx, similarFilters(request, sources))
+	if err != nil {
+		return nil, err
+	}
+
+	size := request.Size
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+	indices, total, err := s.indexRepo.SearchByVector(ctx, string(vectorJSON), s.vectorFilters(filters), 1, size)
+	if err != nil {
+		return nil, fmt.Errorf("similar search failed: %v", err)
+	}
+
+	return &model.SearchResponse{
+		Total: total,
+		Items: s.convertToSearchResults(indices),
+		Page:  1,
+		Size:  size,
+	}, nil
+}
+
+// similarSources 获取相似内容查询的源分块：指定搜索索引ID时为该分块，否则为文档版本的全部分块
+func (s *searchService) similarSources(ctx context.Context, request *model.SimilarRequest) ([]*model.SearchIndex, error) {
+	if request.IndexID != "" {
+		index, err := s.indexRepo.GetByID(ctx, request.IndexID)
+		if err != nil || index == nil {
+			return nil, fmt.Errorf("%w: search index %s", ErrSimilarSourceNotFound, request.IndexID)
+		}
+		return []*model.SearchIndex{index}, nil
+	}
+	if request.DocumentID == "" {
+		return nil, ErrSimilarSourceRequired
+	}
+
+	version := request.SourceVersion
+	if version == "" {
+		latest, err := s.versionRepo.GetLatestVersion(ctx, request.DocumentID)
+		if err != nil || latest == nil {
+			return nil, fmt.Errorf("%w: document %s", ErrSimilarSourceNotFound, request.DocumentID)
+		}
+		version = latest.Version
+	}
+	indices, err := s.indexRepo.GetByDocumentIDAndVersion(ctx, request.DocumentID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source chunks: %v", err)
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("%w: document %s version %s has no index", ErrSimilarSourceNotFound, request.DocumentID, version)
+	}
+	return indices, nil
+}
+
+// similarFilters 在请求的过滤条件上排除源文档，包含同一文档时只排除源分块本身
+func similarFilters(request *model.SimilarRequest, sources []*model.SearchIndex) map[string]interface{} {
+	filters := make(map[string]interface{}, len(request.Filters)+1)
+	for key, value := range request.Filters {
+		filters[key] = value
+	}
+
+	if !request.IncludeSameDocument && len(sources) > 0 {
+		filters["exclude_document_id"] = sources[0].DocumentID
+		return filters
+	}
+	ids := make([]string, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.ID)
+	}
+	filters["exclude_ids"] = ids
+	return filters
+}
+
+// activeModelSources 返回向量由活动嵌入模型生成的源分块，其他模型的向量不能与搜索索引比较
+func activeModelSources(sources []*model.SearchIndex, embeddingService EmbeddingService) []*model.SearchIndex {
+	if embeddingService == nil {
+		return sources
+	}
+	embeddingModel := embeddingService.ModelName()
+	active := make([]*model.SearchIndex, 0, len(sources))
+	for _, source := range sources {
+		if source.EmbeddingModel == embeddingModel {
+			active = append(active, source)
+		}
+	}
+	return active
+}
+
+// averageVectors 计算源分块向量的归一化平均值，只使用与第一个有效向量维度相同的向量，没有向量时返回 nil
+func averageVectors(sources []*model.SearchIndex) []float32 {
+	var sum []float32
+	for _, source := range sources {
+		var vector []float32
+		if err := json.Unmarshal([]byte(source.Vector), &vector); err != nil || len(vector) == 0 {
+			continue
+		}
+		if sum == nil {
+			sum = make([]float32, len(vector))
+		}
+		if len(vector) != len(sum) {
+			continue
+		}
+
+		// 先归一化，避免长度较大的向量主导平均方向
+		norm := vectorNorm(vector)
+		if norm == 0 {
+			continue
+		}
+		for i, v := range vector {
+			sum[i] += v / norm
+		}
+	}
+
+	norm := vectorNorm(sum)
+	if norm == 0 {
+		return nil
+	}
+	for i := range sum {
+		sum[i] /= norm
+	}
+	return sum
+}
+
+// vectorNorm 计算向量的欧几里得范数
+func vectorNorm(vector []float32) float32 {
+	var sum float32
+	for _, v := range vector {
+		sum += v * v
+	}
+	return float32(math.Sqrt(float64(sum)))
+}
+
+// joinSourceContent 拼接源分块内容，超过 limit 字节时截断
+func joinSourceContent(sources []*model.SearchIndex, limit int) string {
+	var builder strings.Builder
+	for _, source := range sources {
+		if builder.Len() >= limit {
+			break
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(source.Content)
+	}
+	content := builder.String()
+	if len(content) <= limit {
+		return content
+	}
+	// 截断位置退回到字符边界，避免产生无效的UTF-8
+	for limit > 0 && !utf8.RuneStart(content[limit]) {
+		limit--
+	}
+	return content[:limit]
+}
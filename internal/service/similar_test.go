@@ -0,0 +1,67 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// TestAverageVectors 测试源分块向量的归一化平均
+func TestAverageVectors(t *testing.T) {
+	sources := []*model.SearchIndex{
+		{Vector: "[3, 0]"},
+		{Vector: "[0, 1]"},
+		{Vector: "[1, 2, 3]"}, // 维度不同，忽略
+		{Vector: ""},          // 没有向量，忽略
+	}
+	got := averageVectors(sources)
+	want := float32(1 / math.Sqrt2)
+	if len(got) != 2 || math.Abs(float64(got[0]-want)) > 1e-6 || math.Abs(float64(got[1]-want)) > 1e-6 {
+		t.Errorf("averageVectors() = %v, want [%v %v]", got, want, want)
+	}
+
+	if got := averageVectors([]*model.SearchIndex{{Vector: "[]"}, {Vector: "[0, 0]"}}); got != nil {
+		t.Errorf("averageVectors() without usable vectors = %v, want nil", got)
+	}
+}
+
+// TestSimilarFilters 测试相似内容查询排除源文档或源分块
+func TestSimilarFilters(t *testing.T) {
+	sources := []*model.SearchIndex{
+		{ID: "idx-1", DocumentID: "doc-1"},
+		{ID: "idx-2", DocumentID: "doc-1"},
+	}
+	request := &model.SimilarRequest{Filters: map[string]interface{}{"library": "gorm"}}
+
+	filters := similarFilters(request, sources)
+	if filters["exclude_document_id"] != "doc-1" || filters["library"] != "gorm" {
+		t.Errorf("filters = %v, want source document excluded and library kept", filters)
+	}
+	if _, ok := filters["exclude_ids"]; ok {
+		t.Errorf("filters = %v, want no chunk exclusion when the document is excluded", filters)
+	}
+	if _, ok := request.Filters["exclude_document_id"]; ok {
+		t.Error("similarFilters() modified the request filters")
+	}
+
+	request.IncludeSameDocument = true
+	filters = similarFilters(request, sources)
+	ids, _ := filters["exclude_ids"].([]string)
+	if _, ok := filters["exclude_document_id"]; ok || len(ids) != 2 || ids[0] != "idx-1" || ids[1] != "idx-2" {
+		t.Errorf("filters = %v, want only source chunks excluded", filters)
+	}
+}
+
+// TestJoinSourceContent 测试拼接源分块内容时按字符边界截断
+func TestJoinSourceContent(t *testing.T) {
+	sources := []*model.SearchIndex{{Content: "连接池"}, {Content: "配置"}}
+	if got := joinSourceContent(sources, 100); got != "连接池\n配置" {
+		t.Errorf("joinSourceContent() = %q, want all content", got)
+	}
+	got := joinSourceContent(sources, 8)
+	if got != "连接" || !utf8.ValidString(got) {
+		t.Errorf("joinSourceContent() = %q, want content truncated at a character boundary", got)
+	}
+}
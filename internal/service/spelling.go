@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+const (
+	maxSpellingCandidates  = 3    // 每个查询词最多返回的候选词数量
+	spellingCandidateLimit = 2000 // 每个查询词从词表中读取的候选词数量上限
+	spellingFrequencyRatio = 10   // 查询词已在词表中时，候选词的文档频率至少为其倍数才作为建议
+	minSpellingTermLength  = 3    // 参与拼写纠正的查询词最小字符数
+)
+
+// suggestSpelling 根据索引词表为查询中的关键词生成拼写建议，返回各词的候选词和使用最佳候选词替换后的查询
+// 词表即BM25的检索词统计，随索引的构建和删除增量更新
+func (s *searchService) suggestSpelling(ctx context.Context, query string, parsed *parsedQuery) ([]model.SpellingSuggestion, string) {
+	if s.statsRepo == nil || s.config.SuggestMaxDistance < 1 {
+		return nil, ""
+	}
+
+	terms := spellingTerms(parsed.Root)
+	if len(terms) == 0 {
+		return nil, ""
+	}
+	docFreqs, err := s.statsRepo.GetDocFreqs(ctx, terms)
+	if err != nil {
+		log.Printf("WARNING: Failed to load term frequencies for spelling suggestions: %v", err)
+		return nil, ""
+	}
+
+	var suggestions []model.SpellingSuggestion
+	replacements := make(map[string]string)
+	for _, term := range terms {
+		maxDistance := s.config.SuggestMaxDistance
+		// 短词允许的编辑距离更小，避免建议完全不相关的词
+		if utf8.RuneCountInString(term) <= 4 && maxDistance > 1 {
+			maxDistance = 1
+		}
+
+		stats, err := s.statsRepo.GetSpellingCandidates(ctx, term, maxDistance, spellingCandidateLimit)
+		if err != nil {
+			log.Printf("WARNING: Failed to load spelling candidates for %q: %v", term, err)
+			continue
+		}
+
+		candidates := rankSpellingCandidates(term, docFreqs[term], stats, maxDistance)
+		if len(candidates) == 0 {
+			continue
+		}
+		suggestions = append(suggestions, model.SpellingSuggestion{Term: term, Candidates: candidates})
+		replacements[term] = candidates[0].Term
+	}
+
+	if len(replacements) == 0 {
+		return nil, ""
+	}
+	return suggestions, replaceQueryWords(query, replacements)
+}
+
+// spellingTerms 返回查询中可以纠正拼写的关键词：不在 NOT 之下、不是短语、前缀或同义词扩展、不含中文的单个英文单词
+func spellingTerms(root *model.QueryNode) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, leaf := range root.PositiveLeaves() {
+		if leaf.Type != model.QueryNodeTerm || leaf.Prefix || leaf.Synonym || containsHan(leaf.Text) {
+			continue
+		}
+		tokens := tokenize(leaf.Text)
+		if len(tokens) != 1 || tokens[0] != strings.ToLower(leaf.Text) || utf8.RuneCountInString(tokens[0]) < minSpellingTermLength {
+			continue
+		}
+		if !seen[tokens[0]] {
+			seen[tokens[0]] = true
+			terms = append(terms, tokens[0])
+		}
+	}
+	return terms
+}
+
+// rankSpellingCandidates 计算候选词与查询词的编辑距离，按距离升序、文档频率降序排列
+// docFreq 为查询词本身的文档频率，大于0时只保留明显更常见的候选词
+func rankSpellingCandidates(term string, docFreq int64, stats []model.SearchTermStat, maxDistance int) []model.SpellingCandidate {
+	var candidates []model.SpellingCandidate
+	for _, stat := range stats {
+		if docFreq > 0 && stat.DocFreq < docFreq*spellingFrequencyRatio {
+			continue
+		}
+		distance := editDistance(term, stat.Term, maxDistance)
+		if distance < 1 || distance > maxDistance {
+			continue
+		}
+		candidates = append(candidates, model.SpellingCandidate{Term: stat.Term, Distance: distance, DocFreq: stat.DocFreq})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Distance != candidates[j].Distance {
+			return candidates[i].Distance < candidates[j].Distance
+		}
+		if candidates[i].DocFreq != candidates[j].DocFreq {
+			return candidates[i].DocFreq > candidates[j].DocFreq
+		}
+		return candidates[i].Term < candidates[j].Term
+	})
+	if len(candidates) > maxSpellingCandidates {
+		candidates = candidates[:maxSpellingCandidates]
+	}
+	return candidates
+}
+
+// editDistance 计算两个词的编辑距离（插入、删除、替换和相邻字符交换各计1）
+// 距离超过 maxDistance 时提前返回 maxDistance+1
+func editDistance(a, b string, maxDistance int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs := len(ra) - len(rb); abs > maxDistance || -abs > maxDistance {
+		return maxDistance + 1
+	}
+
+	// prev2、prev、curr 分别为前两行、前一行和当前行
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minOf(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minOf(curr[j], prev2[j-2]+1)
+			}
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+// minOf 返回若干整数中最小的一个
+func minOf(first int, rest ...int) int {
+	for _, value := range rest {
+		if value < first {
+			first = value
+		}
+	}
+	return first
+}
+
+// replaceQueryWords 将查询中的单词替换为纠正后的词，保留查询中的运算符、引号和字段限定符
+// 字段限定符的值（冒号之后的单词）不做替换
+func replaceQueryWords(query string, replacements map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, word := range wordSpans(query) {
+		replacement, ok := replacements[word.text]
+		if !ok || (word.start > 0 && query[word.start-1] == ':') {
+			continue
+		}
+		b.WriteString(query[last:word.start])
+		b.WriteString(replacement)
+		last = word.end
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
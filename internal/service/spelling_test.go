@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// fakeSearchStatsRepository 基于内存词表的检索统计仓库
+type fakeSearchStatsRepository struct {
+	docFreqs   map[string]int64
+	chunkCount int64
+}
+
+func (r *fakeSearchStatsRepository) ApplyDelta(ctx context.Context, termDelta map[string]int64, chunkDelta, lengthDelta int64) error {
+	if r.docFreqs == nil {
+		r.docFreqs = make(map[string]int64)
+	}
+	for term, delta := range termDelta {
+		r.docFreqs[term] += delta
+		if r.docFreqs[term] <= 0 {
+			delete(r.docFreqs, term)
+		}
+	}
+	r.chunkCount += chunkDelta
+	return nil
+}
+
+func (r *fakeSearchStatsRepository) Replace(ctx context.Context, termFreqs map[string]int64, chunkCount, totalLength int64) error {
+	return nil
+}
+
+func (r *fakeSearchStatsRepository) GetDocFreqs(ctx context.Context, terms []string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	for _, term := range terms {
+		if freq, ok := r.docFreqs[term]; ok {
+			result[term] = freq
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSearchStatsRepository) GetPrefixDocFreqs(ctx context.Context, prefixes []string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (r *fakeSearchStatsRepository) GetCorpusStats(ctx context.Context) (*model.SearchCorpusStats, error) {
+	return &model.SearchCorpusStats{ChunkCount: r.chunkCount}, nil
+}
+
+func (r *fakeSearchStatsRepository) GetSpellingCandidates(ctx context.Context, term string, maxDistance, limit int) ([]model.SearchTermStat, error) {
+	var stats []model.SearchTermStat
+	for candidate, freq := range r.docFreqs {
+		if candidate != term {
+			stats = append(stats, model.SearchTermStat{Term: candidate, DocFreq: freq})
+		}
+	}
+	return stats, nil
+}
+
+func (r *fakeSearchStatsRepository) ListTopTerms(ctx context.Context, minDocFreq int64, limit int) ([]model.SearchTermStat, error) {
+	var stats []model.SearchTermStat
+	for term, freq := range r.docFreqs {
+		if freq >= minDocFreq {
+			stats = append(stats, model.SearchTermStat{Term: term, DocFreq: freq})
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].DocFreq > stats[j].DocFreq })
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// TestEditDistance 测试编辑距离
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		maxDistance int
+		want        int
+	}{
+		{"kubernetes", "kubernetes", 2, 0},
+		{"kuberentes", "kubernetes", 2, 1},
+		{"postgre", "postgres", 2, 1},
+		{"midleware", "middleware", 2, 1},
+		{"conection", "connections", 2, 2},
+		{"gin", "gorm", 2, 3},
+		{"abc", "xyz", 2, 3},
+		{"config", "configuration", 2, 3},
+		{"配置", "配制", 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"->"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b, tt.maxDistance); got != tt.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRankSpellingCandidates 测试候选词的过滤和排序
+func TestRankSpellingCandidates(t *testing.T) {
+	stats := []model.SearchTermStat{
+		{Term: "router", DocFreq: 40},
+		{Term: "routes", DocFreq: 90},
+		{Term: "rooter", DocFreq: 3},
+		{Term: "route", DocFreq: 300},
+		{Term: "reader", DocFreq: 500},
+	}
+
+	got := rankSpellingCandidates("routr", 0, stats, 1)
+	var terms []string
+	for _, candidate := range got {
+		terms = append(terms, candidate.Term)
+	}
+	if strings.Join(terms, ",") != "route,router" {
+		t.Errorf("rankSpellingCandidates() = %v, want route,router", terms)
+	}
+
+	// 查询词已在词表中时，只建议明显更常见的词
+	got = rankSpellingCandidates("router", 40, stats, 1)
+	if len(got) != 0 {
+		t.Errorf("rankSpellingCandidates() = %v, want none", got)
+	}
+	got = rankSpellingCandidates("rooter", 3, stats, 1)
+	if len(got) != 1 || got[0].Term != "router" {
+		t.Errorf("rankSpellingCandidates() = %v, want router", got)
+	}
+}
+
+// TestSpellingTerms 测试可纠正拼写的查询词
+func TestSpellingTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"kuberentes deploy", []string{"kuberentes", "deploy"}},
+		{"Gin AND midleware", []string{"gin", "midleware"}},
+		{"routr NOT midleware", []string{"routr"}},
+		{`"conection pool" confg`, []string{"confg"}},
+		{"rout* library:gin", nil},
+		{"go 中文检索", nil},
+		{"deploy deploy", []string{"deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery(%q) error = %v", tt.query, err)
+			}
+			got := spellingTerms(parsed.Root)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("spellingTerms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReplaceQueryWords 测试替换查询中的单词并保留查询语法
+func TestReplaceQueryWords(t *testing.T) {
+	replacements := map[string]string{"kuberentes": "kubernetes", "gin": "gn"}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"kuberentes deploy", "kubernetes deploy"},
+		{`"kuberentes" AND (deploy OR scale)`, `"kubernetes" AND (deploy OR scale)`},
+		{"library:gin kuberentes", "library:gin kubernetes"},
+		{"Kuberentes", "kubernetes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := replaceQueryWords(tt.query, replacements); got != tt.want {
+				t.Errorf("replaceQueryWords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSuggestSpelling 测试根据索引词表生成拼写建议
+func TestSuggestSpelling(t *testing.T) {
+	statsRepo := &fakeSearchStatsRepository{docFreqs: map[string]int64{
+		"kubernetes": 120,
+		"deploy":     80,
+		"deployment": 60,
+		"gin":        200,
+		"gorm":       150,
+	}}
+	s := &searchService{statsRepo: statsRepo, config: DefaultSearchConfig()}
+
+	parsed, err := parseSearchQuery("kuberentes deploy AND gni")
+	if err != nil {
+		t.Fatalf("parseSearchQuery() error = %v", err)
+	}
+	suggestions, suggested := s.suggestSpelling(context.Background(), "kuberentes deploy AND gni", parsed)
+
+	if suggested != "kubernetes deploy AND gin" {
+		t.Errorf("suggested query = %q, want %q", suggested, "kubernetes deploy AND gin")
+	}
+	if len(suggestions) != 2 || suggestions[0].Term != "kuberentes" || suggestions[0].Candidates[0].Distance != 1 {
+		t.Errorf("suggestions = %+v", suggestions)
+	}
+
+	// 所有查询词都拼写正确时没有建议
+	parsed, _ = parseSearchQuery("gin gorm")
+	if suggestions, suggested := s.suggestSpelling(context.Background(), "gin gorm", parsed); suggestions != nil || suggested != "" {
+		t.Errorf("suggestSpelling() = %v, %q, want none", suggestions, suggested)
+	}
+}
+
+// TestSuggestSpellingAfterDelete 测试删除文档后其中独有的词不再作为拼写建议
+func TestSuggestSpellingAfterDelete(t *testing.T) {
+	statsRepo := &fakeSearchStatsRepository{docFreqs: map[string]int64{"gin": 200}, chunkCount: 50}
+	documentService, _ := newIndexedDocumentService(t, statsRepo)
+	s := &searchService{statsRepo: statsRepo, config: DefaultSearchConfig()}
+
+	parsed, _ := parseSearchQuery("kuberentes")
+	if _, suggested := s.suggestSpelling(context.Background(), "kuberentes", parsed); suggested != "kubernetes" {
+		t.Fatalf("suggested query before delete = %q, want kubernetes", suggested)
+	}
+
+	if err := documentService.DeleteDocument(context.Background(), "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if suggestions, suggested := s.suggestSpelling(context.Background(), "kuberentes", parsed); suggestions != nil || suggested != "" {
+		t.Errorf("suggestSpelling() after delete = %v, %q, want none", suggestions, suggested)
+	}
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+	"github.com/UniverseHappiness/LAST-doc/internal/repository"
+)
+
+// synonymRefreshInterval 同义词词典的刷新间隔，多实例部署时其他实例的修改在该时间内生效
+const synonymRefreshInterval = time.Minute
+
+// ErrInvalidSynonymSet 同义词组不合法
+var ErrInvalidSynonymSet = errors.New("invalid synonym set")
+
+// ErrSynonymSetNotFound 同义词组不存在
+var ErrSynonymSetNotFound = errors.New("synonym set not found")
+
+// SynonymService 同义词服务接口
+type SynonymService interface {
+	// CreateSynonymSet 创建同义词组
+	CreateSynonymSet(ctx context.Context, request *model.SynonymSetRequest) (*model.SynonymSet, error)
+	// UpdateSynonymSet 更新同义词组
+	UpdateSynonymSet(ctx context.Context, id string, request *model.SynonymSetRequest) (*model.SynonymSet, error)
+	// DeleteSynonymSet 删除同义词组
+	DeleteSynonymSet(ctx context.Context, id string) error
+	// GetSynonymSet 获取同义词组
+	GetSynonymSet(ctx context.Context, id string) (*model.SynonymSet, error)
+	// ListSynonymSets 获取同义词组列表，library 不为空时只返回该库的同义词组
+	ListSynonymSets(ctx context.Context, library string) ([]*model.SynonymSet, error)
+	// Synonyms 返回在指定库中生效的同义词词典（全局同义词和该库的同义词），键为规范化后的词语
+	Synonyms(ctx context.Context, library string) map[string][]string
+}
+
+// synonymDictionary 同义词词典，按库分组，全局同义词的库为空字符串
+type synonymDictionary map[string]map[string][]string
+
+// synonymService 同义词服务实现
+type synonymService struct {
+	repo         repository.SynonymRepository
+	cacheService CacheService
+
+	mutex      sync.RWMutex
+	dictionary synonymDictionary
+	loadedAt   time.Time
+}
+
+// NewSynonymService 创建同义词服务实例
+// 同义词修改后会清空搜索缓存，避免返回按旧词典扩展的结果
+func NewSynonymService(repo repository.SynonymRepository, cacheService CacheService) SynonymService {
+	return &synonymService{
+		repo:         repo,
+		cacheService: cacheService,
+	}
+}
+
+// CreateSynonymSet 创建同义词组
+func (s *synonymService) CreateSynonymSet(ctx context.Context, request *model.SynonymSetRequest) (*model.SynonymSet, error) {
+	set, err := newSynonymSet(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, set); err != nil {
+		return nil, fmt.Errorf("failed to create synonym set: %v", err)
+	}
+	s.invalidate()
+	return set, nil
+}
+
+// UpdateSynonymSet 更新同义词组
+func (s *synonymService) UpdateSynonymSet(ctx context.Context, id string, request *model.SynonymSetRequest) (*model.SynonymSet, error) {
+	set, err := newSynonymSet(request)
+	if err != nil {
+		return nil, err
+	}
+	set.ID = id
+	if err := s.repo.Update(ctx, set); err != nil {
+		return nil, synonymRepositoryError(id, err)
+	}
+	s.invalidate()
+	return s.GetSynonymSet(ctx, id)
+}
+
+// DeleteSynonymSet 删除同义词组
+func (s *synonymService) DeleteSynonymSet(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return synonymRepositoryError(id, err)
+	}
+	s.invalidate()
+	return nil
+}
+
+// GetSynonymSet 获取同义词组
+func (s *synonymService) GetSynonymSet(ctx context.Context, id string) (*model.SynonymSet, error) {
+	set, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, synonymRepositoryError(id, err)
+	}
+	return set, nil
+}
+
+// ListSynonymSets 获取同义词组列表
+func (s *synonymService) ListSynonymSets(ctx context.Context, library string) ([]*model.SynonymSet, error) {
+	sets, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list synonym sets: %v", err)
+	}
+	if library == "" {
+		return sets, nil
+	}
+
+	filtered := make([]*model.SynonymSet, 0, len(sets))
+	for _, set := range sets {
+		if set.Library == library {
+			filtered = append(filtered, set)
+		}
+	}
+	return filtered, nil
+}
+
+// Synonyms 返回在指定库中生效的同义词词典
+func (s *synonymService) Synonyms(ctx context.Context, library string) map[string][]string {
+	dictionary := s.load(ctx)
+	if library == "" || len(dictionary[library]) == 0 {
+		return dictionary[""]
+	}
+
+	merged := make(map[string][]string)
+	for _, scope := range []string{"", library} {
+		for term, synonyms := range dictionary[scope] {
+			merged[term] = appendUnique(merged[term], synonyms...)
+		}
+	}
+	return merged
+}
+
+// load 返回同义词词典，超过刷新间隔时重新从数据库加载，加载失败时继续使用原有词典
+func (s *synonymService) load(ctx context.Context) synonymDictionary {
+	s.mutex.RLock()
+	dictionary, loadedAt := s.dictionary, s.loadedAt
+	s.mutex.RUnlock()
+	if dictionary != nil && time.Since(loadedAt) < synonymRefreshInterval {
+		return dictionary
+	}
+
+	sets, err := s.repo.List(ctx)
+	if err != nil {
+		log.Printf("WARNING: Failed to load synonym sets: %v", err)
+		return dictionary
+	}
+	dictionary = buildSynonymDictionary(sets)
+
+	s.mutex.Lock()
+	s.dictionary, s.loadedAt = dictionary, time.Now()
+	s.mutex.Unlock()
+	return dictionary
+}
+
+// invalidate 同义词修改后使词典和搜索缓存失效
+func (s *synonymService) invalidate() {
+	s.mutex.Lock()
+	s.dictionary = nil
+	s.mutex.Unlock()
+
+	if s.cacheService != nil {
+		if err := s.cacheService.Clear(); err != nil {
+			log.Printf("WARNING: Failed to clear search cache after synonym update: %v", err)
+		}
+	}
+}
+
+// newSynonymSet 校验请求并创建同义词组，词语规范化为小写并去除重复
+func newSynonymSet(request *model.SynonymSetRequest) (*model.SynonymSet, error) {
+	var terms []string
+	for _, term := range request.Terms {
+		term = normalizeSynonymTerm(term)
+		if term == "" {
+			continue
+		}
+		if strings.ContainsAny(term, "\"*():") {
+			return nil, fmt.Errorf("%w: term %q contains query syntax characters", ErrInvalidSynonymSet, term)
+		}
+		terms = appendUnique(terms, term)
+	}
+	if len(terms) < 2 {
+		return nil, fmt.Errorf("%w: at least two distinct terms are required", ErrInvalidSynonymSet)
+	}
+
+	return &model.SynonymSet{
+		Library:     strings.TrimSpace(request.Library),
+		Terms:       terms,
+		Description: request.Description,
+	}, nil
+}
+
+// synonymRepositoryError 将记录不存在的错误转换为 ErrSynonymSetNotFound
+func synonymRepositoryError(id string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s", ErrSynonymSetNotFound, id)
+	}
+	return err
+}
+
+// buildSynonymDictionary 根据同义词组构建词典，组内每个词都映射到组内其他词
+func buildSynonymDictionary(sets []*model.SynonymSet) synonymDictionary {
+	dictionary := make(synonymDictionary)
+	for _, set := range sets {
+		scope := dictionary[set.Library]
+		if scope == nil {
+			scope = make(map[string][]string)
+			dictionary[set.Library] = scope
+		}
+		for _, term := range set.Terms {
+			term = normalizeSynonymTerm(term)
+			for _, synonym := range set.Terms {
+				if synonym = normalizeSynonymTerm(synonym); synonym != term {
+					scope[term] = appendUnique(scope[term], synonym)
+				}
+			}
+		}
+	}
+	for _, scope := range dictionary {
+		for _, synonyms := range scope {
+			sort.Strings(synonyms)
+		}
+	}
+	return dictionary
+}
+
+// normalizeSynonymTerm 规范化同义词：转为小写，合并连续空白
+func normalizeSynonymTerm(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(values []string, items ...string) []string {
+	for _, item := range items {
+		exists := false
+		for _, value := range values {
+			if value == item {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// expandSynonyms 使用同义词扩展查询：关键词和短语节点替换为原词与同义词的 OR 节点，同义词节点标记为扩展生成
+// 前缀匹配的关键词不扩展；返回新的语法树，不修改原语法树
+func expandSynonyms(root *model.QueryNode, synonyms map[string][]string) (*model.QueryNode, []model.QueryExpansion) {
+	if root == nil || len(synonyms) == 0 {
+		return root, nil
+	}
+	var expansions []model.QueryExpansion
+	expanded := expandSynonymNode(root, synonyms, &expansions, make(map[string]bool))
+	return expanded, expansions
+}
+
+// expandSynonymNode 递归扩展语法树节点
+func expandSynonymNode(node *model.QueryNode, synonyms map[string][]string, expansions *[]model.QueryExpansion, seen map[string]bool) *model.QueryNode {
+	if node == nil {
+		return nil
+	}
+	if !node.IsLeaf() {
+		copied := *node
+		copied.Children = make([]*model.QueryNode, len(node.Children))
+		for i, child := range node.Children {
+			copied.Children[i] = expandSynonymNode(child, synonyms, expansions, seen)
+		}
+		return &copied
+	}
+	if node.Prefix || node.Synonym {
+		return node
+	}
+
+	term := normalizeSynonymTerm(node.Text)
+	matched := synonyms[term]
+	if len(matched) == 0 {
+		return node
+	}
+	if !seen[term] {
+		seen[term] = true
+		*expansions = append(*expansions, model.QueryExpansion{Term: term, Synonyms: matched})
+	}
+
+	expanded := &model.QueryNode{Type: model.QueryNodeOr, Children: []*model.QueryNode{node}}
+	for _, synonym := range matched {
+		child := &model.QueryNode{Type: model.QueryNodeTerm, Text: synonym, Synonym: true}
+		if strings.Contains(synonym, " ") {
+			child.Type = model.QueryNodePhrase
+		}
+		expanded.Children = append(expanded.Children, child)
+	}
+	return expanded
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/UniverseHappiness/LAST-doc/internal/model"
+)
+
+// fakeSynonymRepository 基于内存的同义词组仓库
+type fakeSynonymRepository struct {
+	sets  []*model.SynonymSet
+	lists int
+}
+
+func (r *fakeSynonymRepository) Create(ctx context.Context, set *model.SynonymSet) error {
+	set.ID = set.Library + ":" + strings.Join(set.Terms, ",")
+	r.sets = append(r.sets, set)
+	return nil
+}
+
+func (r *fakeSynonymRepository) Update(ctx context.Context, set *model.SynonymSet) error {
+	for i, existing := range r.sets {
+		if existing.ID == set.ID {
+			r.sets[i] = set
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeSynonymRepository) Delete(ctx context.Context, id string) error {
+	for i, existing := range r.sets {
+		if existing.ID == id {
+			r.sets = append(r.sets[:i], r.sets[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeSynonymRepository) GetByID(ctx context.Context, id string) (*model.SynonymSet, error) {
+	for _, existing := range r.sets {
+		if existing.ID == id {
+			return existing, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSynonymRepository) List(ctx context.Context) ([]*model.SynonymSet, error) {
+	r.lists++
+	return r.sets, nil
+}
+
+// TestNewSynonymSet 测试同义词组的规范化和校验
+func TestNewSynonymSet(t *testing.T) {
+	set, err := newSynonymSet(&model.SynonymSetRequest{Library: " gin ", Terms: []string{"K8s", " kubernetes ", "k8s", "Spring   Boot", ""}})
+	if err != nil {
+		t.Fatalf("newSynonymSet() error = %v", err)
+	}
+	if set.Library != "gin" || strings.Join(set.Terms, ",") != "k8s,kubernetes,spring boot" {
+		t.Errorf("newSynonymSet() = %+v", set)
+	}
+
+	for _, terms := range [][]string{{"pg", "PG"}, {"pg", "post*"}, {"pg", "library:gorm"}} {
+		if _, err := newSynonymSet(&model.SynonymSetRequest{Terms: terms}); !errors.Is(err, ErrInvalidSynonymSet) {
+			t.Errorf("newSynonymSet(%v) error = %v, want ErrInvalidSynonymSet", terms, err)
+		}
+	}
+}
+
+// TestSynonymService_Synonyms 测试全局同义词和库同义词的合并
+func TestSynonymService_Synonyms(t *testing.T) {
+	repo := &fakeSynonymRepository{}
+	cache := NewMemoryCache()
+	s := NewSynonymService(repo, cache)
+	ctx := context.Background()
+
+	if _, err := s.CreateSynonymSet(ctx, &model.SynonymSetRequest{Terms: []string{"pg", "postgres", "postgresql"}}); err != nil {
+		t.Fatalf("CreateSynonymSet() error = %v", err)
+	}
+	if _, err := s.CreateSynonymSet(ctx, &model.SynonymSetRequest{Library: "gorm", Terms: []string{"pg", "pgx"}}); err != nil {
+		t.Fatalf("CreateSynonymSet() error = %v", err)
+	}
+
+	tests := []struct {
+		library string
+		want    string
+	}{
+		{"", "postgres,postgresql"},
+		{"gin", "postgres,postgresql"},
+		{"gorm", "postgres,postgresql,pgx"},
+	}
+	for _, tt := range tests {
+		t.Run("library="+tt.library, func(t *testing.T) {
+			if got := strings.Join(s.Synonyms(ctx, tt.library)["pg"], ","); got != tt.want {
+				t.Errorf("Synonyms()[pg] = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if repo.lists != 1 {
+		t.Errorf("词典应只加载一次, 加载了 %d 次", repo.lists)
+	}
+
+	// 修改后重新加载词典并清空搜索缓存
+	cache.Set("search", "cached", time.Minute)
+	if err := s.DeleteSynonymSet(ctx, "gorm:pg,pgx"); err != nil {
+		t.Fatalf("DeleteSynonymSet() error = %v", err)
+	}
+	if _, found := cache.Get("search"); found {
+		t.Error("修改同义词后应清空搜索缓存")
+	}
+	if got := s.Synonyms(ctx, "gorm")["pgx"]; got != nil {
+		t.Errorf("Synonyms()[pgx] = %v, want nil", got)
+	}
+	if err := s.DeleteSynonymSet(ctx, "missing"); !errors.Is(err, ErrSynonymSetNotFound) {
+		t.Errorf("DeleteSynonymSet() error = %v, want ErrSynonymSetNotFound", err)
+	}
+}
+
+// TestExpandSynonyms 测试使用同义词扩展查询语法树
+func TestExpandSynonyms(t *testing.T) {
+	synonyms := map[string][]string{
+		"k8s":         {"kubernetes"},
+		"认证":          {"authentication", "auth"},
+		"spring boot": {"springboot"},
+		"sb":          {"spring boot"},
+	}
+
+	tests := []struct {
+		query      string
+		keywords   string
+		synonyms   string
+		expansions int
+	}{
+		{"k8s deploy", "k8s,deploy", "kubernetes", 1},
+		{"认证 AND k8s", "认证,k8s", "authentication,auth,kubernetes", 2},
+		{`"spring boot" sb`, "spring boot,sb", "springboot,spring boot", 2},
+		{"k8s* deploy", "k8s*,deploy", "", 0},
+		{"deploy -k8s", "deploy", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery(%q) error = %v", tt.query, err)
+			}
+			original := parsed.Text()
+
+			root, expansions := expandSynonyms(parsed.Root, synonyms)
+			expanded := &parsedQuery{Root: root}
+			if got := strings.Join(expanded.Keywords(), ","); got != tt.keywords {
+				t.Errorf("Keywords() = %s, want %s", got, tt.keywords)
+			}
+			if got := strings.Join(expanded.SynonymKeywords(), ","); got != tt.synonyms {
+				t.Errorf("SynonymKeywords() = %s, want %s", got, tt.synonyms)
+			}
+			if len(expansions) != tt.expansions {
+				t.Errorf("expansions = %+v, want %d", expansions, tt.expansions)
+			}
+			if expanded.Text() != original || parsed.Text() != original {
+				t.Error("同义词扩展不应改变原查询文本")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxTokenLength 检索词的最大字节数，过长的词（如哈希、base64）不参与统计
+const maxTokenLength = 64
+
+// tokenize 将文本切分为检索词
+// 英文和数字按连续的字母数字切分并转为小写，中文按相邻两字切分为二元组（单独的汉字保留为单字）
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	var han []rune
+
+	flushWord := func() {
+		if word.Len() > 0 && word.Len() <= maxTokenLength {
+			tokens = append(tokens, word.String())
+		}
+		word.Reset()
+	}
+	flushHan := func() {
+		switch len(han) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(han))
+		default:
+			for i := 0; i+1 < len(han); i++ {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+
+	return tokens
+}
+
+// termFrequencies 统计检索词出现次数
+func termFrequencies(tokens []string) map[string]int {
+	freqs := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		freqs[token]++
+	}
+	return freqs
+}
+
+// containsHan 判断字符串是否包含中文字符
+func containsHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTokenize 测试检索词切分
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"英文转小写", "Hello, World!", []string{"hello", "world"}},
+		{"字母数字混合", "http.Server v1.2", []string{"http", "server", "v1", "2"}},
+		{"中文二元组", "全文检索", []string{"全文", "文检", "检索"}},
+		{"单个汉字", "用 go 写", []string{"用", "go", "写"}},
+		{"中英混合", "安装gin框架", []string{"安装", "gin", "框架"}},
+		{"过长的词被忽略", "a " + strings.Repeat("x", maxTokenLength+1), []string{"a"}},
+		{"空文本", "  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenize(tt.text)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+-- 创建嵌入向量缓存表的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建
+
+CREATE TABLE IF NOT EXISTS embedding_cache (
+    model TEXT NOT NULL,
+    text_hash VARCHAR(64) NOT NULL,
+    vector JSONB NOT NULL,
+    dimension BIGINT,
+    hits BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (model, text_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_last_used_at ON embedding_cache(last_used_at);
+
+-- 添加注释
+COMMENT ON TABLE embedding_cache IS '按嵌入模型和规范化文本的SHA-256缓存的嵌入向量';
+COMMENT ON COLUMN embedding_cache.last_used_at IS '写入或最近一次命中的时间，用于淘汰长期未使用的向量';
@@ -0,0 +1,25 @@
+-- 创建嵌入模型登记表、为搜索索引表添加嵌入模型列的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建；已有分块的模型信息在启动时按向量维度补齐
+
+CREATE TABLE IF NOT EXISTS embedding_models (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    dimension BIGINT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    activated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embedding_models_name ON embedding_models(name);
+CREATE INDEX IF NOT EXISTS idx_embedding_models_active ON embedding_models(active);
+
+ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS embedding_dimension BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_search_indices_embedding_model ON search_indices(embedding_model);
+
+-- 添加注释
+COMMENT ON TABLE embedding_models IS '登记的嵌入模型，同一时间只有一个活动模型';
+COMMENT ON COLUMN search_indices.embedding_model IS '生成向量的嵌入模型，语义搜索只比较活动模型的向量';
+COMMENT ON COLUMN search_indices.embedding_dimension IS '向量维度';
@@ -0,0 +1,36 @@
+-- 创建文档解析和索引任务表的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建
+
+CREATE TABLE IF NOT EXISTS indexing_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    priority BIGINT NOT NULL DEFAULT 0,
+    document_id TEXT,
+    version TEXT,
+    file_path TEXT,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    max_attempts BIGINT NOT NULL DEFAULT 3,
+    last_error TEXT,
+    progress_done BIGINT NOT NULL DEFAULT 0,
+    progress_total BIGINT NOT NULL DEFAULT 0,
+    result JSONB,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    worker_id TEXT,
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_indexing_jobs_type ON indexing_jobs(type);
+CREATE INDEX IF NOT EXISTS idx_indexing_jobs_status ON indexing_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_indexing_jobs_document_id ON indexing_jobs(document_id);
+CREATE INDEX IF NOT EXISTS idx_indexing_jobs_run_at ON indexing_jobs(run_at);
+
+-- 添加注释
+COMMENT ON TABLE indexing_jobs IS '文档解析和索引任务队列，工作进程通过 FOR UPDATE SKIP LOCKED 领取任务';
+COMMENT ON COLUMN indexing_jobs.priority IS '优先级，数值越大越先执行';
+COMMENT ON COLUMN indexing_jobs.run_at IS '最早执行时间，失败重试时按指数退避推后';
+COMMENT ON COLUMN indexing_jobs.heartbeat_at IS '执行中任务的最近心跳，超时的任务会重新放回队列';
@@ -0,0 +1,48 @@
+-- 创建搜索分析表的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建
+
+CREATE TABLE IF NOT EXISTS search_query_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    query TEXT NOT NULL,
+    normalized_query TEXT NOT NULL,
+    search_type TEXT NOT NULL,
+    filters JSONB,
+    library TEXT,
+    result_count BIGINT,
+    result_ids CHARACTER VARYING[],
+    result_document_ids CHARACTER VARYING[],
+    latency_ms BIGINT,
+    source TEXT NOT NULL,
+    caller TEXT,
+    error TEXT,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_query_logs_normalized_query ON search_query_logs(normalized_query);
+CREATE INDEX IF NOT EXISTS idx_search_query_logs_library ON search_query_logs(library);
+CREATE INDEX IF NOT EXISTS idx_search_query_logs_source ON search_query_logs(source);
+CREATE INDEX IF NOT EXISTS idx_search_query_logs_caller ON search_query_logs(caller);
+CREATE INDEX IF NOT EXISTS idx_search_query_logs_created_at ON search_query_logs(created_at);
+
+CREATE TABLE IF NOT EXISTS search_clicks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    query_log_id UUID NOT NULL,
+    target_id TEXT NOT NULL,
+    document_id TEXT,
+    position BIGINT,
+    caller TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_clicks_query_log_id ON search_clicks(query_log_id);
+CREATE INDEX IF NOT EXISTS idx_search_clicks_caller ON search_clicks(caller);
+CREATE INDEX IF NOT EXISTS idx_search_clicks_created_at ON search_clicks(created_at);
+
+-- 添加注释
+COMMENT ON TABLE search_query_logs IS '搜索请求日志，记录 REST 和 MCP 搜索的查询、过滤条件、结果和耗时';
+COMMENT ON COLUMN search_query_logs.normalized_query IS '小写并合并空白后的查询，用于聚合统计';
+COMMENT ON COLUMN search_query_logs.caller IS '调用方：用户ID、MCP API密钥ID或 ip:客户端IP';
+COMMENT ON COLUMN search_query_logs.clicks IS '该次搜索后获取结果内容的次数';
+COMMENT ON TABLE search_clicks IS '搜索结果点击，点击窗口内获取内容的请求关联到同一调用方最近返回该结果的搜索';
+COMMENT ON COLUMN search_clicks.position IS '目标在搜索结果中的排名，从1开始';
@@ -0,0 +1,9 @@
+-- 为搜索索引表添加分块内容哈希的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建；已有分块的哈希为空，增量重建索引时会按内容计算并补齐
+
+ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_search_indices_content_hash ON search_indices(content_hash);
+
+-- 添加注释
+COMMENT ON COLUMN search_indices.content_hash IS '分块内容的SHA-256，内容未变化的分块在重建索引时复用已有向量';
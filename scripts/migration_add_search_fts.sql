@@ -0,0 +1,36 @@
+-- 为搜索索引表添加全文检索支持的迁移脚本
+-- search_vector 列由触发器维护：章节标题权重为 A，正文权重为 B
+
+-- 添加全文检索向量列
+ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- 创建维护全文检索向量的触发器函数
+CREATE OR REPLACE FUNCTION search_indices_search_vector_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', coalesce(NEW.section, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(NEW.content, '')), 'B');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- 创建触发器
+DROP TRIGGER IF EXISTS search_indices_search_vector_update ON search_indices;
+CREATE TRIGGER search_indices_search_vector_update
+    BEFORE INSERT OR UPDATE OF content, section ON search_indices
+    FOR EACH ROW
+    EXECUTE FUNCTION search_indices_search_vector_update();
+
+-- 创建GIN索引
+CREATE INDEX IF NOT EXISTS idx_search_indices_search_vector ON search_indices USING gin(search_vector);
+
+-- 回填已有数据
+UPDATE search_indices
+SET search_vector =
+    setweight(to_tsvector('simple', coalesce(section, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+WHERE search_vector IS NULL;
+
+-- 添加注释
+COMMENT ON COLUMN search_indices.search_vector IS '全文检索向量，由触发器根据章节和内容自动维护';
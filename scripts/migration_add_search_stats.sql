@@ -0,0 +1,22 @@
+-- 创建BM25语料统计表的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建，统计为空时会根据现有索引自动重建
+
+-- 检索词统计表
+CREATE TABLE IF NOT EXISTS search_term_stats (
+    term VARCHAR(255) PRIMARY KEY,
+    doc_freq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 语料统计表（只有一行记录）
+CREATE TABLE IF NOT EXISTS search_corpus_stats (
+    id INTEGER PRIMARY KEY,
+    chunk_count BIGINT NOT NULL DEFAULT 0,
+    total_length BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 添加注释
+COMMENT ON TABLE search_term_stats IS '检索词统计表，记录包含每个检索词的分块数量，用于计算BM25的IDF';
+COMMENT ON COLUMN search_term_stats.doc_freq IS '包含该检索词的分块数量';
+COMMENT ON TABLE search_corpus_stats IS '索引语料统计表，记录分块总数和检索词总数，用于计算平均分块长度';
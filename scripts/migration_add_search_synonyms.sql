@@ -0,0 +1,18 @@
+-- 创建检索同义词组表的迁移脚本
+-- 服务启动时会通过 AutoMigrate 自动创建
+
+CREATE TABLE IF NOT EXISTS search_synonym_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    library TEXT NOT NULL DEFAULT '',
+    terms CHARACTER VARYING[] NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_synonym_sets_library ON search_synonym_sets(library);
+
+-- 添加注释
+COMMENT ON TABLE search_synonym_sets IS '检索同义词组表，组内的词（包括缩写）在关键词检索时互相扩展';
+COMMENT ON COLUMN search_synonym_sets.library IS '所属库，为空表示对所有库生效';
+COMMENT ON COLUMN search_synonym_sets.terms IS '规范化为小写的同义词';
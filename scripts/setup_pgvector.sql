@@ -0,0 +1,26 @@
+-- 设置pgvector向量检索的迁移脚本
+-- 服务启动时会根据嵌入服务的维度自动执行以下步骤，手动执行时请将 1536 替换为实际的嵌入维度
+-- （EMBEDDING_DIMENSIONS，模拟嵌入服务为 384）
+
+-- 创建扩展
+CREATE EXTENSION IF NOT EXISTS vector;
+
+-- 检查已有 embedding 列的维度（vector 类型的 atttypmod 即为维度），与嵌入服务不一致时需要重新生成向量
+SELECT a.atttypmod AS embedding_dimension
+FROM pg_attribute a
+WHERE a.attrelid = 'search_indices'::regclass AND a.attname = 'embedding' AND NOT a.attisdropped;
+
+-- 添加 embedding 列
+ALTER TABLE search_indices ADD COLUMN IF NOT EXISTS embedding vector(1536);
+
+-- 从 JSON 格式的 vector 列回填维度一致的向量
+UPDATE search_indices SET embedding = vector::text::vector
+WHERE embedding IS NULL AND jsonb_typeof(vector) = 'array' AND jsonb_array_length(vector) = 1536;
+
+-- 创建HNSW索引（余弦距离），pgvector 0.5.0 以上版本支持
+CREATE INDEX IF NOT EXISTS idx_search_indices_embedding_hnsw ON search_indices USING hnsw (embedding vector_cosine_ops);
+
+-- 或者使用IVFFlat索引（建议在数据导入后创建，lists 约为行数的 1/1000）
+-- CREATE INDEX IF NOT EXISTS idx_search_indices_embedding_ivfflat ON search_indices USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100);
+
+COMMENT ON COLUMN search_indices.embedding IS '嵌入向量，使用pgvector存储，用于近似最近邻检索';